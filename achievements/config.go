@@ -0,0 +1,126 @@
+package achievements
+
+import (
+	_ "embed"
+	"encoding/json"
+	"opencourse/common/openerrors"
+	"opencourse/events"
+	"os"
+)
+
+/*
+This file contains achievements config. Rules are declared in JSON, so achievements are added without code
+changes. Default rules are embedded into binary and replaced by file from OPENCOURSE_ACHIEVEMENTS.
+*/
+
+//go:embed default.json
+var defaultConfig []byte
+
+// Rule achievement rule. Rule is checked on every event of its type
+type Rule struct {
+	Id           string `json:"id"`                       // Achievement id. Changing of id awards achievement again
+	Name         string `json:"name"`                     // Achievement name
+	Description  string `json:"description"`              // Achievement description
+	Event        string `json:"event"`                    // Event type
+	Count        int    `json:"count,omitempty"`          // Minimum count of events of type for user
+	Value        int    `json:"value,omitempty"`          // Minimum value of event, for example streak days
+	CourseId     string `json:"course_id,omitempty"`      // Rule is checked only for events of course
+	Repeat       bool   `json:"repeat,omitempty"`         // Reward is given for every matched event, not once
+	Xp           int    `json:"xp,omitempty"`             // Rating added to user
+	CourseRating int    `json:"course_rating,omitempty"`  // Points added to course of event. Rating of reviews isn't changed
+	BadgeClassId string `json:"badge_class_id,omitempty"` // Badge awarded to user
+}
+
+// Config achievements config
+type Config struct {
+	Rules []*Rule `json:"rules"` // Achievement rules
+}
+
+/*
+LoadConfig read config from file. Empty path returns default config. Parameters:
+path - path of JSON file;
+*/
+func LoadConfig(path string) (*Config, error) {
+	content := defaultConfig
+
+	if len(path) > 0 {
+		var err error
+
+		content, err = os.ReadFile(path)
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "achievements/config.go",
+					Method: "LoadConfig",
+				},
+				Msg: err.Error(),
+			}
+		}
+	}
+
+	return ParseConfig(content)
+}
+
+/*
+ParseConfig parse and validate config. Parameters:
+content - JSON config;
+*/
+func ParseConfig(content []byte) (*Config, error) {
+	var config Config
+
+	err := json.Unmarshal(content, &config)
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "achievements/config.go",
+				Method: "ParseConfig",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	ids := make(map[string]bool, len(config.Rules))
+
+	for _, rule := range config.Rules {
+		field := ""
+
+		switch {
+		case rule == nil:
+			field = "rules"
+		case len(rule.Id) == 0 || ids[rule.Id]:
+			field = "rule.Id"
+		case len(rule.Name) == 0:
+			field = "rule.Name"
+		case !knownEvent(rule.Event):
+			field = "rule.Event"
+		case rule.Xp == 0 && rule.CourseRating == 0 && len(rule.BadgeClassId) == 0:
+			field = "rule.Xp"
+		}
+
+		if len(field) > 0 {
+			return nil, openerrors.FieldEmptyErr{
+				Field: field,
+				BaseErr: openerrors.BaseErr{
+					File:   "achievements/config.go",
+					Method: "ParseConfig",
+				},
+			}
+		}
+
+		ids[rule.Id] = true
+	}
+
+	return &config, nil
+}
+
+// knownEvent check that event type is published
+func knownEvent(eventType string) bool {
+	switch eventType {
+	case events.TestAnswered, events.TestPassed, events.StageCompleted, events.StreakKept, events.CourseFinished:
+		return true
+	}
+
+	return false
+}
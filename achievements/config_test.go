@@ -0,0 +1,41 @@
+package achievements
+
+import (
+	"opencourse/events"
+	"testing"
+)
+
+// TestDefaultConfig embedded config is valid
+func TestDefaultConfig(t *testing.T) {
+	config, err := LoadConfig("")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(config.Rules) == 0 {
+		t.Error("default config must contain rules")
+	}
+}
+
+// TestParseConfig rules without id, name, known event or reward are rejected
+func TestParseConfig(t *testing.T) {
+	valid := `{"id": "first", "name": "First", "event": "` + events.TestPassed + `", "xp": 10}`
+
+	for content, ok := range map[string]bool{
+		`{"rules": [` + valid + `]}`:                                                          true,
+		`{"rules": [` + valid + `, ` + valid + `]}`:                                           false,
+		`{"rules": [{"name": "First", "event": "test_passed", "xp": 10}]}`:                    false,
+		`{"rules": [{"id": "first", "event": "test_passed", "xp": 10}]}`:                      false,
+		`{"rules": [{"id": "first", "name": "First", "event": "unknown"}]}`:                   false,
+		`{"rules": [{"id": "first", "name": "First", "event": "` + events.TestPassed + `"}]}`: false,
+		`{"rules": [null]}`: false,
+		`{"rules": `:        false,
+	} {
+		_, err := ParseConfig([]byte(content))
+
+		if (err == nil) != ok {
+			t.Errorf("config %s: expected valid %v, got error %v", content, ok, err)
+		}
+	}
+}
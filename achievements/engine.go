@@ -0,0 +1,190 @@
+package achievements
+
+import (
+	"opencourse/common"
+	"opencourse/database"
+	"opencourse/events"
+)
+
+/*
+This file contains achievements engine. Engine counts events of user, keeps daily streak and gives rewards
+of matched rules: rating (XP) of user, rating of course and badges.
+*/
+
+// Engine achievements engine
+type Engine struct {
+	db     *database.DbContext
+	rules  []*Rule
+	byType map[string][]*Rule
+}
+
+/*
+NewEngine return engine with rules of config. Parameters:
+db - database context;
+config - achievements config;
+*/
+func NewEngine(db *database.DbContext, config *Config) *Engine {
+	engine := &Engine{db: db, byType: make(map[string][]*Rule)}
+
+	if config != nil {
+		engine.rules = config.Rules
+	}
+
+	for _, rule := range engine.rules {
+		engine.byType[rule.Event] = append(engine.byType[rule.Event], rule)
+	}
+
+	return engine
+}
+
+/*
+Subscribe subscribe engine to events of learner progress. Parameters:
+bus - events bus;
+*/
+func (engine *Engine) Subscribe(bus *events.Bus) {
+	for _, eventType := range []string{events.TestAnswered, events.TestPassed, events.StageCompleted,
+		events.StreakKept, events.CourseFinished} {
+
+		bus.Subscribe(eventType, engine.Handle)
+	}
+}
+
+/*
+Handle count event and give rewards of matched rules. Activity of user keeps streak and publishes
+streak kept event with the first activity of day. Parameters:
+event - domain event;
+*/
+func (engine *Engine) Handle(event events.Event) error {
+	count, err := engine.db.IncEventCounter(event.UserId, event.Type)
+
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range engine.byType[event.Type] {
+		err = engine.apply(rule, event, count)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if event.Type != events.TestAnswered {
+		return nil
+	}
+
+	date := event.Date
+
+	if date.IsZero() {
+		date = engine.db.Now()
+	}
+
+	streak, kept, err := engine.db.KeepStreak(event.UserId, date)
+
+	if err != nil || !kept {
+		return err
+	}
+
+	return engine.db.Events.Publish(events.Event{
+		Type:   events.StreakKept,
+		UserId: event.UserId,
+		Value:  streak,
+		Date:   date,
+	})
+}
+
+/*
+Achievements return achievements of config with earned flag of user. Parameters:
+userId - user id;
+*/
+func (engine *Engine) Achievements(userId string) ([]*common.Achievement, error) {
+	progress, err := engine.db.GetUserProgress(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	earned := make(map[string]bool)
+
+	if progress != nil {
+		for _, id := range progress.Achievements {
+			earned[id] = true
+		}
+	}
+
+	result := make([]*common.Achievement, 0, len(engine.rules))
+
+	for _, rule := range engine.rules {
+		// Repeated rewards aren't achievements, which are earned
+		if rule.Repeat {
+			continue
+		}
+
+		result = append(result, &common.Achievement{
+			Id:          rule.Id,
+			Name:        rule.Name,
+			Description: rule.Description,
+			Event:       rule.Event,
+			Xp:          rule.Xp,
+			Earned:      earned[rule.Id],
+		})
+	}
+
+	return result, nil
+}
+
+/*
+apply give rewards of rule, if event matches it. Parameters:
+rule - achievement rule;
+event - domain event;
+count - count of events of type for user including event;
+*/
+func (engine *Engine) apply(rule *Rule, event events.Event, count int) error {
+	if len(rule.CourseId) > 0 && rule.CourseId != event.CourseId {
+		return nil
+	}
+
+	if count < rule.Count || event.Value < rule.Value {
+		return nil
+	}
+
+	if !rule.Repeat {
+		added, err := engine.db.AddAchievement(event.UserId, rule.Id)
+
+		if err != nil || !added {
+			return err
+		}
+	}
+
+	if rule.Xp != 0 {
+		err := engine.db.AddUserRating(event.UserId, event.CourseId, rule.Xp)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if rule.CourseRating != 0 && len(event.CourseId) > 0 {
+		err := engine.db.AddCoursePoints(event.CourseId, rule.CourseRating)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(rule.BadgeClassId) > 0 {
+		user, err := engine.db.GetUser(event.UserId)
+
+		if err != nil || user == nil {
+			return err
+		}
+
+		_, _, err = engine.db.AwardBadge(rule.BadgeClassId, event.UserId, user.Email, rule.Description)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package badges
+
+import (
+	"fmt"
+	"opencourse/common"
+	"opencourse/database"
+	"time"
+)
+
+/*
+This file contains award rules of badges. Badge class kind defines milestone: course completed is earned with
+certificate of course, lemmings with count of collected lemmings and streak with days in a row, when user
+answered tests. Badge is awarded once for user.
+*/
+
+const dayLayout = "2006-01-02"
+
+/*
+AwardEarned award every badge, which user earned and doesn't have. Returns new awarded badges. Parameters:
+db - database context;
+userId - user id;
+*/
+func AwardEarned(db *database.DbContext, userId string) ([]*common.BadgeAssertion, error) {
+	badgeClasses, err := db.GetBadgeClasses()
+
+	if err != nil || len(badgeClasses) == 0 {
+		return nil, err
+	}
+
+	user, err := db.GetUser(userId)
+
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	userBadges, err := db.GetUserBadges(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	awarded := make(map[string]bool, len(userBadges))
+
+	for _, badge := range userBadges {
+		awarded[badge.BadgeClassId] = true
+	}
+
+	var certificates map[string]*common.Certificate
+	streak := -1
+
+	var result []*common.BadgeAssertion
+
+	for _, badgeClass := range badgeClasses {
+		if awarded[badgeClass.Id] {
+			continue
+		}
+
+		evidence := ""
+
+		switch badgeClass.Kind {
+		case common.BadgeCourseCompleted:
+			if certificates == nil {
+				certificates, err = userCertificates(db, userId)
+
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if certificate, ok := certificates[badgeClass.CourseId]; ok {
+				evidence = fmt.Sprintf("Completed course %q, certificate %s.", certificate.CourseName, certificate.Code)
+			}
+		case common.BadgeLemmings:
+			if user.Lemmings >= badgeClass.Threshold {
+				evidence = fmt.Sprintf("Collected %d lemmings.", user.Lemmings)
+			}
+		case common.BadgeStreak:
+			if streak < 0 {
+				streak, err = currentStreak(db, userId, maxStreakThreshold(badgeClasses))
+
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if streak >= badgeClass.Threshold {
+				evidence = fmt.Sprintf("Answered tests %d days in a row.", streak)
+			}
+		}
+
+		if len(evidence) == 0 {
+			continue
+		}
+
+		assertion, isNew, err := db.AwardBadge(badgeClass.Id, userId, user.Email, evidence)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if isNew {
+			result = append(result, assertion)
+		}
+	}
+
+	return result, nil
+}
+
+// userCertificates return certificates of user by course id
+func userCertificates(db *database.DbContext, userId string) (map[string]*common.Certificate, error) {
+	certificates, err := db.GetUserCertificates(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byCourse := make(map[string]*common.Certificate, len(certificates))
+
+	for _, certificate := range certificates {
+		byCourse[certificate.CourseId] = certificate
+	}
+
+	return byCourse, nil
+}
+
+// maxStreakThreshold return the longest streak, which is needed by badge classes
+func maxStreakThreshold(badgeClasses []*common.BadgeClass) int {
+	max := 0
+
+	for _, badgeClass := range badgeClasses {
+		if badgeClass.Kind == common.BadgeStreak && badgeClass.Threshold > max {
+			max = badgeClass.Threshold
+		}
+	}
+
+	return max
+}
+
+/*
+currentStreak return count of days in a row, when user answered tests. Streak ends today or yesterday,
+so it isn't broken before the end of day. Parameters:
+db - database context;
+userId - user id;
+limit - the longest streak, which is counted;
+*/
+func currentStreak(db *database.DbContext, userId string, limit int) (int, error) {
+	today := db.Now().UTC().Truncate(24 * time.Hour)
+
+	days, err := db.GetActivityDays(userId, today.AddDate(0, 0, -limit))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return Streak(days, today), nil
+}
+
+/*
+Streak return count of consecutive days, which end today or yesterday. Parameters:
+days - ordered days in format 2006-01-02;
+today - current day;
+*/
+func Streak(days []string, today time.Time) int {
+	active := make(map[string]bool, len(days))
+
+	for _, day := range days {
+		active[day] = true
+	}
+
+	day := today
+
+	if !active[day.Format(dayLayout)] {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	count := 0
+
+	for active[day.Format(dayLayout)] {
+		count++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return count
+}
@@ -0,0 +1,48 @@
+package badges
+
+import (
+	"opencourse/common"
+	"testing"
+	"time"
+)
+
+// TestStreak streak ends today or yesterday and is broken by missed day
+func TestStreak(t *testing.T) {
+	today := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		days   []string
+		streak int
+	}{
+		{nil, 0},
+		{[]string{"2024-03-08", "2024-03-09", "2024-03-10"}, 3},
+		{[]string{"2024-03-08", "2024-03-09"}, 2},
+		{[]string{"2024-03-07", "2024-03-08"}, 0},
+		{[]string{"2024-03-06", "2024-03-08", "2024-03-09", "2024-03-10"}, 3},
+		{[]string{"2024-02-28", "2024-02-29", "2024-03-01"}, 0},
+	}
+
+	for _, c := range cases {
+		if streak := Streak(c.days, today); streak != c.streak {
+			t.Errorf("streak of %v is %d, expected %d", c.days, streak, c.streak)
+		}
+	}
+
+	// Month boundary of leap year
+	if streak := Streak([]string{"2024-02-28", "2024-02-29", "2024-03-01"}, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)); streak != 3 {
+		t.Errorf("streak over month boundary is %d", streak)
+	}
+}
+
+// TestMaxStreakThreshold only streak badge classes are counted
+func TestMaxStreakThreshold(t *testing.T) {
+	badgeClasses := []*common.BadgeClass{
+		{Kind: common.BadgeStreak, Threshold: 7},
+		{Kind: common.BadgeStreak, Threshold: 30},
+		{Kind: common.BadgeLemmings, Threshold: 1000},
+	}
+
+	if max := maxStreakThreshold(badgeClasses); max != 30 {
+		t.Errorf("max threshold is %d", max)
+	}
+}
@@ -0,0 +1,250 @@
+package badges
+
+import (
+	"opencourse/common"
+	"strings"
+	"time"
+)
+
+/*
+This file contains Open Badges documents. Open Badges 2.0 assertions are hosted: verifier fetches assertion
+by its id url, so they aren't signed. Open Badges 3.0 credential is built from the same assertion and is signed
+by Signer.
+*/
+
+const (
+	issuerName = "OpenCourse"
+
+	ob2Context         = "https://w3id.org/openbadges/v2"
+	vcContext          = "https://www.w3.org/2018/credentials/v1"
+	ob3Context         = "https://purl.imsglobal.org/spec/ob/v3p0/context-3.0.3.json"
+	recipientEmailType = "email"
+)
+
+// Issuer Open Badges 2.0 issuer profile
+type Issuer struct {
+	Context string `json:"@context"`
+	Type    string `json:"type"`
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Url     string `json:"url"`
+}
+
+// Criteria criteria of badge class
+type Criteria struct {
+	Narrative string `json:"narrative"`
+}
+
+// BadgeClass Open Badges 2.0 badge class
+type BadgeClass struct {
+	Context     string   `json:"@context"`
+	Type        string   `json:"type"`
+	Id          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Image       string   `json:"image"`
+	Criteria    Criteria `json:"criteria"`
+	Issuer      string   `json:"issuer"`
+}
+
+// Recipient hashed recipient of assertion
+type Recipient struct {
+	Type     string `json:"type"`
+	Hashed   bool   `json:"hashed"`
+	Salt     string `json:"salt"`
+	Identity string `json:"identity"`
+}
+
+// Verification verification method of assertion
+type Verification struct {
+	Type string `json:"type"`
+}
+
+// Evidence evidence of earned badge
+type Evidence struct {
+	Type      []string `json:"type,omitempty"`
+	Narrative string   `json:"narrative"`
+}
+
+// Assertion Open Badges 2.0 assertion
+type Assertion struct {
+	Context          string       `json:"@context"`
+	Type             string       `json:"type"`
+	Id               string       `json:"id"`
+	Recipient        Recipient    `json:"recipient"`
+	Badge            string       `json:"badge"`
+	Verification     Verification `json:"verification"`
+	IssuedOn         string       `json:"issuedOn"`
+	Evidence         []Evidence   `json:"evidence,omitempty"`
+	Revoked          bool         `json:"revoked,omitempty"`
+	RevocationReason string       `json:"revocationReason,omitempty"`
+}
+
+// Profile Open Badges 3.0 issuer profile
+type Profile struct {
+	Id   string   `json:"id"`
+	Type []string `json:"type"`
+	Name string   `json:"name"`
+	Url  string   `json:"url,omitempty"`
+}
+
+// Image Open Badges 3.0 image
+type Image struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// Achievement Open Badges 3.0 achievement
+type Achievement struct {
+	Id          string   `json:"id"`
+	Type        []string `json:"type"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Criteria    Criteria `json:"criteria"`
+	Image       *Image   `json:"image,omitempty"`
+}
+
+// IdentityObject Open Badges 3.0 hashed identity of recipient
+type IdentityObject struct {
+	Type         string `json:"type"`
+	IdentityHash string `json:"identityHash"`
+	IdentityType string `json:"identityType"`
+	Hashed       bool   `json:"hashed"`
+	Salt         string `json:"salt"`
+}
+
+// AchievementSubject Open Badges 3.0 credential subject
+type AchievementSubject struct {
+	Type        []string         `json:"type"`
+	Identifier  []IdentityObject `json:"identifier"`
+	Achievement Achievement      `json:"achievement"`
+}
+
+// Credential Open Badges 3.0 verifiable credential
+type Credential struct {
+	Context           []string           `json:"@context"`
+	Id                string             `json:"id"`
+	Type              []string           `json:"type"`
+	Issuer            Profile            `json:"issuer"`
+	IssuanceDate      string             `json:"issuanceDate"`
+	Name              string             `json:"name"`
+	CredentialSubject AchievementSubject `json:"credentialSubject"`
+	Evidence          []Evidence         `json:"evidence,omitempty"`
+}
+
+// IssuerUrl return url of issuer profile
+func IssuerUrl(baseUrl string) string {
+	return strings.TrimRight(baseUrl, "/") + "/v1/badges/issuer"
+}
+
+// BadgeClassUrl return url of hosted badge class
+func BadgeClassUrl(baseUrl string, badgeClassId string) string {
+	return strings.TrimRight(baseUrl, "/") + "/v1/badges/classes/" + badgeClassId
+}
+
+// AssertionUrl return url of hosted Open Badges 2.0 assertion
+func AssertionUrl(baseUrl string, assertionId string) string {
+	return strings.TrimRight(baseUrl, "/") + "/v1/badges/assertions/" + assertionId
+}
+
+// CredentialUrl return url of hosted Open Badges 3.0 credential
+func CredentialUrl(baseUrl string, assertionId string) string {
+	return AssertionUrl(baseUrl, assertionId) + "/credential"
+}
+
+// NewIssuer return Open Badges 2.0 issuer profile
+func NewIssuer(baseUrl string) *Issuer {
+	return &Issuer{
+		Context: ob2Context,
+		Type:    "Issuer",
+		Id:      IssuerUrl(baseUrl),
+		Name:    issuerName,
+		Url:     baseUrl,
+	}
+}
+
+// NewBadgeClass return Open Badges 2.0 badge class
+func NewBadgeClass(baseUrl string, badgeClass *common.BadgeClass) *BadgeClass {
+	return &BadgeClass{
+		Context:     ob2Context,
+		Type:        "BadgeClass",
+		Id:          BadgeClassUrl(baseUrl, badgeClass.Id),
+		Name:        badgeClass.Name,
+		Description: badgeClass.Description,
+		Image:       badgeClass.Image,
+		Criteria:    Criteria{Narrative: badgeClass.Criteria},
+		Issuer:      IssuerUrl(baseUrl),
+	}
+}
+
+// NewAssertion return Open Badges 2.0 hosted assertion
+func NewAssertion(baseUrl string, assertion *common.BadgeAssertion) *Assertion {
+	result := &Assertion{
+		Context: ob2Context,
+		Type:    "Assertion",
+		Id:      AssertionUrl(baseUrl, assertion.Id),
+		Recipient: Recipient{
+			Type:     recipientEmailType,
+			Hashed:   true,
+			Salt:     assertion.RecipientSalt,
+			Identity: assertion.RecipientIdentity,
+		},
+		Badge:            BadgeClassUrl(baseUrl, assertion.BadgeClassId),
+		Verification:     Verification{Type: "hosted"},
+		IssuedOn:         assertion.IssuedOn.UTC().Format(time.RFC3339),
+		Revoked:          assertion.Revoked,
+		RevocationReason: assertion.RevocationReason,
+	}
+
+	if len(assertion.Evidence) > 0 {
+		result.Evidence = []Evidence{{Narrative: assertion.Evidence}}
+	}
+
+	return result
+}
+
+// NewCredential return Open Badges 3.0 credential for assertion
+func NewCredential(baseUrl string, assertion *common.BadgeAssertion, badgeClass *common.BadgeClass) *Credential {
+	achievement := Achievement{
+		Id:          BadgeClassUrl(baseUrl, badgeClass.Id),
+		Type:        []string{"Achievement"},
+		Name:        badgeClass.Name,
+		Description: badgeClass.Description,
+		Criteria:    Criteria{Narrative: badgeClass.Criteria},
+	}
+
+	if len(badgeClass.Image) > 0 {
+		achievement.Image = &Image{Id: badgeClass.Image, Type: "Image"}
+	}
+
+	credential := &Credential{
+		Context: []string{vcContext, ob3Context},
+		Id:      CredentialUrl(baseUrl, assertion.Id),
+		Type:    []string{"VerifiableCredential", "OpenBadgeCredential"},
+		Issuer: Profile{
+			Id:   IssuerUrl(baseUrl),
+			Type: []string{"Profile"},
+			Name: issuerName,
+			Url:  baseUrl,
+		},
+		IssuanceDate: assertion.IssuedOn.UTC().Format(time.RFC3339),
+		Name:         badgeClass.Name,
+		CredentialSubject: AchievementSubject{
+			Type: []string{"AchievementSubject"},
+			Identifier: []IdentityObject{{
+				Type:         "IdentityObject",
+				IdentityHash: assertion.RecipientIdentity,
+				IdentityType: "emailAddress",
+				Hashed:       true,
+				Salt:         assertion.RecipientSalt,
+			}},
+			Achievement: achievement,
+		},
+	}
+
+	if len(assertion.Evidence) > 0 {
+		credential.Evidence = []Evidence{{Type: []string{"Evidence"}, Narrative: assertion.Evidence}}
+	}
+
+	return credential
+}
@@ -0,0 +1,216 @@
+package badges
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"opencourse/common/openerrors"
+	"os"
+	"time"
+)
+
+/*
+This file contains signing of Open Badges 3.0 credentials. Credential is a verifiable credential, so it's
+returned as VC-JWT signed by RS256 issuer key. Public key is published as JWK set of issuer.
+*/
+
+// CredentialMediaType media type of signed Open Badges 3.0 credential
+const CredentialMediaType = "application/vc+ld+json+jwt"
+
+// Signer signs Open Badges 3.0 credentials with issuer RSA key
+type Signer struct {
+	key       jwk.Key // Private key
+	publicKey jwk.Key // Public key published in JWK set
+}
+
+/*
+LoadSigner return signer with RSA private key from PEM file. PKCS #1 and PKCS #8 keys are supported. Parameters:
+path - path of PEM file;
+*/
+func LoadSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "badges/signer.go",
+				Method: "LoadSigner",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "badges/signer.go",
+				Method: "LoadSigner",
+			},
+			Msg: "PEM block isn't found",
+		}
+	}
+
+	privateKey, err := parseRsaKey(block.Bytes)
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "badges/signer.go",
+				Method: "LoadSigner",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return NewSigner(privateKey)
+}
+
+/*
+NewSigner return signer with RSA private key. Key id is RFC 7638 thumbprint of public key. Parameters:
+privateKey - issuer private key;
+*/
+func NewSigner(privateKey *rsa.PrivateKey) (*Signer, error) {
+	key, err := jwk.New(privateKey)
+
+	if err == nil {
+		err = setKeyId(key)
+	}
+
+	var publicKey jwk.Key
+
+	if err == nil {
+		publicKey, err = jwk.PublicKeyOf(key)
+	}
+
+	if err == nil {
+		err = publicKey.Set(jwk.AlgorithmKey, jwa.RS256)
+	}
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "badges/signer.go",
+				Method: "NewSigner",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return &Signer{key: key, publicKey: publicKey}, nil
+}
+
+// KeySet return JWK set with public key of issuer
+func (signer *Signer) KeySet() jwk.Set {
+	set := jwk.NewSet()
+	set.Add(signer.publicKey)
+
+	return set
+}
+
+/*
+Sign return credential as compact VC-JWT. Registered claims are set from credential, so verifier checks
+issuer, id and issuance date of signed token. Parameters:
+credential - Open Badges 3.0 credential;
+*/
+func (signer *Signer) Sign(credential *Credential) (string, error) {
+	issuanceDate, err := time.Parse(time.RFC3339, credential.IssuanceDate)
+
+	if err != nil {
+		return "", openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "badges/signer.go",
+				Method: "Sign",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	token := jwt.New()
+	headers := jws.NewHeaders()
+
+	for _, claim := range []struct {
+		name  string
+		value interface{}
+	}{
+		{jwt.IssuerKey, credential.Issuer.Id},
+		{jwt.JwtIDKey, credential.Id},
+		{jwt.NotBeforeKey, issuanceDate},
+		{jwt.IssuedAtKey, issuanceDate},
+		{"vc", credential},
+	} {
+		if err == nil {
+			err = token.Set(claim.name, claim.value)
+		}
+	}
+
+	if err == nil {
+		err = headers.Set(jws.KeyIDKey, signer.publicKey.KeyID())
+	}
+
+	if err == nil {
+		err = headers.Set(jws.JWKKey, signer.publicKey)
+	}
+
+	if err == nil {
+		err = headers.Set(jws.TypeKey, "JWT")
+	}
+
+	var signed []byte
+
+	if err == nil {
+		signed, err = jwt.Sign(token, jwa.RS256, signer.key, jwt.WithHeaders(headers))
+	}
+
+	if err != nil {
+		return "", openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "badges/signer.go",
+				Method: "Sign",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return string(signed), nil
+}
+
+// parseRsaKey parse PKCS #1 or PKCS #8 RSA private key
+func parseRsaKey(der []byte) (*rsa.PrivateKey, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+
+	if !ok {
+		return nil, errors.New("key isn't RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// setKeyId set thumbprint of key as its id
+func setKeyId(key jwk.Key) error {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+
+	if err != nil {
+		return err
+	}
+
+	return key.Set(jwk.KeyIDKey, base64.RawURLEncoding.EncodeToString(thumbprint))
+}
@@ -0,0 +1,137 @@
+package badges
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"opencourse/common"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testCredential() *Credential {
+	assertion := &common.BadgeAssertion{
+		Id:                "a1",
+		BadgeClassId:      "c1",
+		RecipientIdentity: "sha256$abc",
+		RecipientSalt:     "salt",
+		IssuedOn:          time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	return NewCredential("https://example.com/", assertion, &common.BadgeClass{Id: "c1", Name: "Streak"})
+}
+
+// TestSign credential is verified by published public key
+func TestSign(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := NewSigner(privateKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credential := testCredential()
+	signed, err := signer.Sign(credential)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, ok := signer.KeySet().Get(0)
+
+	if !ok {
+		t.Fatal("key set is empty")
+	}
+
+	token, err := jwt.Parse([]byte(signed), jwt.WithVerify(jwa.RS256, publicKey))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.Issuer() != "https://example.com/v1/badges/issuer" || token.JwtID() != credential.Id ||
+		!token.NotBefore().Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected claims %v %v %v", token.Issuer(), token.JwtID(), token.NotBefore())
+	}
+
+	vc, _ := token.Get("vc")
+	data, _ := json.Marshal(vc)
+
+	var claimed Credential
+
+	if err = json.Unmarshal(data, &claimed); err != nil || !reflect.DeepEqual(&claimed, credential) {
+		t.Errorf("unexpected vc claim %s", data)
+	}
+
+	message, err := jws.Parse([]byte(signed))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if kid := message.Signatures()[0].ProtectedHeaders().KeyID(); kid != publicKey.KeyID() || len(kid) == 0 {
+		t.Errorf("unexpected key id %q", kid)
+	}
+
+	if _, ok := publicKey.(jwk.RSAPublicKey); !ok {
+		t.Error("private key is published")
+	}
+}
+
+// TestLoadSigner PKCS #1 and PKCS #8 keys are loaded
+func TestLoadSigner(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := map[string]*pem.Block{
+		"pkcs1.pem": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)},
+		"pkcs8.pem": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	}
+
+	dir := t.TempDir()
+
+	for name, block := range blocks {
+		path := filepath.Join(dir, name)
+
+		if err = os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = LoadSigner(path); err != nil {
+			t.Errorf("%s isn't loaded: %v", name, err)
+		}
+	}
+
+	path := filepath.Join(dir, "empty.pem")
+
+	if err = os.WriteFile(path, []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = LoadSigner(path); err == nil {
+		t.Error("file without PEM block must return error")
+	}
+}
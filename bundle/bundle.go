@@ -0,0 +1,515 @@
+package bundle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"path"
+	"strings"
+)
+
+/*
+This file contains course bundle format. Bundle is a JSON document (course.json) or
+a ZIP archive with course.json and media files stored in media/ folder.
+*/
+
+const (
+	CourseFile  = "course.json" // Bundle document file name in archive
+	MediaFolder = "media/"      // Folder for media files in archive
+)
+
+// Limits of uncompressed content of archive. Upload is limited by compressed size, which doesn't limit its content
+var (
+	MaxCourseFileSize int64 = 16 << 20  // Max size of course.json
+	MaxMediaFileSize  int64 = 64 << 20  // Max size of media file
+	MaxMediaSize      int64 = 512 << 20 // Max size of all media files of bundle
+)
+
+// ErrTooLarge error of archive file, which is larger than its limit
+var ErrTooLarge = errors.New("file is too large")
+
+/*
+Validate check structure of bundle before import. Content of tests is validated by database on import
+with the same rules as new tests. Parameters:
+bundle - course bundle;
+*/
+func Validate(bundle *common.CourseBundle) error {
+	if bundle == nil || bundle.Course == nil {
+		return openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "bundle/bundle.go",
+				Method: "Validate",
+			},
+			Model: "bundle",
+		}
+	}
+
+	if bundle.Version < 1 || bundle.Version > common.BundleVersion {
+		return openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "bundle/bundle.go",
+				Method: "Validate",
+			},
+			Msg: fmt.Sprintf("unsupported bundle version %d", bundle.Version),
+		}
+	}
+
+	if len(bundle.Id) == 0 {
+		return fieldEmptyErr("bundle.Id")
+	}
+
+	if len(bundle.Course.Id) == 0 {
+		return fieldEmptyErr("bundle.Course.Id")
+	}
+
+	if len(bundle.Course.Name) < 2 {
+		return fieldEmptyErr("bundle.Course.Name")
+	}
+
+	// ids are used for idempotent import, so they must be unique in bundle
+	ids := map[string]bool{bundle.Course.Id: true}
+
+	for i, bundleStage := range bundle.Stages {
+		if bundleStage == nil || bundleStage.Stage == nil {
+			return fieldEmptyErr(fmt.Sprintf("bundle.Stages[%d]", i))
+		}
+
+		stage := bundleStage.Stage
+
+		if len(stage.Id) == 0 || ids[stage.Id] {
+			return fieldEmptyErr(fmt.Sprintf("bundle.Stages[%d].Id", i))
+		}
+
+		ids[stage.Id] = true
+
+		if len(stage.Name) < 2 {
+			return fieldEmptyErr(fmt.Sprintf("bundle.Stages[%d].Name", i))
+		}
+
+		if stage.Content == nil {
+			return fieldEmptyErr(fmt.Sprintf("bundle.Stages[%d].Content", i))
+		}
+
+		for j, test := range bundleStage.Tests {
+			field := fmt.Sprintf("bundle.Stages[%d].Tests[%d]", i, j)
+
+			if test == nil {
+				return fieldEmptyErr(field)
+			}
+
+			if len(test.Id) == 0 || ids[test.Id] {
+				return fieldEmptyErr(field + ".Id")
+			}
+
+			ids[test.Id] = true
+		}
+	}
+
+	for _, media := range bundle.Media {
+		if _, ok := CleanMediaPath(media); !ok {
+			return openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "bundle/bundle.go",
+					Method: "Validate",
+				},
+				Msg: fmt.Sprintf("invalid media path %s", media),
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+WriteJSON write bundle as JSON document without media files. Parameters:
+writer - output;
+bundle - course bundle;
+*/
+func WriteJSON(writer io.Writer, bundle *common.CourseBundle) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(bundle)
+
+	if err != nil {
+		return openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "bundle/bundle.go",
+				Method: "WriteJSON",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+ReadJSON read and validate bundle from JSON document. Parameters:
+reader - input;
+*/
+func ReadJSON(reader io.Reader) (*common.CourseBundle, error) {
+	var bundle common.CourseBundle
+
+	err := json.NewDecoder(reader).Decode(&bundle)
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "bundle/bundle.go",
+				Method: "ReadJSON",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	err = Validate(&bundle)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &bundle, nil
+}
+
+/*
+WriteZip write bundle as ZIP archive with media files from store. Media items which are not
+found in store (external links) stay in stages as references. Parameters:
+writer - output;
+bundle - course bundle;
+store - media store. Optional, may be nil;
+*/
+func WriteZip(writer io.Writer, bundle *common.CourseBundle, store MediaStore) error {
+	archive := zip.NewWriter(writer)
+
+	bundle.Media = nil
+
+	if store != nil {
+		for _, media := range collectMedia(bundle) {
+			err := writeMedia(archive, store, media)
+
+			if err == ErrMediaNotFound {
+				continue
+			}
+
+			if err != nil {
+				return openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "bundle/bundle.go",
+						Method: "WriteZip",
+					},
+					Msg: err.Error(),
+				}
+			}
+
+			bundle.Media = append(bundle.Media, media)
+		}
+	}
+
+	file, err := archive.Create(CourseFile)
+
+	if err != nil {
+		return openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "bundle/bundle.go",
+				Method: "WriteZip",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	err = WriteJSON(file, bundle)
+
+	if err != nil {
+		return err
+	}
+
+	err = archive.Close()
+
+	if err != nil {
+		return openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "bundle/bundle.go",
+				Method: "WriteZip",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+ReadZip read and validate bundle from ZIP archive. Media files are saved by SaveMedia after import. Parameters:
+reader - input;
+size - archive size;
+*/
+func ReadZip(reader io.ReaderAt, size int64) (*common.CourseBundle, error) {
+	archive, err := zip.NewReader(reader, size)
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "bundle/bundle.go",
+				Method: "ReadZip",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	file, err := openEntry(archive, CourseFile, MaxCourseFileSize)
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "bundle/bundle.go",
+				Method: "ReadZip",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	bundle, err := ReadJSON(limitReader(file, MaxCourseFileSize))
+	_ = file.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Declared sizes are checked before import, actual sizes are checked by SaveMedia
+	var total int64
+
+	for _, media := range bundle.Media {
+		clean, _ := CleanMediaPath(media)
+
+		info, err := fs.Stat(archive, MediaFolder+clean)
+
+		if err == nil {
+			total += info.Size()
+
+			if info.Size() > MaxMediaFileSize || total > MaxMediaSize {
+				err = fmt.Errorf("%s: %w", MediaFolder+clean, ErrTooLarge)
+			}
+		}
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "bundle/bundle.go",
+					Method: "ReadZip",
+				},
+				Msg: err.Error(),
+			}
+		}
+	}
+
+	return bundle, nil
+}
+
+/*
+SaveMedia save media files of bundle from ZIP archive to store. Parameters:
+reader - input;
+size - archive size;
+bundle - bundle read from archive;
+store - media store of imported course;
+*/
+func SaveMedia(reader io.ReaderAt, size int64, bundle *common.CourseBundle, store MediaStore) error {
+	archive, err := zip.NewReader(reader, size)
+
+	if err != nil {
+		return openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "bundle/bundle.go",
+				Method: "SaveMedia",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	left := MaxMediaSize
+
+	for _, media := range bundle.Media {
+		var read int64
+
+		read, err = readMedia(archive, store, media, left)
+		left -= read
+
+		if err != nil {
+			return openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "bundle/bundle.go",
+					Method: "SaveMedia",
+				},
+				Msg: err.Error(),
+			}
+		}
+	}
+
+	return nil
+}
+
+// CleanMediaPath return relative media path without parent references. Returns false for external links
+func CleanMediaPath(media string) (string, bool) {
+	if strings.Contains(media, "://") {
+		return "", false
+	}
+
+	clean := path.Clean("/" + strings.ReplaceAll(media, "\\", "/"))
+	clean = strings.TrimPrefix(clean, "/")
+
+	if len(clean) == 0 || clean == "." {
+		return "", false
+	}
+
+	return clean, true
+}
+
+// collectMedia return unique local media paths from course and stages
+func collectMedia(bundle *common.CourseBundle) []string {
+	var result []string
+	unique := make(map[string]bool)
+
+	add := func(media string) {
+		clean, ok := CleanMediaPath(media)
+
+		if ok && !unique[clean] {
+			unique[clean] = true
+			result = append(result, clean)
+		}
+	}
+
+	add(bundle.Course.IconImg)
+	add(bundle.Course.HeaderImg)
+
+	for _, bundleStage := range bundle.Stages {
+		add(bundleStage.Stage.HeaderImg)
+
+		if bundleStage.Stage.Content != nil {
+			for _, media := range bundleStage.Stage.Content.MediaItems {
+				add(media)
+			}
+		}
+	}
+
+	return result
+}
+
+func writeMedia(archive *zip.Writer, store MediaStore, media string) error {
+	reader, err := store.Open(media)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	file, err := archive.Create(MediaFolder + media)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, reader)
+
+	return err
+}
+
+/*
+readMedia save media file from archive to store and return count of read bytes. Parameters:
+archive - ZIP archive;
+store - media store;
+media - media path;
+left - size left for media files of bundle;
+*/
+func readMedia(archive *zip.Reader, store MediaStore, media string, left int64) (int64, error) {
+	clean, _ := CleanMediaPath(media)
+
+	limit := MaxMediaFileSize
+
+	if left < limit {
+		limit = left
+	}
+
+	file, err := openEntry(archive, MediaFolder+clean, limit)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	reader := limitReader(file, limit)
+	err = store.Save(clean, reader)
+
+	if errors.Is(err, ErrTooLarge) {
+		err = fmt.Errorf("%s: %w", MediaFolder+clean, err)
+	}
+
+	return reader.read, err
+}
+
+// openEntry open file of archive, if its declared size isn't larger than limit
+func openEntry(archive *zip.Reader, name string, limit int64) (fs.File, error) {
+	file, err := archive.Open(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+
+	if err == nil && info.Size() > limit {
+		err = fmt.Errorf("%s: %w", name, ErrTooLarge)
+	}
+
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// sizeReader reader, which fails with ErrTooLarge, if source is larger than limit. Declared size of archive file
+// may be wrong, so read bytes are counted
+type sizeReader struct {
+	reader io.Reader // Source limited by limit and one byte
+	limit  int64     // Max count of bytes
+	read   int64     // Count of read bytes
+}
+
+// limitReader return reader of source, which fails with ErrTooLarge after limit bytes
+func limitReader(source io.Reader, limit int64) *sizeReader {
+	return &sizeReader{reader: io.LimitReader(source, limit+1), limit: limit}
+}
+
+// Read read from source and count bytes
+func (reader *sizeReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.read += int64(n)
+
+	if reader.read > reader.limit {
+		return n, ErrTooLarge
+	}
+
+	return n, err
+}
+
+func fieldEmptyErr(field string) error {
+	return openerrors.FieldEmptyErr{
+		BaseErr: openerrors.BaseErr{
+			File:   "bundle/bundle.go",
+			Method: "Validate",
+		},
+		Field: field,
+	}
+}
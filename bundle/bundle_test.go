@@ -0,0 +1,291 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"opencourse/common"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func getBundle() *common.CourseBundle {
+	return &common.CourseBundle{
+		Version: common.BundleVersion,
+		Id:      "bundle",
+		Course:  &common.Course{Id: "course", Name: "The greatest golang", IconImg: "img/icon.png"},
+		Stages: []*common.BundleStage{
+			{
+				Stage: &common.Stage{Id: "stage", Name: "Basics", Content: &common.PostContent{
+					Body:       "Hello",
+					MediaItems: []string{"img/../img/stage.png", "https://example.com/remote.png"},
+				}},
+				Tests: []*common.Test{{Id: "test", TestType: common.TestRewrite, LemmingsCount: 1}},
+			},
+		},
+	}
+}
+
+// TestValidate
+func TestValidate(t *testing.T) {
+	err := Validate(getBundle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duplicate := getBundle()
+	duplicate.Stages[0].Tests[0].Id = "stage"
+
+	if Validate(duplicate) == nil {
+		t.Fatal("duplicate id must be rejected")
+	}
+
+	version := getBundle()
+	version.Version = common.BundleVersion + 1
+
+	if Validate(version) == nil {
+		t.Fatal("unsupported version must be rejected")
+	}
+
+	// Content of tests is validated by database
+	content := getBundle()
+	content.Stages[0].Tests[0].RewriteTest = nil
+
+	if Validate(content) != nil {
+		t.Fatal("test content mustn't be validated by bundle")
+	}
+}
+
+// TestCleanMediaPath
+func TestCleanMediaPath(t *testing.T) {
+	cases := map[string]string{
+		"img/a.png":        "img/a.png",
+		"../../etc/passwd": "etc/passwd",
+		"img\\..\\b.png":   "b.png",
+		"/abs/c.png":       "abs/c.png",
+	}
+
+	for media, expected := range cases {
+		clean, ok := CleanMediaPath(media)
+
+		if !ok || clean != expected {
+			t.Fatalf("media %s: expected %s, got %s", media, expected, clean)
+		}
+	}
+
+	for _, media := range []string{"https://example.com/a.png", "", ".", "/"} {
+		if _, ok := CleanMediaPath(media); ok {
+			t.Fatalf("media %q must be rejected", media)
+		}
+	}
+}
+
+// TestZipMedia
+func TestZipMedia(t *testing.T) {
+	source := CourseStore(t.TempDir(), "source")
+
+	for name, content := range map[string]string{"img/icon.png": "icon", "img/stage.png": "stage"} {
+		err := source.Save(name, bytes.NewBufferString(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buffer bytes.Buffer
+
+	err := WriteZip(&buffer, getBundle(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	courseBundle, err := ReadZip(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(courseBundle.Media) != 2 {
+		t.Fatalf("expected 2 media files, got %v", courseBundle.Media)
+	}
+
+	mediaDir := t.TempDir()
+
+	// File of other course with the same name isn't replaced
+	other := CourseStore(mediaDir, "other")
+
+	err = other.Save("img/icon.png", bytes.NewBufferString("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := CourseStore(mediaDir, "target")
+
+	err = SaveMedia(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), courseBundle, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for store, expected := range map[DirStore]string{target: "icon", other: "other"} {
+		reader, err := store.Open("img/icon.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(reader)
+		_ = reader.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(content) != expected {
+			t.Fatalf("expected %s, got %s", expected, content)
+		}
+	}
+}
+
+// TestCourseStore
+func TestCourseStore(t *testing.T) {
+	mediaDir := t.TempDir()
+
+	store := CourseStore(mediaDir, "../escape")
+
+	if filepath.Dir(store.Dir) != filepath.Join(mediaDir, "courses") {
+		t.Fatalf("course store %s is outside of media directory", store.Dir)
+	}
+
+	if CourseStore("", "course").Dir != "" {
+		t.Fatal("store without media directory must be empty")
+	}
+
+	if _, err := os.Stat(filepath.Join(mediaDir, "courses")); !os.IsNotExist(err) {
+		t.Fatal("course store mustn't create directories before save")
+	}
+}
+
+/*
+writeArchive return ZIP archive with bundle and files. Parameters:
+courseBundle - bundle, which is written to course.json;
+files - files of archive by names;
+*/
+func writeArchive(t *testing.T, courseBundle *common.CourseBundle, files map[string][]byte) []byte {
+	var buffer bytes.Buffer
+
+	archive := zip.NewWriter(&buffer)
+
+	data, err := json.Marshal(courseBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files[CourseFile] = data
+
+	for name, content := range files {
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = file.Write(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// TestZipLimits archive with course file or media files, which are larger than limits, is rejected
+func TestZipLimits(t *testing.T) {
+	defer func(course int64, file int64, total int64) {
+		MaxCourseFileSize, MaxMediaFileSize, MaxMediaSize = course, file, total
+	}(MaxCourseFileSize, MaxMediaFileSize, MaxMediaSize)
+
+	MaxMediaFileSize, MaxMediaSize = 1024, 1536
+
+	courseBundle := getBundle()
+	courseBundle.Media = []string{"img/icon.png", "img/stage.png"}
+
+	for _, item := range []struct {
+		icon  int
+		stage int
+		valid bool
+	}{
+		{1024, 512, true},
+		{1025, 0, false},
+		{1024, 513, false},
+	} {
+		data := writeArchive(t, courseBundle, map[string][]byte{
+			MediaFolder + "img/icon.png":  make([]byte, item.icon),
+			MediaFolder + "img/stage.png": make([]byte, item.stage),
+		})
+
+		_, err := ReadZip(bytes.NewReader(data), int64(len(data)))
+
+		if (err == nil) != item.valid {
+			t.Errorf("media of %d and %d bytes: expected valid %v, got %v", item.icon, item.stage, item.valid, err)
+		}
+
+		store := CourseStore(t.TempDir(), "course")
+
+		err = SaveMedia(bytes.NewReader(data), int64(len(data)), courseBundle, store)
+
+		if (err == nil) != item.valid {
+			t.Errorf("media of %d and %d bytes: expected saved %v, got %v", item.icon, item.stage, item.valid, err)
+		}
+	}
+
+	MaxCourseFileSize = 64
+
+	data := writeArchive(t, courseBundle, map[string][]byte{})
+
+	_, err := ReadZip(bytes.NewReader(data), int64(len(data)))
+
+	if err == nil {
+		t.Error("course file larger than limit must be rejected")
+	}
+}
+
+// TestSaveTooLarge media file, which is larger than declared size, isn't saved and partial file is removed
+func TestSaveTooLarge(t *testing.T) {
+	store := CourseStore(t.TempDir(), "course")
+
+	err := store.Save("img/icon.png", bytes.NewBufferString("icon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Save("img/icon.png", limitReader(bytes.NewReader(make([]byte, 2048)), 1024))
+
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(store.Dir, "img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("partial file must be removed, got %d files", len(entries))
+	}
+
+	reader, err := store.Open("img/icon.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := io.ReadAll(reader)
+	_ = reader.Close()
+
+	if err != nil || string(content) != "icon" {
+		t.Errorf("failed save mustn't change existing file, got %q, %v", content, err)
+	}
+}
@@ -0,0 +1,105 @@
+package bundle
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrMediaNotFound error of store, if media file isn't found. External links are not found too
+var ErrMediaNotFound = errors.New("media file is not found")
+
+// MediaStore storage for course media files. Names are relative slash separated paths
+type MediaStore interface {
+	Open(name string) (io.ReadCloser, error)  // Open media file for read
+	Save(name string, reader io.Reader) error // Create or replace media file
+}
+
+// DirStore media store in local directory
+type DirStore struct {
+	Dir string // Root directory for media files
+}
+
+/*
+CourseStore return store of course media files. Every course has own folder, so import of one course
+doesn't replace files of other courses. Parameters:
+dir - root directory for media files. Store is empty, if it isn't set;
+courseId - course id;
+*/
+func CourseStore(dir string, courseId string) DirStore {
+	if len(dir) == 0 || len(courseId) == 0 {
+		return DirStore{}
+	}
+
+	return DirStore{Dir: filepath.Join(dir, "courses", filepath.Base(courseId))}
+}
+
+// Open media file from directory
+func (store DirStore) Open(name string) (io.ReadCloser, error) {
+	clean, ok := CleanMediaPath(name)
+
+	if !ok || len(store.Dir) == 0 {
+		return nil, ErrMediaNotFound
+	}
+
+	file, err := os.Open(filepath.Join(store.Dir, filepath.FromSlash(clean)))
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrMediaNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Save media file to directory. File is written to temporary file and renamed, so failed save doesn't leave
+// partial file and doesn't change existing one
+func (store DirStore) Save(name string, reader io.Reader) error {
+	clean, ok := CleanMediaPath(name)
+
+	if !ok || len(store.Dir) == 0 {
+		return ErrMediaNotFound
+	}
+
+	fullPath := filepath.Join(store.Dir, filepath.FromSlash(clean))
+
+	err := os.MkdirAll(filepath.Dir(fullPath), 0755)
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(fullPath), ".save-*")
+
+	if err != nil {
+		return err
+	}
+
+	// Temporary file is private, media file has mode of created files
+	err = file.Chmod(0644)
+
+	if err == nil {
+		_, err = io.Copy(file, reader)
+	}
+
+	closeErr := file.Close()
+
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), fullPath)
+	}
+
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
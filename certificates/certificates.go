@@ -0,0 +1,166 @@
+package certificates
+
+import (
+	"opencourse/common"
+	"opencourse/database"
+	"opencourse/events"
+	"opencourse/selection"
+)
+
+/*
+This file contains course completion rules. Course is completed, when every stage is completed:
+all tests drawn for learner are passed, and for timed stage a quiz session is passed. Stage without tests
+is completed. Certificate is issued once for user and course.
+*/
+
+/*
+Completed check that user completed every stage of course. Parameters:
+db - database context;
+userId - user id;
+courseId - course id;
+*/
+func Completed(db *database.DbContext, userId string, courseId string) (bool, error) {
+	stages, err := db.GetStages(courseId, 0, 0)
+
+	if err != nil {
+		return false, err
+	}
+
+	if len(stages) == 0 {
+		return false, nil
+	}
+
+	passed, err := db.GetPassedTests(userId, courseId)
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, preview := range stages {
+		stage, err := db.GetStage(preview.Id)
+
+		if err != nil {
+			return false, err
+		}
+
+		// Stage is deleted after list is read
+		if stage == nil {
+			continue
+		}
+
+		completed, err := stageCompleted(db, userId, stage, passed)
+
+		if err != nil || !completed {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+/*
+StageCompleted check that user completed stage. Parameters:
+db - database context;
+userId - user id;
+stage - stage of course;
+*/
+func StageCompleted(db *database.DbContext, userId string, stage *common.Stage) (bool, error) {
+	passed, err := db.GetPassedTests(userId, stage.CourseId)
+
+	if err != nil {
+		return false, err
+	}
+
+	return stageCompleted(db, userId, stage, passed)
+}
+
+/*
+stageCompleted check that user completed stage. Parameters:
+db - database context;
+userId - user id;
+stage - stage of course;
+passed - passed tests of course;
+*/
+func stageCompleted(db *database.DbContext, userId string, stage *common.Stage, passed map[string]bool) (bool, error) {
+	if stage.TimeLimit > 0 {
+		tests, err := db.GetStageTests(stage.Id)
+
+		if err != nil {
+			return false, err
+		}
+
+		if len(tests) == 0 {
+			return true, nil
+		}
+
+		return db.HasPassedSession(userId, stage.Id)
+	}
+
+	_, tests, err := selection.Select(db, userId, stage, "")
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, test := range tests {
+		if !passed[test.Id] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+/*
+IssueIfCompleted issue certificate, if user completed course. Returns nil, if course isn't completed,
+and issued certificate, if it was issued before. The first issue publishes course finished event. Parameters:
+db - database context;
+userId - user id;
+courseId - course id;
+*/
+func IssueIfCompleted(db *database.DbContext, userId string, courseId string) (*common.Certificate, error) {
+	completed, err := Completed(db, userId, courseId)
+
+	if err != nil || !completed {
+		return nil, err
+	}
+
+	user, err := db.GetUser(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	course, err := db.GetCourse(courseId)
+
+	if err != nil || course == nil {
+		return nil, err
+	}
+
+	userName := ""
+
+	if user != nil {
+		userName = user.Name
+	}
+
+	certificate, issued, err := db.IssueCertificate(userId, courseId, userName, course.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if issued {
+		err = db.Events.Publish(events.Event{
+			Type:     events.CourseFinished,
+			UserId:   userId,
+			CourseId: courseId,
+			Date:     certificate.DateIssue,
+		})
+
+		if err != nil {
+			return certificate, err
+		}
+	}
+
+	return certificate, nil
+}
@@ -0,0 +1,104 @@
+package certificates
+
+import (
+	"bytes"
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+)
+
+/*
+This file contains PDF rendering of certificates. Go fonts are embedded as UTF-8 fonts, so names and courses
+in Latin, Cyrillic and Greek scripts are printed as is.
+*/
+
+// fontFamily family of embedded Go fonts
+const fontFamily = "Go"
+
+/*
+Render return certificate as one page landscape A4 PDF. Parameters:
+certificate - issued certificate;
+verifyUrl - url of public verification page. Empty url isn't printed;
+*/
+func Render(certificate *common.Certificate, verifyUrl string) ([]byte, error) {
+	if certificate == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "certificates/pdf.go",
+				Method: "Render",
+			},
+			Model: "certificate",
+		}
+	}
+
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(fontFamily, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(fontFamily, "B", gobold.TTF)
+	pdf.AddUTF8FontFromBytes(fontFamily, "BI", gobolditalic.TTF)
+
+	width, height := pdf.GetPageSize()
+
+	pdf.SetTitle("Certificate "+certificate.Code, true)
+	pdf.SetCreator("OpenCourse", true)
+	pdf.SetCreationDate(certificate.DateIssue)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	// Double frame
+	pdf.SetDrawColor(40, 70, 120)
+	pdf.SetLineWidth(1.5)
+	pdf.Rect(10, 10, width-20, height-20, "D")
+	pdf.SetLineWidth(0.5)
+	pdf.Rect(14, 14, width-28, height-28, "D")
+
+	pdf.SetTextColor(40, 70, 120)
+	pdf.SetY(40)
+	pdf.SetFont(fontFamily, "B", 34)
+	pdf.CellFormat(0, 16, "Certificate of Completion", "", 1, "C", false, 0, "")
+
+	pdf.SetTextColor(60, 60, 60)
+	pdf.Ln(10)
+	pdf.SetFont(fontFamily, "", 16)
+	pdf.CellFormat(0, 10, "This certifies that", "", 1, "C", false, 0, "")
+
+	pdf.Ln(4)
+	pdf.SetFont(fontFamily, "BI", 30)
+	pdf.CellFormat(0, 16, certificate.UserName, "", 1, "C", false, 0, "")
+
+	pdf.Ln(4)
+	pdf.SetFont(fontFamily, "", 16)
+	pdf.CellFormat(0, 10, "has successfully completed the course", "", 1, "C", false, 0, "")
+
+	pdf.Ln(4)
+	pdf.SetFont(fontFamily, "B", 22)
+	pdf.MultiCell(0, 12, certificate.CourseName, "", "C", false)
+
+	pdf.SetY(height - 50)
+	pdf.SetFont(fontFamily, "", 12)
+	pdf.CellFormat(0, 7, "Issued on "+certificate.DateIssue.Format("2 January 2006"), "", 1, "C", false, 0, "")
+	pdf.CellFormat(0, 7, "Verification code: "+certificate.Code, "", 1, "C", false, 0, "")
+
+	if len(verifyUrl) > 0 {
+		pdf.SetFont(fontFamily, "", 10)
+		pdf.CellFormat(0, 6, verifyUrl, "", 1, "C", false, 0, verifyUrl)
+	}
+
+	var buffer bytes.Buffer
+
+	err := pdf.Output(&buffer)
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "certificates/pdf.go",
+				Method: "Render",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
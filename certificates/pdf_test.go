@@ -0,0 +1,57 @@
+package certificates
+
+import (
+	"bytes"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"opencourse/common"
+	"testing"
+	"time"
+)
+
+// TestRenderUnicode names and courses out of cp1252 are rendered with glyphs of embedded fonts
+func TestRenderUnicode(t *testing.T) {
+	certificate := &common.Certificate{
+		Code:       "AB12CD34",
+		UserName:   "Иван Петров",
+		CourseName: "Основы Go και Ελληνικά",
+		DateIssue:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	data, err := Render(certificate, "https://example.com/certificates/AB12CD34")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Fatal("result isn't PDF")
+	}
+
+	for _, ttf := range [][]byte{goregular.TTF, gobold.TTF, gobolditalic.TTF} {
+		font, err := sfnt.Parse(ttf)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buffer sfnt.Buffer
+
+		for _, r := range certificate.UserName + certificate.CourseName {
+			index, err := font.GlyphIndex(&buffer, r)
+
+			if err != nil || index == 0 {
+				t.Errorf("font hasn't glyph of %q", r)
+			}
+		}
+	}
+}
+
+// TestRenderNil nil certificate isn't rendered
+func TestRenderNil(t *testing.T) {
+	if _, err := Render(nil, ""); err == nil {
+		t.Error("nil certificate must return error")
+	}
+}
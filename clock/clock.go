@@ -0,0 +1,110 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+This file contains clock abstraction. Code which depends on current time or waits uses Clock,
+so tests can move time with ManualClock.
+*/
+
+// Clock source of current time and timers
+type Clock interface {
+	Now() time.Time                         // Now return current time
+	After(d time.Duration) <-chan time.Time // After return channel which receives current time after duration
+}
+
+// SystemClock clock of operating system
+type SystemClock struct{}
+
+// Now return current UTC time
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// After wait for duration
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// ManualClock clock which time is moved by Set and Advance. Use it in tests
+type ManualClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter channel waiting for time
+type waiter struct {
+	until   time.Time
+	channel chan time.Time
+}
+
+// NewManualClock create manual clock with start time
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now.UTC()}
+}
+
+// Now return clock time
+func (clock *ManualClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+// After return channel which receives time, when clock is moved by duration
+func (clock *ManualClock) After(d time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	channel := make(chan time.Time, 1)
+
+	if d <= 0 {
+		channel <- clock.now
+		return channel
+	}
+
+	clock.waiters = append(clock.waiters, &waiter{until: clock.now.Add(d), channel: channel})
+
+	return channel
+}
+
+// Advance move clock forward by duration
+func (clock *ManualClock) Advance(d time.Duration) {
+	clock.Set(clock.Now().Add(d))
+}
+
+// Set set clock time and fire timers which are due
+func (clock *ManualClock) Set(now time.Time) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.now = now.UTC()
+
+	sort.Slice(clock.waiters, func(i, j int) bool { return clock.waiters[i].until.Before(clock.waiters[j].until) })
+
+	var pending []*waiter
+
+	for _, w := range clock.waiters {
+		if w.until.After(clock.now) {
+			pending = append(pending, w)
+			continue
+		}
+
+		w.channel <- clock.now
+	}
+
+	clock.waiters = pending
+}
+
+// Waiters return count of timers which are not fired. Tests use it to wait until code starts waiting
+func (clock *ManualClock) Waiters() int {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return len(clock.waiters)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"opencourse/bundle"
+	"opencourse/common"
+	"opencourse/database"
+	"opencourse/markdown"
+	"os"
+	"strings"
+)
+
+/*
+runCommand run CLI subcommand instead of http server. Commands:
+export <courseId> <file> - export course to bundle file (.zip or .json);
+import <file> <authorId> [categoryId] - import course of author from bundle file (.zip or .json);
+export-md <courseId> <dir> - export course to Markdown directory tree;
+import-md <dir> <authorId> [categoryId] - import course of author from Markdown directory tree;
+*/
+func runCommand(dbContext *database.DbContext, args []string) error {
+	switch args[0] {
+	case "export":
+		if len(args) < 3 {
+			return errors.New("usage: opencourse export <courseId> <file>")
+		}
+
+		return exportCourse(dbContext, args[1], args[2])
+	case "import":
+		if len(args) < 3 {
+			return errors.New("usage: opencourse import <file> <authorId> [categoryId]")
+		}
+
+		categoryId := ""
+		if len(args) > 3 {
+			categoryId = args[3]
+		}
+
+		return importCourse(dbContext, args[1], args[2], categoryId)
+	case "export-md":
+		if len(args) < 3 {
+			return errors.New("usage: opencourse export-md <courseId> <dir>")
+		}
+
+		courseBundle, err := dbContext.ExportCourse(args[1])
+
+		if err != nil {
+			return err
+		}
+
+		return markdown.WriteDir(args[2], courseBundle)
+	case "import-md":
+		if len(args) < 3 {
+			return errors.New("usage: opencourse import-md <dir> <authorId> [categoryId]")
+		}
+
+		courseBundle, err := markdown.ReadDir(args[1])
+
+		if err != nil {
+			return err
+		}
+
+		categoryId := ""
+		if len(args) > 3 {
+			categoryId = args[3]
+		}
+
+		id, err := dbContext.ImportCourse(courseBundle, categoryId, args[2])
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(id)
+
+		return nil
+	default:
+		return fmt.Errorf("unknown command %s", args[0])
+	}
+}
+
+func exportCourse(dbContext *database.DbContext, courseId string, fileName string) error {
+	courseBundle, err := dbContext.ExportCourse(courseId)
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(fileName, ".json") {
+		err = bundle.WriteJSON(file, courseBundle)
+	} else {
+		err = bundle.WriteZip(file, courseBundle, bundle.CourseStore(dbContext.MediaDir, courseId))
+	}
+
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func importCourse(dbContext *database.DbContext, fileName string, authorId string, categoryId string) error {
+	file, err := os.Open(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var courseBundle *common.CourseBundle
+	var size int64
+
+	isJSON := strings.HasSuffix(fileName, ".json")
+
+	if isJSON {
+		courseBundle, err = bundle.ReadJSON(file)
+	} else {
+		info, statErr := file.Stat()
+
+		if statErr != nil {
+			return statErr
+		}
+
+		size = info.Size()
+		courseBundle, err = bundle.ReadZip(file, size)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	id, err := dbContext.ImportCourse(courseBundle, categoryId, authorId)
+
+	if err != nil {
+		return err
+	}
+
+	if !isJSON {
+		err = bundle.SaveMedia(file, size, courseBundle, bundle.CourseStore(dbContext.MediaDir, id))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(id)
+
+	return nil
+}
@@ -15,16 +15,88 @@ const (
 	LangRu = "ru" // Russian
 )
 
+// Langs supported languages
+var Langs = []string{LangEn, LangFr, LangDe, LangIt, LangRu}
+
+// FallbackLang language of content, which isn't translated to requested languages
+const FallbackLang = LangEn
+
+// Translation kinds. Content of kind is linked with translations by translation group
+const (
+	TranslationCourse   = "course"   // Course translation
+	TranslationCategory = "category" // Category translation
+	TranslationStage    = "stage"    // Stage translation. Stage has language of course
+	TranslationTest     = "test"     // Test translation. Test has language of stage course
+)
+
 // User roles
 const (
-	RoleUser  = "user"  // Simple user role
-	RoleAdmin = "admin" // Privileged user role
+	RoleUser      = "user"      // Simple user role
+	RoleAuthor    = "author"    // Course author role
+	RoleAdmin     = "admin"     // Privileged user role
+	RoleModerator = "moderator" // Moderator of user content role
+)
+
+// Quiz session statuses
+const (
+	SessionActive    = "active"    // Session is started, answers are accepted until deadline
+	SessionSubmitted = "submitted" // Session is submitted by user before deadline
+	SessionExpired   = "expired"   // Session is submitted automatically after deadline
+)
+
+// Badge criteria kinds
+const (
+	BadgeCourseCompleted = "course_completed" // Badge for certificate of course
+	BadgeLemmings        = "lemmings"         // Badge for count of lemmings
+	BadgeStreak          = "streak"           // Badge for days in a row with answered tests
+)
+
+// Leaderboard metrics, scopes and time windows
+const (
+	MetricRating   = "rating"   // Users are ranked by rating (XP)
+	MetricLemmings = "lemmings" // Users are ranked by lemmings
+	ScopeGlobal    = "global"   // Leaderboard of all users
+	ScopeCategory  = "category" // Leaderboard of category courses
+	ScopeCourse    = "course"   // Leaderboard of course
+	WindowAll      = "all"      // Points for all time
+	WindowWeek     = "week"     // Points for the last 7 days
+	WindowMonth    = "month"    // Points for the last 30 days
+)
+
+// Spaced repetition defaults
+const (
+	ReviewEase    = 2.5 // Initial ease factor of review schedule
+	ReviewMinEase = 1.3 // Minimal ease factor
+)
+
+// Rewrite test matching rules
+const (
+	MatchExact      = "exact"      // Answer is equal to accepted answer
+	MatchNormalized = "normalized" // Answer is equal to accepted answer after normalization
+	MatchPattern    = "pattern"    // Answer matches regular expression
+	MatchFuzzy      = "fuzzy"      // Answer is close to accepted answer by Levenshtein distance
 )
 
 // Test types
 const (
-	TestOption  = "option"  // Test with options (variant answers)
-	TestRewrite = "rewrite" // Rewrite test. User need write key text from the question
+	TestOption      = "option"       // Test with options (variant answers)
+	TestRewrite     = "rewrite"      // Rewrite test. User need write key text from the question
+	TestMultiSelect = "multi_select" // Test with options where many answers may be right
+	TestOrdering    = "ordering"     // User need put items in the right order
+	TestMatching    = "matching"     // User need match left and right items of pairs
+	TestCloze       = "cloze"        // Fill-in-the-blank test. User need fill blanks in the text
+	TestNumeric     = "numeric"      // User need write number. Answer is checked with tolerance
+	TestCode        = "code"         // Programming exercise. User program is run for every test case
+)
+
+// BundleVersion current version of course bundle format
+const BundleVersion = 1
+
+// Quiz import formats
+const (
+	QuizFormatGift  = "gift"  // Moodle GIFT format
+	QuizFormatAiken = "aiken" // Aiken format
+	QuizFormatQti   = "qti"   // IMS QTI 2.1 XML format
 )
 
 // Promotion types
@@ -33,15 +105,19 @@ const (
 	PromotionUpdate = "update" // Updated promotion record
 )
 
+// MaxPageSize max count of items in list page
+const MaxPageSize = 100
+
 // UserPreview user preview model for client
 type UserPreview struct {
-	Id     string   `json:"id"`     // User id
-	Login  string   `json:"login"`  // User login
-	Name   string   `json:"name"`   // User display name
-	Email  string   `json:"email"`  // Email user address
-	Avatar string   `json:"avatar"` // User avatar image path
-	Rating int      `json:"rating"` // User rating
-	Roles  []string `json:"roles"`  // User roles
+	Id       string   `json:"id"`       // User id
+	Login    string   `json:"login"`    // User login
+	Name     string   `json:"name"`     // User display name
+	Email    string   `json:"email"`    // Email user address
+	Avatar   string   `json:"avatar"`   // User avatar image path
+	Rating   int      `json:"rating"`   // User rating
+	Lemmings int      `json:"lemmings"` // Count of lemmings for passed tests
+	Roles    []string `json:"roles"`    // User roles
 }
 
 // User model
@@ -51,6 +127,7 @@ type User struct {
 	Avatar     string      `json:"avatar"`     // Avatar image path
 	Credential *Credential `json:"credential"` // User credential properties
 	Rating     int         `json:"rating"`     // User rating
+	Lemmings   int         `json:"lemmings"`   // Count of lemmings for passed tests
 	Email      string      `json:"email"`      // User email address
 }
 
@@ -76,19 +153,30 @@ type AddUserQuery struct {
 
 // Category for course
 type Category struct {
-	Id        string `json:"id"`         // Category id
-	Lang      string `json:"lang"`       // Support language
-	Name      string `json:"name"`       // Category name
-	IconImg   string `json:"icon_img"`   // Icon for category
-	HeaderImg string `json:"header_img"` // Header image
+	Id          string      `json:"id"`                  // Category id
+	ParentId    string      `json:"parent_id,omitempty"` // Parent category id. Empty for root category
+	Path        string      `json:"path"`                // Ids of ancestors and category. Example: "/a/b/"
+	Group       string      `json:"group,omitempty"`     // Translation group. Translations of category have the same group
+	Lang        string      `json:"lang"`                // Support language
+	Name        string      `json:"name"`                // Category name
+	IconImg     string      `json:"icon_img"`            // Icon for category
+	HeaderImg   string      `json:"header_img"`          // Header image
+	CourseCount int         `json:"course_count"`        // Count of courses in category and descendants
+	Children    []*Category `json:"children,omitempty"`  // Child categories. Set for categories tree only
 }
 
 // AddCategoryQuery model for create category
 type AddCategoryQuery struct {
-	Lang      string `json:"lang"`       // Support language
-	Name      string `json:"name"`       // Category name
-	IconImg   string `json:"icon_img"`   // Icon for category
-	HeaderImg string `json:"header_img"` // Header image
+	ParentId  string `json:"parent_id,omitempty"` // Parent category id. Optional, category is root if it's empty
+	Lang      string `json:"lang"`                // Support language
+	Name      string `json:"name"`                // Category name
+	IconImg   string `json:"icon_img"`            // Icon for category
+	HeaderImg string `json:"header_img"`          // Header image
+}
+
+// MoveCategoryQuery model for move category with descendants
+type MoveCategoryQuery struct {
+	ParentId string `json:"parent_id"` // New parent category id. Category becomes root if it's empty
 }
 
 // UpdateCategoryQuery model for update category
@@ -100,93 +188,266 @@ type UpdateCategoryQuery struct {
 
 // Course model
 type Course struct {
-	Id          string    `json:"id"`                    // Course id
-	Name        string    `json:"name"`                  // Course name
-	CategoryId  string    `json:"category_id"`           // Course category
-	Enabled     bool      `json:"enabled"`               // Enabled course
-	Tags        []string  `json:"tags"`                  // Course tags
-	Rating      int       `json:"rating"`                // Course rating
-	Description string    `json:"description,omitempty"` // Course description
-	IconImg     string    `json:"icon_img"`              // Icon for category
-	HeaderImg   string    `json:"header_img"`            // Header image
-	DateCreate  time.Time `json:"date_create"`           // Date create course
-	DateUpdate  time.Time `json:"date_update"`           // Date update course
+	Id          string         `json:"id"`                    // Course id
+	Name        string         `json:"name"`                  // Course name
+	CategoryId  string         `json:"category_id"`           // Course category
+	AuthorId    string         `json:"author_id,omitempty"`   // Course author (owner) id
+	Lang        string         `json:"lang,omitempty"`        // Course language
+	Group       string         `json:"group,omitempty"`       // Translation group. Translations of course have the same group
+	Enabled     bool           `json:"enabled"`               // Enabled course
+	Tags        []string       `json:"tags"`                  // Course tags
+	Rating      int            `json:"rating"`                // Course rating. Average of ratings multiplied by 100
+	Points      int            `json:"points"`                // Points of achievements
+	Description string         `json:"description,omitempty"` // Course description
+	IconImg     string         `json:"icon_img"`              // Icon for category
+	HeaderImg   string         `json:"header_img"`            // Header image
+	DateCreate  time.Time      `json:"date_create"`           // Date create course
+	DateUpdate  time.Time      `json:"date_update"`           // Date update course
+	Labels      []string       `json:"labels,omitempty"`      // Labels of active course promotions
+	Ratings     *CourseRatings `json:"ratings,omitempty"`     // Aggregate of course ratings
+}
+
+// CourseRatings aggregate of visible course reviews. Course rating is average multiplied by 100
+type CourseRatings struct {
+	Average   float64        `json:"average"`   // Average stars
+	Count     int            `json:"count"`     // Count of ratings
+	Histogram map[string]int `json:"histogram"` // Count of ratings by stars from "1" to "5"
+}
+
+// CourseReview model
+type CourseReview struct {
+	Id         string       `json:"id"`              // Review id
+	CourseId   string       `json:"course_id"`       // Course id
+	UserId     string       `json:"user_id"`         // Reviewer id
+	UserName   string       `json:"user_name"`       // Reviewer name
+	Stars      int          `json:"stars"`           // Rating from 1 to 5
+	Text       string       `json:"text,omitempty"`  // Review text
+	Hidden     bool         `json:"hidden"`          // Review is hidden by moderator
+	Reply      *ReviewReply `json:"reply,omitempty"` // Reply of course author
+	DateCreate time.Time    `json:"date_create"`     // Date create review
+	DateUpdate time.Time    `json:"date_update"`     // Date update review
+}
+
+// ReviewReply reply of course author to review
+type ReviewReply struct {
+	AuthorId   string    `json:"author_id"`   // Reply author id
+	Text       string    `json:"text"`        // Reply text
+	DateCreate time.Time `json:"date_create"` // Date of reply
+}
+
+// AddCourseReviewQuery model for rate course. Review of user is replaced
+type AddCourseReviewQuery struct {
+	Stars int    `json:"stars"` // Rating from 1 to 5
+	Text  string `json:"text"`  // Review text. Optional
+}
+
+// ReplyCourseReviewQuery model for reply to review
+type ReplyCourseReviewQuery struct {
+	Text string `json:"text"` // Reply text
+}
+
+// HideCourseReviewQuery model for hide or show review
+type HideCourseReviewQuery struct {
+	Hidden bool `json:"hidden"` // Review is hidden
+}
+
+// Comments sorts
+const (
+	CommentsNewest = "newest" // The newest comments first
+	CommentsVotes  = "votes"  // Comments with more upvotes first
+)
+
+// StageComment comment of stage discussion. Thread is root comment with replies
+type StageComment struct {
+	Id         string    `json:"id"`                  // Comment id
+	StageId    string    `json:"stage_id"`            // Stage id
+	CourseId   string    `json:"course_id"`           // Course id of stage
+	ParentId   string    `json:"parent_id,omitempty"` // Root comment of thread. Root comment hasn't parent
+	UserId     string    `json:"user_id"`             // Comment author id
+	UserName   string    `json:"user_name"`           // Comment author name
+	Body       string    `json:"body"`                // Markdown body. Deleted comment has empty body
+	Votes      int       `json:"votes"`               // Count of upvotes
+	IsAnswer   bool      `json:"is_answer"`           // Reply is marked as answer of thread
+	AnswerId   string    `json:"answer_id,omitempty"` // Marked answer of thread. Set for root comment
+	ReplyCount int       `json:"reply_count"`         // Count of thread replies. Set for root comment
+	Hidden     bool      `json:"hidden"`              // Comment is hidden by moderator
+	Deleted    bool      `json:"deleted"`             // Comment is deleted, thread is kept
+	DateCreate time.Time `json:"date_create"`         // Date create comment
+	DateUpdate time.Time `json:"date_update"`         // Date of the last edit
+}
+
+// AddStageCommentQuery model for add comment or reply
+type AddStageCommentQuery struct {
+	ParentId string `json:"parent_id"` // Replied comment. Optional, reply of reply is added to the same thread
+	Body     string `json:"body"`      // Markdown body
+}
+
+// UpdateStageCommentQuery model for edit comment
+type UpdateStageCommentQuery struct {
+	Body string `json:"body"` // Markdown body
+}
+
+// HideStageCommentQuery model for hide or show comment
+type HideStageCommentQuery struct {
+	Hidden bool `json:"hidden"` // Comment is hidden
+}
+
+// Notification kinds
+const (
+	NotificationCommentReply   = "comment_reply"   // Reply to comment of user
+	NotificationCourseUpdate   = "course_update"   // Course of learner is changed
+	NotificationReviewReply    = "review_reply"    // Course author replied to review of user
+	NotificationReviewDecision = "review_decision" // Moderator hid or showed review of user
+	NotificationBadge          = "badge"           // Badge is awarded to user
+)
+
+// Notification notification of user
+type Notification struct {
+	Id         string    `json:"id"`                  // Notification id
+	UserId     string    `json:"user_id"`             // Recipient id
+	Kind       string    `json:"kind"`                // Notification kind
+	Message    string    `json:"message"`             // Notification text
+	CourseId   string    `json:"course_id,omitempty"` // Related course
+	StageId    string    `json:"stage_id,omitempty"`  // Related stage
+	TargetId   string    `json:"target_id,omitempty"` // Related comment, review or badge assertion
+	Read       bool      `json:"read"`                // Notification is read
+	DateCreate time.Time `json:"date_create"`         // Date of notification
+}
+
+// ReadNotificationsQuery model for mark notifications read
+type ReadNotificationsQuery struct {
+	Ids []string `json:"ids"` // Notification ids. Every notification is read, if ids are empty
 }
 
 // DbCoursePromotion collection
 type DbCoursePromotion struct {
-	Id             string    `json:"id"`              // Course promotion id
+	Id             string    `json:"id"`               // Course promotion id
+	CourseId       string    `json:"course_id"`        // Course id
+	PromotionType  string    `json:"promotion_type"`   // Promotion type
+	Label          string    `json:"label"`            // Promotion label text
+	ExpirationTime time.Time `json:"expiration_time"`  // Promotion expiration time. After this time doc will be removed
+	Course         *Course   `json:"course,omitempty"` // Promoted course. Set for promotions feed
+}
+
+// AddPromotionQuery model for create course promotion
+type AddPromotionQuery struct {
 	CourseId       string    `json:"course_id"`       // Course id
 	PromotionType  string    `json:"promotion_type"`  // Promotion type
 	Label          string    `json:"label"`           // Promotion label text
-	ExpirationTime time.Time `json:"expiration_time"` // Promotion expiration time. After this time doc will be removed
+	ExpirationTime time.Time `json:"expiration_time"` // Promotion expiration time. Must be in future
 }
 
 // AddCourseQuery add course query
 type AddCourseQuery struct {
 	Name        string   `json:"name"`        // Course name
 	CategoryId  string   `json:"category_id"` // Course category
+	AuthorId    string   `json:"-"`           // Course author id. Set from auth token
+	Lang        string   `json:"lang"`        // Course language
 	Tags        []string `json:"tags"`        // Course tags
 	Description string   `json:"description"` // Course description
 	IconImg     string   `json:"icon_img"`    // Icon for category
 	HeaderImg   string   `json:"header_img"`  // Header image
 }
 
+// CloneCourseQuery options for deep course copy
+type CloneCourseQuery struct {
+	Name       string `json:"name"`        // Name of the copy. Optional, source name by default
+	CategoryId string `json:"category_id"` // Target category. Optional, source category by default
+	Lang       string `json:"lang"`        // Target language. Optional, source language by default
+}
+
 type PostContent struct {
 	Body       string   `json:"body"`        // Post's body
 	MediaItems []string `json:"media_items"` // Various attachments
 }
 
 type Stage struct {
-	Id          string       `json:"id"`           // Stage id
-	CourseId    string       `json:"course_id"`    // Course id. One course has many stages
-	Name        string       `json:"name"`         // Course stage name
-	Content     *PostContent `json:"content"`      // Stage contents
-	HeaderImg   string       `json:"header_img"`   // Header image
-	OrderNumber int          `json:"order_number"` // Stage order number
+	Id          string          `json:"id"`              // Stage id
+	CourseId    string          `json:"course_id"`       // Course id. One course has many stages
+	Group       string          `json:"group,omitempty"` // Translation group. Translations of stage have the same group
+	Name        string          `json:"name"`            // Course stage name
+	Content     *PostContent    `json:"content"`         // Stage contents
+	HeaderImg   string          `json:"header_img"`      // Header image
+	OrderNumber int             `json:"order_number"`    // Stage order number
+	TimeLimit   int             `json:"time_limit"`      // Seconds for stage tests in quiz session. 0 is not timed
+	Pools       []*QuestionPool `json:"pools,omitempty"` // Question pools. Tests of pool are drawn for every learner
 }
 
 type StagePreview struct {
-	Id          string `json:"id"`           // Stage id
-	CourseId    string `json:"course_id"`    // Course id. One course has many stages
-	Name        string `json:"name"`         // Course stage name
-	HeaderImg   string `json:"header_img"`   // Header image
-	OrderNumber int    `json:"order_number"` // Stage order number
+	Id          string `json:"id"`              // Stage id
+	CourseId    string `json:"course_id"`       // Course id. One course has many stages
+	Group       string `json:"group,omitempty"` // Translation group. Translations of stage have the same group
+	Name        string `json:"name"`            // Course stage name
+	HeaderImg   string `json:"header_img"`      // Header image
+	OrderNumber int    `json:"order_number"`    // Stage order number
+	TimeLimit   int    `json:"time_limit"`      // Seconds for stage tests in quiz session. 0 is not timed
+}
+
+// QuestionPool group of stage tests from which learner gets DrawCount random tests
+type QuestionPool struct {
+	Name      string `json:"name"`       // Pool name. Tests are added to pool by name
+	DrawCount int    `json:"draw_count"` // Count of tests drawn for learner. 0 or pool size draws all tests
 }
 
 type AddStageQuery struct {
-	CourseId    string       `json:"course_id"`    // Course id. One course has many stages
-	Name        string       `json:"name"`         // Course stage name
-	Content     *PostContent `json:"content"`      // Stage contents
-	HeaderImg   string       `json:"header_img"`   // Header image
-	OrderNumber int          `json:"order_number"` // Stage order number
+	CourseId    string          `json:"course_id"`       // Course id. One course has many stages
+	Name        string          `json:"name"`            // Course stage name
+	Content     *PostContent    `json:"content"`         // Stage contents
+	HeaderImg   string          `json:"header_img"`      // Header image
+	OrderNumber int             `json:"order_number"`    // Stage order number
+	TimeLimit   int             `json:"time_limit"`      // Seconds for stage tests in quiz session. 0 is not timed
+	Pools       []*QuestionPool `json:"pools,omitempty"` // Question pools. Tests of pool are drawn for every learner
 }
 
 type UpdateStageQuery struct {
-	StageId     string       `json:"stage_id"`     // Stage id
-	CourseId    string       `json:"course_id"`    // Course id. One course has many stages
-	Name        string       `json:"name"`         // Course stage name
-	Content     *PostContent `json:"content"`      // Stage contents
-	HeaderImg   string       `json:"header_img"`   // Header image
-	OrderNumber int          `json:"order_number"` // Stage order number
+	StageId     string          `json:"stage_id"`        // Stage id
+	CourseId    string          `json:"course_id"`       // Course id. One course has many stages
+	Name        string          `json:"name"`            // Course stage name
+	Content     *PostContent    `json:"content"`         // Stage contents
+	HeaderImg   string          `json:"header_img"`      // Header image
+	OrderNumber int             `json:"order_number"`    // Stage order number
+	TimeLimit   int             `json:"time_limit"`      // Seconds for stage tests in quiz session. 0 is not timed
+	Pools       []*QuestionPool `json:"pools,omitempty"` // Question pools. Tests of pool are drawn for every learner
 }
 
 type Test struct {
-	Id            string       `json:"_id,omitempty"`          // Test id
-	StageId       string       `json:"stage_id"`               // Stage id
-	TestType      string       `json:"test_type"`              // Test type
-	LemmingsCount int          `json:"lemmings_count"`         // Count of lemmings for passed test
-	OptionTest    *OptionTest  `json:"option_test,omitempty"`  // Option test. Test with option variant answers. Optional
-	RewriteTest   *RewriteTest `json:"rewrite_test,omitempty"` // Rewrite test. Test with phrase how need write. Optional
-	OrderNumber   int          `json:"order_number"`           // Test order number
+	Id              string           `json:"_id,omitempty"`               // Test id
+	StageId         string           `json:"stage_id"`                    // Stage id
+	Group           string           `json:"group,omitempty"`             // Translation group. Translations of test have the same group
+	TestType        string           `json:"test_type"`                   // Test type
+	LemmingsCount   int              `json:"lemmings_count"`              // Count of lemmings for passed test
+	OptionTest      *OptionTest      `json:"option_test,omitempty"`       // Option test. Test with option variant answers. Optional
+	RewriteTest     *RewriteTest     `json:"rewrite_test,omitempty"`      // Rewrite test. Test with phrase how need write. Optional
+	MultiSelectTest *MultiSelectTest `json:"multi_select_test,omitempty"` // Multi-select test. Optional
+	OrderingTest    *OrderingTest    `json:"ordering_test,omitempty"`     // Ordering test. Optional
+	MatchingTest    *MatchingTest    `json:"matching_test,omitempty"`     // Matching test. Optional
+	ClozeTest       *ClozeTest       `json:"cloze_test,omitempty"`        // Fill-in-the-blank test. Optional
+	NumericTest     *NumericTest     `json:"numeric_test,omitempty"`      // Numeric test. Optional
+	CodeTest        *CodeTest        `json:"code_test,omitempty"`         // Programming exercise. Optional
+	OrderNumber     int              `json:"order_number"`                // Test order number
+	MaxAttempts     int              `json:"max_attempts"`                // Max count of attempts. 0 is unlimited
+	Cooldown        int              `json:"cooldown"`                    // Min seconds between attempts. 0 is no cooldown
+	Pool            string           `json:"pool,omitempty"`              // Question pool name. Test without pool is given to every learner
 }
 
+// TestPreview test for learner without right answers
 type TestPreview struct {
-	Id            string `json:"_id,omitempty"`  // Test id
-	StageId       string `json:"stage_id"`       // Stage id
-	TestType      string `json:"test_type"`      // Test type
-	LemmingsCount int    `json:"lemmings_count"` // Count of lemmings for passed test
-	OrderNumber   int    `json:"order_number"`   // Test order number
+	Id            string      `json:"_id,omitempty"`          // Test id
+	StageId       string      `json:"stage_id"`               // Stage id
+	Group         string      `json:"group,omitempty"`        // Translation group. Translations of test have the same group
+	TestType      string      `json:"test_type"`              // Test type
+	LemmingsCount int         `json:"lemmings_count"`         // Count of lemmings for passed test
+	OrderNumber   int         `json:"order_number"`           // Test order number
+	MaxAttempts   int         `json:"max_attempts"`           // Max count of attempts. 0 is unlimited
+	Cooldown      int         `json:"cooldown"`               // Min seconds between attempts. 0 is no cooldown
+	Question      string      `json:"question"`               // Question. For cloze test it's a text with blanks
+	Options       []string    `json:"options,omitempty"`      // Answers of option and multi-select tests
+	Items         []string    `json:"items,omitempty"`        // Items of ordering test and left items of matching test
+	Pairs         []string    `json:"pairs,omitempty"`        // Right items of matching test
+	BlanksCount   int         `json:"blanks_count,omitempty"` // Count of blanks in cloze test
+	Language      string      `json:"language,omitempty"`     // Program language of code test
+	StarterCode   string      `json:"starter_code,omitempty"` // Starter code of code test
+	Examples      []*CodeCase `json:"examples,omitempty"`     // Not hidden cases of code test
 }
 
 type Option struct {
@@ -200,17 +461,215 @@ type OptionTest struct {
 }
 
 type RewriteTest struct {
-	Question    string `json:"question"`
-	RightAnswer string `json:"right_answer"`
+	Question    string           `json:"question"`
+	RightAnswer string           `json:"right_answer"`
+	Matching    *RewriteMatching `json:"matching,omitempty"` // Answer matching settings. If nil, answer must be equal to right answer
+}
+
+// RewriteMatching answer matching settings of rewrite test
+type RewriteMatching struct {
+	IgnoreCase        bool     `json:"ignore_case"`                // Compare answers without case
+	IgnoreWhitespace  bool     `json:"ignore_whitespace"`          // Collapse whitespaces to one space
+	IgnorePunctuation bool     `json:"ignore_punctuation"`         // Remove punctuation
+	UnicodeNormalize  bool     `json:"unicode_normalize"`          // Compare NFKC forms of answers
+	FoldDiacritics    bool     `json:"fold_diacritics"`            // Remove diacritics: "ё" is "е", "ü" is "u", "ß" is "ss"
+	AcceptedAnswers   []string `json:"accepted_answers,omitempty"` // Other accepted answers
+	Patterns          []string `json:"patterns,omitempty"`         // Regular expressions. Answer must fully match one of them
+	MaxDistance       int      `json:"max_distance"`               // Max Levenshtein distance, capped to a third of accepted answer length. 0 disables fuzzy matching
+}
+
+// MultiSelectTest test with options where many answers may be right
+type MultiSelectTest struct {
+	Question      string    `json:"question"`       // Question
+	Options       []*Option `json:"options"`        // Options. Many options may be right
+	PartialCredit bool      `json:"partial_credit"` // If true, user gets score for every right option
+}
+
+// OrderingTest test where user need put items in the right order
+type OrderingTest struct {
+	Question string   `json:"question"` // Question
+	Items    []string `json:"items"`    // Items in the right order
+}
+
+// MatchPair pair of matching test
+type MatchPair struct {
+	Left  string `json:"left"`  // Left item
+	Right string `json:"right"` // Right item which matches left item
+}
+
+// MatchingTest test where user need match left and right items
+type MatchingTest struct {
+	Question string       `json:"question"` // Question
+	Pairs    []*MatchPair `json:"pairs"`    // Right pairs
+}
+
+// ClozeBlank blank of cloze test
+type ClozeBlank struct {
+	Answers []string `json:"answers"` // Accepted answers. Compared without case and surrounding spaces
+}
+
+// ClozeTest fill-in-the-blank test
+type ClozeTest struct {
+	Text   string        `json:"text"`   // Text with blanks placeholders {{1}}, {{2}}...
+	Blanks []*ClozeBlank `json:"blanks"` // Blanks in placeholders order
+}
+
+// NumericTest test where user need write number
+type NumericTest struct {
+	Question  string  `json:"question"`  // Question
+	Answer    float64 `json:"answer"`    // Right answer
+	Tolerance float64 `json:"tolerance"` // Allowed absolute difference from the right answer
+}
+
+// CodeCase test case of programming exercise
+type CodeCase struct {
+	Input          string `json:"input"`           // Program standard input
+	ExpectedOutput string `json:"expected_output"` // Expected standard output. Compared without trailing spaces of lines
+	Hidden         bool   `json:"hidden"`          // Hidden cases are not shown to learner
+}
+
+// CodeTest programming exercise
+type CodeTest struct {
+	Question    string      `json:"question"`     // Task description
+	Language    string      `json:"language"`     // Program language. Only "go" is supported
+	StarterCode string      `json:"starter_code"` // Code which learner starts from
+	Cases       []*CodeCase `json:"cases"`        // Test cases. Program passes test if it passes all cases
+}
+
+// CaseResult result of program run for test case
+type CaseResult struct {
+	Passed   bool   `json:"passed"`           // Program output is equal to expected output
+	Hidden   bool   `json:"hidden"`           // Case is hidden. Input and output are not returned
+	Input    string `json:"input,omitempty"`  // Case input
+	Output   string `json:"output,omitempty"` // Program output
+	Error    string `json:"error,omitempty"`  // Program error output or limit violation
+	Duration int64  `json:"duration"`         // Run duration in milliseconds
+}
+
+// TestAnswer user answer for test. Only field for test type is used
+type TestAnswer struct {
+	Options []string          `json:"options,omitempty"` // Chosen answers for option and multi-select tests
+	Text    string            `json:"text,omitempty"`    // Answer for rewrite test
+	Order   []string          `json:"order,omitempty"`   // Items in user order for ordering test
+	Pairs   map[string]string `json:"pairs,omitempty"`   // Left to right items for matching test
+	Blanks  []string          `json:"blanks,omitempty"`  // Answers for cloze test blanks
+	Number  float64           `json:"number,omitempty"`  // Answer for numeric test
+	Code    string            `json:"code,omitempty"`    // Program for code test
+}
+
+// GradeResult result of answer check
+type GradeResult struct {
+	Score         float64       `json:"score"`                    // Score from 0 to 1
+	IsPassed      bool          `json:"is_passed"`                // Test is passed if answer is fully right
+	LemmingsCount int           `json:"lemmings_count"`           // Count of lemmings earned by this answer
+	Attempt       int           `json:"attempt"`                  // Attempt number
+	MatchedRule   string        `json:"matched_rule,omitempty"`   // Rule matched answer of rewrite test
+	MatchedAnswer string        `json:"matched_answer,omitempty"` // Accepted answer or pattern matched answer of rewrite test
+	CompileOutput string        `json:"compile_output,omitempty"` // Compiler output of code test program
+	Cases         []*CaseResult `json:"cases,omitempty"`          // Case results of code test
+	SelectionId   string        `json:"selection_id,omitempty"`   // Test selection shown to learner
+	SessionId     string        `json:"session_id,omitempty"`     // Quiz session of answer. Attempt of session is saved once
+	Question      string        `json:"-"`                        // Question shown to learner. Saved with attempt
+	Options       []string      `json:"-"`                        // Options in order shown to learner. Saved with attempt
+	Certificate   *Certificate  `json:"certificate,omitempty"`    // Certificate issued, if answer completed course
+}
+
+// TestAttempt user answer for test
+type TestAttempt struct {
+	Id          string      `json:"id"`                     // Attempt id
+	UserId      string      `json:"user_id"`                // User id
+	TestId      string      `json:"test_id"`                // Test id
+	Number      int         `json:"number"`                 // Attempt number, starts from 1
+	Answer      *TestAnswer `json:"answer"`                 // User answer
+	Score       float64     `json:"score"`                  // Score from 0 to 1
+	IsPassed    bool        `json:"is_passed"`              // Answer is fully right
+	DateCreate  time.Time   `json:"date_create"`            // Attempt date
+	SelectionId string      `json:"selection_id,omitempty"` // Test selection shown to learner
+	SessionId   string      `json:"session_id,omitempty"`   // Quiz session of attempt
+	Question    string      `json:"question,omitempty"`     // Question shown to learner
+	Options     []string    `json:"options,omitempty"`      // Options of option and multi-select tests in order shown to learner
+}
+
+// TestSelection tests drawn from stage question pools for learner. Selection is stored, so
+// review shows the same tests and options order
+type TestSelection struct {
+	Id         string    `json:"id"`                   // Selection id
+	UserId     string    `json:"user_id"`              // User id
+	StageId    string    `json:"stage_id"`             // Stage id
+	SessionId  string    `json:"session_id,omitempty"` // Quiz session id. Empty for stage without time limit
+	Seed       int64     `json:"seed"`                 // Seed of tests draw and options shuffling
+	TestIds    []string  `json:"test_ids"`             // Drawn tests in order shown to learner
+	DateCreate time.Time `json:"date_create"`          // Selection date
+}
+
+// QuizSession timed session for answering all tests of stage
+type QuizSession struct {
+	Id         string           `json:"id"`                // Session id
+	UserId     string           `json:"user_id"`           // User id
+	StageId    string           `json:"stage_id"`          // Stage id
+	CourseId   string           `json:"course_id"`         // Course id of stage
+	Status     string           `json:"status"`            // Session status
+	DateStart  time.Time        `json:"date_start"`        // Session start date
+	Deadline   time.Time        `json:"deadline"`          // Answers are rejected after deadline
+	DateSubmit time.Time        `json:"date_submit"`       // Submit date. Zero for active session
+	Answers    []*SessionAnswer `json:"answers"`           // Saved answers. Graded on submit
+	Results    []*SessionResult `json:"results,omitempty"` // Results for every stage test. Set on submit
+	Score      float64          `json:"score"`             // Average score of stage tests from 0 to 1
+	IsPassed   bool             `json:"is_passed"`         // All stage tests are passed
+}
+
+// SessionAnswer user answer for test in quiz session
+type SessionAnswer struct {
+	TestId     string      `json:"test_id"`     // Test id
+	Answer     *TestAnswer `json:"answer"`      // User answer
+	DateUpdate time.Time   `json:"date_update"` // Date of the last answer change
+}
+
+// SessionResult grade result for test in quiz session
+type SessionResult struct {
+	TestId   string  `json:"test_id"`   // Test id
+	Answered bool    `json:"answered"`  // User answered test
+	Score    float64 `json:"score"`     // Score from 0 to 1
+	IsPassed bool    `json:"is_passed"` // Answer is fully right
 }
 
 type AddTestQuery struct {
-	StageId       string       `json:"stage_id"`               // Stage id
-	TestType      string       `json:"test_type"`              // Test type
-	LemmingsCount int          `json:"lemmings_count"`         // Count of lemmings for passed test
-	OptionTest    *OptionTest  `json:"option_test,omitempty"`  // Option test. Test with option variant answers. Optional
-	RewriteTest   *RewriteTest `json:"rewrite_test,omitempty"` // Rewrite test. Test with phrase how need write. Optional
-	OrderNumber   int          `json:"order_number"`           // Test order number
+	StageId         string           `json:"stage_id"`                    // Stage id
+	TestType        string           `json:"test_type"`                   // Test type
+	LemmingsCount   int              `json:"lemmings_count"`              // Count of lemmings for passed test
+	OptionTest      *OptionTest      `json:"option_test,omitempty"`       // Option test. Test with option variant answers. Optional
+	RewriteTest     *RewriteTest     `json:"rewrite_test,omitempty"`      // Rewrite test. Test with phrase how need write. Optional
+	MultiSelectTest *MultiSelectTest `json:"multi_select_test,omitempty"` // Multi-select test. Optional
+	OrderingTest    *OrderingTest    `json:"ordering_test,omitempty"`     // Ordering test. Optional
+	MatchingTest    *MatchingTest    `json:"matching_test,omitempty"`     // Matching test. Optional
+	ClozeTest       *ClozeTest       `json:"cloze_test,omitempty"`        // Fill-in-the-blank test. Optional
+	NumericTest     *NumericTest     `json:"numeric_test,omitempty"`      // Numeric test. Optional
+	CodeTest        *CodeTest        `json:"code_test,omitempty"`         // Programming exercise. Optional
+	OrderNumber     int              `json:"order_number"`                // Test order number
+	MaxAttempts     int              `json:"max_attempts"`                // Max count of attempts. 0 is unlimited
+	Cooldown        int              `json:"cooldown"`                    // Min seconds between attempts. 0 is no cooldown
+	Pool            string           `json:"pool,omitempty"`              // Question pool name. Test without pool is given to every learner
+}
+
+// ImportTestsQuery model for bulk import tests to stage
+type ImportTestsQuery struct {
+	Format        string `json:"format"`         // Quiz format: gift, aiken or qti
+	Content       string `json:"content"`        // Quiz file content
+	LemmingsCount int    `json:"lemmings_count"` // Count of lemmings for every imported test. Optional, 1 by default
+	DryRun        bool   `json:"dry_run"`        // Only parse and report, don't save tests
+}
+
+// ImportIssue item of quiz file which is not imported
+type ImportIssue struct {
+	Line    int    `json:"line"`    // Line number of item in quiz file
+	Message string `json:"message"` // Issue description
+}
+
+// ImportTestsResult result of bulk tests import
+type ImportTestsResult struct {
+	TestIds []string        `json:"test_ids,omitempty"` // Ids of created tests. Empty for dry run
+	Tests   []*AddTestQuery `json:"tests"`              // Parsed tests
+	Issues  []*ImportIssue  `json:"issues"`             // Unsupported or invalid items
 }
 
 type LoginQuery struct {
@@ -237,3 +696,232 @@ type UserConfirm struct {
 	ConfirmaCode   string    `json:"confirm_code"`     // Confirmation code for registration
 	Confirmed      bool      `json:"confirmed"`        // Confirmed if true
 }
+
+// CourseBundle portable course representation for moving courses between OpenCourse instances
+type CourseBundle struct {
+	Version    int            `json:"version"`     // Bundle format version
+	Id         string         `json:"id"`          // Bundle id. Stable for all exports of the same course
+	ExportDate time.Time      `json:"export_date"` // Date of export
+	Course     *Course        `json:"course"`      // Course data. Ids are source instance ids
+	Category   *CategoryRef   `json:"category"`    // Course category reference
+	Stages     []*BundleStage `json:"stages"`      // Course stages ordered by order number
+	Media      []string       `json:"media"`       // Media files paths are included in bundle archive
+}
+
+// CategoryRef reference to category. Category is resolved by id and then by name and language
+type CategoryRef struct {
+	Id   string `json:"id"`   // Category id in source instance
+	Name string `json:"name"` // Category name
+	Lang string `json:"lang"` // Category language
+}
+
+// BundleStage stage with tests in bundle
+type BundleStage struct {
+	Stage *Stage  `json:"stage"` // Stage data
+	Tests []*Test `json:"tests"` // Stage tests ordered by order number
+}
+
+// Certificate course completion certificate. Names are saved on issue
+type Certificate struct {
+	Id         string    `json:"id"`          // Certificate id
+	Code       string    `json:"code"`        // Unique verification code
+	UserId     string    `json:"user_id"`     // User id
+	CourseId   string    `json:"course_id"`   // Course id
+	UserName   string    `json:"user_name"`   // User name on issue date
+	CourseName string    `json:"course_name"` // Course name on issue date
+	DateIssue  time.Time `json:"date_issue"`  // Issue date
+}
+
+// CertificateVerification public result of certificate verification
+type CertificateVerification struct {
+	Valid      bool      `json:"valid"`                 // Certificate with code is issued
+	Code       string    `json:"code"`                  // Verification code
+	UserName   string    `json:"user_name,omitempty"`   // User name
+	CourseName string    `json:"course_name,omitempty"` // Course name
+	DateIssue  time.Time `json:"date_issue,omitempty"`  // Issue date
+}
+
+// BadgeClass Open Badges achievement definition
+type BadgeClass struct {
+	Id          string    `json:"id"`                  // Badge class id
+	Name        string    `json:"name"`                // Badge name
+	Description string    `json:"description"`         // Badge description
+	Image       string    `json:"image"`               // Badge image url
+	Criteria    string    `json:"criteria"`            // Human-readable criteria
+	Kind        string    `json:"kind"`                // Criteria kind, which is checked on award
+	CourseId    string    `json:"course_id,omitempty"` // Course id for course completed badge
+	Threshold   int       `json:"threshold,omitempty"` // Count of lemmings or streak days
+	DateCreate  time.Time `json:"date_create"`         // Date create badge class
+}
+
+// AddBadgeClassQuery model for create badge class
+type AddBadgeClassQuery struct {
+	Name        string `json:"name"`                // Badge name
+	Description string `json:"description"`         // Badge description
+	Image       string `json:"image"`               // Badge image url
+	Criteria    string `json:"criteria"`            // Human-readable criteria
+	Kind        string `json:"kind"`                // Criteria kind
+	CourseId    string `json:"course_id,omitempty"` // Course id for course completed badge
+	Threshold   int    `json:"threshold,omitempty"` // Count of lemmings or streak days
+}
+
+// BadgeAssertion badge awarded to user. Recipient is email hashed with salt
+type BadgeAssertion struct {
+	Id                string    `json:"id"`                          // Assertion id
+	BadgeClassId      string    `json:"badge_class_id"`              // Badge class id
+	UserId            string    `json:"user_id"`                     // User id
+	RecipientIdentity string    `json:"recipient_identity"`          // Hashed recipient email: sha256$hex
+	RecipientSalt     string    `json:"recipient_salt"`              // Salt of recipient hash
+	Evidence          string    `json:"evidence,omitempty"`          // Evidence narrative
+	IssuedOn          time.Time `json:"issued_on"`                   // Award date
+	Revoked           bool      `json:"revoked"`                     // Assertion is revoked
+	RevocationReason  string    `json:"revocation_reason,omitempty"` // Reason of revocation
+}
+
+// RevokeBadgeQuery model for revoke badge assertion
+type RevokeBadgeQuery struct {
+	Reason string `json:"reason"` // Reason of revocation
+}
+
+// WalletBadge badge in learner wallet
+type WalletBadge struct {
+	Assertion     *BadgeAssertion `json:"assertion"`                // Awarded assertion
+	BadgeClass    *BadgeClass     `json:"badge_class"`              // Badge definition
+	AssertionUrl  string          `json:"assertion_url"`            // Hosted Open Badges 2.0 assertion
+	CredentialUrl string          `json:"credential_url,omitempty"` // Signed Open Badges 3.0 credential. Empty, if issuer key isn't set
+}
+
+// Achievement achievement declared in achievements config
+type Achievement struct {
+	Id          string `json:"id"`          // Achievement id
+	Name        string `json:"name"`        // Achievement name
+	Description string `json:"description"` // Achievement description
+	Event       string `json:"event"`       // Event type, which is checked by rule
+	Xp          int    `json:"xp"`          // Rating added to user
+	Earned      bool   `json:"earned"`      // User earned achievement
+}
+
+// UserProgress gamification progress of user
+type UserProgress struct {
+	UserId        string         `json:"user_id"`                // User id
+	Rating        int            `json:"rating"`                 // User rating (XP)
+	Counters      map[string]int `json:"counters"`               // Count of events by event type
+	Streak        int            `json:"streak"`                 // Current days in a row with activity
+	LongestStreak int            `json:"longest_streak"`         // The longest streak
+	LastActive    time.Time      `json:"last_active,omitempty"`  // The last day with activity
+	Achievements  []string       `json:"achievements,omitempty"` // Earned achievement ids
+}
+
+// LeaderboardEntry place of user in leaderboard
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`      // Rank from 1, users with equal score have equal rank
+	UserId   string `json:"user_id"`   // User id
+	UserName string `json:"user_name"` // User name
+	Score    int    `json:"score"`     // Rating or lemmings for window
+}
+
+// Leaderboard page of leaderboard snapshot
+type Leaderboard struct {
+	Metric      string              `json:"metric"`             // Ranking metric
+	Scope       string              `json:"scope"`              // Leaderboard scope
+	ScopeId     string              `json:"scope_id,omitempty"` // Category or course id
+	Window      string              `json:"window"`             // Time window
+	DateRefresh time.Time           `json:"date_refresh"`       // Date of snapshot
+	Total       int64               `json:"total"`              // Count of ranked users
+	Entries     []*LeaderboardEntry `json:"entries"`            // Page of entries
+	Me          *LeaderboardEntry   `json:"me,omitempty"`       // Entry of caller, if caller is ranked
+}
+
+// ReviewSchedule spaced repetition schedule of passed test for user
+type ReviewSchedule struct {
+	UserId      string    `json:"user_id"`               // User id
+	TestId      string    `json:"test_id"`               // Test id
+	StageId     string    `json:"stage_id"`              // Stage id of test
+	CourseId    string    `json:"course_id"`             // Course id of test
+	Ease        float64   `json:"ease"`                  // Ease factor, at least 1.3
+	Interval    int       `json:"interval"`              // Days between the last and the next review
+	Repetitions int       `json:"repetitions"`           // Count of successful reviews in a row
+	LastQuality int       `json:"last_quality"`          // Quality of the last review from 0 to 5
+	DateDue     time.Time `json:"date_due"`              // Date of the next review
+	DateReview  time.Time `json:"date_review,omitempty"` // Date of the last review
+}
+
+// ReviewItem test due for review
+type ReviewItem struct {
+	Schedule *ReviewSchedule `json:"schedule"` // Review schedule
+	Test     *TestPreview    `json:"test"`     // Test without right answers
+}
+
+// ReviewResult result of review answer
+type ReviewResult struct {
+	Grade    *GradeResult    `json:"grade"`    // Grade of answer
+	Quality  int             `json:"quality"`  // Recall quality from 0 to 5
+	Schedule *ReviewSchedule `json:"schedule"` // Updated schedule
+}
+
+// SearchQuery query of course search. Empty fields aren't filtered
+type SearchQuery struct {
+	Text       string    `json:"text,omitempty"`        // Full-text query over course and stage content
+	Lang       string    `json:"lang,omitempty"`        // Course language
+	CategoryId string    `json:"category_id,omitempty"` // Course category
+	Tags       []string  `json:"tags,omitempty"`        // Course has every tag
+	MinRating  *int      `json:"min_rating,omitempty"`  // Minimal course rating
+	MaxRating  *int      `json:"max_rating,omitempty"`  // Maximal course rating
+	DateFrom   time.Time `json:"date_from,omitempty"`   // Course is created at or after date
+	DateTo     time.Time `json:"date_to,omitempty"`     // Course is created before date
+	Take       int64     `json:"take"`                  // Count of courses
+	Skip       int64     `json:"skip"`                  // Count of skipped courses
+}
+
+// FacetCount count of found courses with facet value
+type FacetCount struct {
+	Value string `json:"value"` // Tag or category id
+	Count int    `json:"count"` // Count of courses
+}
+
+// SearchFacets facet counts of found courses
+type SearchFacets struct {
+	Tags       []*FacetCount `json:"tags"`       // Counts by tag
+	Categories []*FacetCount `json:"categories"` // Counts by category
+}
+
+// SearchResult page of found courses ordered by relevance, then by rating
+type SearchResult struct {
+	Total      int64         `json:"total"`                // Count of found courses
+	Courses    []*Course     `json:"courses"`              // Page of courses
+	Facets     *SearchFacets `json:"facets"`               // Facet counts of all found courses
+	Highlights []*Highlight  `json:"highlights,omitempty"` // Matched fragments of stages of page courses
+}
+
+// Highlight matched fragments of stage content
+type Highlight struct {
+	CourseId  string   `json:"course_id"` // Course id
+	StageId   string   `json:"stage_id"`  // Stage id
+	Fragments []string `json:"fragments"` // Fragments of stage body with matched words in <mark> tags
+}
+
+// PageQuery position and size of list page
+type PageQuery struct {
+	Cursor string `json:"cursor,omitempty"` // Cursor of page, which is returned with previous page. Skip isn't used with cursor
+	Take   int64  `json:"take"`             // Count of items. 0 is all items
+	Skip   int64  `json:"skip"`             // Count of skipped items. It's kept for compatibility
+}
+
+// Page position of next list page
+type Page struct {
+	NextCursor string `json:"next_cursor,omitempty"` // Opaque cursor of next page
+	HasMore    bool   `json:"has_more"`              // List has next page
+}
+
+// Translation content variant of translation group
+type Translation struct {
+	Id   string `json:"id"`   // Content id
+	Lang string `json:"lang"` // Content language
+}
+
+// LinkTranslationQuery model for add content to translation group of source content
+type LinkTranslationQuery struct {
+	Kind          string `json:"kind"`           // Translation kind
+	SourceId      string `json:"source_id"`      // Source content id
+	TranslationId string `json:"translation_id"` // Translated content id. Its group is merged to group of source
+}
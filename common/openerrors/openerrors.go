@@ -1,5 +1,7 @@
 package openerrors
 
+import "time"
+
 // NOTE! In this project, as an experiment, all openerrors are wrapped in special types
 // Open - project prefix
 
@@ -49,6 +51,22 @@ type DbErr struct {
 	DbErr   string  // Database's error
 }
 
+// AttemptDeniedErr error if user can't answer test now
+type AttemptDeniedErr struct {
+	BaseErr     BaseErr   // File contains error
+	Attempts    int       // Count of user attempts
+	MaxAttempts int       // Max count of attempts
+	RetryAfter  time.Time // Date of the next allowed attempt. Zero if attempts limit is reached
+}
+
+// SessionClosedErr error if quiz session doesn't accept answers
+type SessionClosedErr struct {
+	BaseErr   BaseErr   // File contains error
+	SessionId string    // Session id
+	Status    string    // Session status
+	Deadline  time.Time // Session deadline
+}
+
 // InvalidIdErr convert id error from user string to db object
 type InvalidIdErr struct {
 	Default   DefaultErr // File contains error
@@ -3,6 +3,7 @@ package openerrors
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 // DefaultErr common error implementation
@@ -48,3 +49,19 @@ func (err InvalidIdErr) Error() string {
 	return fmt.Sprintf("%s | user id value: %s | converter: %s",
 		err.Default.Error(), err.Id, err.Converter)
 }
+
+// AttemptDeniedErr implementation
+func (err AttemptDeniedErr) Error() string {
+	if err.RetryAfter.IsZero() {
+		return fmt.Sprintf("%s | message: attempts limit %d is reached", err.BaseErr.Error(), err.MaxAttempts)
+	}
+
+	return fmt.Sprintf("%s | message: the next attempt is allowed after %s",
+		err.BaseErr.Error(), err.RetryAfter.Format(time.RFC3339))
+}
+
+// SessionClosedErr implementation
+func (err SessionClosedErr) Error() string {
+	return fmt.Sprintf("%s | message: session %s with status %s and deadline %s doesn't accept answers",
+		err.BaseErr.Error(), err.SessionId, err.Status, err.Deadline.Format(time.RFC3339))
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"opencourse/clock"
+	"opencourse/common"
+	"testing"
+	"time"
+)
+
+// TestCheckAttempt attempt is denied from the attempts limit and until cooldown from date_update is over
+func TestCheckAttempt(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	manual := clock.NewManualClock(start)
+
+	userTest := &DbUserTest{Attempts: 1, DateUpdate: primitive.NewDateTimeFromTime(start)}
+	test := &common.Test{MaxAttempts: 2, Cooldown: 60}
+
+	manual.Advance(59 * time.Second)
+
+	if checkAttempt(userTest, test, manual.Now(), "TestCheckAttempt") == nil {
+		t.Error("attempt before cooldown is over must be denied")
+	}
+
+	manual.Advance(time.Second)
+
+	if err := checkAttempt(userTest, test, manual.Now(), "TestCheckAttempt"); err != nil {
+		t.Errorf("attempt after cooldown must be allowed: %v", err)
+	}
+
+	userTest.Attempts = 2
+
+	if checkAttempt(userTest, test, manual.Now(), "TestCheckAttempt") == nil {
+		t.Error("attempt after limit must be denied")
+	}
+
+	// The first attempt hasn't cooldown
+	if err := checkAttempt(&DbUserTest{}, test, start, "TestCheckAttempt"); err != nil {
+		t.Errorf("the first attempt must be allowed: %v", err)
+	}
+}
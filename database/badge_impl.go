@@ -0,0 +1,542 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/events"
+	"strings"
+)
+
+// ClearBadges remove all data from badge_classes and badge_assertions collections
+func (ctx *DbContext) ClearBadges() error {
+	for _, collection := range []string{BadgeClassCollection, BadgeAssertionCollection} {
+		col := ctx.Client.Database(DbName).Collection(collection)
+
+		_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+		if err != nil {
+			return openerrors.DbErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/badge_impl.go",
+					Method: "ClearBadges",
+				},
+				DbName: ctx.DbName,
+				ConStr: ctx.ConStr,
+				DbErr:  err.Error(),
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+AddBadgeClass create badge class and return its id. Parameters:
+query - badge class. Course id is required for course completed badge, threshold for lemmings and streak badges;
+*/
+func (ctx *DbContext) AddBadgeClass(query *common.AddBadgeClassQuery) (string, error) {
+	col := ctx.Client.Database(DbName).Collection(BadgeClassCollection)
+
+	if query == nil {
+		return "", openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "AddBadgeClass",
+			},
+			Model: "query",
+		}
+	}
+
+	field := ""
+
+	switch {
+	case len(query.Name) == 0:
+		field = "query.Name"
+	case len(query.Description) == 0:
+		field = "query.Description"
+	case len(query.Image) == 0:
+		field = "query.Image"
+	case len(query.Criteria) == 0:
+		field = "query.Criteria"
+	case query.Kind == common.BadgeCourseCompleted && len(query.CourseId) == 0:
+		field = "query.CourseId"
+	case (query.Kind == common.BadgeLemmings || query.Kind == common.BadgeStreak) && query.Threshold <= 0:
+		field = "query.Threshold"
+	case query.Kind != common.BadgeCourseCompleted && query.Kind != common.BadgeLemmings && query.Kind != common.BadgeStreak:
+		field = "query.Kind"
+	}
+
+	if len(field) > 0 {
+		return "", openerrors.FieldEmptyErr{
+			Field: field,
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "AddBadgeClass",
+			},
+		}
+	}
+
+	dbBadgeClass := DbBadgeClass{
+		Name:        query.Name,
+		Description: query.Description,
+		Image:       query.Image,
+		Criteria:    query.Criteria,
+		Kind:        query.Kind,
+		Threshold:   query.Threshold,
+		DateCreate:  primitive.NewDateTimeFromTime(ctx.Now()),
+	}
+
+	if query.Kind == common.BadgeCourseCompleted {
+		objectCourseId, err := primitive.ObjectIDFromHex(query.CourseId)
+
+		if err != nil {
+			return "", openerrors.InvalidIdErr{
+				Id:        query.CourseId,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/badge_impl.go",
+						Method: "AddBadgeClass",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		dbBadgeClass.CourseId = objectCourseId
+	}
+
+	result, err := col.InsertOne(context.Background(), dbBadgeClass)
+
+	if err != nil {
+		return "", openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "AddBadgeClass",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetBadgeClasses return all badge classes ordered by create date
+func (ctx *DbContext) GetBadgeClasses() ([]*common.BadgeClass, error) {
+	col := ctx.Client.Database(DbName).Collection(BadgeClassCollection)
+
+	ops := options.Find().SetSort(bson.D{{"date_create", 1}})
+
+	cursor, err := col.Find(context.Background(), bson.D{{}}, ops)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "GetBadgeClasses",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbBadgeClasses []*DbBadgeClass
+
+	err = cursor.All(context.Background(), &dbBadgeClasses)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "GetBadgeClasses",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var badgeClasses []*common.BadgeClass
+
+	for _, dbBadgeClass := range dbBadgeClasses {
+		badgeClass, err := dbBadgeClass.ToBadgeClass()
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/badge_impl.go",
+					Method: "GetBadgeClasses",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		badgeClasses = append(badgeClasses, badgeClass)
+	}
+
+	return badgeClasses, nil
+}
+
+/*
+GetBadgeClass return badge class by id. If badge class doesn't exist, return nil. Parameters:
+badgeClassId - badge class id;
+*/
+func (ctx *DbContext) GetBadgeClass(badgeClassId string) (*common.BadgeClass, error) {
+	col := ctx.Client.Database(DbName).Collection(BadgeClassCollection)
+
+	objectBadgeClassId, err := primitive.ObjectIDFromHex(badgeClassId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        badgeClassId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/badge_impl.go",
+					Method: "GetBadgeClass",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbBadgeClass DbBadgeClass
+
+	err = col.FindOne(context.Background(), bson.D{{"_id", objectBadgeClassId}}).Decode(&dbBadgeClass)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "GetBadgeClass",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	badgeClass, err := dbBadgeClass.ToBadgeClass()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "GetBadgeClass",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return badgeClass, nil
+}
+
+/*
+AwardBadge award badge to user. Recipient email is saved as salted SHA-256 hash. If user has badge,
+it's returned and false. Parameters:
+badgeClassId - badge class id;
+userId - user id;
+email - recipient email;
+evidence - evidence narrative;
+*/
+func (ctx *DbContext) AwardBadge(badgeClassId string, userId string, email string,
+	evidence string) (*common.BadgeAssertion, bool, error) {
+
+	col := ctx.Client.Database(DbName).Collection(BadgeAssertionCollection)
+
+	objectIds := make(map[string]primitive.ObjectID, 2)
+
+	for _, id := range []string{badgeClassId, userId} {
+		objectId, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			return nil, false, openerrors.InvalidIdErr{
+				Id:        id,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/badge_impl.go",
+						Method: "AwardBadge",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		objectIds[id] = objectId
+	}
+
+	salt := make([]byte, 8)
+
+	_, err := rand.Read(salt)
+
+	if err != nil {
+		return nil, false, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "AwardBadge",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	dbAssertion := DbBadgeAssertion{
+		BadgeClassId:  objectIds[badgeClassId],
+		UserId:        objectIds[userId],
+		RecipientSalt: hex.EncodeToString(salt),
+		Evidence:      evidence,
+		IssuedOn:      primitive.NewDateTimeFromTime(ctx.Now()),
+	}
+
+	hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email)) + dbAssertion.RecipientSalt))
+	dbAssertion.RecipientIdentity = "sha256$" + hex.EncodeToString(hash[:])
+
+	filter := bson.D{{"badge_class_id", dbAssertion.BadgeClassId}, {"user_id", dbAssertion.UserId}}
+	update := bson.D{{"$setOnInsert", dbAssertion}}
+	ops := options.Update().SetUpsert(true)
+
+	result, err := col.UpdateOne(context.Background(), filter, update, ops)
+
+	// Concurrent award of the same badge, the first one is kept
+	if mongo.IsDuplicateKeyError(err) {
+		err = nil
+		result = &mongo.UpdateResult{}
+	}
+
+	if err != nil {
+		return nil, false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "AwardBadge",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	err = col.FindOne(context.Background(), filter).Decode(&dbAssertion)
+
+	if err != nil {
+		return nil, false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "AwardBadge",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	assertion, err := dbAssertion.ToBadgeAssertion()
+
+	if err != nil {
+		return nil, false, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "AwardBadge",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	if result.UpsertedCount == 1 {
+		ctx.publish(events.Event{Type: events.BadgeAwarded, UserId: userId, TargetId: assertion.Id})
+	}
+
+	return assertion, result.UpsertedCount == 1, nil
+}
+
+/*
+GetBadgeAssertion return badge assertion by id. If assertion doesn't exist, return nil. Parameters:
+assertionId - assertion id;
+*/
+func (ctx *DbContext) GetBadgeAssertion(assertionId string) (*common.BadgeAssertion, error) {
+	col := ctx.Client.Database(DbName).Collection(BadgeAssertionCollection)
+
+	objectAssertionId, err := primitive.ObjectIDFromHex(assertionId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        assertionId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/badge_impl.go",
+					Method: "GetBadgeAssertion",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbAssertion DbBadgeAssertion
+
+	err = col.FindOne(context.Background(), bson.D{{"_id", objectAssertionId}}).Decode(&dbAssertion)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "GetBadgeAssertion",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	assertion, err := dbAssertion.ToBadgeAssertion()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "GetBadgeAssertion",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return assertion, nil
+}
+
+/*
+GetUserBadges return badges awarded to user ordered by award date. Parameters:
+userId - user id;
+*/
+func (ctx *DbContext) GetUserBadges(userId string) ([]*common.BadgeAssertion, error) {
+	col := ctx.Client.Database(DbName).Collection(BadgeAssertionCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/badge_impl.go",
+					Method: "GetUserBadges",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	ops := options.Find().SetSort(bson.D{{"issued_on", 1}})
+
+	cursor, err := col.Find(context.Background(), bson.D{{"user_id", objectUserId}}, ops)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "GetUserBadges",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbAssertions []*DbBadgeAssertion
+
+	err = cursor.All(context.Background(), &dbAssertions)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "GetUserBadges",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var assertions []*common.BadgeAssertion
+
+	for _, dbAssertion := range dbAssertions {
+		assertion, err := dbAssertion.ToBadgeAssertion()
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/badge_impl.go",
+					Method: "GetUserBadges",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		assertions = append(assertions, assertion)
+	}
+
+	return assertions, nil
+}
+
+/*
+RevokeBadge revoke badge assertion. Revoked assertion isn't valid, but it's kept for verifiers.
+Returns false, if assertion isn't found. Parameters:
+assertionId - assertion id;
+reason - reason of revocation;
+*/
+func (ctx *DbContext) RevokeBadge(assertionId string, reason string) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(BadgeAssertionCollection)
+
+	objectAssertionId, err := primitive.ObjectIDFromHex(assertionId)
+
+	if err != nil {
+		return false, openerrors.InvalidIdErr{
+			Id:        assertionId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/badge_impl.go",
+					Method: "RevokeBadge",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	update := bson.D{{"$set", bson.D{{"revoked", true}, {"revocation_reason", reason}}}}
+
+	result, err := col.UpdateOne(context.Background(), bson.D{{"_id", objectAssertionId}}, update)
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/badge_impl.go",
+				Method: "RevokeBadge",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return result.MatchedCount > 0, nil
+}
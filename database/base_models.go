@@ -34,6 +34,96 @@ type DbOptionTest struct {
 }
 
 type DbRewriteTest struct {
-	Question    string `bson:"question"`
-	RightAnswer string `bson:"right_answer"`
+	Question    string             `bson:"question"`
+	RightAnswer string             `bson:"right_answer"`
+	Matching    *DbRewriteMatching `bson:"matching,omitempty"`
+}
+
+type DbRewriteMatching struct {
+	IgnoreCase        bool     `bson:"ignore_case"`
+	IgnoreWhitespace  bool     `bson:"ignore_whitespace"`
+	IgnorePunctuation bool     `bson:"ignore_punctuation"`
+	UnicodeNormalize  bool     `bson:"unicode_normalize"`
+	FoldDiacritics    bool     `bson:"fold_diacritics"`
+	AcceptedAnswers   []string `bson:"accepted_answers,omitempty"`
+	Patterns          []string `bson:"patterns,omitempty"`
+	MaxDistance       int      `bson:"max_distance"`
+}
+
+type DbMultiSelectTest struct {
+	Question      string      `bson:"question"`
+	Options       []*DbOption `bson:"options"`
+	PartialCredit bool        `bson:"partial_credit"`
+}
+
+type DbOrderingTest struct {
+	Question string   `bson:"question"`
+	Items    []string `bson:"items"` // Items in the right order
+}
+
+type DbMatchPair struct {
+	Left  string `bson:"left"`
+	Right string `bson:"right"`
+}
+
+type DbMatchingTest struct {
+	Question string         `bson:"question"`
+	Pairs    []*DbMatchPair `bson:"pairs"`
+}
+
+type DbClozeBlank struct {
+	Answers []string `bson:"answers"`
+}
+
+type DbClozeTest struct {
+	Text   string          `bson:"text"` // Text with blanks placeholders {{1}}, {{2}}...
+	Blanks []*DbClozeBlank `bson:"blanks"`
+}
+
+type DbNumericTest struct {
+	Question  string  `bson:"question"`
+	Answer    float64 `bson:"answer"`
+	Tolerance float64 `bson:"tolerance"`
+}
+
+type DbCodeCase struct {
+	Input          string `bson:"input"`
+	ExpectedOutput string `bson:"expected_output"`
+	Hidden         bool   `bson:"hidden"`
+}
+
+type DbCodeTest struct {
+	Question    string        `bson:"question"`
+	Language    string        `bson:"language"`
+	StarterCode string        `bson:"starter_code"`
+	Cases       []*DbCodeCase `bson:"cases"`
+}
+
+// DbTestAnswer user answer. Matching pairs are stored as array, because left items may contain dots
+type DbTestAnswer struct {
+	Options []string       `bson:"options,omitempty"`
+	Text    string         `bson:"text,omitempty"`
+	Order   []string       `bson:"order,omitempty"`
+	Pairs   []*DbMatchPair `bson:"pairs,omitempty"`
+	Blanks  []string       `bson:"blanks,omitempty"`
+	Number  float64        `bson:"number,omitempty"`
+	Code    string         `bson:"code,omitempty"`
+}
+
+type DbSessionAnswer struct {
+	TestId     primitive.ObjectID `bson:"test_id"`
+	Answer     *DbTestAnswer      `bson:"answer"`
+	DateUpdate primitive.DateTime `bson:"date_update"`
+}
+
+type DbSessionResult struct {
+	TestId   primitive.ObjectID `bson:"test_id"`
+	Answered bool               `bson:"answered"`
+	Score    float64            `bson:"score"`
+	IsPassed bool               `bson:"is_passed"`
+}
+
+type DbQuestionPool struct {
+	Name      string `bson:"name"`
+	DrawCount int    `bson:"draw_count"`
 }
@@ -0,0 +1,581 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/events"
+	"strings"
+	"time"
+)
+
+/*
+ExportCourse collect course, category reference, stages and tests to bundle. Parameters:
+courseId - course id;
+*/
+func (ctx *DbContext) ExportCourse(courseId string) (*common.CourseBundle, error) {
+	db := ctx.Client.Database(DbName)
+
+	objectCourseId, err := primitive.ObjectIDFromHex(courseId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        courseId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/bundle_impl.go",
+					Method: "ExportCourse",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbCourse DbCourse
+	err = db.Collection(CourseCollection).FindOne(context.Background(), bson.D{{"_id", objectCourseId}}).Decode(&dbCourse)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/bundle_impl.go",
+				Method: "ExportCourse",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	course, err := dbCourse.ToCourse()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/bundle_impl.go",
+				Method: "ExportCourse",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	bundle := &common.CourseBundle{
+		Version:    common.BundleVersion,
+		Id:         course.Id,
+		ExportDate: time.Now().UTC(),
+		Course:     course,
+		Category:   &common.CategoryRef{Id: course.CategoryId},
+	}
+
+	var dbCategory DbCategory
+	err = db.Collection(CategoryCollection).FindOne(context.Background(),
+		bson.D{{"_id", dbCourse.CategoryId}}).Decode(&dbCategory)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/bundle_impl.go",
+				Method: "ExportCourse",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	if err == nil {
+		bundle.Category.Name = dbCategory.Name
+		bundle.Category.Lang = dbCategory.Lang
+	}
+
+	ops := options.Find().SetSort(bson.D{{"order_number", 1}})
+
+	cursor, err := db.Collection(StageCollection).Find(context.Background(), bson.D{{"course_id", objectCourseId}}, ops)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/bundle_impl.go",
+				Method: "ExportCourse",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbStages []*DbStage
+
+	err = cursor.All(context.Background(), &dbStages)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/bundle_impl.go",
+				Method: "ExportCourse",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	for _, dbStage := range dbStages {
+		stage, err := dbStage.ToStage()
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/bundle_impl.go",
+					Method: "ExportCourse",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		cursor, err := db.Collection(TestCollection).Find(context.Background(), bson.D{{"stage_id", dbStage.Id}}, ops)
+
+		if err != nil {
+			return nil, openerrors.DbErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/bundle_impl.go",
+					Method: "ExportCourse",
+				},
+				DbName: ctx.DbName,
+				ConStr: ctx.ConStr,
+				DbErr:  err.Error(),
+			}
+		}
+
+		var dbTests []*DbTest
+
+		err = cursor.All(context.Background(), &dbTests)
+
+		if err != nil {
+			return nil, openerrors.DbErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/bundle_impl.go",
+					Method: "ExportCourse",
+				},
+				DbName: ctx.DbName,
+				ConStr: ctx.ConStr,
+				DbErr:  err.Error(),
+			}
+		}
+
+		bundleStage := &common.BundleStage{Stage: stage}
+
+		for _, dbTest := range dbTests {
+			test, err := dbTest.ToTest()
+
+			if err != nil {
+				return nil, openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/bundle_impl.go",
+						Method: "ExportCourse",
+					},
+					Msg: err.Error(),
+				}
+			}
+
+			bundleStage.Tests = append(bundleStage.Tests, test)
+		}
+
+		bundle.Stages = append(bundle.Stages, bundleStage)
+	}
+
+	return bundle, nil
+}
+
+/*
+ImportCourse create or update course from bundle in one transaction. Repeated import of the same bundle
+by the same author updates documents created by the previous import. Parameters:
+bundle - validated course bundle;
+categoryId - target category id. Optional, may be set empty string, then category is resolved by bundle reference;
+authorId - id of author, who imports course. Imported course belongs to author;
+*/
+func (ctx *DbContext) ImportCourse(bundle *common.CourseBundle, categoryId string, authorId string) (string, error) {
+	if bundle == nil || bundle.Course == nil {
+		return "", openerrors.ModelNilOrEmptyErr{
+			Model: "bundle",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/bundle_impl.go",
+				Method: "ImportCourse",
+			},
+		}
+	}
+
+	if len(bundle.Id) == 0 {
+		return "", openerrors.FieldEmptyErr{
+			Field: "bundle.Id",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/bundle_impl.go",
+				Method: "ImportCourse",
+			},
+		}
+	}
+
+	objectAuthorId, err := primitive.ObjectIDFromHex(authorId)
+
+	if err != nil {
+		return "", openerrors.InvalidIdErr{
+			Id:        authorId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/bundle_impl.go",
+					Method: "ImportCourse",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	// Tests are validated before any change with the same rules as new tests
+	tests, err := bundleTests(bundle)
+
+	if err != nil {
+		return "", err
+	}
+
+	objectCategoryId, err := ctx.resolveBundleCategory(bundle.Category, categoryId)
+
+	if err != nil {
+		return "", openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/bundle_impl.go",
+				Method: "ImportCourse",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	session, err := ctx.Client.StartSession()
+
+	if err != nil {
+		return "", openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/bundle_impl.go",
+				Method: "ImportCourse",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer session.EndSession(context.Background())
+
+	// Stages removed since previous import. Transaction can be retried, so they are collected by every run
+	var removedStageIds []string
+
+	result, err := session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		db := ctx.Client.Database(DbName)
+		replaceOps := options.Replace().SetUpsert(true)
+		removedStageIds = nil
+		dateNow := primitive.NewDateTimeFromTime(time.Now().UTC())
+
+		// Ids are mapped for author, so bundle of other author creates own copy of course
+		courseId, err := ctx.mapBundleId(sc, objectAuthorId, bundle.Id, MappingCourse, bundle.Course.Id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var dbCourse DbCourse
+
+		err = db.Collection(CourseCollection).FindOne(sc, bson.D{{"_id", courseId}}).Decode(&dbCourse)
+
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+
+		// New course starts disabled and without rating, updated course keeps them
+		if err == mongo.ErrNoDocuments {
+			dbCourse.DateCreate = dateNow
+			dbCourse.AuthorId = objectAuthorId
+		}
+
+		if dbCourse.AuthorId != objectAuthorId {
+			return nil, errors.New("course belongs to other author")
+		}
+
+		dbCourse.Id = courseId
+		dbCourse.CategoryId = objectCategoryId
+		dbCourse.Name = bundle.Course.Name
+		dbCourse.Lang = bundle.Course.Lang
+		dbCourse.Tags = bundle.Course.Tags
+		dbCourse.Description = bundle.Course.Description
+		dbCourse.IconImg = bundle.Course.IconImg
+		dbCourse.HeaderImg = bundle.Course.HeaderImg
+		dbCourse.DateUpdate = dateNow
+
+		_, err = db.Collection(CourseCollection).ReplaceOne(sc, bson.D{{"_id", courseId}}, dbCourse, replaceOps)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var stageIds []primitive.ObjectID
+		var testIds []primitive.ObjectID
+
+		for _, bundleStage := range bundle.Stages {
+			dbStage, err := ToDbStage(bundleStage.Stage)
+
+			if err != nil {
+				return nil, err
+			}
+
+			dbStage.Id, err = ctx.mapBundleId(sc, objectAuthorId, bundle.Id, MappingStage, bundleStage.Stage.Id)
+
+			if err != nil {
+				return nil, err
+			}
+
+			dbStage.CourseId = courseId
+			stageIds = append(stageIds, dbStage.Id)
+
+			_, err = db.Collection(StageCollection).ReplaceOne(sc, bson.D{{"_id", dbStage.Id}}, dbStage, replaceOps)
+
+			if err != nil {
+				return nil, err
+			}
+
+			for _, test := range bundleStage.Tests {
+				dbTest, err := ToDbTest(tests[test])
+
+				if err != nil {
+					return nil, err
+				}
+
+				dbTest.Id, err = ctx.mapBundleId(sc, objectAuthorId, bundle.Id, MappingTest, test.Id)
+
+				if err != nil {
+					return nil, err
+				}
+
+				dbTest.StageId = dbStage.Id
+				testIds = append(testIds, dbTest.Id)
+
+				_, err = db.Collection(TestCollection).ReplaceOne(sc, bson.D{{"_id", dbTest.Id}}, dbTest, replaceOps)
+
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// Remove stages and tests which were deleted from bundle since previous import
+		var oldStages []*DbStage
+
+		cursor, err := db.Collection(StageCollection).Find(sc, bson.D{{"course_id", courseId}},
+			options.Find().SetProjection(bson.D{{"_id", 1}}))
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = cursor.All(sc, &oldStages)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var oldStageIds []primitive.ObjectID
+		imported := make(map[primitive.ObjectID]bool, len(stageIds))
+
+		for _, stageId := range stageIds {
+			imported[stageId] = true
+		}
+
+		for _, oldStage := range oldStages {
+			oldStageIds = append(oldStageIds, oldStage.Id)
+
+			if !imported[oldStage.Id] {
+				removedStageIds = append(removedStageIds, oldStage.Id.Hex())
+			}
+		}
+
+		if len(oldStageIds) > 0 {
+			_, err = db.Collection(TestCollection).DeleteMany(sc, bson.D{
+				{"stage_id", bson.D{{"$in", oldStageIds}}},
+				{"_id", bson.D{{"$nin", append(testIds, primitive.NilObjectID)}}},
+			})
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = db.Collection(StageCollection).DeleteMany(sc, bson.D{
+			{"course_id", courseId},
+			{"_id", bson.D{{"$nin", append(stageIds, primitive.NilObjectID)}}},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return courseId, nil
+	})
+
+	if err != nil {
+		return "", openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/bundle_impl.go",
+				Method: "ImportCourse",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	courseId := result.(primitive.ObjectID).Hex()
+
+	// Events are published after commit. Saved course event updates course with all its stages
+	for _, stageId := range removedStageIds {
+		ctx.publishContent(events.StageDeleted, courseId, stageId)
+	}
+
+	ctx.publishContent(events.CourseSaved, courseId, "")
+
+	return courseId, nil
+}
+
+// resolveBundleCategory find target category by explicit id, by bundle reference id or by name and language
+func (ctx *DbContext) resolveBundleCategory(ref *common.CategoryRef, categoryId string) (primitive.ObjectID, error) {
+	col := ctx.Client.Database(DbName).Collection(CategoryCollection)
+
+	if len(categoryId) > 0 {
+		return primitive.ObjectIDFromHex(categoryId)
+	}
+
+	if ref == nil {
+		return primitive.NilObjectID, errors.New("bundle hasn't category reference, set target category")
+	}
+
+	var dbCategory DbCategory
+
+	objectCategoryId, err := primitive.ObjectIDFromHex(ref.Id)
+
+	if err == nil {
+		err = col.FindOne(context.Background(), bson.D{{"_id", objectCategoryId}}).Decode(&dbCategory)
+
+		if err == nil {
+			return dbCategory.Id, nil
+		}
+
+		if err != mongo.ErrNoDocuments {
+			return primitive.NilObjectID, err
+		}
+	}
+
+	err = col.FindOne(context.Background(), bson.D{{"name", ref.Name}, {"lang", ref.Lang}}).Decode(&dbCategory)
+
+	if err == mongo.ErrNoDocuments {
+		return primitive.NilObjectID, errors.New("category from bundle is not found, set target category")
+	}
+
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return dbCategory.Id, nil
+}
+
+// mapBundleId return id of document created by previous import of author or register new id for bundle document
+func (ctx *DbContext) mapBundleId(sc mongo.SessionContext, ownerId primitive.ObjectID, bundleId string, kind string,
+	sourceId string) (primitive.ObjectID, error) {
+
+	col := ctx.Client.Database(DbName).Collection(ImportMappingCollection)
+
+	filter := bson.D{{"owner_id", ownerId}, {"bundle_id", bundleId}, {"kind", kind}, {"source_id", sourceId}}
+
+	var dbMapping DbImportMapping
+	err := col.FindOne(sc, filter).Decode(&dbMapping)
+
+	if err == nil {
+		return dbMapping.TargetId, nil
+	}
+
+	if err != mongo.ErrNoDocuments {
+		return primitive.NilObjectID, err
+	}
+
+	dbMapping.OwnerId = ownerId
+	dbMapping.BundleId = bundleId
+	dbMapping.Kind = kind
+	dbMapping.SourceId = sourceId
+	dbMapping.TargetId = primitive.NewObjectID()
+
+	_, err = col.InsertOne(sc, dbMapping)
+
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return dbMapping.TargetId, nil
+}
+
+/*
+bundleTests validate tests of bundle with the same rules as new tests. Returns tests with content only for
+test type by bundle tests. Parameters:
+bundle - course bundle;
+*/
+func bundleTests(bundle *common.CourseBundle) (map[*common.Test]*common.Test, error) {
+	tests := make(map[*common.Test]*common.Test)
+
+	for i, bundleStage := range bundle.Stages {
+		for j, test := range bundleStage.Tests {
+			field := fmt.Sprintf("bundle.Stages[%d].Tests[%d].", i, j)
+
+			if test.LemmingsCount < 1 {
+				return nil, openerrors.FieldEmptyErr{
+					Field: field + "LemmingsCount",
+					BaseErr: openerrors.BaseErr{
+						File:   "database/bundle_impl.go",
+						Method: "ImportCourse",
+					},
+				}
+			}
+
+			valid, invalidField := testContent(&common.AddTestQuery{
+				TestType:        test.TestType,
+				LemmingsCount:   test.LemmingsCount,
+				OptionTest:      test.OptionTest,
+				RewriteTest:     test.RewriteTest,
+				MultiSelectTest: test.MultiSelectTest,
+				OrderingTest:    test.OrderingTest,
+				MatchingTest:    test.MatchingTest,
+				ClozeTest:       test.ClozeTest,
+				NumericTest:     test.NumericTest,
+				CodeTest:        test.CodeTest,
+				OrderNumber:     test.OrderNumber,
+				MaxAttempts:     test.MaxAttempts,
+				Cooldown:        test.Cooldown,
+				Pool:            test.Pool,
+			})
+
+			if valid == nil {
+				return nil, openerrors.FieldEmptyErr{
+					Field: field + strings.TrimPrefix(invalidField, "query."),
+					BaseErr: openerrors.BaseErr{
+						File:   "database/bundle_impl.go",
+						Method: "ImportCourse",
+					},
+				}
+			}
+
+			tests[test] = valid
+		}
+	}
+
+	return tests, nil
+}
@@ -4,19 +4,27 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"opencourse/common"
 	"opencourse/common/openerrors"
+	"regexp"
+	"strings"
 )
 
-// GetCategories return all categories from db
-func (ctx *DbContext) GetCategories(lang string) ([]*common.Category, error) {
+/*
+GetCategories return categories from db with counts of courses in categories and descendants. Category of
+translation group is returned in the first language of group. Parameters:
+langs - categories languages in order of preference;
+*/
+func (ctx *DbContext) GetCategories(langs ...string) ([]*common.Category, error) {
 	col := ctx.Client.Database(DbName).Collection(CategoryCollection)
 
 	find := bson.D{
-		{"lang", lang},
+		{"lang", bson.D{{"$in", langs}}},
 	}
 
-	cursor, err := col.Find(context.Background(), find)
+	cursor, err := col.Find(context.Background(), find, options.Find().SetSort(bson.D{{"name", 1}}))
 
 	if err != nil {
 		return nil, openerrors.DbErr{
@@ -45,9 +53,27 @@ func (ctx *DbContext) GetCategories(lang string) ([]*common.Category, error) {
 		}
 	}
 
+	best := make(map[primitive.ObjectID]*DbCategory)
+
+	for _, dbCategory := range dbCategories {
+		group := dbCategory.Group
+
+		if !group.IsZero() && (best[group] == nil || langRank(langs, dbCategory.Lang) < langRank(langs, best[group].Lang)) {
+			best[group] = dbCategory
+		}
+	}
+
 	var categories []*common.Category
 
+	// Id of skipped variant -> id of returned variant of group
+	variantOf := make(map[string]string)
+
 	for _, dbCategory := range dbCategories {
+		if !dbCategory.Group.IsZero() && best[dbCategory.Group] != dbCategory {
+			variantOf[dbCategory.Id.Hex()] = best[dbCategory.Group].Id.Hex()
+			continue
+		}
+
 		category, err := dbCategory.ToCategory()
 
 		if err != nil {
@@ -63,9 +89,68 @@ func (ctx *DbContext) GetCategories(lang string) ([]*common.Category, error) {
 		categories = append(categories, category)
 	}
 
+	counts, err := ctx.countCategoryCourses()
+
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[string]*common.Category, len(categories))
+
+	for _, category := range categories {
+		byId[category.Id] = category
+
+		if variant, ok := variantOf[category.ParentId]; ok {
+			category.ParentId = variant
+		}
+	}
+
+	// Courses of category are counted for the category and every ancestor. Variants of group are counted together
+	for _, dbCategory := range dbCategories {
+		for _, id := range strings.Split(strings.Trim(categoryPath(dbCategory), "/"), "/") {
+			if variant, ok := variantOf[id]; ok {
+				id = variant
+			}
+
+			if ancestor, ok := byId[id]; ok {
+				ancestor.CourseCount += counts[dbCategory.Id.Hex()]
+			}
+		}
+	}
+
 	return categories, nil
 }
 
+/*
+GetCategoryTree return root categories with children. Parameters:
+langs - categories languages in order of preference;
+*/
+func (ctx *DbContext) GetCategoryTree(langs ...string) ([]*common.Category, error) {
+	categories, err := ctx.GetCategories(langs...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[string]*common.Category, len(categories))
+
+	for _, category := range categories {
+		byId[category.Id] = category
+	}
+
+	roots := make([]*common.Category, 0)
+
+	for _, category := range categories {
+		if parent, ok := byId[category.ParentId]; ok {
+			parent.Children = append(parent.Children, category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	return roots, nil
+}
+
 // AddCategory method for add new category and sub categories to database
 func (ctx *DbContext) AddCategory(addCategoryQuery *common.AddCategoryQuery) (string, error) {
 	col := ctx.Client.Database(DbName).Collection(CategoryCollection)
@@ -122,11 +207,34 @@ func (ctx *DbContext) AddCategory(addCategoryQuery *common.AddCategoryQuery) (st
 
 	category := DbCategory{}
 
+	category.Id = primitive.NewObjectID()
+	category.Path = "/" + category.Id.Hex() + "/"
 	category.Name = addCategoryQuery.Name
 	category.Lang = addCategoryQuery.Lang
 	category.IconImg = addCategoryQuery.IconImg
 	category.HeaderImg = addCategoryQuery.HeaderImg
 
+	if len(addCategoryQuery.ParentId) > 0 {
+		parent, err := ctx.getDbCategory(addCategoryQuery.ParentId, "AddCategory")
+
+		if err != nil {
+			return "", err
+		}
+
+		if parent.Lang != category.Lang {
+			return "", openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/category_impl.go",
+					Method: "AddCategory",
+				},
+				Msg: "parent category has other language",
+			}
+		}
+
+		category.ParentId = parent.Id
+		category.Path = categoryPath(parent) + category.Id.Hex() + "/"
+	}
+
 	result, err := col.InsertOne(context.Background(), category)
 
 	if err != nil {
@@ -195,3 +303,309 @@ func (ctx *DbContext) UpdateCategory(categoryId string, name string, lang string
 
 	return nil
 }
+
+/*
+MoveCategory move category with descendants to other parent. Parameters:
+categoryId - category id;
+parentId - new parent category id. Category becomes root if it's empty;
+*/
+func (ctx *DbContext) MoveCategory(categoryId string, parentId string) error {
+	col := ctx.Client.Database(DbName).Collection(CategoryCollection)
+
+	category, err := ctx.getDbCategory(categoryId, "MoveCategory")
+
+	if err != nil {
+		return err
+	}
+
+	oldPath := categoryPath(category)
+	newPath := "/" + category.Id.Hex() + "/"
+	parentUpdate := bson.D{{"$unset", bson.D{{"parent_id", ""}}}}
+
+	if len(parentId) > 0 {
+		parent, err := ctx.getDbCategory(parentId, "MoveCategory")
+
+		if err != nil {
+			return err
+		}
+
+		// Category can't be moved to itself or to descendant
+		if strings.HasPrefix(categoryPath(parent), oldPath) || parent.Lang != category.Lang {
+			return openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/category_impl.go",
+					Method: "MoveCategory",
+				},
+				Msg: "category can't be moved to own subtree or to category with other language",
+			}
+		}
+
+		newPath = categoryPath(parent) + category.Id.Hex() + "/"
+		parentUpdate = bson.D{{"$set", bson.D{{"parent_id", parent.Id}}}}
+	}
+
+	session, err := ctx.Client.StartSession()
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/category_impl.go",
+				Method: "MoveCategory",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		subtree := bson.D{{"$or", bson.A{bson.D{{"_id", category.Id}}, subtreeFilter(oldPath)}}}
+
+		_, err := col.UpdateMany(sc, subtree, pathUpdate(oldPath, newPath))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return col.UpdateOne(sc, bson.D{{"_id", category.Id}}, parentUpdate)
+	})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/category_impl.go",
+				Method: "MoveCategory",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+DeleteCategory delete category. Courses of category are moved to target category, children are moved
+to parent of category. Target must have the same language and can't be in subtree of category. Parameters:
+categoryId - category id;
+targetId - id of category, which gets courses of deleted category;
+*/
+func (ctx *DbContext) DeleteCategory(categoryId string, targetId string) error {
+	db := ctx.Client.Database(DbName)
+
+	if len(targetId) == 0 || targetId == categoryId {
+		return openerrors.FieldEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/category_impl.go",
+				Method: "DeleteCategory",
+			},
+			Field: "targetId",
+		}
+	}
+
+	category, err := ctx.getDbCategory(categoryId, "DeleteCategory")
+
+	if err != nil {
+		return err
+	}
+
+	target, err := ctx.getDbCategory(targetId, "DeleteCategory")
+
+	if err != nil {
+		return err
+	}
+
+	oldPath := categoryPath(category)
+
+	// Descendants of category are deleted with it or moved, so they can't get its courses
+	if strings.HasPrefix(categoryPath(target), oldPath) || target.Lang != category.Lang {
+		return openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/category_impl.go",
+				Method: "DeleteCategory",
+			},
+			Msg: "courses can't be moved to subtree of category or to category with other language",
+		}
+	}
+
+	newPath := strings.TrimSuffix(oldPath, category.Id.Hex()+"/")
+	childrenUpdate := bson.D{{"$unset", bson.D{{"parent_id", ""}}}}
+
+	if !category.ParentId.IsZero() {
+		childrenUpdate = bson.D{{"$set", bson.D{{"parent_id", category.ParentId}}}}
+	}
+
+	session, err := ctx.Client.StartSession()
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/category_impl.go",
+				Method: "DeleteCategory",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		_, err := db.Collection(CourseCollection).UpdateMany(sc, bson.D{{"category_id", category.Id}},
+			bson.D{{"$set", bson.D{{"category_id", target.Id}}}})
+
+		if err != nil {
+			return nil, err
+		}
+
+		col := db.Collection(CategoryCollection)
+
+		descendants := append(subtreeFilter(oldPath), bson.E{Key: "_id", Value: bson.D{{"$ne", category.Id}}})
+
+		_, err = col.UpdateMany(sc, descendants, pathUpdate(oldPath, newPath))
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = col.UpdateMany(sc, bson.D{{"parent_id", category.Id}}, childrenUpdate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return col.DeleteOne(sc, bson.D{{"_id", category.Id}})
+	})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/category_impl.go",
+				Method: "DeleteCategory",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+getDbCategory return category or error, if it isn't found. Parameters:
+categoryId - category id;
+method - method name for errors;
+*/
+func (ctx *DbContext) getDbCategory(categoryId string, method string) (*DbCategory, error) {
+	col := ctx.Client.Database(DbName).Collection(CategoryCollection)
+
+	objectCategoryId, err := primitive.ObjectIDFromHex(categoryId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        categoryId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/category_impl.go",
+					Method: method,
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbCategory DbCategory
+
+	err = col.FindOne(context.Background(), bson.D{{"_id", objectCategoryId}}).Decode(&dbCategory)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/category_impl.go",
+				Method: method,
+			},
+			Msg: "category " + categoryId + " isn't found",
+		}
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/category_impl.go",
+				Method: method,
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return &dbCategory, nil
+}
+
+// countCategoryCourses return count of courses by category id
+func (ctx *DbContext) countCategoryCourses() (map[string]int, error) {
+	col := ctx.Client.Database(DbName).Collection(CourseCollection)
+
+	pipeline := mongo.Pipeline{
+		bson.D{{"$group", bson.D{{"_id", "$category_id"}, {"count", bson.D{{"$sum", 1}}}}}},
+	}
+
+	cursor, err := col.Aggregate(context.Background(), pipeline)
+
+	var dbCounts []*struct {
+		CategoryId primitive.ObjectID `bson:"_id"`
+		Count      int                `bson:"count"`
+	}
+
+	if err == nil {
+		err = cursor.All(context.Background(), &dbCounts)
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/category_impl.go",
+				Method: "countCategoryCourses",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	counts := make(map[string]int, len(dbCounts))
+
+	for _, dbCount := range dbCounts {
+		counts[dbCount.CategoryId.Hex()] = dbCount.Count
+	}
+
+	return counts, nil
+}
+
+// categoryPath return materialized path of category. Categories, which are created before hierarchy, are roots
+func categoryPath(dbCategory *DbCategory) string {
+	if len(dbCategory.Path) > 0 {
+		return dbCategory.Path
+	}
+
+	return "/" + dbCategory.Id.Hex() + "/"
+}
+
+// subtreeFilter return filter of categories, which path starts with path
+func subtreeFilter(path string) bson.D {
+	return bson.D{{"path", primitive.Regex{Pattern: "^" + regexp.QuoteMeta(path)}}}
+}
+
+// pathUpdate return update, which replaces prefix of category path
+func pathUpdate(oldPrefix string, newPrefix string) mongo.Pipeline {
+	rest := bson.D{{"$substrBytes", bson.A{bson.D{{"$ifNull", bson.A{"$path", ""}}}, len(oldPrefix), -1}}}
+
+	return mongo.Pipeline{bson.D{{"$set", bson.D{{"path", bson.D{{"$concat", bson.A{newPrefix, rest}}}}}}}}
+}
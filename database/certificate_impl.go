@@ -0,0 +1,337 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"strings"
+)
+
+// certificateCodeRetries count of attempts to generate unique verification code
+const certificateCodeRetries = 3
+
+// ClearCertificates remove all data from certificates collection
+func (ctx *DbContext) ClearCertificates() error {
+	col := ctx.Client.Database(DbName).Collection(CertificateCollection)
+
+	_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/certificate_impl.go",
+				Method: "ClearCertificates",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+IssueCertificate issue certificate for course with unique verification code. If user has certificate
+for course, it's returned and false. Parameters:
+userId - user id;
+courseId - course id;
+userName - user name on certificate;
+courseName - course name on certificate;
+*/
+func (ctx *DbContext) IssueCertificate(userId string, courseId string, userName string,
+	courseName string) (*common.Certificate, bool, error) {
+
+	col := ctx.Client.Database(DbName).Collection(CertificateCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, false, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/certificate_impl.go",
+					Method: "IssueCertificate",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	objectCourseId, err := primitive.ObjectIDFromHex(courseId)
+
+	if err != nil {
+		return nil, false, openerrors.InvalidIdErr{
+			Id:        courseId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/certificate_impl.go",
+					Method: "IssueCertificate",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	filter := bson.D{{"user_id", objectUserId}, {"course_id", objectCourseId}}
+
+	var dbCertificate DbCertificate
+
+	for i := 0; i < certificateCodeRetries; i++ {
+		code, err := newCertificateCode()
+
+		if err != nil {
+			return nil, false, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/certificate_impl.go",
+					Method: "IssueCertificate",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		dbCertificate = DbCertificate{
+			Id:         primitive.NewObjectID(),
+			Code:       code,
+			UserId:     objectUserId,
+			CourseId:   objectCourseId,
+			UserName:   userName,
+			CourseName: courseName,
+			DateIssue:  primitive.NewDateTimeFromTime(ctx.Now()),
+		}
+
+		_, err = col.InsertOne(context.Background(), dbCertificate)
+
+		if err == nil {
+			certificate, err := dbCertificate.ToCertificate()
+
+			if err != nil {
+				return nil, false, openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/certificate_impl.go",
+						Method: "IssueCertificate",
+					},
+					Msg: err.Error(),
+				}
+			}
+
+			return certificate, true, nil
+		}
+
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, false, openerrors.DbErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/certificate_impl.go",
+					Method: "IssueCertificate",
+				},
+				DbName: ctx.DbName,
+				ConStr: ctx.ConStr,
+				DbErr:  err.Error(),
+			}
+		}
+
+		// Duplicate is issued certificate or the same code, the code is generated again
+		err = col.FindOne(context.Background(), filter).Decode(&dbCertificate)
+
+		if err == nil {
+			certificate, err := dbCertificate.ToCertificate()
+
+			if err != nil {
+				return nil, false, openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/certificate_impl.go",
+						Method: "IssueCertificate",
+					},
+					Msg: err.Error(),
+				}
+			}
+
+			return certificate, false, nil
+		}
+
+		if err != mongo.ErrNoDocuments {
+			return nil, false, openerrors.DbErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/certificate_impl.go",
+					Method: "IssueCertificate",
+				},
+				DbName: ctx.DbName,
+				ConStr: ctx.ConStr,
+				DbErr:  err.Error(),
+			}
+		}
+	}
+
+	return nil, false, openerrors.DefaultErr{
+		BaseErr: openerrors.BaseErr{
+			File:   "database/certificate_impl.go",
+			Method: "IssueCertificate",
+		},
+		Msg: "can't generate unique verification code",
+	}
+}
+
+/*
+GetCertificateByCode return certificate by verification code. If certificate doesn't exist, return nil. Parameters:
+code - verification code. Case and dashes are ignored;
+*/
+func (ctx *DbContext) GetCertificateByCode(code string) (*common.Certificate, error) {
+	col := ctx.Client.Database(DbName).Collection(CertificateCollection)
+
+	code = normalizeCertificateCode(code)
+
+	if len(code) == 0 {
+		return nil, openerrors.FieldEmptyErr{
+			Field: "code",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/certificate_impl.go",
+				Method: "GetCertificateByCode",
+			},
+		}
+	}
+
+	var dbCertificate DbCertificate
+
+	err := col.FindOne(context.Background(), bson.D{{"code", code}}).Decode(&dbCertificate)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/certificate_impl.go",
+				Method: "GetCertificateByCode",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	certificate, err := dbCertificate.ToCertificate()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/certificate_impl.go",
+				Method: "GetCertificateByCode",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return certificate, nil
+}
+
+/*
+GetUserCertificates return user certificates ordered by issue date. Parameters:
+userId - user id;
+*/
+func (ctx *DbContext) GetUserCertificates(userId string) ([]*common.Certificate, error) {
+	col := ctx.Client.Database(DbName).Collection(CertificateCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/certificate_impl.go",
+					Method: "GetUserCertificates",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	ops := options.Find().SetSort(bson.D{{"date_issue", 1}})
+
+	cursor, err := col.Find(context.Background(), bson.D{{"user_id", objectUserId}}, ops)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/certificate_impl.go",
+				Method: "GetUserCertificates",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbCertificates []*DbCertificate
+
+	err = cursor.All(context.Background(), &dbCertificates)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/certificate_impl.go",
+				Method: "GetUserCertificates",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var certificates []*common.Certificate
+
+	for _, dbCertificate := range dbCertificates {
+		certificate, err := dbCertificate.ToCertificate()
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/certificate_impl.go",
+					Method: "GetUserCertificates",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	return certificates, nil
+}
+
+// newCertificateCode return random verification code in XXXX-XXXX-XXXX-XXXX format
+func newCertificateCode() (string, error) {
+	buffer := make([]byte, 10)
+
+	_, err := rand.Read(buffer)
+
+	if err != nil {
+		return "", err
+	}
+
+	code := base32.StdEncoding.EncodeToString(buffer)
+
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// normalizeCertificateCode return code in stored format. Code is accepted in any case and with or without dashes
+func normalizeCertificateCode(code string) string {
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+
+	if len(code) != 16 {
+		return code
+	}
+
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+}
@@ -14,6 +14,7 @@ type DbUser struct {
 	Avatar     string             `bson:"avatar"`        // Avatar image path
 	Credential *DbCredential      `bson:"credential"`    // User credential properties
 	Rating     int                `bson:"rating"`        // User rating
+	Lemmings   int                `bson:"lemmings"`      // Count of lemmings for passed tests
 	Email      string             `bson:"email"`         // User email address
 }
 
@@ -32,26 +33,95 @@ type DbUserConfirm struct {
 
 // DbCategory of curses collection
 type DbCategory struct {
-	Id        primitive.ObjectID `bson:"_id,omitempty"` // Category id
-	Lang      string             `bson:"lang"`          // Language
-	Name      string             `bson:"name"`          // Category name
-	IconImg   string             `bson:"icon_img"`      // Icon for category
-	HeaderImg string             `bson:"header_img"`    // Header image
+	Id        primitive.ObjectID `bson:"_id,omitempty"`       // Category id
+	ParentId  primitive.ObjectID `bson:"parent_id,omitempty"` // Parent category. Root category hasn't parent
+	Path      string             `bson:"path,omitempty"`      // Materialized path of ancestors and category ids. Example: "/a/b/"
+	Group     primitive.ObjectID `bson:"group,omitempty"`     // Translation group. Translations of category have the same group
+	Lang      string             `bson:"lang"`                // Language
+	Name      string             `bson:"name"`                // Category name
+	IconImg   string             `bson:"icon_img"`            // Icon for category
+	HeaderImg string             `bson:"header_img"`          // Header image
 }
 
 // DbCourse collection
 type DbCourse struct {
-	Id          primitive.ObjectID `bson:"_id,omitempty"`  // Course id
-	CategoryId  primitive.ObjectID `bson:"category_id"`    // Course category
-	Enabled     bool               `bson:"enabled"`        // Enabled course
-	Name        string             `bson:"name"`           // Course names
-	Tags        []string           `bson:"tags,omitempty"` // Course tags
-	Rating      int                `bson:"rating"`         // Course rating
-	Description string             `bson:"description"`    // Course description
-	IconImg     string             `bson:"icon_img"`       // Icon for category
-	HeaderImg   string             `bson:"header_img"`     // Header image
-	DateCreate  primitive.DateTime `bson:"date_create"`    // Date create course
-	DateUpdate  primitive.DateTime `bson:"date_update"`    // Date update course
+	Id          primitive.ObjectID `bson:"_id,omitempty"`     // Course id
+	CategoryId  primitive.ObjectID `bson:"category_id"`       // Course category
+	AuthorId    primitive.ObjectID `bson:"author_id"`         // Course author (owner)
+	Lang        string             `bson:"lang"`              // Course language
+	Group       primitive.ObjectID `bson:"group,omitempty"`   // Translation group. Translations of course have the same group
+	Enabled     bool               `bson:"enabled"`           // Enabled course
+	Name        string             `bson:"name"`              // Course names
+	Tags        []string           `bson:"tags,omitempty"`    // Course tags
+	Rating      int                `bson:"rating"`            // Course rating. Average of ratings multiplied by 100
+	Points      int                `bson:"points"`            // Points of achievements. They aren't counted in rating
+	Ratings     *DbCourseRatings   `bson:"ratings,omitempty"` // Aggregate of ratings, which is updated incrementally
+	Description string             `bson:"description"`       // Course description
+	IconImg     string             `bson:"icon_img"`          // Icon for category
+	HeaderImg   string             `bson:"header_img"`        // Header image
+	DateCreate  primitive.DateTime `bson:"date_create"`       // Date create course
+	DateUpdate  primitive.DateTime `bson:"date_update"`       // Date update course
+}
+
+// DbCourseRatings aggregate of visible course reviews
+type DbCourseRatings struct {
+	Count     int            `bson:"count"`     // Count of ratings
+	Sum       int            `bson:"sum"`       // Sum of stars
+	Histogram map[string]int `bson:"histogram"` // Count of ratings by stars from "1" to "5"
+}
+
+// DbCourseReview collection. One review of user for course
+type DbCourseReview struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty"`   // Review id
+	CourseId   primitive.ObjectID `bson:"course_id"`       // Course id
+	UserId     primitive.ObjectID `bson:"user_id"`         // Reviewer id
+	UserName   string             `bson:"user_name"`       // Reviewer name on review date
+	Stars      int                `bson:"stars"`           // Rating from 1 to 5
+	Text       string             `bson:"text"`            // Review text
+	Hidden     bool               `bson:"hidden"`          // Review is hidden by moderator and isn't in aggregate
+	Reply      *DbReviewReply     `bson:"reply,omitempty"` // Reply of course author
+	DateCreate primitive.DateTime `bson:"date_create"`     // Date create review
+	DateUpdate primitive.DateTime `bson:"date_update"`     // Date update review
+}
+
+// DbReviewReply reply of course author to review
+type DbReviewReply struct {
+	AuthorId   primitive.ObjectID `bson:"author_id"`   // Reply author id
+	Text       string             `bson:"text"`        // Reply text
+	DateCreate primitive.DateTime `bson:"date_create"` // Date of reply
+}
+
+// DbStageComment collection. Comments of stage discussion, replies are in thread of root comment
+type DbStageComment struct {
+	Id         primitive.ObjectID   `bson:"_id,omitempty"`       // Comment id
+	StageId    primitive.ObjectID   `bson:"stage_id"`            // Stage id
+	CourseId   primitive.ObjectID   `bson:"course_id"`           // Course id of stage
+	ParentId   primitive.ObjectID   `bson:"parent_id,omitempty"` // Root comment of thread. Root comment hasn't parent
+	UserId     primitive.ObjectID   `bson:"user_id"`             // Comment author id
+	UserName   string               `bson:"user_name"`           // Comment author name on comment date
+	Body       string               `bson:"body"`                // Markdown body
+	Votes      int                  `bson:"votes"`               // Count of upvotes
+	Voters     []primitive.ObjectID `bson:"voters"`              // Users, who upvoted comment
+	IsAnswer   bool                 `bson:"is_answer"`           // Reply is marked as answer of thread
+	AnswerId   primitive.ObjectID   `bson:"answer_id,omitempty"` // Marked answer of thread. Set for root comment
+	ReplyCount int                  `bson:"reply_count"`         // Count of thread replies. Set for root comment
+	Hidden     bool                 `bson:"hidden"`              // Comment is hidden by moderator
+	Deleted    bool                 `bson:"deleted"`             // Comment is deleted by author or moderator
+	DateCreate primitive.DateTime   `bson:"date_create"`         // Date create comment
+	DateUpdate primitive.DateTime   `bson:"date_update"`         // Date of the last edit
+}
+
+// DbNotification collection. Notifications of users
+type DbNotification struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty"`       // Notification id
+	UserId     primitive.ObjectID `bson:"user_id"`             // Recipient id
+	Kind       string             `bson:"kind"`                // Notification kind
+	Message    string             `bson:"message"`             // Notification text
+	CourseId   primitive.ObjectID `bson:"course_id,omitempty"` // Related course
+	StageId    primitive.ObjectID `bson:"stage_id,omitempty"`  // Related stage
+	TargetId   string             `bson:"target_id,omitempty"` // Related comment, review or badge assertion
+	Read       bool               `bson:"read"`                // Notification is read
+	DateCreate primitive.DateTime `bson:"date_create"`         // Date of notification. Refreshed notification gets new date
 }
 
 // DbCoursePromotion collection
@@ -65,29 +135,196 @@ type DbCoursePromotion struct {
 
 // DbStage collection
 type DbStage struct {
-	Id          primitive.ObjectID `bson:"_id,omitempty"` // Stage id
-	CourseId    primitive.ObjectID `bson:"course_id"`     // Course id. One course has many stages
-	Name        string             `bson:"name"`          // Course stage name
-	Content     *DbPostContent     `bson:"content"`       // Stage contents
-	HeaderImg   string             `bson:"header_img"`    // Header image
-	OrderNumber int                `bson:"order_number"`  // Stage order number
+	Id          primitive.ObjectID `bson:"_id,omitempty"`   // Stage id
+	CourseId    primitive.ObjectID `bson:"course_id"`       // Course id. One course has many stages
+	Group       primitive.ObjectID `bson:"group,omitempty"` // Translation group. Translations of stage have the same group
+	Name        string             `bson:"name"`            // Course stage name
+	Content     *DbPostContent     `bson:"content"`         // Stage contents
+	HeaderImg   string             `bson:"header_img"`      // Header image
+	OrderNumber int                `bson:"order_number"`    // Stage order number
+	TimeLimit   int                `bson:"time_limit"`      // Seconds for stage tests in quiz session. 0 is not timed
+	Pools       []*DbQuestionPool  `bson:"pools,omitempty"` // Question pools
 }
 
 // DbTest collection
 type DbTest struct {
-	Id            primitive.ObjectID `bson:"_id,omitempty"`          // Test id
-	StageId       primitive.ObjectID `bson:"stage_id"`               // Stage id
-	TestType      string             `bson:"test_type"`              // Test type
-	LemmingsCount int                `bson:"lemmings_count"`         // Count of lemmings for passed test
-	OptionTest    *DbOptionTest      `bson:"option_test,omitempty"`  // Option test. Test with option variant answers. Optional
-	RewriteTest   *DbRewriteTest     `bson:"rewrite_test,omitempty"` // Rewrite test. Test with phrase how need write. Optional
-	OrderNumber   int                `bson:"order_number"`           // Test order number
+	Id              primitive.ObjectID `bson:"_id,omitempty"`               // Test id
+	StageId         primitive.ObjectID `bson:"stage_id"`                    // Stage id
+	Group           primitive.ObjectID `bson:"group,omitempty"`             // Translation group. Translations of test have the same group
+	TestType        string             `bson:"test_type"`                   // Test type
+	LemmingsCount   int                `bson:"lemmings_count"`              // Count of lemmings for passed test
+	OptionTest      *DbOptionTest      `bson:"option_test,omitempty"`       // Option test. Test with option variant answers. Optional
+	RewriteTest     *DbRewriteTest     `bson:"rewrite_test,omitempty"`      // Rewrite test. Test with phrase how need write. Optional
+	MultiSelectTest *DbMultiSelectTest `bson:"multi_select_test,omitempty"` // Multi-select test. Optional
+	OrderingTest    *DbOrderingTest    `bson:"ordering_test,omitempty"`     // Ordering test. Optional
+	MatchingTest    *DbMatchingTest    `bson:"matching_test,omitempty"`     // Matching test. Optional
+	ClozeTest       *DbClozeTest       `bson:"cloze_test,omitempty"`        // Fill-in-the-blank test. Optional
+	NumericTest     *DbNumericTest     `bson:"numeric_test,omitempty"`      // Numeric test. Optional
+	CodeTest        *DbCodeTest        `bson:"code_test,omitempty"`         // Programming exercise. Optional
+	OrderNumber     int                `bson:"order_number"`                // Test order number
+	MaxAttempts     int                `bson:"max_attempts"`                // Max count of attempts. 0 is unlimited
+	Cooldown        int                `bson:"cooldown"`                    // Min seconds between attempts
+	Pool            string             `bson:"pool,omitempty"`              // Question pool name
 }
 
 // DbUserTest collection
 type DbUserTest struct {
-	Id       primitive.ObjectID `bson:"_id,omitempty"`     // User and Test records
-	TestId   primitive.ObjectID `bson:"test_id,omitempty"` // Test id
-	UserId   primitive.ObjectID `bson:"user_id"`           // User id
-	IsPassed bool               `bson:"is_passed"`         // Check passed test
+	Id         primitive.ObjectID `bson:"_id,omitempty"`     // User and Test records
+	TestId     primitive.ObjectID `bson:"test_id,omitempty"` // Test id
+	UserId     primitive.ObjectID `bson:"user_id"`           // User id
+	StageId    primitive.ObjectID `bson:"stage_id"`          // Stage id of test
+	CourseId   primitive.ObjectID `bson:"course_id"`         // Course id of test stage
+	IsPassed   bool               `bson:"is_passed"`         // Check passed test
+	Score      float64            `bson:"score"`             // Best score from 0 to 1
+	Attempts   int                `bson:"attempts"`          // Count of attempts
+	DateUpdate primitive.DateTime `bson:"date_update"`       // Date of the last answer
+}
+
+// DbTestAttempt collection. Stores every user answer for test
+type DbTestAttempt struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty"`          // Attempt id
+	UserId      primitive.ObjectID `bson:"user_id"`                // User id
+	TestId      primitive.ObjectID `bson:"test_id"`                // Test id
+	StageId     primitive.ObjectID `bson:"stage_id"`               // Stage id of test
+	CourseId    primitive.ObjectID `bson:"course_id"`              // Course id of test stage
+	Number      int                `bson:"number"`                 // Attempt number, starts from 1
+	Answer      *DbTestAnswer      `bson:"answer"`                 // User answer
+	Score       float64            `bson:"score"`                  // Score from 0 to 1
+	IsPassed    bool               `bson:"is_passed"`              // Answer is fully right
+	DateCreate  primitive.DateTime `bson:"date_create"`            // Attempt date
+	SelectionId primitive.ObjectID `bson:"selection_id,omitempty"` // Test selection shown to learner
+	SessionId   primitive.ObjectID `bson:"session_id,omitempty"`   // Quiz session of attempt
+	Question    string             `bson:"question,omitempty"`     // Question shown to learner
+	Options     []string           `bson:"options,omitempty"`      // Options in order shown to learner
+}
+
+// DbTestSelection collection. Tests drawn for learner
+type DbTestSelection struct {
+	Id         primitive.ObjectID   `bson:"_id,omitempty"`        // Selection id
+	UserId     primitive.ObjectID   `bson:"user_id"`              // User id
+	StageId    primitive.ObjectID   `bson:"stage_id"`             // Stage id
+	SessionId  primitive.ObjectID   `bson:"session_id,omitempty"` // Quiz session id
+	Seed       int64                `bson:"seed"`                 // Seed of tests draw and options shuffling
+	TestIds    []primitive.ObjectID `bson:"test_ids"`             // Drawn tests
+	DateCreate primitive.DateTime   `bson:"date_create"`          // Selection date
+}
+
+// DbQuizSession collection. Timed sessions for stage tests
+type DbQuizSession struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty"`     // Session id
+	UserId     primitive.ObjectID `bson:"user_id"`           // User id
+	StageId    primitive.ObjectID `bson:"stage_id"`          // Stage id
+	CourseId   primitive.ObjectID `bson:"course_id"`         // Course id of stage
+	Status     string             `bson:"status"`            // Session status
+	DateStart  primitive.DateTime `bson:"date_start"`        // Session start date
+	Deadline   primitive.DateTime `bson:"deadline"`          // Answers are rejected after deadline
+	DateSubmit primitive.DateTime `bson:"date_submit"`       // Submit date
+	Answers    []*DbSessionAnswer `bson:"answers"`           // Saved answers
+	Results    []*DbSessionResult `bson:"results,omitempty"` // Results for every stage test
+	Score      float64            `bson:"score"`             // Average score of stage tests
+	IsPassed   bool               `bson:"is_passed"`         // All stage tests are passed
+}
+
+// DbImportMapping collection. Maps ids from imported bundle to ids in this instance
+type DbImportMapping struct {
+	Id       primitive.ObjectID `bson:"_id,omitempty"` // Mapping id
+	OwnerId  primitive.ObjectID `bson:"owner_id"`      // Id of author, who imports bundle
+	BundleId string             `bson:"bundle_id"`     // Bundle id
+	Kind     string             `bson:"kind"`          // Mapping kind: course, stage or test
+	SourceId string             `bson:"source_id"`     // Document id in bundle
+	TargetId primitive.ObjectID `bson:"target_id"`     // Document id in this instance
+}
+
+// DbCertificate collection. Course completion certificates
+type DbCertificate struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty"` // Certificate id
+	Code       string             `bson:"code"`          // Unique verification code
+	UserId     primitive.ObjectID `bson:"user_id"`       // User id
+	CourseId   primitive.ObjectID `bson:"course_id"`     // Course id
+	UserName   string             `bson:"user_name"`     // User name on issue date
+	CourseName string             `bson:"course_name"`   // Course name on issue date
+	DateIssue  primitive.DateTime `bson:"date_issue"`    // Issue date
+}
+
+// DbBadgeClass collection. Open Badges achievement definitions
+type DbBadgeClass struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty"`       // Badge class id
+	Name        string             `bson:"name"`                // Badge name
+	Description string             `bson:"description"`         // Badge description
+	Image       string             `bson:"image"`               // Badge image url
+	Criteria    string             `bson:"criteria"`            // Human-readable criteria
+	Kind        string             `bson:"kind"`                // Criteria kind
+	CourseId    primitive.ObjectID `bson:"course_id,omitempty"` // Course id for course completed badge
+	Threshold   int                `bson:"threshold"`           // Count of lemmings or streak days
+	DateCreate  primitive.DateTime `bson:"date_create"`         // Date create badge class
+}
+
+// DbBadgeAssertion collection. Badges awarded to users
+type DbBadgeAssertion struct {
+	Id                primitive.ObjectID `bson:"_id,omitempty"`               // Assertion id
+	BadgeClassId      primitive.ObjectID `bson:"badge_class_id"`              // Badge class id
+	UserId            primitive.ObjectID `bson:"user_id"`                     // User id
+	RecipientIdentity string             `bson:"recipient_identity"`          // Hashed recipient email
+	RecipientSalt     string             `bson:"recipient_salt"`              // Salt of recipient hash
+	Evidence          string             `bson:"evidence,omitempty"`          // Evidence narrative
+	IssuedOn          primitive.DateTime `bson:"issued_on"`                   // Award date
+	Revoked           bool               `bson:"revoked"`                     // Assertion is revoked
+	RevocationReason  string             `bson:"revocation_reason,omitempty"` // Reason of revocation
+}
+
+// DbUserProgress collection. Gamification progress of user
+type DbUserProgress struct {
+	Id            primitive.ObjectID `bson:"_id,omitempty"`  // Progress id
+	UserId        primitive.ObjectID `bson:"user_id"`        // User id
+	Counters      map[string]int     `bson:"counters"`       // Count of events by event type
+	Streak        int                `bson:"streak"`         // Current days in a row with activity
+	LongestStreak int                `bson:"longest_streak"` // The longest streak
+	LastActive    primitive.DateTime `bson:"last_active"`    // The last day with activity, start of day in UTC
+	Achievements  []string           `bson:"achievements"`   // Earned achievement ids
+}
+
+// DbScoreEvent collection. Rating and lemmings earned by user, it's source of leaderboards by course and time
+type DbScoreEvent struct {
+	Id       primitive.ObjectID `bson:"_id,omitempty"`       // Score event id
+	UserId   primitive.ObjectID `bson:"user_id"`             // User id
+	CourseId primitive.ObjectID `bson:"course_id,omitempty"` // Course id, if points are earned in course
+	Metric   string             `bson:"metric"`              // Metric of points
+	Points   int                `bson:"points"`              // Earned points
+	Date     primitive.DateTime `bson:"date"`                // Date of earning
+}
+
+// DbLeaderboardEntry collection. Precomputed place of user in leaderboard
+type DbLeaderboardEntry struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty"` // Entry id
+	Generation int64              `bson:"generation"`    // Refresh generation of snapshot
+	Metric     string             `bson:"metric"`        // Ranking metric
+	Scope      string             `bson:"scope"`         // Leaderboard scope
+	ScopeId    string             `bson:"scope_id"`      // Category or course id, empty for global scope
+	Window     string             `bson:"window"`        // Time window
+	Rank       int                `bson:"rank"`          // Rank from 1
+	UserId     primitive.ObjectID `bson:"user_id"`       // User id
+	UserName   string             `bson:"user_name"`     // User name
+	Score      int                `bson:"score"`         // Score for window
+}
+
+// DbLeaderboardRefresh collection. Completed refreshes of leaderboards, the latest one is read
+type DbLeaderboardRefresh struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty"` // Refresh id
+	Generation  int64              `bson:"generation"`    // Refresh generation
+	DateRefresh primitive.DateTime `bson:"date_refresh"`  // Date of refresh
+}
+
+// DbReviewSchedule collection. Spaced repetition schedules of passed tests
+type DbReviewSchedule struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty"`         // Schedule id
+	UserId      primitive.ObjectID `bson:"user_id"`               // User id
+	TestId      primitive.ObjectID `bson:"test_id"`               // Test id
+	StageId     primitive.ObjectID `bson:"stage_id"`              // Stage id of test
+	CourseId    primitive.ObjectID `bson:"course_id"`             // Course id of test
+	Ease        float64            `bson:"ease"`                  // Ease factor
+	Interval    int                `bson:"interval"`              // Days between the last and the next review
+	Repetitions int                `bson:"repetitions"`           // Count of successful reviews in a row
+	LastQuality int                `bson:"last_quality"`          // Quality of the last review
+	DateDue     primitive.DateTime `bson:"date_due"`              // Date of the next review
+	DateReview  primitive.DateTime `bson:"date_review,omitempty"` // Date of the last review
 }
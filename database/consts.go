@@ -2,13 +2,36 @@ package database
 
 // Collections names
 const (
-	UserCollection        = "users"         // Collection for store users
-	CategoryCollection    = "categories"    // Collection for course categories
-	StageCollection       = "stages"        // Collection store stages for courses
-	CourseCollection      = "courses"       // Collection store courses
-	TestCollection        = "tests"         // Collection for store stage's tests
-	UserTestCollection    = "user_tests"    // Collection for store user and test relations and passed status
-	UserConfirmCollection = "user_confirms" // Collection for store confirmation link for user registration. Use TTL index for auto remove documents.
+	UserCollection           = "users"            // Collection for store users
+	CategoryCollection       = "categories"       // Collection for course categories
+	StageCollection          = "stages"           // Collection store stages for courses
+	CourseCollection         = "courses"          // Collection store courses
+	TestCollection           = "tests"            // Collection for store stage's tests
+	UserTestCollection       = "user_tests"       // Collection for store user and test relations and passed status
+	UserConfirmCollection    = "user_confirms"    // Collection for store confirmation link for user registration. Use TTL index for auto remove documents.
+	ImportMappingCollection  = "import_mappings"  // Collection for store source to target id mappings of imported bundles
+	TestAttemptCollection    = "test_attempts"    // Collection for store every user answer for test
+	QuizSessionCollection    = "quiz_sessions"    // Collection for store timed sessions for stage tests
+	TestSelectionCollection  = "test_selections"  // Collection for store tests drawn from question pools for learners
+	CertificateCollection    = "certificates"     // Collection for store course completion certificates
+	BadgeClassCollection     = "badge_classes"    // Collection for store Open Badges achievement definitions
+	BadgeAssertionCollection = "badge_assertions" // Collection for store badges awarded to users
+	UserProgressCollection   = "user_progress"    // Collection for store gamification progress of users
+	ScoreEventCollection     = "score_events"     // Collection for store rating and lemmings earned by users
+	LeaderboardCollection    = "leaderboards"     // Collection for store precomputed leaderboards
+	BoardRefreshCollection   = "board_refreshes"  // Collection for store completed refreshes of leaderboards
+	ReviewCollection         = "review_schedules" // Collection for store spaced repetition schedules of passed tests
+	PromotionCollection      = "promotions"       // Collection for store course promotions. Use TTL index for auto remove documents.
+	CourseReviewCollection   = "course_reviews"   // Collection for store course ratings and reviews of learners
+	StageCommentCollection   = "stage_comments"   // Collection for store discussion threads of stages
+	NotificationCollection   = "notifications"    // Collection for store notifications of users
 )
 
 const DbName = "opencourse" // Database name
+
+// Bundle import mapping kinds
+const (
+	MappingCourse = "course" // Imported course
+	MappingStage  = "stage"  // Imported stage
+	MappingTest   = "test"   // Imported test
+)
@@ -4,9 +4,11 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"opencourse/common"
 	"opencourse/common/openerrors"
+	"opencourse/events"
 	"time"
 )
 
@@ -32,7 +34,7 @@ func (ctx *DbContext) ClearCourses() error {
 }
 
 /*
-GetCourse return course from db by id. Parameters:
+GetCourse return course from db by id. Returns nil, if course isn't found. Parameters:
 courseId - course id;
 */
 func (ctx *DbContext) GetCourse(courseId string) (*common.Course, error) {
@@ -60,6 +62,10 @@ func (ctx *DbContext) GetCourse(courseId string) (*common.Course, error) {
 	var dbCourse DbCourse
 	err = col.FindOne(context.Background(), filter).Decode(&dbCourse)
 
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
 	if err != nil {
 		return nil, openerrors.DbErr{
 			BaseErr: openerrors.BaseErr{
@@ -94,56 +100,116 @@ take - how many records need to return;
 skip - how many records need to skip;
 */
 func (ctx *DbContext) GetCourses(categoryId string, take int64, skip int64) ([]*common.Course, error) {
+	courses, _, err := ctx.GetCoursesPage(categoryId, nil, &common.PageQuery{Take: take, Skip: skip})
+
+	return courses, err
+}
+
+/*
+GetCoursesPage return page of courses ordered by rating and position of next page. If languages are set, translation
+group is listed once as its variant of the best language. Parameters:
+categoryId - category id. Optional, may be set empty string. Example: "".
+langs - languages in order of preference. Optional, may be nil;
+query - page query;
+*/
+func (ctx *DbContext) GetCoursesPage(categoryId string, langs []string,
+	query *common.PageQuery) ([]*common.Course, *common.Page, error) {
 	col := ctx.Client.Database(DbName).Collection(CourseCollection)
 
-	var filters []bson.D
+	filter := bson.D{}
 
 	if len(categoryId) > 1 {
 		objectCategoryId, err := primitive.ObjectIDFromHex(categoryId)
 
 		if err != nil {
-			return nil, openerrors.InvalidIdErr{
+			return nil, nil, openerrors.InvalidIdErr{
 				Id:        categoryId,
 				Converter: "ObjectIDFromHex",
 				Default: openerrors.DefaultErr{
 					BaseErr: openerrors.BaseErr{
 						File:   "database/course_impl.go",
-						Method: "GetCourses",
+						Method: "GetCoursesPage",
 					},
 					Msg: err.Error(),
 				},
 			}
 		}
 
-		filters = append(filters, bson.D{{"category_id", objectCategoryId}})
+		filter = append(filter, bson.E{Key: "category_id", Value: objectCategoryId})
 	}
 
-	ops := options.Find().SetLimit(take).SetSkip(skip).
-		SetSort(bson.D{{"rating", -1}, {"date_update", -1}})
+	sort := bson.D{{"rating", -1}, {"date_update", -1}, {"_id", 1}}
 
-	cursor, err := col.Find(context.Background(), filters, ops)
+	var docs []bson.Raw
+	var page *common.Page
+	var err error
+
+	if len(langs) > 0 {
+		docs, page, err = ctx.aggregatePage(col, translatedCourses(filter, langs), sort, query)
+	} else {
+		docs, page, err = ctx.findPage(col, filter, sort, nil, query)
+	}
 
 	if err != nil {
-		return nil, openerrors.DbErr{
-			BaseErr: openerrors.BaseErr{
-				File:   "database/course_impl.go",
-				Method: "GetCourses",
-			},
-			DbName: ctx.DbName,
-			ConStr: ctx.ConStr,
-			DbErr:  err.Error(),
+		return nil, nil, err
+	}
+
+	var courses []*common.Course
+
+	for _, doc := range docs {
+		var dbCourse DbCourse
+
+		err = bson.Unmarshal(doc, &dbCourse)
+
+		if err != nil {
+			return nil, nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/course_impl.go",
+					Method: "GetCoursesPage",
+				},
+				Msg: err.Error(),
+			}
 		}
+
+		course, err := dbCourse.ToCourse()
+
+		if err != nil {
+			return nil, nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/course_impl.go",
+					Method: "GetCoursesPage",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		courses = append(courses, course)
 	}
 
-	var dbCourses []*DbCourse
+	return courses, page, nil
+}
+
+// GetCourseIds return ids of all courses
+func (ctx *DbContext) GetCourseIds() ([]string, error) {
+	col := ctx.Client.Database(DbName).Collection(CourseCollection)
+
+	ops := options.Find().SetProjection(bson.D{{"_id", 1}})
+
+	cursor, err := col.Find(context.Background(), bson.D{}, ops)
 
-	err = cursor.All(context.Background(), &dbCourses)
+	var dbCourses []*struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+
+	if err == nil {
+		err = cursor.All(context.Background(), &dbCourses)
+	}
 
 	if err != nil {
 		return nil, openerrors.DbErr{
 			BaseErr: openerrors.BaseErr{
 				File:   "database/course_impl.go",
-				Method: "GetCourses",
+				Method: "GetCourseIds",
 			},
 			DbName: ctx.DbName,
 			ConStr: ctx.ConStr,
@@ -151,25 +217,13 @@ func (ctx *DbContext) GetCourses(categoryId string, take int64, skip int64) ([]*
 		}
 	}
 
-	var courses []*common.Course
+	courseIds := make([]string, 0, len(dbCourses))
 
 	for _, dbCourse := range dbCourses {
-		course, err := dbCourse.ToCourse()
-
-		if err != nil {
-			return nil, openerrors.DefaultErr{
-				BaseErr: openerrors.BaseErr{
-					File:   "database/course_impl.go",
-					Method: "GetCourses",
-				},
-				Msg: err.Error(),
-			}
-		}
-
-		courses = append(courses, course)
+		courseIds = append(courseIds, dbCourse.Id.Hex())
 	}
 
-	return courses, nil
+	return courseIds, nil
 }
 
 /*
@@ -198,8 +252,31 @@ func (ctx *DbContext) AddCourse(addCourseQuery *common.AddCourseQuery) (string,
 	}
 
 	dbCourse.CategoryId = objectCategoryId
+
+	if len(addCourseQuery.AuthorId) > 0 {
+		objectAuthorId, err := primitive.ObjectIDFromHex(addCourseQuery.AuthorId)
+
+		if err != nil {
+			return "", openerrors.InvalidIdErr{
+				Id:        addCourseQuery.AuthorId,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/course_impl.go",
+						Method: "AddCourse",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		dbCourse.AuthorId = objectAuthorId
+	}
+
+	dbCourse.Lang = addCourseQuery.Lang
 	dbCourse.Tags = addCourseQuery.Tags
 	dbCourse.Rating = 0
+	dbCourse.Points = 0
 
 	dateNow := time.Now().UTC()
 	dbCourse.DateCreate = primitive.NewDateTimeFromTime(dateNow)
@@ -221,6 +298,8 @@ func (ctx *DbContext) AddCourse(addCourseQuery *common.AddCourseQuery) (string,
 
 	id := result.InsertedID.(primitive.ObjectID)
 
+	ctx.publishContent(events.CourseSaved, id.Hex(), "")
+
 	return id.Hex(), err
 }
 
@@ -274,6 +353,8 @@ func (ctx *DbContext) AddCourseTags(id string, tags []string) error {
 		}
 	}
 
+	ctx.publishContent(events.CourseSaved, id, "")
+
 	return nil
 }
 
@@ -325,9 +406,239 @@ func (ctx *DbContext) RemoveCourseTags(id string, tags []string) error {
 		}
 	}
 
+	ctx.publishContent(events.CourseSaved, id, "")
+
 	return nil
 }
 
+/*
+CloneCourse make a deep copy of course with all stages and tests in one transaction. Parameters:
+courseId - source course id;
+authorId - id of user who will own the copy. Optional, may be set empty string;
+query - clone options (target name, category and language);
+*/
+func (ctx *DbContext) CloneCourse(courseId string, authorId string, query *common.CloneCourseQuery) (string, error) {
+	if query == nil {
+		return "", openerrors.ModelNilOrEmptyErr{
+			Model: "query",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_impl.go",
+				Method: "CloneCourse",
+			},
+		}
+	}
+
+	objectCourseId, err := primitive.ObjectIDFromHex(courseId)
+
+	if err != nil {
+		return "", openerrors.InvalidIdErr{
+			Id:        courseId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/course_impl.go",
+					Method: "CloneCourse",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	objectCategoryId := primitive.NilObjectID
+
+	if len(query.CategoryId) > 0 {
+		objectCategoryId, err = primitive.ObjectIDFromHex(query.CategoryId)
+
+		if err != nil {
+			return "", openerrors.InvalidIdErr{
+				Id:        query.CategoryId,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/course_impl.go",
+						Method: "CloneCourse",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+	}
+
+	objectAuthorId := primitive.NilObjectID
+
+	if len(authorId) > 0 {
+		objectAuthorId, err = primitive.ObjectIDFromHex(authorId)
+
+		if err != nil {
+			return "", openerrors.InvalidIdErr{
+				Id:        authorId,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/course_impl.go",
+						Method: "CloneCourse",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+	}
+
+	session, err := ctx.Client.StartSession()
+
+	if err != nil {
+		return "", openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_impl.go",
+				Method: "CloneCourse",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer session.EndSession(context.Background())
+
+	result, err := session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		db := ctx.Client.Database(DbName)
+
+		var dbCourse DbCourse
+		err := db.Collection(CourseCollection).FindOne(sc, bson.D{{"_id", objectCourseId}}).Decode(&dbCourse)
+
+		if err != nil {
+			return nil, err
+		}
+
+		dateNow := primitive.NewDateTimeFromTime(time.Now().UTC())
+
+		// The copy is disabled until the author publishes the new edition. It isn't translation of source course
+		dbCourse.Id = primitive.NewObjectID()
+		dbCourse.Group = primitive.NilObjectID
+		dbCourse.Enabled = false
+		dbCourse.Rating = 0
+		dbCourse.Points = 0
+		dbCourse.Ratings = nil
+		dbCourse.DateCreate = dateNow
+		dbCourse.DateUpdate = dateNow
+
+		if len(query.Name) > 0 {
+			dbCourse.Name = query.Name
+		}
+
+		if !objectCategoryId.IsZero() {
+			dbCourse.CategoryId = objectCategoryId
+		}
+
+		if len(query.Lang) > 0 {
+			dbCourse.Lang = query.Lang
+		}
+
+		if !objectAuthorId.IsZero() {
+			dbCourse.AuthorId = objectAuthorId
+		}
+
+		_, err = db.Collection(CourseCollection).InsertOne(sc, dbCourse)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cursor, err := db.Collection(StageCollection).Find(sc, bson.D{{"course_id", objectCourseId}})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var dbStages []*DbStage
+
+		err = cursor.All(sc, &dbStages)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(dbStages) == 0 {
+			return dbCourse.Id, nil
+		}
+
+		// old stage id -> new stage id
+		stageIds := make(map[primitive.ObjectID]primitive.ObjectID, len(dbStages))
+		oldStageIds := make([]primitive.ObjectID, 0, len(dbStages))
+		stageDocs := make([]interface{}, 0, len(dbStages))
+
+		for _, dbStage := range dbStages {
+			newStageId := primitive.NewObjectID()
+			stageIds[dbStage.Id] = newStageId
+			oldStageIds = append(oldStageIds, dbStage.Id)
+
+			dbStage.Id = newStageId
+			dbStage.CourseId = dbCourse.Id
+			dbStage.Group = primitive.NilObjectID
+			stageDocs = append(stageDocs, dbStage)
+		}
+
+		_, err = db.Collection(StageCollection).InsertMany(sc, stageDocs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cursor, err = db.Collection(TestCollection).Find(sc, bson.D{{"stage_id", bson.D{{"$in", oldStageIds}}}})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var dbTests []*DbTest
+
+		err = cursor.All(sc, &dbTests)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(dbTests) == 0 {
+			return dbCourse.Id, nil
+		}
+
+		testDocs := make([]interface{}, 0, len(dbTests))
+
+		for _, dbTest := range dbTests {
+			dbTest.Id = primitive.NewObjectID()
+			dbTest.StageId = stageIds[dbTest.StageId]
+			dbTest.Group = primitive.NilObjectID
+			testDocs = append(testDocs, dbTest)
+		}
+
+		_, err = db.Collection(TestCollection).InsertMany(sc, testDocs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return dbCourse.Id, nil
+	})
+
+	if err != nil {
+		return "", openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_impl.go",
+				Method: "CloneCourse",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	cloneId := result.(primitive.ObjectID).Hex()
+
+	ctx.publishContent(events.CourseSaved, cloneId, "")
+
+	return cloneId, nil
+}
+
 /*
 DeleteCourse - remove course. Parameters:
 courseId - course id;
@@ -336,3 +647,44 @@ TODO: add implementation add transaction
 func (ctx *DbContext) DeleteCourse(courseId string) (*common.Course, error) {
 	return nil, nil
 }
+
+/*
+AddCoursePoints add points of achievements to course. Points are kept apart from rating of reviews. Parameters:
+courseId - course id;
+points - added points, negative points decrease them;
+*/
+func (ctx *DbContext) AddCoursePoints(courseId string, points int) error {
+	col := ctx.Client.Database(DbName).Collection(CourseCollection)
+
+	objectId, err := primitive.ObjectIDFromHex(courseId)
+
+	if err != nil {
+		return openerrors.InvalidIdErr{
+			Id:        courseId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/course_impl.go",
+					Method: "AddCoursePoints",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	_, err = col.UpdateOne(context.Background(), bson.D{{"_id", objectId}}, bson.D{{"$inc", bson.D{{"points", points}}}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_impl.go",
+				Method: "AddCoursePoints",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
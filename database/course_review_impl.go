@@ -0,0 +1,542 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"math"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/events"
+	"strconv"
+)
+
+/*
+This file contains course ratings and reviews of learners. Course keeps aggregate of visible reviews, which is
+changed in transaction with review, so it isn't recomputed from all reviews.
+*/
+
+// maxReviewLength max length of review and reply text
+const maxReviewLength = 5000
+
+// ClearCourseReviews remove all data from course reviews collection
+func (ctx *DbContext) ClearCourseReviews() error {
+	col := ctx.Client.Database(DbName).Collection(CourseReviewCollection)
+
+	_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "ClearCourseReviews",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+HasCourseProgress check that user has answered at least one test of course. Parameters:
+userId - user id;
+courseId - course id;
+*/
+func (ctx *DbContext) HasCourseProgress(userId string, courseId string) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(UserTestCollection)
+
+	objectUserId, objectCourseId, err := reviewIds(userId, courseId, "HasCourseProgress")
+
+	if err != nil {
+		return false, err
+	}
+
+	count, err := col.CountDocuments(context.Background(),
+		bson.D{{"user_id", objectUserId}, {"course_id", objectCourseId}}, options.Count().SetLimit(1))
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "HasCourseProgress",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return count > 0, nil
+}
+
+/*
+SaveCourseReview add review of user or replace stars and text of previous review. Hidden review stays hidden.
+Parameters:
+userId - reviewer id;
+userName - reviewer name;
+courseId - course id;
+query - stars and text of review;
+*/
+func (ctx *DbContext) SaveCourseReview(userId string, userName string, courseId string,
+	query *common.AddCourseReviewQuery) (*common.CourseReview, error) {
+
+	col := ctx.Client.Database(DbName).Collection(CourseReviewCollection)
+
+	if query == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "SaveCourseReview",
+			},
+			Model: "query",
+		}
+	}
+
+	if query.Stars < 1 || query.Stars > 5 || len(query.Text) > maxReviewLength {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "SaveCourseReview",
+			},
+			Msg: "stars must be from 1 to 5, text must be shorter than " + strconv.Itoa(maxReviewLength),
+		}
+	}
+
+	objectUserId, objectCourseId, err := reviewIds(userId, courseId, "SaveCourseReview")
+
+	if err != nil {
+		return nil, err
+	}
+
+	course, err := ctx.GetCourse(courseId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if course == nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "SaveCourseReview",
+			},
+			Msg: "course " + courseId + " isn't found",
+		}
+	}
+
+	session, err := ctx.Client.StartSession()
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "SaveCourseReview",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer session.EndSession(context.Background())
+
+	filter := bson.D{{"course_id", objectCourseId}, {"user_id", objectUserId}}
+	dateNow := primitive.NewDateTimeFromTime(ctx.Now())
+
+	update := bson.D{
+		{"$set", bson.D{{"user_name", userName}, {"stars", query.Stars}, {"text", query.Text}, {"date_update", dateNow}}},
+		{"$setOnInsert", bson.D{{"hidden", false}, {"date_create", dateNow}}},
+	}
+
+	var dbReview DbCourseReview
+
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		previous := DbCourseReview{}
+
+		err := col.FindOne(sc, filter).Decode(&previous)
+
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+
+		ops := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+		err = col.FindOneAndUpdate(sc, filter, update, ops).Decode(&dbReview)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, ctx.updateRatings(sc, objectCourseId, visibleStars(&previous), visibleStars(&dbReview))
+	})
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "SaveCourseReview",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return dbReview.ToCourseReview(), nil
+}
+
+/*
+GetCourseReview return review. Returns nil, if review isn't found. Parameters:
+reviewId - review id;
+*/
+func (ctx *DbContext) GetCourseReview(reviewId string) (*common.CourseReview, error) {
+	col := ctx.Client.Database(DbName).Collection(CourseReviewCollection)
+
+	objectReviewId, err := primitive.ObjectIDFromHex(reviewId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        reviewId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/course_review_impl.go",
+					Method: "GetCourseReview",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbReview DbCourseReview
+
+	err = col.FindOne(context.Background(), bson.D{{"_id", objectReviewId}}).Decode(&dbReview)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "GetCourseReview",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return dbReview.ToCourseReview(), nil
+}
+
+/*
+GetCourseReviewsPage return page of visible course reviews, the newest first. Parameters:
+courseId - course id;
+query - page query;
+*/
+func (ctx *DbContext) GetCourseReviewsPage(courseId string, query *common.PageQuery) ([]*common.CourseReview, *common.Page, error) {
+	col := ctx.Client.Database(DbName).Collection(CourseReviewCollection)
+
+	objectCourseId, err := primitive.ObjectIDFromHex(courseId)
+
+	if err != nil {
+		return nil, nil, openerrors.InvalidIdErr{
+			Id:        courseId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/course_review_impl.go",
+					Method: "GetCourseReviewsPage",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	docs, page, err := ctx.findPage(col, bson.D{{"course_id", objectCourseId}, {"hidden", false}},
+		bson.D{{"_id", -1}}, nil, query)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reviews := make([]*common.CourseReview, 0, len(docs))
+
+	for _, doc := range docs {
+		var dbReview DbCourseReview
+
+		err = bson.Unmarshal(doc, &dbReview)
+
+		if err != nil {
+			return nil, nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/course_review_impl.go",
+					Method: "GetCourseReviewsPage",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		reviews = append(reviews, dbReview.ToCourseReview())
+	}
+
+	return reviews, page, nil
+}
+
+/*
+ReplyCourseReview set reply of course author to review. The previous reply is replaced.
+Returns false, if review isn't found. Parameters:
+reviewId - review id;
+authorId - reply author id;
+text - reply text;
+*/
+func (ctx *DbContext) ReplyCourseReview(reviewId string, authorId string, text string) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(CourseReviewCollection)
+
+	if len(text) == 0 || len(text) > maxReviewLength {
+		return false, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "ReplyCourseReview",
+			},
+			Msg: "reply text must be not empty and shorter than " + strconv.Itoa(maxReviewLength),
+		}
+	}
+
+	objectReviewId, objectAuthorId, err := reviewIds(reviewId, authorId, "ReplyCourseReview")
+
+	if err != nil {
+		return false, err
+	}
+
+	reply := DbReviewReply{
+		AuthorId:   objectAuthorId,
+		Text:       text,
+		DateCreate: primitive.NewDateTimeFromTime(ctx.Now()),
+	}
+
+	result, err := col.UpdateOne(context.Background(), bson.D{{"_id", objectReviewId}},
+		bson.D{{"$set", bson.D{{"reply", reply}}}})
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "ReplyCourseReview",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	if result.MatchedCount > 0 {
+		ctx.publish(events.Event{Type: events.ReviewReplied, UserId: authorId, TargetId: reviewId})
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+/*
+HideCourseReview hide review or show hidden review. Hidden review isn't listed and isn't counted in course rating.
+Returns false, if review isn't found. Parameters:
+reviewId - review id;
+hidden - review is hidden;
+*/
+func (ctx *DbContext) HideCourseReview(reviewId string, hidden bool) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(CourseReviewCollection)
+
+	objectReviewId, err := primitive.ObjectIDFromHex(reviewId)
+
+	if err != nil {
+		return false, openerrors.InvalidIdErr{
+			Id:        reviewId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/course_review_impl.go",
+					Method: "HideCourseReview",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	session, err := ctx.Client.StartSession()
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "HideCourseReview",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer session.EndSession(context.Background())
+
+	found := false
+
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		var dbReview DbCourseReview
+
+		err := col.FindOneAndUpdate(sc, bson.D{{"_id", objectReviewId}},
+			bson.D{{"$set", bson.D{{"hidden", hidden}}}}).Decode(&dbReview)
+
+		found = err == nil
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		previous := visibleStars(&dbReview)
+		dbReview.Hidden = hidden
+
+		return nil, ctx.updateRatings(sc, dbReview.CourseId, previous, visibleStars(&dbReview))
+	})
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/course_review_impl.go",
+				Method: "HideCourseReview",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	if found {
+		value := 0
+
+		if hidden {
+			value = 1
+		}
+
+		ctx.publish(events.Event{Type: events.ReviewModerated, TargetId: reviewId, Value: value})
+	}
+
+	return found, nil
+}
+
+/*
+updateRatings replace rating in course aggregate and set course rating to its average. Parameters:
+sc - session context of transaction;
+courseId - course id;
+removed - stars of removed rating, 0 if rating isn't removed;
+added - stars of added rating, 0 if rating isn't added;
+*/
+func (ctx *DbContext) updateRatings(sc mongo.SessionContext, courseId primitive.ObjectID, removed int, added int) error {
+	if removed == added {
+		return nil
+	}
+
+	col := ctx.Client.Database(DbName).Collection(CourseCollection)
+
+	var dbCourse DbCourse
+
+	err := col.FindOne(sc, bson.D{{"_id", courseId}}, options.FindOne().SetProjection(bson.D{{"ratings", 1}})).Decode(&dbCourse)
+
+	if err != nil {
+		return err
+	}
+
+	ratings := DbCourseRatings{}
+
+	if dbCourse.Ratings != nil {
+		ratings = *dbCourse.Ratings
+	}
+
+	count := 0
+	inc := bson.D{}
+
+	if removed > 0 {
+		count--
+		inc = append(inc, bson.E{Key: "ratings.histogram." + strconv.Itoa(removed), Value: -1})
+	}
+
+	if added > 0 {
+		count++
+		inc = append(inc, bson.E{Key: "ratings.histogram." + strconv.Itoa(added), Value: 1})
+	}
+
+	ratings.Count += count
+	ratings.Sum += added - removed
+
+	inc = append(inc,
+		bson.E{Key: "ratings.count", Value: count},
+		bson.E{Key: "ratings.sum", Value: added - removed})
+
+	// Concurrent update of aggregate is a write conflict, so transaction is retried with the new aggregate
+	update := bson.D{{"$inc", inc}, {"$set", bson.D{{"rating", averageRating(&ratings)}}}}
+
+	_, err = col.UpdateOne(sc, bson.D{{"_id", courseId}}, update)
+
+	return err
+}
+
+// averageRating return average of ratings multiplied by 100
+func averageRating(ratings *DbCourseRatings) int {
+	if ratings.Count <= 0 {
+		return 0
+	}
+
+	return int(math.Round(float64(ratings.Sum) * 100 / float64(ratings.Count)))
+}
+
+// visibleStars return stars of review, which is counted in course rating. Hidden review isn't counted
+func visibleStars(dbReview *DbCourseReview) int {
+	if dbReview.Hidden {
+		return 0
+	}
+
+	return dbReview.Stars
+}
+
+/*
+reviewIds convert pair of ids to object ids. Parameters:
+firstId - the first id;
+secondId - the second id;
+method - method name for errors;
+*/
+func reviewIds(firstId string, secondId string, method string) (primitive.ObjectID, primitive.ObjectID, error) {
+	ids := [2]primitive.ObjectID{}
+
+	for i, id := range []string{firstId, secondId} {
+		objectId, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			return ids[0], ids[1], openerrors.InvalidIdErr{
+				Id:        id,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/course_review_impl.go",
+						Method: method,
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		ids[i] = objectId
+	}
+
+	return ids[0], ids[1], nil
+}
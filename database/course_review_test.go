@@ -0,0 +1,33 @@
+package database
+
+import "testing"
+
+// TestAverageRating average is multiplied by 100 and rounded, course without ratings has zero rating
+func TestAverageRating(t *testing.T) {
+	cases := []struct {
+		ratings DbCourseRatings
+		rating  int
+	}{
+		{DbCourseRatings{}, 0},
+		{DbCourseRatings{Count: 1, Sum: 5}, 500},
+		{DbCourseRatings{Count: 3, Sum: 13}, 433},
+		{DbCourseRatings{Count: 3, Sum: 14}, 467},
+	}
+
+	for _, c := range cases {
+		if rating := averageRating(&c.ratings); rating != c.rating {
+			t.Errorf("rating of %+v is %d, expected %d", c.ratings, rating, c.rating)
+		}
+	}
+}
+
+// TestVisibleStars hidden review isn't counted in rating
+func TestVisibleStars(t *testing.T) {
+	if stars := visibleStars(&DbCourseReview{Stars: 4}); stars != 4 {
+		t.Errorf("stars of visible review are %d", stars)
+	}
+
+	if stars := visibleStars(&DbCourseReview{Stars: 4, Hidden: true}); stars != 0 {
+		t.Errorf("stars of hidden review are %d", stars)
+	}
+}
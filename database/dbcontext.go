@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/clock"
 	"opencourse/common/openerrors"
+	"opencourse/events"
+	"time"
 )
 
 // DbContext is a context for work with mongo db
@@ -15,6 +18,9 @@ type DbContext struct {
 	SmtpAccount     string        // SMTP account
 	SmtpAccountPass string        // SMTP account password
 	Endpoint        string        // Endpoint (base url)
+	MediaDir        string        // Directory for course media files
+	Clock           clock.Clock   // Clock for deadlines, attempts and cooldowns
+	Events          *events.Bus   // Domain events of learner progress
 	Client          *mongo.Client // Client connection for db
 }
 
@@ -26,6 +32,28 @@ func (ctx *DbContext) Defaults(conStr string, smtpAccount string, smtpPass strin
 	ctx.SmtpAccount = smtpAccount
 	ctx.Endpoint = endpoint
 	ctx.ConStr = conStr
+	ctx.Clock = clock.SystemClock{}
+	ctx.Events = events.NewBus()
+}
+
+// Now return current UTC time of context clock
+func (ctx *DbContext) Now() time.Time {
+	if ctx.Clock == nil {
+		return time.Now().UTC()
+	}
+
+	return ctx.Clock.Now().UTC()
+}
+
+// publishContent publish event of course content. Content is saved, so subscribers handle their errors
+func (ctx *DbContext) publishContent(eventType string, courseId string, stageId string) {
+	_ = ctx.Events.Publish(events.Event{Type: eventType, CourseId: courseId, StageId: stageId, Date: ctx.Now()})
+}
+
+// publish publish event of user. Change is saved, so subscribers handle their errors
+func (ctx *DbContext) publish(event events.Event) {
+	event.Date = ctx.Now()
+	_ = ctx.Events.Publish(event)
 }
 
 // Connect to db
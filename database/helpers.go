@@ -3,6 +3,7 @@ package database
 import (
 	"opencourse/common"
 	"opencourse/common/openerrors"
+	"sort"
 )
 
 /*
@@ -23,16 +24,32 @@ func (dbCourse *DbCourse) ToCourse() (*common.Course, error) {
 
 	course.Id = dbCourse.Id.Hex()
 	course.CategoryId = dbCourse.CategoryId.Hex()
+	if !dbCourse.AuthorId.IsZero() {
+		course.AuthorId = dbCourse.AuthorId.Hex()
+	}
+	course.Lang = dbCourse.Lang
+	if !dbCourse.Group.IsZero() {
+		course.Group = dbCourse.Group.Hex()
+	}
 	course.Name = dbCourse.Name
 	course.Tags = dbCourse.Tags
 	course.Description = dbCourse.Description
 	course.Rating = dbCourse.Rating
+	course.Points = dbCourse.Points
 	course.Enabled = dbCourse.Enabled
 	course.IconImg = dbCourse.IconImg
 	course.HeaderImg = dbCourse.HeaderImg
 	course.DateCreate = dbCourse.DateCreate.Time()
 	course.DateUpdate = dbCourse.DateUpdate.Time()
 
+	if dbCourse.Ratings != nil && dbCourse.Ratings.Count > 0 {
+		course.Ratings = &common.CourseRatings{
+			Average:   float64(dbCourse.Ratings.Sum) / float64(dbCourse.Ratings.Count),
+			Count:     dbCourse.Ratings.Count,
+			Histogram: dbCourse.Ratings.Histogram,
+		}
+	}
+
 	return &course, nil
 }
 
@@ -53,11 +70,20 @@ func (dbCategory *DbCategory) ToCategory() (*common.Category, error) {
 	var category common.Category
 
 	category.Id = dbCategory.Id.Hex()
+	category.Path = categoryPath(dbCategory)
 	category.Name = dbCategory.Name
 	category.Lang = dbCategory.Lang
 	category.IconImg = dbCategory.IconImg
 	category.HeaderImg = dbCategory.HeaderImg
 
+	if !dbCategory.ParentId.IsZero() {
+		category.ParentId = dbCategory.ParentId.Hex()
+	}
+
+	if !dbCategory.Group.IsZero() {
+		category.Group = dbCategory.Group.Hex()
+	}
+
 	return &category, nil
 }
 
@@ -80,8 +106,16 @@ func (dbStage *DbStage) ToStage() (*common.Stage, error) {
 	stage.Id = dbStage.Id.Hex()
 	stage.Name = dbStage.Name
 	stage.CourseId = dbStage.CourseId.Hex()
+	if !dbStage.Group.IsZero() {
+		stage.Group = dbStage.Group.Hex()
+	}
 	stage.HeaderImg = dbStage.HeaderImg
 	stage.OrderNumber = dbStage.OrderNumber
+	stage.TimeLimit = dbStage.TimeLimit
+
+	for _, dbPool := range dbStage.Pools {
+		stage.Pools = append(stage.Pools, &common.QuestionPool{Name: dbPool.Name, DrawCount: dbPool.DrawCount})
+	}
 
 	if dbStage.Content != nil {
 		stage.Content = &common.PostContent{}
@@ -111,8 +145,12 @@ func (dbStage *DbStage) ToStagePreview() (*common.StagePreview, error) {
 	stage.Id = dbStage.Id.Hex()
 	stage.Name = dbStage.Name
 	stage.CourseId = dbStage.CourseId.Hex()
+	if !dbStage.Group.IsZero() {
+		stage.Group = dbStage.Group.Hex()
+	}
 	stage.HeaderImg = dbStage.HeaderImg
 	stage.OrderNumber = dbStage.OrderNumber
+	stage.TimeLimit = dbStage.TimeLimit
 
 	return &stage, nil
 }
@@ -135,9 +173,15 @@ func (dbTest *DbTest) ToTest() (*common.Test, error) {
 
 	test.Id = dbTest.Id.Hex()
 	test.StageId = dbTest.StageId.Hex()
+	if !dbTest.Group.IsZero() {
+		test.Group = dbTest.Group.Hex()
+	}
 	test.TestType = dbTest.TestType
 	test.LemmingsCount = dbTest.LemmingsCount
 	test.OrderNumber = dbTest.OrderNumber
+	test.MaxAttempts = dbTest.MaxAttempts
+	test.Cooldown = dbTest.Cooldown
+	test.Pool = dbTest.Pool
 
 	if dbTest.OptionTest != nil {
 		test.OptionTest = &common.OptionTest{}
@@ -154,6 +198,76 @@ func (dbTest *DbTest) ToTest() (*common.Test, error) {
 			Question:    dbTest.RewriteTest.Question,
 			RightAnswer: dbTest.RewriteTest.RightAnswer,
 		}
+
+		if dbMatching := dbTest.RewriteTest.Matching; dbMatching != nil {
+			test.RewriteTest.Matching = &common.RewriteMatching{
+				IgnoreCase:        dbMatching.IgnoreCase,
+				IgnoreWhitespace:  dbMatching.IgnoreWhitespace,
+				IgnorePunctuation: dbMatching.IgnorePunctuation,
+				UnicodeNormalize:  dbMatching.UnicodeNormalize,
+				FoldDiacritics:    dbMatching.FoldDiacritics,
+				AcceptedAnswers:   dbMatching.AcceptedAnswers,
+				Patterns:          dbMatching.Patterns,
+				MaxDistance:       dbMatching.MaxDistance,
+			}
+		}
+	}
+
+	if dbTest.MultiSelectTest != nil {
+		test.MultiSelectTest = &common.MultiSelectTest{
+			Question:      dbTest.MultiSelectTest.Question,
+			PartialCredit: dbTest.MultiSelectTest.PartialCredit,
+		}
+
+		for _, dbOption := range dbTest.MultiSelectTest.Options {
+			test.MultiSelectTest.Options =
+				append(test.MultiSelectTest.Options, &common.Option{Answer: dbOption.Answer, IsRight: dbOption.IsRight})
+		}
+	}
+
+	if dbTest.OrderingTest != nil {
+		test.OrderingTest = &common.OrderingTest{
+			Question: dbTest.OrderingTest.Question,
+			Items:    dbTest.OrderingTest.Items,
+		}
+	}
+
+	if dbTest.MatchingTest != nil {
+		test.MatchingTest = &common.MatchingTest{Question: dbTest.MatchingTest.Question}
+
+		for _, dbPair := range dbTest.MatchingTest.Pairs {
+			test.MatchingTest.Pairs =
+				append(test.MatchingTest.Pairs, &common.MatchPair{Left: dbPair.Left, Right: dbPair.Right})
+		}
+	}
+
+	if dbTest.ClozeTest != nil {
+		test.ClozeTest = &common.ClozeTest{Text: dbTest.ClozeTest.Text}
+
+		for _, dbBlank := range dbTest.ClozeTest.Blanks {
+			test.ClozeTest.Blanks = append(test.ClozeTest.Blanks, &common.ClozeBlank{Answers: dbBlank.Answers})
+		}
+	}
+
+	if dbTest.NumericTest != nil {
+		test.NumericTest = &common.NumericTest{
+			Question:  dbTest.NumericTest.Question,
+			Answer:    dbTest.NumericTest.Answer,
+			Tolerance: dbTest.NumericTest.Tolerance,
+		}
+	}
+
+	if dbTest.CodeTest != nil {
+		test.CodeTest = &common.CodeTest{
+			Question:    dbTest.CodeTest.Question,
+			Language:    dbTest.CodeTest.Language,
+			StarterCode: dbTest.CodeTest.StarterCode,
+		}
+
+		for _, dbCase := range dbTest.CodeTest.Cases {
+			test.CodeTest.Cases = append(test.CodeTest.Cases,
+				&common.CodeCase{Input: dbCase.Input, ExpectedOutput: dbCase.ExpectedOutput, Hidden: dbCase.Hidden})
+		}
 	}
 
 	return &test, nil
@@ -177,9 +291,62 @@ func (dbTest *DbTest) ToTestPreview() (*common.TestPreview, error) {
 
 	test.Id = dbTest.Id.Hex()
 	test.StageId = dbTest.StageId.Hex()
+	if !dbTest.Group.IsZero() {
+		test.Group = dbTest.Group.Hex()
+	}
 	test.TestType = dbTest.TestType
 	test.LemmingsCount = dbTest.LemmingsCount
 	test.OrderNumber = dbTest.OrderNumber
+	test.MaxAttempts = dbTest.MaxAttempts
+	test.Cooldown = dbTest.Cooldown
+
+	// Right answers are not sent to learner. Ordering items and matching right items are sorted,
+	// so their stored order doesn't leak the answer
+	switch {
+	case dbTest.OptionTest != nil:
+		test.Question = dbTest.OptionTest.Question
+
+		for _, dbOption := range dbTest.OptionTest.Options {
+			test.Options = append(test.Options, dbOption.Answer)
+		}
+	case dbTest.RewriteTest != nil:
+		test.Question = dbTest.RewriteTest.Question
+	case dbTest.MultiSelectTest != nil:
+		test.Question = dbTest.MultiSelectTest.Question
+
+		for _, dbOption := range dbTest.MultiSelectTest.Options {
+			test.Options = append(test.Options, dbOption.Answer)
+		}
+	case dbTest.OrderingTest != nil:
+		test.Question = dbTest.OrderingTest.Question
+		test.Items = append(test.Items, dbTest.OrderingTest.Items...)
+		sort.Strings(test.Items)
+	case dbTest.MatchingTest != nil:
+		test.Question = dbTest.MatchingTest.Question
+
+		for _, dbPair := range dbTest.MatchingTest.Pairs {
+			test.Items = append(test.Items, dbPair.Left)
+			test.Pairs = append(test.Pairs, dbPair.Right)
+		}
+
+		sort.Strings(test.Pairs)
+	case dbTest.ClozeTest != nil:
+		test.Question = dbTest.ClozeTest.Text
+		test.BlanksCount = len(dbTest.ClozeTest.Blanks)
+	case dbTest.NumericTest != nil:
+		test.Question = dbTest.NumericTest.Question
+	case dbTest.CodeTest != nil:
+		test.Question = dbTest.CodeTest.Question
+		test.Language = dbTest.CodeTest.Language
+		test.StarterCode = dbTest.CodeTest.StarterCode
+
+		for _, dbCase := range dbTest.CodeTest.Cases {
+			if !dbCase.Hidden {
+				test.Examples = append(test.Examples,
+					&common.CodeCase{Input: dbCase.Input, ExpectedOutput: dbCase.ExpectedOutput})
+			}
+		}
+	}
 
 	return &test, nil
 }
@@ -234,6 +401,7 @@ func (dbUser *DbUser) ToUser() (*common.User, error) {
 	user.Avatar = dbUser.Avatar
 	user.Email = dbUser.Email
 	user.Rating = dbUser.Rating
+	user.Lemmings = dbUser.Lemmings
 	user.Credential = &common.Credential{
 		Login:            dbUser.Credential.Login,
 		Password:         dbUser.Credential.Password,
@@ -267,8 +435,600 @@ func ToUserPreview(user *common.User) (*common.UserPreview, error) {
 	userPreview.Login = user.Credential.Login
 	userPreview.Name = user.Name
 	userPreview.Rating = user.Rating
+	userPreview.Lemmings = user.Lemmings
 	userPreview.Roles = user.Credential.Roles
 	userPreview.Avatar = user.Avatar
 
 	return &userPreview, nil
 }
+
+/*
+ToDbStage map Stage to DbStage. Ids are not mapped
+*/
+func ToDbStage(stage *common.Stage) (*DbStage, error) {
+	if stage == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/helpers.go",
+				Method: "ToDbStage",
+			},
+			Model: "stage",
+		}
+	}
+
+	var dbStage DbStage
+
+	dbStage.Name = stage.Name
+	dbStage.HeaderImg = stage.HeaderImg
+	dbStage.OrderNumber = stage.OrderNumber
+	dbStage.TimeLimit = stage.TimeLimit
+	dbStage.Pools = ToDbQuestionPools(stage.Pools)
+
+	if stage.Content != nil {
+		dbStage.Content = &DbPostContent{}
+		dbStage.Content.Body = stage.Content.Body
+		dbStage.Content.MediaItems = stage.Content.MediaItems
+	}
+
+	return &dbStage, nil
+}
+
+/*
+ToDbTest map Test to DbTest. Ids are not mapped
+*/
+func ToDbTest(test *common.Test) (*DbTest, error) {
+	if test == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/helpers.go",
+				Method: "ToDbTest",
+			},
+			Model: "test",
+		}
+	}
+
+	var dbTest DbTest
+
+	dbTest.TestType = test.TestType
+	dbTest.LemmingsCount = test.LemmingsCount
+	dbTest.OrderNumber = test.OrderNumber
+	dbTest.MaxAttempts = test.MaxAttempts
+	dbTest.Cooldown = test.Cooldown
+	dbTest.Pool = test.Pool
+
+	if test.OptionTest != nil {
+		dbTest.OptionTest = &DbOptionTest{}
+		dbTest.OptionTest.Question = test.OptionTest.Question
+
+		for _, option := range test.OptionTest.Options {
+			if option == nil {
+				continue
+			}
+
+			dbTest.OptionTest.Options =
+				append(dbTest.OptionTest.Options, &DbOption{Answer: option.Answer, IsRight: option.IsRight})
+		}
+	}
+
+	if test.RewriteTest != nil {
+		dbTest.RewriteTest = &DbRewriteTest{
+			Question:    test.RewriteTest.Question,
+			RightAnswer: test.RewriteTest.RightAnswer,
+		}
+
+		if matching := test.RewriteTest.Matching; matching != nil {
+			dbTest.RewriteTest.Matching = &DbRewriteMatching{
+				IgnoreCase:        matching.IgnoreCase,
+				IgnoreWhitespace:  matching.IgnoreWhitespace,
+				IgnorePunctuation: matching.IgnorePunctuation,
+				UnicodeNormalize:  matching.UnicodeNormalize,
+				FoldDiacritics:    matching.FoldDiacritics,
+				AcceptedAnswers:   matching.AcceptedAnswers,
+				Patterns:          matching.Patterns,
+				MaxDistance:       matching.MaxDistance,
+			}
+		}
+	}
+
+	if test.MultiSelectTest != nil {
+		dbTest.MultiSelectTest = &DbMultiSelectTest{
+			Question:      test.MultiSelectTest.Question,
+			PartialCredit: test.MultiSelectTest.PartialCredit,
+		}
+
+		for _, option := range test.MultiSelectTest.Options {
+			if option == nil {
+				continue
+			}
+
+			dbTest.MultiSelectTest.Options =
+				append(dbTest.MultiSelectTest.Options, &DbOption{Answer: option.Answer, IsRight: option.IsRight})
+		}
+	}
+
+	if test.OrderingTest != nil {
+		dbTest.OrderingTest = &DbOrderingTest{
+			Question: test.OrderingTest.Question,
+			Items:    test.OrderingTest.Items,
+		}
+	}
+
+	if test.MatchingTest != nil {
+		dbTest.MatchingTest = &DbMatchingTest{Question: test.MatchingTest.Question}
+
+		for _, pair := range test.MatchingTest.Pairs {
+			if pair == nil {
+				continue
+			}
+
+			dbTest.MatchingTest.Pairs = append(dbTest.MatchingTest.Pairs, &DbMatchPair{Left: pair.Left, Right: pair.Right})
+		}
+	}
+
+	if test.ClozeTest != nil {
+		dbTest.ClozeTest = &DbClozeTest{Text: test.ClozeTest.Text}
+
+		for _, blank := range test.ClozeTest.Blanks {
+			if blank == nil {
+				continue
+			}
+
+			dbTest.ClozeTest.Blanks = append(dbTest.ClozeTest.Blanks, &DbClozeBlank{Answers: blank.Answers})
+		}
+	}
+
+	if test.NumericTest != nil {
+		dbTest.NumericTest = &DbNumericTest{
+			Question:  test.NumericTest.Question,
+			Answer:    test.NumericTest.Answer,
+			Tolerance: test.NumericTest.Tolerance,
+		}
+	}
+
+	if test.CodeTest != nil {
+		dbTest.CodeTest = &DbCodeTest{
+			Question:    test.CodeTest.Question,
+			Language:    test.CodeTest.Language,
+			StarterCode: test.CodeTest.StarterCode,
+		}
+
+		for _, codeCase := range test.CodeTest.Cases {
+			if codeCase == nil {
+				continue
+			}
+
+			dbTest.CodeTest.Cases = append(dbTest.CodeTest.Cases,
+				&DbCodeCase{Input: codeCase.Input, ExpectedOutput: codeCase.ExpectedOutput, Hidden: codeCase.Hidden})
+		}
+	}
+
+	return &dbTest, nil
+}
+
+/*
+ToDbTestAnswer map TestAnswer to DbTestAnswer. Matching pairs are sorted by left item
+*/
+func ToDbTestAnswer(answer *common.TestAnswer) *DbTestAnswer {
+	if answer == nil {
+		return nil
+	}
+
+	dbAnswer := &DbTestAnswer{
+		Options: answer.Options,
+		Text:    answer.Text,
+		Order:   answer.Order,
+		Blanks:  answer.Blanks,
+		Number:  answer.Number,
+		Code:    answer.Code,
+	}
+
+	for left, right := range answer.Pairs {
+		dbAnswer.Pairs = append(dbAnswer.Pairs, &DbMatchPair{Left: left, Right: right})
+	}
+
+	sort.Slice(dbAnswer.Pairs, func(i, j int) bool { return dbAnswer.Pairs[i].Left < dbAnswer.Pairs[j].Left })
+
+	return dbAnswer
+}
+
+/*
+ToTestAttempt map DbTestAttempt to TestAttempt
+*/
+func (dbAttempt *DbTestAttempt) ToTestAttempt() (*common.TestAttempt, error) {
+	if dbAttempt == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/helpers.go",
+				Method: "ToTestAttempt",
+			},
+			Model: "dbAttempt",
+		}
+	}
+
+	var attempt common.TestAttempt
+
+	attempt.Id = dbAttempt.Id.Hex()
+	attempt.UserId = dbAttempt.UserId.Hex()
+	attempt.TestId = dbAttempt.TestId.Hex()
+	attempt.Number = dbAttempt.Number
+	attempt.Score = dbAttempt.Score
+	attempt.IsPassed = dbAttempt.IsPassed
+	attempt.DateCreate = dbAttempt.DateCreate.Time()
+	attempt.Answer = dbAttempt.Answer.ToTestAnswer()
+	attempt.Question = dbAttempt.Question
+	attempt.Options = dbAttempt.Options
+
+	if !dbAttempt.SelectionId.IsZero() {
+		attempt.SelectionId = dbAttempt.SelectionId.Hex()
+	}
+
+	if !dbAttempt.SessionId.IsZero() {
+		attempt.SessionId = dbAttempt.SessionId.Hex()
+	}
+
+	return &attempt, nil
+}
+
+/*
+ToTestAnswer map DbTestAnswer to TestAnswer
+*/
+func (dbAnswer *DbTestAnswer) ToTestAnswer() *common.TestAnswer {
+	if dbAnswer == nil {
+		return nil
+	}
+
+	answer := &common.TestAnswer{
+		Options: dbAnswer.Options,
+		Text:    dbAnswer.Text,
+		Order:   dbAnswer.Order,
+		Blanks:  dbAnswer.Blanks,
+		Number:  dbAnswer.Number,
+		Code:    dbAnswer.Code,
+	}
+
+	if len(dbAnswer.Pairs) > 0 {
+		answer.Pairs = make(map[string]string, len(dbAnswer.Pairs))
+
+		for _, dbPair := range dbAnswer.Pairs {
+			answer.Pairs[dbPair.Left] = dbPair.Right
+		}
+	}
+
+	return answer
+}
+
+/*
+ToQuizSession map DbQuizSession to QuizSession
+*/
+func (dbSession *DbQuizSession) ToQuizSession() (*common.QuizSession, error) {
+	if dbSession == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/helpers.go",
+				Method: "ToQuizSession",
+			},
+			Model: "dbSession",
+		}
+	}
+
+	var session common.QuizSession
+
+	session.Id = dbSession.Id.Hex()
+	session.UserId = dbSession.UserId.Hex()
+	session.StageId = dbSession.StageId.Hex()
+	session.CourseId = dbSession.CourseId.Hex()
+	session.Status = dbSession.Status
+	session.DateStart = dbSession.DateStart.Time()
+	session.Deadline = dbSession.Deadline.Time()
+	session.Score = dbSession.Score
+	session.IsPassed = dbSession.IsPassed
+	session.Answers = []*common.SessionAnswer{}
+
+	if dbSession.DateSubmit != 0 {
+		session.DateSubmit = dbSession.DateSubmit.Time()
+	}
+
+	for _, dbAnswer := range dbSession.Answers {
+		session.Answers = append(session.Answers, &common.SessionAnswer{
+			TestId:     dbAnswer.TestId.Hex(),
+			Answer:     dbAnswer.Answer.ToTestAnswer(),
+			DateUpdate: dbAnswer.DateUpdate.Time(),
+		})
+	}
+
+	for _, dbResult := range dbSession.Results {
+		session.Results = append(session.Results, &common.SessionResult{
+			TestId:   dbResult.TestId.Hex(),
+			Answered: dbResult.Answered,
+			Score:    dbResult.Score,
+			IsPassed: dbResult.IsPassed,
+		})
+	}
+
+	return &session, nil
+}
+
+/*
+ToTestSelection map DbTestSelection to TestSelection
+*/
+func (dbSelection *DbTestSelection) ToTestSelection() (*common.TestSelection, error) {
+	if dbSelection == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/helpers.go",
+				Method: "ToTestSelection",
+			},
+			Model: "dbSelection",
+		}
+	}
+
+	var selection common.TestSelection
+
+	selection.Id = dbSelection.Id.Hex()
+	selection.UserId = dbSelection.UserId.Hex()
+	selection.StageId = dbSelection.StageId.Hex()
+	selection.Seed = dbSelection.Seed
+	selection.DateCreate = dbSelection.DateCreate.Time()
+	selection.TestIds = []string{}
+
+	if !dbSelection.SessionId.IsZero() {
+		selection.SessionId = dbSelection.SessionId.Hex()
+	}
+
+	for _, testId := range dbSelection.TestIds {
+		selection.TestIds = append(selection.TestIds, testId.Hex())
+	}
+
+	return &selection, nil
+}
+
+// ToDbQuestionPools map question pools to DbQuestionPool. Pools without name are skipped
+func ToDbQuestionPools(pools []*common.QuestionPool) []*DbQuestionPool {
+	var dbPools []*DbQuestionPool
+
+	for _, pool := range pools {
+		if pool == nil || len(pool.Name) == 0 {
+			continue
+		}
+
+		dbPools = append(dbPools, &DbQuestionPool{Name: pool.Name, DrawCount: pool.DrawCount})
+	}
+
+	return dbPools
+}
+
+/*
+ToCertificate map DbCertificate to Certificate
+*/
+func (dbCertificate *DbCertificate) ToCertificate() (*common.Certificate, error) {
+	if dbCertificate == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/helpers.go",
+				Method: "ToCertificate",
+			},
+			Model: "dbCertificate",
+		}
+	}
+
+	var certificate common.Certificate
+
+	certificate.Id = dbCertificate.Id.Hex()
+	certificate.Code = dbCertificate.Code
+	certificate.UserId = dbCertificate.UserId.Hex()
+	certificate.CourseId = dbCertificate.CourseId.Hex()
+	certificate.UserName = dbCertificate.UserName
+	certificate.CourseName = dbCertificate.CourseName
+	certificate.DateIssue = dbCertificate.DateIssue.Time()
+
+	return &certificate, nil
+}
+
+/*
+ToBadgeClass map DbBadgeClass to BadgeClass
+*/
+func (dbBadgeClass *DbBadgeClass) ToBadgeClass() (*common.BadgeClass, error) {
+	if dbBadgeClass == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/helpers.go",
+				Method: "ToBadgeClass",
+			},
+			Model: "dbBadgeClass",
+		}
+	}
+
+	var badgeClass common.BadgeClass
+
+	badgeClass.Id = dbBadgeClass.Id.Hex()
+	badgeClass.Name = dbBadgeClass.Name
+	badgeClass.Description = dbBadgeClass.Description
+	badgeClass.Image = dbBadgeClass.Image
+	badgeClass.Criteria = dbBadgeClass.Criteria
+	badgeClass.Kind = dbBadgeClass.Kind
+	badgeClass.Threshold = dbBadgeClass.Threshold
+	badgeClass.DateCreate = dbBadgeClass.DateCreate.Time()
+
+	if !dbBadgeClass.CourseId.IsZero() {
+		badgeClass.CourseId = dbBadgeClass.CourseId.Hex()
+	}
+
+	return &badgeClass, nil
+}
+
+/*
+ToBadgeAssertion map DbBadgeAssertion to BadgeAssertion
+*/
+func (dbAssertion *DbBadgeAssertion) ToBadgeAssertion() (*common.BadgeAssertion, error) {
+	if dbAssertion == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/helpers.go",
+				Method: "ToBadgeAssertion",
+			},
+			Model: "dbAssertion",
+		}
+	}
+
+	var assertion common.BadgeAssertion
+
+	assertion.Id = dbAssertion.Id.Hex()
+	assertion.BadgeClassId = dbAssertion.BadgeClassId.Hex()
+	assertion.UserId = dbAssertion.UserId.Hex()
+	assertion.RecipientIdentity = dbAssertion.RecipientIdentity
+	assertion.RecipientSalt = dbAssertion.RecipientSalt
+	assertion.Evidence = dbAssertion.Evidence
+	assertion.IssuedOn = dbAssertion.IssuedOn.Time()
+	assertion.Revoked = dbAssertion.Revoked
+	assertion.RevocationReason = dbAssertion.RevocationReason
+
+	return &assertion, nil
+}
+
+/*
+ToUserProgress map DbUserProgress to UserProgress
+*/
+func (dbProgress *DbUserProgress) ToUserProgress() (*common.UserProgress, error) {
+	if dbProgress == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/helpers.go",
+				Method: "ToUserProgress",
+			},
+			Model: "dbProgress",
+		}
+	}
+
+	var progress common.UserProgress
+
+	progress.UserId = dbProgress.UserId.Hex()
+	progress.Counters = dbProgress.Counters
+	progress.Streak = dbProgress.Streak
+	progress.LongestStreak = dbProgress.LongestStreak
+	progress.Achievements = dbProgress.Achievements
+
+	if dbProgress.LastActive > 0 {
+		progress.LastActive = dbProgress.LastActive.Time().UTC()
+	}
+
+	return &progress, nil
+}
+
+// ToLeaderboardEntry map DbLeaderboardEntry to LeaderboardEntry
+func (dbEntry *DbLeaderboardEntry) ToLeaderboardEntry() *common.LeaderboardEntry {
+	return &common.LeaderboardEntry{
+		Rank:     dbEntry.Rank,
+		UserId:   dbEntry.UserId.Hex(),
+		UserName: dbEntry.UserName,
+		Score:    dbEntry.Score,
+	}
+}
+
+// ToReviewSchedule map DbReviewSchedule to ReviewSchedule
+func (dbSchedule *DbReviewSchedule) ToReviewSchedule() *common.ReviewSchedule {
+	schedule := &common.ReviewSchedule{
+		UserId:      dbSchedule.UserId.Hex(),
+		TestId:      dbSchedule.TestId.Hex(),
+		StageId:     dbSchedule.StageId.Hex(),
+		CourseId:    dbSchedule.CourseId.Hex(),
+		Ease:        dbSchedule.Ease,
+		Interval:    dbSchedule.Interval,
+		Repetitions: dbSchedule.Repetitions,
+		LastQuality: dbSchedule.LastQuality,
+		DateDue:     dbSchedule.DateDue.Time().UTC(),
+	}
+
+	if dbSchedule.DateReview > 0 {
+		schedule.DateReview = dbSchedule.DateReview.Time().UTC()
+	}
+
+	return schedule
+}
+
+// ToCoursePromotion map DbCoursePromotion to common DbCoursePromotion
+func (dbPromotion *DbCoursePromotion) ToCoursePromotion() *common.DbCoursePromotion {
+	return &common.DbCoursePromotion{
+		Id:             dbPromotion.Id.Hex(),
+		CourseId:       dbPromotion.CourseId.Hex(),
+		PromotionType:  dbPromotion.PromotionType,
+		Label:          dbPromotion.Label,
+		ExpirationTime: dbPromotion.ExpirationTime.Time().UTC(),
+	}
+}
+
+// ToCourseReview map DbCourseReview to CourseReview
+func (dbReview *DbCourseReview) ToCourseReview() *common.CourseReview {
+	review := &common.CourseReview{
+		Id:         dbReview.Id.Hex(),
+		CourseId:   dbReview.CourseId.Hex(),
+		UserId:     dbReview.UserId.Hex(),
+		UserName:   dbReview.UserName,
+		Stars:      dbReview.Stars,
+		Text:       dbReview.Text,
+		Hidden:     dbReview.Hidden,
+		DateCreate: dbReview.DateCreate.Time().UTC(),
+		DateUpdate: dbReview.DateUpdate.Time().UTC(),
+	}
+
+	if dbReview.Reply != nil {
+		review.Reply = &common.ReviewReply{
+			AuthorId:   dbReview.Reply.AuthorId.Hex(),
+			Text:       dbReview.Reply.Text,
+			DateCreate: dbReview.Reply.DateCreate.Time().UTC(),
+		}
+	}
+
+	return review
+}
+
+// ToStageComment map DbStageComment to StageComment
+func (dbComment *DbStageComment) ToStageComment() *common.StageComment {
+	comment := &common.StageComment{
+		Id:         dbComment.Id.Hex(),
+		StageId:    dbComment.StageId.Hex(),
+		CourseId:   dbComment.CourseId.Hex(),
+		UserId:     dbComment.UserId.Hex(),
+		UserName:   dbComment.UserName,
+		Body:       dbComment.Body,
+		Votes:      dbComment.Votes,
+		IsAnswer:   dbComment.IsAnswer,
+		ReplyCount: dbComment.ReplyCount,
+		Hidden:     dbComment.Hidden,
+		Deleted:    dbComment.Deleted,
+		DateCreate: dbComment.DateCreate.Time().UTC(),
+		DateUpdate: dbComment.DateUpdate.Time().UTC(),
+	}
+
+	if !dbComment.ParentId.IsZero() {
+		comment.ParentId = dbComment.ParentId.Hex()
+	}
+
+	if !dbComment.AnswerId.IsZero() {
+		comment.AnswerId = dbComment.AnswerId.Hex()
+	}
+
+	return comment
+}
+
+// ToNotification map DbNotification to Notification
+func (dbNotification *DbNotification) ToNotification() *common.Notification {
+	notification := &common.Notification{
+		Id:         dbNotification.Id.Hex(),
+		UserId:     dbNotification.UserId.Hex(),
+		Kind:       dbNotification.Kind,
+		Message:    dbNotification.Message,
+		TargetId:   dbNotification.TargetId,
+		Read:       dbNotification.Read,
+		DateCreate: dbNotification.DateCreate.Time().UTC(),
+	}
+
+	if !dbNotification.CourseId.IsZero() {
+		notification.CourseId = dbNotification.CourseId.Hex()
+	}
+
+	if !dbNotification.StageId.IsZero() {
+		notification.StageId = dbNotification.StageId.Hex()
+	}
+
+	return notification
+}
@@ -0,0 +1,298 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+)
+
+// collectionIndexes indexes required by collections
+var collectionIndexes = map[string][]mongo.IndexModel{
+	// One document for user and test. Concurrent first attempts conflict on insert and are retried.
+	// Learners of course are found by course
+	UserTestCollection: {
+		{Keys: bson.D{{"user_id", 1}, {"test_id", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"course_id", 1}, {"user_id", 1}}},
+	},
+	// Attempt of quiz session is saved once
+	TestAttemptCollection: {
+		{Keys: bson.D{{"user_id", 1}, {"test_id", 1}, {"number", 1}}, Options: options.Index().SetUnique(true)},
+		{
+			Keys: bson.D{{"user_id", 1}, {"test_id", 1}, {"session_id", 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.D{{"session_id", bson.D{{"$exists", true}}}}),
+		},
+	},
+	// One active session for user and stage
+	QuizSessionCollection: {
+		{
+			Keys:    bson.D{{"user_id", 1}, {"stage_id", 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.D{{"status", common.SessionActive}}),
+		},
+		{Keys: bson.D{{"status", 1}, {"deadline", 1}}},
+	},
+	// One selection for user and stage without time limit and one for every quiz session
+	TestSelectionCollection: {
+		{Keys: bson.D{{"user_id", 1}, {"stage_id", 1}, {"session_id", 1}}, Options: options.Index().SetUnique(true)},
+	},
+	// One certificate for user and course, verification code is unique
+	CertificateCollection: {
+		{Keys: bson.D{{"code", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"user_id", 1}, {"course_id", 1}}, Options: options.Index().SetUnique(true)},
+	},
+	// Badge is awarded to user once
+	BadgeAssertionCollection: {
+		{Keys: bson.D{{"badge_class_id", 1}, {"user_id", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"user_id", 1}, {"issued_on", 1}}},
+	},
+	// One progress document for user
+	UserProgressCollection: {
+		{Keys: bson.D{{"user_id", 1}}, Options: options.Index().SetUnique(true)},
+	},
+	// Leaderboards are computed for time windows
+	ScoreEventCollection: {
+		{Keys: bson.D{{"metric", 1}, {"date", 1}}},
+	},
+	// Full-text search. Courses have different languages, so words aren't stemmed.
+	// Translation group has one course of language
+	CourseCollection: {
+		{
+			Keys: bson.D{{"name", "text"}, {"description", "text"}, {"tags", "text"}},
+			Options: options.Index().SetName("course_text").SetDefaultLanguage("none").
+				SetWeights(bson.D{{"name", 10}, {"tags", 5}, {"description", 1}}),
+		},
+		{Keys: bson.D{{"category_id", 1}}},
+		{Keys: bson.D{{"group", 1}, {"lang", 1}}, Options: translationIndex()},
+	},
+	// Subtree is found by prefix of materialized path. Translation group has one category of language
+	CategoryCollection: {
+		{Keys: bson.D{{"path", 1}}},
+		{Keys: bson.D{{"parent_id", 1}}},
+		{Keys: bson.D{{"group", 1}, {"lang", 1}}, Options: translationIndex()},
+	},
+	StageCollection: {
+		{
+			Keys: bson.D{{"name", "text"}, {"content.body", "text"}},
+			Options: options.Index().SetName("stage_text").SetDefaultLanguage("none").
+				SetWeights(bson.D{{"name", 5}, {"content.body", 1}}),
+		},
+		{Keys: bson.D{{"group", 1}}},
+	},
+	// Variants of translation group are read by group
+	TestCollection: {
+		{Keys: bson.D{{"group", 1}}},
+	},
+	// Schedules are synced from user_tests by user and test, due tests are read by date
+	ReviewCollection: {
+		{Keys: bson.D{{"user_id", 1}, {"test_id", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"user_id", 1}, {"date_due", 1}}},
+	},
+	// Expired promotions are removed by TTL monitor
+	PromotionCollection: {
+		{Keys: bson.D{{"expiration_time", 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{"course_id", 1}, {"expiration_time", 1}}},
+	},
+	// One review for user and course. Visible reviews of course are listed from the newest
+	CourseReviewCollection: {
+		{Keys: bson.D{{"course_id", 1}, {"user_id", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"course_id", 1}, {"hidden", 1}, {"_id", -1}}},
+	},
+	// Threads of stage are listed by date or votes, replies of thread by date
+	StageCommentCollection: {
+		{Keys: bson.D{{"stage_id", 1}, {"parent_id", 1}, {"hidden", 1}, {"_id", -1}}},
+		{Keys: bson.D{{"stage_id", 1}, {"parent_id", 1}, {"hidden", 1}, {"votes", -1}, {"_id", -1}}},
+		{Keys: bson.D{{"parent_id", 1}, {"hidden", 1}, {"_id", 1}}},
+	},
+	// Notifications of user are listed from the newest, unread course update is refreshed
+	NotificationCollection: {
+		{Keys: bson.D{{"user_id", 1}, {"date_create", -1}, {"_id", -1}}},
+		{Keys: bson.D{{"user_id", 1}, {"read", 1}, {"date_create", -1}, {"_id", -1}}},
+		{Keys: bson.D{{"user_id", 1}, {"kind", 1}, {"course_id", 1}, {"read", 1}}},
+	},
+	// Bundle ids are mapped for every author once
+	ImportMappingCollection: {
+		{
+			Keys:    bson.D{{"owner_id", 1}, {"bundle_id", 1}, {"kind", 1}, {"source_id", 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	},
+	// Leaderboard page is read by rank, caller entry by user
+	LeaderboardCollection: {
+		{Keys: bson.D{{"generation", 1}, {"metric", 1}, {"scope", 1}, {"scope_id", 1}, {"window", 1}, {"rank", 1}}},
+		{Keys: bson.D{{"generation", 1}, {"metric", 1}, {"scope", 1}, {"scope_id", 1}, {"window", 1}, {"user_id", 1}}},
+	},
+}
+
+// translationIndex return options of unique index of translation group and language. Content without group isn't indexed
+func translationIndex() *options.IndexOptions {
+	return options.Index().SetUnique(true).SetPartialFilterExpression(bson.D{{"group", bson.D{{"$exists", true}}}})
+}
+
+/*
+EnsureIndexes create collections indexes if they don't exist. Data, which breaks unique indexes, is merged before,
+so server starts on database of old versions
+*/
+func (ctx *DbContext) EnsureIndexes() error {
+	db := ctx.Client.Database(DbName)
+
+	err := ctx.mergeUserTests()
+
+	if err != nil {
+		return err
+	}
+
+	err = ctx.splitCourseRatings()
+
+	if err != nil {
+		return err
+	}
+
+	for collection, indexes := range collectionIndexes {
+		_, err := db.Collection(collection).Indexes().CreateMany(context.Background(), indexes)
+
+		if err != nil {
+			return openerrors.DbErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/indexes.go",
+					Method: "EnsureIndexes",
+				},
+				DbName: ctx.DbName,
+				ConStr: ctx.ConStr,
+				DbErr:  err.Error(),
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+mergeUserTests merge duplicate results of user for test, which were saved by concurrent first attempts before
+unique index. The first document gets the best result, sum of attempts and the last date, others are removed
+*/
+func (ctx *DbContext) mergeUserTests() error {
+	col := ctx.Client.Database(DbName).Collection(UserTestCollection)
+
+	dbErr := func(err error) error {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/indexes.go",
+				Method: "mergeUserTests",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{"$sort", bson.D{{"_id", 1}}}},
+		{{"$group", bson.D{
+			{"_id", bson.D{{"user_id", "$user_id"}, {"test_id", "$test_id"}}},
+			{"ids", bson.D{{"$push", "$_id"}}},
+			{"is_passed", bson.D{{"$max", "$is_passed"}}},
+			{"score", bson.D{{"$max", "$score"}}},
+			{"attempts", bson.D{{"$sum", "$attempts"}}},
+			{"date_update", bson.D{{"$max", "$date_update"}}},
+		}}},
+		{{"$match", bson.D{{"ids.1", bson.D{{"$exists", true}}}}}},
+	}
+
+	cursor, err := col.Aggregate(context.Background(), pipeline, options.Aggregate().SetAllowDiskUse(true))
+
+	if err != nil {
+		return dbErr(err)
+	}
+
+	var duplicates []struct {
+		Ids        []primitive.ObjectID `bson:"ids"`
+		IsPassed   bool                 `bson:"is_passed"`
+		Score      float64              `bson:"score"`
+		Attempts   int                  `bson:"attempts"`
+		DateUpdate primitive.DateTime   `bson:"date_update"`
+	}
+
+	err = cursor.All(context.Background(), &duplicates)
+
+	if err != nil {
+		return dbErr(err)
+	}
+
+	for _, duplicate := range duplicates {
+		_, err = col.UpdateByID(context.Background(), duplicate.Ids[0], bson.D{{"$set", bson.D{
+			{"is_passed", duplicate.IsPassed},
+			{"score", duplicate.Score},
+			{"attempts", duplicate.Attempts},
+			{"date_update", duplicate.DateUpdate},
+		}}})
+
+		if err != nil {
+			return dbErr(err)
+		}
+
+		_, err = col.DeleteMany(context.Background(), bson.D{{"_id", bson.D{{"$in", duplicate.Ids[1:]}}}})
+
+		if err != nil {
+			return dbErr(err)
+		}
+	}
+
+	return nil
+}
+
+// splitCourseRatings move points of achievements out of rating of courses, which were saved with them.
+// Rating is set to average of reviews and the rest is saved as points
+func (ctx *DbContext) splitCourseRatings() error {
+	col := ctx.Client.Database(DbName).Collection(CourseCollection)
+
+	dbErr := func(err error) error {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/indexes.go",
+				Method: "splitCourseRatings",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	filter := bson.D{{"points", bson.D{{"$exists", false}}}}
+	projection := bson.D{{"rating", 1}, {"ratings", 1}}
+
+	cursor, err := col.Find(context.Background(), filter, options.Find().SetProjection(projection))
+
+	if err != nil {
+		return dbErr(err)
+	}
+
+	var dbCourses []*DbCourse
+
+	err = cursor.All(context.Background(), &dbCourses)
+
+	if err != nil {
+		return dbErr(err)
+	}
+
+	for _, dbCourse := range dbCourses {
+		rating := 0
+
+		if dbCourse.Ratings != nil {
+			rating = averageRating(dbCourse.Ratings)
+		}
+
+		update := bson.D{{"$set", bson.D{{"rating", rating}, {"points", dbCourse.Rating - rating}}}}
+
+		_, err = col.UpdateOne(context.Background(), append(filter, bson.E{Key: "_id", Value: dbCourse.Id}), update)
+
+		if err != nil {
+			return dbErr(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,423 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"time"
+)
+
+// leaderboardBatch count of entries inserted at once on refresh
+const leaderboardBatch = 1000
+
+// ClearLeaderboards remove all data from score_events, leaderboards and board_refreshes collections
+func (ctx *DbContext) ClearLeaderboards() error {
+	for _, collection := range []string{ScoreEventCollection, LeaderboardCollection, BoardRefreshCollection} {
+		col := ctx.Client.Database(DbName).Collection(collection)
+
+		_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+		if err != nil {
+			return openerrors.DbErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/leaderboard_impl.go",
+					Method: "ClearLeaderboards",
+				},
+				DbName: ctx.DbName,
+				ConStr: ctx.ConStr,
+				DbErr:  err.Error(),
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+RefreshLeaderboard compute leaderboard snapshot of generation. Global leaderboard for all time is ranked
+by user totals, other leaderboards by score events. Returns count of entries. Parameters:
+generation - refresh generation;
+metric - ranking metric;
+scope - leaderboard scope;
+window - time window;
+from - start date of window. Zero date is used for all time;
+*/
+func (ctx *DbContext) RefreshLeaderboard(generation int64, metric string, scope string, window string,
+	from time.Time) (int, error) {
+
+	var cursor *mongo.Cursor
+	var err error
+
+	if scope == common.ScopeGlobal && window == common.WindowAll {
+		pipeline := mongo.Pipeline{
+			{{"$match", bson.D{{metric, bson.D{{"$gt", 0}}}}}},
+			{{"$sort", bson.D{{metric, -1}, {"_id", 1}}}},
+			{{"$project", bson.D{{"user_id", "$_id"}, {"user_name", "$name"}, {"score", "$" + metric}}}},
+		}
+
+		cursor, err = ctx.Client.Database(DbName).Collection(UserCollection).
+			Aggregate(context.Background(), pipeline, options.Aggregate().SetAllowDiskUse(true))
+	} else {
+		cursor, err = ctx.Client.Database(DbName).Collection(ScoreEventCollection).
+			Aggregate(context.Background(), scorePipeline(metric, scope, from), options.Aggregate().SetAllowDiskUse(true))
+	}
+
+	if err != nil {
+		return 0, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/leaderboard_impl.go",
+				Method: "RefreshLeaderboard",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer cursor.Close(context.Background())
+
+	col := ctx.Client.Database(DbName).Collection(LeaderboardCollection)
+	batch := make([]interface{}, 0, leaderboardBatch)
+	count := 0
+
+	var previous *DbLeaderboardEntry
+	position := 0
+
+	// Rows are ordered by scope and score, so rank is counted in one pass
+	for cursor.Next(context.Background()) {
+		var row struct {
+			ScopeId  interface{}        `bson:"scope_id"`
+			UserId   primitive.ObjectID `bson:"user_id"`
+			UserName string             `bson:"user_name"`
+			Score    int                `bson:"score"`
+		}
+
+		err = cursor.Decode(&row)
+
+		if err != nil {
+			break
+		}
+
+		entry := DbLeaderboardEntry{
+			Generation: generation,
+			Metric:     metric,
+			Scope:      scope,
+			Window:     window,
+			UserId:     row.UserId,
+			UserName:   row.UserName,
+			Score:      row.Score,
+		}
+
+		if scopeId, ok := row.ScopeId.(primitive.ObjectID); ok {
+			entry.ScopeId = scopeId.Hex()
+		}
+
+		position = rank(&entry, previous, position)
+
+		batch = append(batch, entry)
+		previous = &entry
+		count++
+
+		if len(batch) == leaderboardBatch {
+			_, err = col.InsertMany(context.Background(), batch)
+			batch = batch[:0]
+
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	if err == nil {
+		err = cursor.Err()
+	}
+
+	if err == nil && len(batch) > 0 {
+		_, err = col.InsertMany(context.Background(), batch)
+	}
+
+	if err != nil {
+		return 0, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/leaderboard_impl.go",
+				Method: "RefreshLeaderboard",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return count, nil
+}
+
+/*
+rank set rank of entry, which follows previous entry of ordered rows, and return position of entry in its scope.
+Users with equal score share rank, the next rank is skipped. Parameters:
+entry - leaderboard entry;
+previous - previous entry. Nil for the first entry;
+position - position of previous entry in its scope;
+*/
+func rank(entry *DbLeaderboardEntry, previous *DbLeaderboardEntry, position int) int {
+	if previous == nil || previous.ScopeId != entry.ScopeId {
+		position = 0
+	}
+
+	position++
+	entry.Rank = position
+
+	if position > 1 && previous.Score == entry.Score {
+		entry.Rank = previous.Rank
+	}
+
+	return position
+}
+
+/*
+scorePipeline return aggregation of score events by scope and user ordered by scope and score. Parameters:
+metric - ranking metric;
+scope - leaderboard scope;
+from - start date of window. Zero date is used for all time;
+*/
+func scorePipeline(metric string, scope string, from time.Time) mongo.Pipeline {
+	match := bson.D{{"metric", metric}}
+
+	if !from.IsZero() {
+		match = append(match, bson.E{Key: "date", Value: bson.D{{"$gte", primitive.NewDateTimeFromTime(from)}}})
+	}
+
+	if scope == common.ScopeCourse {
+		match = append(match, bson.E{Key: "course_id", Value: bson.D{{"$exists", true}}})
+	}
+
+	pipeline := mongo.Pipeline{{{"$match", match}}}
+	var scopeId interface{}
+
+	switch scope {
+	case common.ScopeCourse:
+		scopeId = "$course_id"
+	case common.ScopeCategory:
+		pipeline = append(pipeline,
+			bson.D{{"$lookup", bson.D{
+				{"from", CourseCollection},
+				{"localField", "course_id"},
+				{"foreignField", "_id"},
+				{"as", "course"},
+			}}},
+			bson.D{{"$unwind", "$course"}})
+		scopeId = "$course.category_id"
+	}
+
+	return append(pipeline,
+		bson.D{{"$group", bson.D{
+			{"_id", bson.D{{"scope_id", scopeId}, {"user_id", "$user_id"}}},
+			{"score", bson.D{{"$sum", "$points"}}},
+		}}},
+		bson.D{{"$match", bson.D{{"score", bson.D{{"$gt", 0}}}}}},
+		bson.D{{"$sort", bson.D{{"_id.scope_id", 1}, {"score", -1}, {"_id.user_id", 1}}}},
+		bson.D{{"$lookup", bson.D{
+			{"from", UserCollection},
+			{"localField", "_id.user_id"},
+			{"foreignField", "_id"},
+			{"as", "user"},
+		}}},
+		bson.D{{"$project", bson.D{
+			{"_id", 0},
+			{"scope_id", "$_id.scope_id"},
+			{"user_id", "$_id.user_id"},
+			{"user_name", bson.D{{"$arrayElemAt", bson.A{"$user.name", 0}}}},
+			{"score", 1},
+		}}})
+}
+
+/*
+CommitLeaderboards make leaderboards of generation current and remove snapshots of previous refreshes. Parameters:
+generation - refresh generation;
+dateRefresh - date of refresh;
+*/
+func (ctx *DbContext) CommitLeaderboards(generation int64, dateRefresh time.Time) error {
+	db := ctx.Client.Database(DbName)
+
+	_, err := db.Collection(BoardRefreshCollection).InsertOne(context.Background(), DbLeaderboardRefresh{
+		Generation:  generation,
+		DateRefresh: primitive.NewDateTimeFromTime(dateRefresh),
+	})
+
+	// Readers use the latest refresh, so old snapshots are removed after commit
+	if err == nil {
+		_, err = db.Collection(LeaderboardCollection).
+			DeleteMany(context.Background(), bson.D{{"generation", bson.D{{"$lt", generation}}}})
+	}
+
+	if err == nil {
+		_, err = db.Collection(BoardRefreshCollection).
+			DeleteMany(context.Background(), bson.D{{"generation", bson.D{{"$lt", generation}}}})
+	}
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/leaderboard_impl.go",
+				Method: "CommitLeaderboards",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+GetLeaderboard return page of the latest leaderboard snapshot and entry of user. If leaderboards aren't refreshed,
+empty leaderboard is returned. Parameters:
+metric - ranking metric;
+scope - leaderboard scope;
+scopeId - category or course id, empty for global scope;
+window - time window;
+userId - user id of caller;
+take - count of entries;
+skip - count of skipped entries;
+*/
+func (ctx *DbContext) GetLeaderboard(metric string, scope string, scopeId string, window string, userId string,
+	take int64, skip int64) (*common.Leaderboard, error) {
+
+	db := ctx.Client.Database(DbName)
+
+	leaderboard := &common.Leaderboard{
+		Metric:  metric,
+		Scope:   scope,
+		ScopeId: scopeId,
+		Window:  window,
+		Entries: []*common.LeaderboardEntry{},
+	}
+
+	var dbRefresh DbLeaderboardRefresh
+
+	ops := options.FindOne().SetSort(bson.D{{"generation", -1}})
+
+	err := db.Collection(BoardRefreshCollection).FindOne(context.Background(), bson.D{{}}, ops).Decode(&dbRefresh)
+
+	if err == mongo.ErrNoDocuments {
+		return leaderboard, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/leaderboard_impl.go",
+				Method: "GetLeaderboard",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	leaderboard.DateRefresh = dbRefresh.DateRefresh.Time().UTC()
+
+	col := db.Collection(LeaderboardCollection)
+	filter := bson.D{
+		{"generation", dbRefresh.Generation},
+		{"metric", metric},
+		{"scope", scope},
+		{"scope_id", scopeId},
+		{"window", window},
+	}
+
+	leaderboard.Total, err = col.CountDocuments(context.Background(), filter)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/leaderboard_impl.go",
+				Method: "GetLeaderboard",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	findOps := options.Find().SetSort(bson.D{{"rank", 1}, {"user_id", 1}}).SetSkip(skip).SetLimit(take)
+
+	cursor, err := col.Find(context.Background(), filter, findOps)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/leaderboard_impl.go",
+				Method: "GetLeaderboard",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbEntries []*DbLeaderboardEntry
+
+	err = cursor.All(context.Background(), &dbEntries)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/leaderboard_impl.go",
+				Method: "GetLeaderboard",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	for _, dbEntry := range dbEntries {
+		leaderboard.Entries = append(leaderboard.Entries, dbEntry.ToLeaderboardEntry())
+	}
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/leaderboard_impl.go",
+					Method: "GetLeaderboard",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbEntry DbLeaderboardEntry
+
+	err = col.FindOne(context.Background(), append(filter, bson.E{Key: "user_id", Value: objectUserId})).Decode(&dbEntry)
+
+	// Caller without points isn't ranked
+	if err == mongo.ErrNoDocuments {
+		return leaderboard, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/leaderboard_impl.go",
+				Method: "GetLeaderboard",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	leaderboard.Me = dbEntry.ToLeaderboardEntry()
+
+	return leaderboard, nil
+}
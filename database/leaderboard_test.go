@@ -0,0 +1,37 @@
+package database
+
+import (
+	"testing"
+)
+
+// TestRank users with equal score share rank, the next rank is skipped and every scope is ranked from 1
+func TestRank(t *testing.T) {
+	rows := []struct {
+		scopeId string
+		score   int
+		rank    int
+	}{
+		{"a", 50, 1},
+		{"a", 40, 2},
+		{"a", 40, 2},
+		{"a", 30, 4},
+		{"b", 30, 1},
+		{"b", 30, 1},
+		{"b", 10, 3},
+	}
+
+	var previous *DbLeaderboardEntry
+	position := 0
+
+	for i, row := range rows {
+		entry := DbLeaderboardEntry{ScopeId: row.scopeId, Score: row.score}
+
+		position = rank(&entry, previous, position)
+
+		if entry.Rank != row.rank {
+			t.Errorf("row %d: expected rank %d, got %d", i, row.rank, entry.Rank)
+		}
+
+		previous = &entry
+	}
+}
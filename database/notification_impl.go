@@ -0,0 +1,399 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+)
+
+// notificationEventSort order of notifications in stream
+var notificationEventSort = bson.D{{"date_create", 1}, {"_id", 1}}
+
+// ClearNotifications remove all data from notifications collection
+func (ctx *DbContext) ClearNotifications() error {
+	col := ctx.Client.Database(DbName).Collection(NotificationCollection)
+
+	_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/notification_impl.go",
+				Method: "ClearNotifications",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+AddNotification add unread notification. Returns saved notification. Parameters:
+notification - notification, id and date are set;
+*/
+func (ctx *DbContext) AddNotification(notification *common.Notification) (*common.Notification, error) {
+	col := ctx.Client.Database(DbName).Collection(NotificationCollection)
+
+	dbNotification, err := toDbNotification(notification, "AddNotification")
+
+	if err != nil {
+		return nil, err
+	}
+
+	dbNotification.Id = primitive.NewObjectID()
+	dbNotification.DateCreate = primitive.NewDateTimeFromTime(ctx.Now())
+
+	_, err = col.InsertOne(context.Background(), dbNotification)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/notification_impl.go",
+				Method: "AddNotification",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return dbNotification.ToNotification(), nil
+}
+
+/*
+RefreshNotification update message and date of unread notification with the same user, kind and course,
+so repeated changes don't flood inbox. If it isn't found, notification is added. Returns saved notification. Parameters:
+notification - notification, id and date are set;
+*/
+func (ctx *DbContext) RefreshNotification(notification *common.Notification) (*common.Notification, error) {
+	col := ctx.Client.Database(DbName).Collection(NotificationCollection)
+
+	dbNotification, err := toDbNotification(notification, "RefreshNotification")
+
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{
+		{"user_id", dbNotification.UserId},
+		{"kind", dbNotification.Kind},
+		{"course_id", dbNotification.CourseId},
+		{"read", false},
+	}
+
+	set := bson.D{
+		{"message", dbNotification.Message},
+		{"target_id", dbNotification.TargetId},
+		{"date_create", primitive.NewDateTimeFromTime(ctx.Now())},
+	}
+
+	update := bson.D{{"$set", set}, {"$unset", bson.D{{"stage_id", ""}}}}
+
+	if !dbNotification.StageId.IsZero() {
+		update = bson.D{{"$set", append(set, bson.E{Key: "stage_id", Value: dbNotification.StageId})}}
+	}
+
+	ops := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	err = col.FindOneAndUpdate(context.Background(), filter, update, ops).Decode(dbNotification)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/notification_impl.go",
+				Method: "RefreshNotification",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return dbNotification.ToNotification(), nil
+}
+
+/*
+GetNotificationsPage return page of user notifications, the newest first. Parameters:
+userId - recipient id;
+unread - only unread notifications are returned;
+query - page query;
+*/
+func (ctx *DbContext) GetNotificationsPage(userId string, unread bool,
+	query *common.PageQuery) ([]*common.Notification, *common.Page, error) {
+
+	col := ctx.Client.Database(DbName).Collection(NotificationCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/notification_impl.go",
+					Method: "GetNotificationsPage",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	filter := bson.D{{"user_id", objectUserId}}
+
+	if unread {
+		filter = append(filter, bson.E{Key: "read", Value: false})
+	}
+
+	docs, page, err := ctx.findPage(col, filter, bson.D{{"date_create", -1}, {"_id", -1}}, nil, query)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notifications, err := toNotifications(docs, "GetNotificationsPage")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return notifications, page, nil
+}
+
+/*
+GetNotificationsAfter return notifications of user, which are created or refreshed after event id, the oldest first.
+Stream sends them to reconnected client. Parameters:
+userId - recipient id;
+eventId - event id of the last received notification;
+*/
+func (ctx *DbContext) GetNotificationsAfter(userId string, eventId string) ([]*common.Notification, error) {
+	col := ctx.Client.Database(DbName).Collection(NotificationCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/notification_impl.go",
+					Method: "GetNotificationsAfter",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	query := &common.PageQuery{Cursor: eventId, Take: common.MaxPageSize}
+
+	docs, _, err := ctx.findPage(col, bson.D{{"user_id", objectUserId}}, notificationEventSort, nil, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toNotifications(docs, "GetNotificationsAfter")
+}
+
+/*
+NotificationEventId return stream event id of notification. It's cursor of notification in order of dates, so
+refreshed notification gets new event id. Parameters:
+notification - saved notification;
+*/
+func NotificationEventId(notification *common.Notification) (string, error) {
+	objectId, err := primitive.ObjectIDFromHex(notification.Id)
+
+	if err != nil {
+		return "", openerrors.InvalidIdErr{
+			Id:        notification.Id,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/notification_impl.go",
+					Method: "NotificationEventId",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	return EncodeCursor(primitive.NewDateTimeFromTime(notification.DateCreate), objectId)
+}
+
+/*
+CountUnreadNotifications return count of unread notifications of user. Parameters:
+userId - recipient id;
+*/
+func (ctx *DbContext) CountUnreadNotifications(userId string) (int64, error) {
+	col := ctx.Client.Database(DbName).Collection(NotificationCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return 0, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/notification_impl.go",
+					Method: "CountUnreadNotifications",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	count, err := col.CountDocuments(context.Background(), bson.D{{"user_id", objectUserId}, {"read", false}})
+
+	if err != nil {
+		return 0, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/notification_impl.go",
+				Method: "CountUnreadNotifications",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return count, nil
+}
+
+/*
+ReadNotifications mark notifications of user read. Returns count of changed notifications. Parameters:
+userId - recipient id;
+notificationIds - notification ids. Every notification of user is read, if ids are empty;
+*/
+func (ctx *DbContext) ReadNotifications(userId string, notificationIds []string) (int64, error) {
+	col := ctx.Client.Database(DbName).Collection(NotificationCollection)
+
+	objectIds := make(bson.A, 0, len(notificationIds))
+
+	for _, id := range append([]string{userId}, notificationIds...) {
+		objectId, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			return 0, openerrors.InvalidIdErr{
+				Id:        id,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/notification_impl.go",
+						Method: "ReadNotifications",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		objectIds = append(objectIds, objectId)
+	}
+
+	filter := bson.D{{"user_id", objectIds[0]}, {"read", false}}
+
+	if len(objectIds) > 1 {
+		filter = append(filter, bson.E{Key: "_id", Value: bson.D{{"$in", objectIds[1:]}}})
+	}
+
+	result, err := col.UpdateMany(context.Background(), filter, bson.D{{"$set", bson.D{{"read", true}}}})
+
+	if err != nil {
+		return 0, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/notification_impl.go",
+				Method: "ReadNotifications",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return result.ModifiedCount, nil
+}
+
+/*
+toDbNotification map Notification to unread DbNotification without id and date. Parameters:
+notification - notification;
+method - method name for errors;
+*/
+func toDbNotification(notification *common.Notification, method string) (*DbNotification, error) {
+	if notification == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/notification_impl.go",
+				Method: method,
+			},
+			Model: "notification",
+		}
+	}
+
+	objectIds := make([]primitive.ObjectID, 3)
+
+	for i, id := range []string{notification.UserId, notification.CourseId, notification.StageId} {
+		// Course and stage are optional
+		if i > 0 && len(id) == 0 {
+			continue
+		}
+
+		objectId, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			return nil, openerrors.InvalidIdErr{
+				Id:        id,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/notification_impl.go",
+						Method: method,
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		objectIds[i] = objectId
+	}
+
+	return &DbNotification{
+		UserId:   objectIds[0],
+		Kind:     notification.Kind,
+		Message:  notification.Message,
+		CourseId: objectIds[1],
+		StageId:  objectIds[2],
+		TargetId: notification.TargetId,
+	}, nil
+}
+
+// toNotifications unmarshal notifications from documents of page
+func toNotifications(docs []bson.Raw, method string) ([]*common.Notification, error) {
+	notifications := make([]*common.Notification, 0, len(docs))
+
+	for _, doc := range docs {
+		var dbNotification DbNotification
+
+		err := bson.Unmarshal(doc, &dbNotification)
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/notification_impl.go",
+					Method: method,
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		notifications = append(notifications, dbNotification.ToNotification())
+	}
+
+	return notifications, nil
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"opencourse/common"
+	"testing"
+	"time"
+)
+
+// TestNotificationEventId event id is cursor of date and id, so refreshed notification gets new one
+func TestNotificationEventId(t *testing.T) {
+	id := primitive.NewObjectID()
+	date := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	eventId, err := NotificationEventId(&common.Notification{Id: id.Hex(), DateCreate: date})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := DecodeCursor(eventId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != len(notificationEventSort) || values[0] != primitive.NewDateTimeFromTime(date) || values[1] != id {
+		t.Errorf("event id must be cursor of date and id, got %v", values)
+	}
+
+	refreshed, err := NotificationEventId(&common.Notification{Id: id.Hex(), DateCreate: date.Add(time.Minute)})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if refreshed == eventId {
+		t.Error("refreshed notification must get new event id")
+	}
+
+	_, err = NotificationEventId(&common.Notification{Id: "invalid"})
+
+	if err == nil {
+		t.Error("notification with invalid id mustn't get event id")
+	}
+}
@@ -0,0 +1,283 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+)
+
+/*
+This file contains cursor pagination of lists. Cursor is encoded bson array with sort values of the last item
+of page. Next page starts after these values, so it isn't shifted by inserted items and skipped items aren't scanned.
+Cursor is sent by client, so only scalar values are accepted: documents, arrays and regular expressions would be
+operators in filter of the next page.
+*/
+
+// dbCursor contents of page cursor
+type dbCursor struct {
+	Values bson.A `bson:"v"` // Sort values of the last item of page
+}
+
+/*
+EncodeCursor return opaque cursor with values. Parameters:
+values - sort values of the last item of page;
+*/
+func EncodeCursor(values ...interface{}) (string, error) {
+	data, err := bson.Marshal(dbCursor{Values: values})
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+/*
+DecodeCursor return values of cursor. Cursor with values, which aren't scalar, is rejected. Parameters:
+cursor - opaque cursor;
+*/
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var dbCursor dbCursor
+
+	err = bson.Unmarshal(data, &dbCursor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range dbCursor.Values {
+		if !isScalar(value) {
+			return nil, fmt.Errorf("cursor value of type %T isn't allowed", value)
+		}
+	}
+
+	return dbCursor.Values, nil
+}
+
+// isScalar check that decoded value is compared as value in filter
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case nil, string, bool, int32, int64, float64, primitive.ObjectID, primitive.DateTime, primitive.Decimal128,
+		primitive.Timestamp:
+		return true
+	}
+
+	return false
+}
+
+/*
+findPage return documents of list page and position of next page. Parameters:
+col - collection of list;
+filter - list filter;
+sort - list sort. The last sort field must be unique, for example "_id";
+projection - projection of documents. Optional, may be nil. Sort fields mustn't be excluded;
+query - page query;
+*/
+func (ctx *DbContext) findPage(col *mongo.Collection, filter bson.D, sort bson.D, projection bson.D,
+	query *common.PageQuery) ([]bson.Raw, *common.Page, error) {
+
+	ops := options.Find().SetSort(sort)
+
+	if projection != nil {
+		ops.SetProjection(projection)
+	}
+
+	after, err := cursorFilter(sort, query, "findPage")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if after != nil {
+		filter = append(filter, bson.E{Key: "$or", Value: after})
+	} else if query.Skip > 0 {
+		ops.SetSkip(query.Skip)
+	}
+
+	// One more document is read to know, that list has next page
+	if query.Take > 0 {
+		ops.SetLimit(query.Take + 1)
+	}
+
+	cursor, err := col.Find(context.Background(), filter, ops)
+
+	var docs []bson.Raw
+
+	if err == nil {
+		err = cursor.All(context.Background(), &docs)
+	}
+
+	if err != nil {
+		return nil, nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/page_impl.go",
+				Method: "findPage",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nextPage(docs, sort, query, "findPage")
+}
+
+/*
+aggregatePage return documents of list page, which are produced by pipeline, and position of next page. Parameters:
+col - collection of list;
+pipeline - pipeline of list documents. Page stages are added to its end;
+sort - list sort. The last sort field must be unique, for example "_id";
+query - page query;
+*/
+func (ctx *DbContext) aggregatePage(col *mongo.Collection, pipeline mongo.Pipeline, sort bson.D,
+	query *common.PageQuery) ([]bson.Raw, *common.Page, error) {
+
+	after, err := cursorFilter(sort, query, "aggregatePage")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if after != nil {
+		pipeline = append(pipeline, bson.D{{"$match", bson.D{{"$or", after}}}})
+	}
+
+	pipeline = append(pipeline, bson.D{{"$sort", sort}})
+
+	if after == nil && query.Skip > 0 {
+		pipeline = append(pipeline, bson.D{{"$skip", query.Skip}})
+	}
+
+	// One more document is read to know, that list has next page
+	if query.Take > 0 {
+		pipeline = append(pipeline, bson.D{{"$limit", query.Take + 1}})
+	}
+
+	cursor, err := col.Aggregate(context.Background(), pipeline)
+
+	var docs []bson.Raw
+
+	if err == nil {
+		err = cursor.All(context.Background(), &docs)
+	}
+
+	if err != nil {
+		return nil, nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/page_impl.go",
+				Method: "aggregatePage",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nextPage(docs, sort, query, "aggregatePage")
+}
+
+// cursorFilter return conditions of documents after cursor of query. Returns nil, if query hasn't cursor
+func cursorFilter(sort bson.D, query *common.PageQuery, method string) (bson.A, error) {
+	if len(query.Cursor) == 0 {
+		return nil, nil
+	}
+
+	values, err := DecodeCursor(query.Cursor)
+
+	if err == nil && len(values) != len(sort) {
+		err = errors.New("cursor doesn't match sort of list")
+	}
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/page_impl.go",
+				Method: method,
+			},
+			Msg: "invalid cursor: " + err.Error(),
+		}
+	}
+
+	return afterFilter(sort, values), nil
+}
+
+// nextPage cut the extra document of page and return cursor of next page with sort values of the last document
+func nextPage(docs []bson.Raw, sort bson.D, query *common.PageQuery, method string) ([]bson.Raw, *common.Page, error) {
+	page := &common.Page{}
+
+	if query.Take <= 0 || int64(len(docs)) <= query.Take {
+		return docs, page, nil
+	}
+
+	docs = docs[:query.Take]
+	page.HasMore = true
+
+	values := make([]interface{}, 0, len(sort))
+
+	for _, field := range sort {
+		value, err := docs[len(docs)-1].LookupErr(field.Key)
+
+		if err != nil {
+			value = bson.RawValue{Type: bson.TypeNull}
+		}
+
+		values = append(values, value)
+	}
+
+	var err error
+
+	page.NextCursor, err = EncodeCursor(values...)
+
+	if err != nil {
+		return nil, nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/page_impl.go",
+				Method: method,
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return docs, page, nil
+}
+
+/*
+afterFilter return conditions of items, which are after sort values. Parameters:
+sort - list sort;
+values - sort values of the last item of previous page;
+*/
+func afterFilter(sort bson.D, values []interface{}) bson.A {
+	conditions := make(bson.A, 0, len(sort))
+
+	for i, field := range sort {
+		condition := bson.D{}
+
+		for j := 0; j < i; j++ {
+			condition = append(condition, bson.E{Key: sort[j].Key, Value: values[j]})
+		}
+
+		operator := "$gt"
+
+		if field.Value == -1 {
+			operator = "$lt"
+		}
+
+		condition = append(condition, bson.E{Key: field.Key, Value: bson.D{{operator, values[i]}}})
+		conditions = append(conditions, condition)
+	}
+
+	return conditions
+}
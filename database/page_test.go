@@ -0,0 +1,93 @@
+package database
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestEncodeCursor values of the last document are restored from cursor
+func TestEncodeCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	date := primitive.NewDateTimeFromTime(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	doc, err := bson.Marshal(bson.D{{"_id", id}, {"rating", int32(450)}, {"name", "Go"}, {"date_update", date}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values []interface{}
+
+	for _, key := range []string{"rating", "name", "date_update", "_id"} {
+		values = append(values, bson.Raw(doc).Lookup(key))
+	}
+
+	values = append(values, bson.RawValue{Type: bson.TypeNull})
+
+	cursor, err := EncodeCursor(values...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeCursor(cursor)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{int32(450), "Go", date, id, nil}
+
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("decoded values %#v, expected %#v", decoded, expected)
+	}
+}
+
+// TestDecodeCursor cursor with operators or not encoded by list is rejected
+func TestDecodeCursor(t *testing.T) {
+	injected := []interface{}{
+		bson.D{{"$ne", nil}},
+		bson.A{1, 2},
+		primitive.Regex{Pattern: ".*"},
+		primitive.JavaScript("true"),
+	}
+
+	for _, value := range injected {
+		cursor, err := EncodeCursor(int32(1), value)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = DecodeCursor(cursor); err == nil {
+			t.Errorf("cursor with %#v must be rejected", value)
+		}
+	}
+
+	for _, cursor := range []string{"not base64!", "AAAA"} {
+		if _, err := DecodeCursor(cursor); err == nil {
+			t.Errorf("cursor %q must be rejected", cursor)
+		}
+	}
+}
+
+// TestAfterFilter documents after the last one are matched by sort direction of every field
+func TestAfterFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+	sort := bson.D{{"rating", -1}, {"date_update", -1}, {"_id", 1}}
+
+	filter := afterFilter(sort, []interface{}{int32(450), "date", id})
+
+	expected := bson.A{
+		bson.D{{"rating", bson.D{{"$lt", int32(450)}}}},
+		bson.D{{"rating", int32(450)}, {"date_update", bson.D{{"$lt", "date"}}}},
+		bson.D{{"rating", int32(450)}, {"date_update", "date"}, {"_id", bson.D{{"$gt", id}}}},
+	}
+
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("filter %v, expected %v", filter, expected)
+	}
+}
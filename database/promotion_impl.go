@@ -0,0 +1,321 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+)
+
+// ClearPromotions remove all data from promotions collection
+func (ctx *DbContext) ClearPromotions() error {
+	col := ctx.Client.Database(DbName).Collection(PromotionCollection)
+
+	_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "ClearPromotions",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+AddPromotion add promotion of course. Promotion is removed by TTL index after expiration time. Parameters:
+query - model for create promotion;
+*/
+func (ctx *DbContext) AddPromotion(query *common.AddPromotionQuery) (string, error) {
+	col := ctx.Client.Database(DbName).Collection(PromotionCollection)
+
+	if query == nil {
+		return "", openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "AddPromotion",
+			},
+			Model: "query",
+		}
+	}
+
+	if len(query.Label) == 0 {
+		return "", openerrors.FieldEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "AddPromotion",
+			},
+			Field: "Label",
+		}
+	}
+
+	if query.PromotionType != common.PromotionNew && query.PromotionType != common.PromotionUpdate {
+		return "", openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "AddPromotion",
+			},
+			Msg: "unknown promotion type " + query.PromotionType,
+		}
+	}
+
+	if !query.ExpirationTime.After(ctx.Now()) {
+		return "", openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "AddPromotion",
+			},
+			Msg: "promotion expiration time must be in future",
+		}
+	}
+
+	course, err := ctx.GetCourse(query.CourseId)
+
+	if err != nil {
+		return "", err
+	}
+
+	if course == nil {
+		return "", openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "AddPromotion",
+			},
+			Msg: "course " + query.CourseId + " isn't found",
+		}
+	}
+
+	objectCourseId, _ := primitive.ObjectIDFromHex(course.Id)
+
+	dbPromotion := DbCoursePromotion{
+		CourseId:       objectCourseId,
+		PromotionType:  query.PromotionType,
+		Label:          query.Label,
+		ExpirationTime: primitive.NewDateTimeFromTime(query.ExpirationTime.UTC()),
+	}
+
+	result, err := col.InsertOne(context.Background(), dbPromotion)
+
+	if err != nil {
+		return "", openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "AddPromotion",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+/*
+DeletePromotion remove promotion. Returns false, if promotion isn't found. Parameters:
+promotionId - promotion id;
+*/
+func (ctx *DbContext) DeletePromotion(promotionId string) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(PromotionCollection)
+
+	objectPromotionId, err := primitive.ObjectIDFromHex(promotionId)
+
+	if err != nil {
+		return false, openerrors.InvalidIdErr{
+			Id:        promotionId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/promotion_impl.go",
+					Method: "DeletePromotion",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	result, err := col.DeleteOne(context.Background(), bson.D{{"_id", objectPromotionId}})
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "DeletePromotion",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+/*
+GetPromotionFeed return active promotions with courses, the newest first. Parameters:
+take - how many promotions need to return;
+*/
+func (ctx *DbContext) GetPromotionFeed(take int64) ([]*common.DbCoursePromotion, error) {
+	db := ctx.Client.Database(DbName)
+
+	ops := options.Find().SetSort(bson.D{{"_id", -1}}).SetLimit(take)
+
+	// TTL monitor removes expired promotions with delay, so they are filtered too
+	filter := bson.D{{"expiration_time", bson.D{{"$gt", primitive.NewDateTimeFromTime(ctx.Now())}}}}
+
+	cursor, err := db.Collection(PromotionCollection).Find(context.Background(), filter, ops)
+
+	var dbPromotions []*DbCoursePromotion
+
+	if err == nil {
+		err = cursor.All(context.Background(), &dbPromotions)
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "GetPromotionFeed",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	courseIds := make(bson.A, 0, len(dbPromotions))
+
+	for _, dbPromotion := range dbPromotions {
+		courseIds = append(courseIds, dbPromotion.CourseId)
+	}
+
+	cursor, err = db.Collection(CourseCollection).Find(context.Background(), bson.D{{"_id", bson.D{{"$in", courseIds}}}})
+
+	var dbCourses []*DbCourse
+
+	if err == nil {
+		err = cursor.All(context.Background(), &dbCourses)
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "GetPromotionFeed",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	courses := make(map[primitive.ObjectID]*common.Course, len(dbCourses))
+	promoted := make([]*common.Course, 0, len(dbCourses))
+
+	for _, dbCourse := range dbCourses {
+		course, err := dbCourse.ToCourse()
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/promotion_impl.go",
+					Method: "GetPromotionFeed",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		courses[dbCourse.Id] = course
+		promoted = append(promoted, course)
+	}
+
+	promotions := make([]*common.DbCoursePromotion, 0, len(dbPromotions))
+
+	for _, dbPromotion := range dbPromotions {
+		// Promotions of deleted courses are skipped
+		course, ok := courses[dbPromotion.CourseId]
+
+		if !ok {
+			continue
+		}
+
+		promotion := dbPromotion.ToCoursePromotion()
+		promotion.Course = course
+		promotions = append(promotions, promotion)
+	}
+
+	err = ctx.AttachPromotionLabels(promoted)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return promotions, nil
+}
+
+/*
+AttachPromotionLabels set labels of active promotions to courses. Parameters:
+courses - courses;
+*/
+func (ctx *DbContext) AttachPromotionLabels(courses []*common.Course) error {
+	col := ctx.Client.Database(DbName).Collection(PromotionCollection)
+
+	courseIds := make(bson.A, 0, len(courses))
+	byId := make(map[string][]*common.Course, len(courses))
+
+	for _, course := range courses {
+		objectCourseId, err := primitive.ObjectIDFromHex(course.Id)
+
+		if err != nil {
+			continue
+		}
+
+		courseIds = append(courseIds, objectCourseId)
+		byId[course.Id] = append(byId[course.Id], course)
+	}
+
+	if len(courseIds) == 0 {
+		return nil
+	}
+
+	filter := bson.D{
+		{"course_id", bson.D{{"$in", courseIds}}},
+		{"expiration_time", bson.D{{"$gt", primitive.NewDateTimeFromTime(ctx.Now())}}},
+	}
+
+	cursor, err := col.Find(context.Background(), filter, options.Find().SetSort(bson.D{{"_id", 1}}))
+
+	var dbPromotions []*DbCoursePromotion
+
+	if err == nil {
+		err = cursor.All(context.Background(), &dbPromotions)
+	}
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/promotion_impl.go",
+				Method: "AttachPromotionLabels",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	for _, dbPromotion := range dbPromotions {
+		for _, course := range byId[dbPromotion.CourseId.Hex()] {
+			course.Labels = append(course.Labels, dbPromotion.Label)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,515 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"time"
+)
+
+// ClearQuizSessions remove all data from quiz_sessions collection
+func (ctx *DbContext) ClearQuizSessions() error {
+	col := ctx.Client.Database(DbName).Collection(QuizSessionCollection)
+
+	_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "ClearQuizSessions",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+StartQuizSession start timed session for stage tests. Deadline is current time plus stage time limit.
+If user has active session for stage, it's returned. Parameters:
+userId - user id;
+stageId - stage id. Stage must have time limit;
+*/
+func (ctx *DbContext) StartQuizSession(userId string, stageId string) (*common.QuizSession, error) {
+	col := ctx.Client.Database(DbName).Collection(QuizSessionCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/quiz_session_impl.go",
+					Method: "StartQuizSession",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	stage, err := ctx.GetStage(stageId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if stage == nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "StartQuizSession",
+			},
+			Msg: "stage " + stageId + " isn't found",
+		}
+	}
+
+	if stage.TimeLimit <= 0 {
+		return nil, openerrors.FieldEmptyErr{
+			Field: "stage.TimeLimit",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "StartQuizSession",
+			},
+		}
+	}
+
+	objectStageId, _ := primitive.ObjectIDFromHex(stage.Id)
+	objectCourseId, _ := primitive.ObjectIDFromHex(stage.CourseId)
+	filter := bson.D{{"user_id", objectUserId}, {"stage_id", objectStageId}, {"status", common.SessionActive}}
+	dateNow := ctx.Now()
+
+	dbSession := DbQuizSession{
+		UserId:    objectUserId,
+		StageId:   objectStageId,
+		CourseId:  objectCourseId,
+		Status:    common.SessionActive,
+		DateStart: primitive.NewDateTimeFromTime(dateNow),
+		Deadline:  primitive.NewDateTimeFromTime(dateNow.Add(time.Duration(stage.TimeLimit) * time.Second)),
+		Answers:   []*DbSessionAnswer{},
+	}
+
+	// Unique partial index allows one active session, so concurrent starts return the same session
+	update := bson.D{{"$setOnInsert", dbSession}}
+	ops := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	err = col.FindOneAndUpdate(context.Background(), filter, update, ops).Decode(&dbSession)
+
+	if mongo.IsDuplicateKeyError(err) {
+		err = col.FindOne(context.Background(), filter).Decode(&dbSession)
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "StartQuizSession",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	session, err := dbSession.ToQuizSession()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "StartQuizSession",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return session, nil
+}
+
+/*
+GetQuizSession return quiz session by id. Parameters:
+sessionId - session id;
+*/
+func (ctx *DbContext) GetQuizSession(sessionId string) (*common.QuizSession, error) {
+	col := ctx.Client.Database(DbName).Collection(QuizSessionCollection)
+
+	objectSessionId, err := primitive.ObjectIDFromHex(sessionId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        sessionId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/quiz_session_impl.go",
+					Method: "GetQuizSession",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbSession DbQuizSession
+
+	err = col.FindOne(context.Background(), bson.D{{"_id", objectSessionId}}).Decode(&dbSession)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "GetQuizSession",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	session, err := dbSession.ToQuizSession()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "GetQuizSession",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return session, nil
+}
+
+/*
+SaveSessionAnswer save or replace answer for test in active session. Answers after deadline are rejected
+with openerrors.SessionClosedErr. Parameters:
+sessionId - session id;
+testId - test id. Test must be from session stage;
+answer - user answer;
+*/
+func (ctx *DbContext) SaveSessionAnswer(sessionId string, testId string, answer *common.TestAnswer) error {
+	col := ctx.Client.Database(DbName).Collection(QuizSessionCollection)
+
+	if answer == nil {
+		return openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "SaveSessionAnswer",
+			},
+			Model: "answer",
+		}
+	}
+
+	objectSessionId, err := primitive.ObjectIDFromHex(sessionId)
+
+	if err != nil {
+		return openerrors.InvalidIdErr{
+			Id:        sessionId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/quiz_session_impl.go",
+					Method: "SaveSessionAnswer",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	objectTestId, err := primitive.ObjectIDFromHex(testId)
+
+	if err != nil {
+		return openerrors.InvalidIdErr{
+			Id:        testId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/quiz_session_impl.go",
+					Method: "SaveSessionAnswer",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	dateNow := primitive.NewDateTimeFromTime(ctx.Now())
+	open := bson.D{{"_id", objectSessionId}, {"status", common.SessionActive}, {"deadline", bson.D{{"$gt", dateNow}}}}
+
+	// Replace answer if test is answered, otherwise add answer
+	replaceFilter := append(open, bson.E{Key: "answers.test_id", Value: objectTestId})
+	replace := bson.D{{"$set", bson.D{
+		{"answers.$.answer", ToDbTestAnswer(answer)},
+		{"answers.$.date_update", dateNow},
+	}}}
+
+	result, err := col.UpdateOne(context.Background(), replaceFilter, replace)
+
+	if err == nil && result.MatchedCount == 0 {
+		addFilter := append(open, bson.E{Key: "answers.test_id", Value: bson.D{{"$ne", objectTestId}}})
+		add := bson.D{{"$push", bson.D{{"answers", DbSessionAnswer{
+			TestId:     objectTestId,
+			Answer:     ToDbTestAnswer(answer),
+			DateUpdate: dateNow,
+		}}}}}
+
+		result, err = col.UpdateOne(context.Background(), addFilter, add)
+	}
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "SaveSessionAnswer",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	session, err := ctx.GetQuizSession(sessionId)
+
+	if err != nil {
+		return err
+	}
+
+	return openerrors.SessionClosedErr{
+		BaseErr: openerrors.BaseErr{
+			File:   "database/quiz_session_impl.go",
+			Method: "SaveSessionAnswer",
+		},
+		SessionId: session.Id,
+		Status:    session.Status,
+		Deadline:  session.Deadline,
+	}
+}
+
+/*
+GetExpiredQuizSessions return active sessions which deadline is passed. Parameters:
+limit - max count of sessions;
+*/
+func (ctx *DbContext) GetExpiredQuizSessions(limit int64) ([]*common.QuizSession, error) {
+	col := ctx.Client.Database(DbName).Collection(QuizSessionCollection)
+
+	filter := bson.D{
+		{"status", common.SessionActive},
+		{"deadline", bson.D{{"$lte", primitive.NewDateTimeFromTime(ctx.Now())}}},
+	}
+
+	ops := options.Find().SetLimit(limit).SetSort(bson.D{{"deadline", 1}})
+
+	cursor, err := col.Find(context.Background(), filter, ops)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "GetExpiredQuizSessions",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbSessions []*DbQuizSession
+
+	err = cursor.All(context.Background(), &dbSessions)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "GetExpiredQuizSessions",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var sessions []*common.QuizSession
+
+	for _, dbSession := range dbSessions {
+		session, err := dbSession.ToQuizSession()
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/quiz_session_impl.go",
+					Method: "GetExpiredQuizSessions",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+/*
+CompleteQuizSession set results of active session and close it. Returns false, if session is already closed,
+so results are saved once. Parameters:
+sessionId - session id;
+status - common.SessionSubmitted or common.SessionExpired;
+results - results for every stage test;
+*/
+func (ctx *DbContext) CompleteQuizSession(sessionId string, status string, results []*common.SessionResult) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(QuizSessionCollection)
+
+	objectSessionId, err := primitive.ObjectIDFromHex(sessionId)
+
+	if err != nil {
+		return false, openerrors.InvalidIdErr{
+			Id:        sessionId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/quiz_session_impl.go",
+					Method: "CompleteQuizSession",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	dbResults := make([]*DbSessionResult, 0, len(results))
+	score := 0.0
+	isPassed := len(results) > 0
+
+	for _, result := range results {
+		objectTestId, err := primitive.ObjectIDFromHex(result.TestId)
+
+		if err != nil {
+			return false, openerrors.InvalidIdErr{
+				Id:        result.TestId,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/quiz_session_impl.go",
+						Method: "CompleteQuizSession",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		dbResults = append(dbResults, &DbSessionResult{
+			TestId:   objectTestId,
+			Answered: result.Answered,
+			Score:    result.Score,
+			IsPassed: result.IsPassed,
+		})
+
+		score += result.Score
+		isPassed = isPassed && result.IsPassed
+	}
+
+	if len(results) > 0 {
+		score /= float64(len(results))
+	}
+
+	update := bson.D{{"$set", bson.D{
+		{"status", status},
+		{"date_submit", primitive.NewDateTimeFromTime(ctx.Now())},
+		{"results", dbResults},
+		{"score", score},
+		{"is_passed", isPassed},
+	}}}
+
+	result, err := col.UpdateOne(context.Background(),
+		bson.D{{"_id", objectSessionId}, {"status", common.SessionActive}}, update)
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "CompleteQuizSession",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+/*
+HasPassedSession check that user has closed session for stage with all tests passed. Parameters:
+userId - user id;
+stageId - stage id;
+*/
+func (ctx *DbContext) HasPassedSession(userId string, stageId string) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(QuizSessionCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return false, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/quiz_session_impl.go",
+					Method: "HasPassedSession",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	objectStageId, err := primitive.ObjectIDFromHex(stageId)
+
+	if err != nil {
+		return false, openerrors.InvalidIdErr{
+			Id:        stageId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/quiz_session_impl.go",
+					Method: "HasPassedSession",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	filter := bson.D{
+		{"user_id", objectUserId},
+		{"stage_id", objectStageId},
+		{"status", bson.D{{"$ne", common.SessionActive}}},
+		{"is_passed", true},
+	}
+
+	count, err := col.CountDocuments(context.Background(), filter, options.Count().SetLimit(1))
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/quiz_session_impl.go",
+				Method: "HasPassedSession",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return count > 0, nil
+}
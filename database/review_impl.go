@@ -0,0 +1,282 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"time"
+)
+
+// ClearReviewSchedules remove all data from review_schedules collection
+func (ctx *DbContext) ClearReviewSchedules() error {
+	col := ctx.Client.Database(DbName).Collection(ReviewCollection)
+
+	_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/review_impl.go",
+				Method: "ClearReviewSchedules",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+SyncReviewSchedules create schedules for passed tests of user, which have no schedule. The first review
+is due a day after the last answer. Parameters:
+userId - user id;
+*/
+func (ctx *DbContext) SyncReviewSchedules(userId string) error {
+	col := ctx.Client.Database(DbName).Collection(UserTestCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/review_impl.go",
+					Method: "SyncReviewSchedules",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	// Existing schedules are kept, so schedules are synced on every read without losing progress
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{{"user_id", objectUserId}, {"is_passed", true}}}},
+		{{"$project", bson.D{
+			{"_id", 0},
+			{"user_id", 1},
+			{"test_id", 1},
+			{"stage_id", 1},
+			{"course_id", 1},
+			{"ease", bson.D{{"$literal", common.ReviewEase}}},
+			{"interval", bson.D{{"$literal", 0}}},
+			{"repetitions", bson.D{{"$literal", 0}}},
+			{"last_quality", bson.D{{"$literal", 0}}},
+			{"date_due", bson.D{{"$add", bson.A{
+				bson.D{{"$ifNull", bson.A{"$date_update", primitive.NewDateTimeFromTime(ctx.Now())}}},
+				int64(24 * time.Hour / time.Millisecond),
+			}}}},
+		}}},
+		{{"$merge", bson.D{
+			{"into", ReviewCollection},
+			{"on", bson.A{"user_id", "test_id"}},
+			{"whenMatched", "keepExisting"},
+			{"whenNotMatched", "insert"},
+		}}},
+	}
+
+	cursor, err := col.Aggregate(context.Background(), pipeline)
+
+	if err == nil {
+		err = cursor.Close(context.Background())
+	}
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/review_impl.go",
+				Method: "SyncReviewSchedules",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+GetDueReviews return schedules of user, which are due, ordered by due date. Parameters:
+userId - user id;
+dateNow - current date;
+take - max count of schedules;
+*/
+func (ctx *DbContext) GetDueReviews(userId string, dateNow time.Time, take int64) ([]*common.ReviewSchedule, error) {
+	col := ctx.Client.Database(DbName).Collection(ReviewCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/review_impl.go",
+					Method: "GetDueReviews",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	filter := bson.D{
+		{"user_id", objectUserId},
+		{"date_due", bson.D{{"$lte", primitive.NewDateTimeFromTime(dateNow)}}},
+	}
+
+	ops := options.Find().SetSort(bson.D{{"date_due", 1}}).SetLimit(take)
+
+	cursor, err := col.Find(context.Background(), filter, ops)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/review_impl.go",
+				Method: "GetDueReviews",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbSchedules []*DbReviewSchedule
+
+	err = cursor.All(context.Background(), &dbSchedules)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/review_impl.go",
+				Method: "GetDueReviews",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	schedules := make([]*common.ReviewSchedule, 0, len(dbSchedules))
+
+	for _, dbSchedule := range dbSchedules {
+		schedules = append(schedules, dbSchedule.ToReviewSchedule())
+	}
+
+	return schedules, nil
+}
+
+/*
+GetReviewSchedule return schedule of user for test. If test has no schedule, return nil. Parameters:
+userId - user id;
+testId - test id;
+*/
+func (ctx *DbContext) GetReviewSchedule(userId string, testId string) (*common.ReviewSchedule, error) {
+	col := ctx.Client.Database(DbName).Collection(ReviewCollection)
+
+	filter, err := reviewFilter(userId, testId, "GetReviewSchedule")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var dbSchedule DbReviewSchedule
+
+	err = col.FindOne(context.Background(), filter).Decode(&dbSchedule)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/review_impl.go",
+				Method: "GetReviewSchedule",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return dbSchedule.ToReviewSchedule(), nil
+}
+
+/*
+UpdateReviewSchedule save schedule after review. Schedule is updated only if it wasn't reviewed concurrently,
+returns false otherwise. Parameters:
+previous - schedule before review;
+schedule - schedule after review;
+*/
+func (ctx *DbContext) UpdateReviewSchedule(previous *common.ReviewSchedule, schedule *common.ReviewSchedule) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(ReviewCollection)
+
+	filter, err := reviewFilter(schedule.UserId, schedule.TestId, "UpdateReviewSchedule")
+
+	if err != nil {
+		return false, err
+	}
+
+	filter = append(filter, bson.E{Key: "date_due", Value: primitive.NewDateTimeFromTime(previous.DateDue)})
+
+	update := bson.D{{"$set", bson.D{
+		{"ease", schedule.Ease},
+		{"interval", schedule.Interval},
+		{"repetitions", schedule.Repetitions},
+		{"last_quality", schedule.LastQuality},
+		{"date_due", primitive.NewDateTimeFromTime(schedule.DateDue)},
+		{"date_review", primitive.NewDateTimeFromTime(schedule.DateReview)},
+	}}}
+
+	result, err := col.UpdateOne(context.Background(), filter, update)
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/review_impl.go",
+				Method: "UpdateReviewSchedule",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// reviewFilter return filter of schedule by user and test
+func reviewFilter(userId string, testId string, method string) (bson.D, error) {
+	objectIds := make(map[string]primitive.ObjectID, 2)
+
+	for _, id := range []string{userId, testId} {
+		objectId, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			return nil, openerrors.InvalidIdErr{
+				Id:        id,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/review_impl.go",
+						Method: method,
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		objectIds[id] = objectId
+	}
+
+	return bson.D{{"user_id", objectIds[userId]}, {"test_id", objectIds[testId]}}, nil
+}
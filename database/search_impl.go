@@ -0,0 +1,330 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+)
+
+// Search limits
+const (
+	stageTextWeight = 0.5 // Weight of stage text score in course relevance
+	maxFacetValues  = 50  // Max count of values in facet
+)
+
+/*
+MatchCourseText return relevance of courses, which name, description, tags or stages content match text.
+Parameters:
+text - full-text query;
+limit - max count of courses and stages with the best scores;
+*/
+func (ctx *DbContext) MatchCourseText(text string, limit int64) (map[string]float64, error) {
+	db := ctx.Client.Database(DbName)
+	filter := bson.D{{"$text", bson.D{{"$search", text}}}}
+	score := bson.D{{"score", bson.D{{"$meta", "textScore"}}}}
+
+	ops := options.Find().SetProjection(score).SetSort(score).SetLimit(limit)
+
+	var dbCourses []*struct {
+		Id    primitive.ObjectID `bson:"_id"`
+		Score float64            `bson:"score"`
+	}
+
+	cursor, err := db.Collection(CourseCollection).Find(context.Background(), filter, ops)
+
+	if err == nil {
+		err = cursor.All(context.Background(), &dbCourses)
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/search_impl.go",
+				Method: "MatchCourseText",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	ops = options.Find().SetProjection(append(bson.D{{"course_id", 1}}, score...)).SetSort(score).SetLimit(limit)
+
+	var dbStages []*struct {
+		CourseId primitive.ObjectID `bson:"course_id"`
+		Score    float64            `bson:"score"`
+	}
+
+	cursor, err = db.Collection(StageCollection).Find(context.Background(), filter, ops)
+
+	if err == nil {
+		err = cursor.All(context.Background(), &dbStages)
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/search_impl.go",
+				Method: "MatchCourseText",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	scores := make(map[string]float64, len(dbCourses)+len(dbStages))
+
+	for _, dbCourse := range dbCourses {
+		scores[dbCourse.Id.Hex()] = dbCourse.Score
+	}
+
+	// Course relevance is increased by the best matched stage
+	best := make(map[string]float64, len(dbStages))
+
+	for _, dbStage := range dbStages {
+		courseId := dbStage.CourseId.Hex()
+
+		if dbStage.Score > best[courseId] {
+			best[courseId] = dbStage.Score
+		}
+	}
+
+	for courseId, stageScore := range best {
+		scores[courseId] += stageScore * stageTextWeight
+	}
+
+	return scores, nil
+}
+
+/*
+SearchCourses return page of courses matched filters of query and facet counts. Parameters:
+query - search query. Text of query isn't matched here;
+scores - relevance of courses matched text. If it's nil, courses aren't filtered by text and are ordered by rating;
+*/
+func (ctx *DbContext) SearchCourses(query *common.SearchQuery, scores map[string]float64) (*common.SearchResult, error) {
+	col := ctx.Client.Database(DbName).Collection(CourseCollection)
+
+	if query == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/search_impl.go",
+				Method: "SearchCourses",
+			},
+			Model: "query",
+		}
+	}
+
+	match, err := searchFilter(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{}
+	sort := bson.D{{"rating", -1}, {"date_update", -1}, {"_id", 1}}
+
+	// Relevance is added to courses, so sort and page are done by db
+	if scores != nil {
+		ids := make(bson.A, 0, len(scores))
+		values := make(bson.A, 0, len(scores))
+
+		for courseId, score := range scores {
+			objectId, err := primitive.ObjectIDFromHex(courseId)
+
+			if err != nil {
+				continue
+			}
+
+			ids = append(ids, objectId)
+			values = append(values, score)
+		}
+
+		match = append(match, bson.E{Key: "_id", Value: bson.D{{"$in", ids}}})
+		sort = append(bson.D{{"score", -1}}, sort...)
+
+		pipeline = append(pipeline, bson.D{{"$match", match}}, bson.D{{"$addFields", bson.D{{"score",
+			bson.D{{"$arrayElemAt", bson.A{values, bson.D{{"$indexOfArray", bson.A{ids, "$_id"}}}}}}}}}})
+	} else {
+		pipeline = append(pipeline, bson.D{{"$match", match}})
+	}
+
+	take := query.Take
+
+	if take <= 0 {
+		take = 20
+	}
+
+	pipeline = append(pipeline, bson.D{{"$facet", bson.D{
+		{"courses", bson.A{
+			bson.D{{"$sort", sort}},
+			bson.D{{"$skip", query.Skip}},
+			bson.D{{"$limit", take}},
+		}},
+		{"total", bson.A{bson.D{{"$count", "count"}}}},
+		{"tags", bson.A{
+			bson.D{{"$unwind", "$tags"}},
+			bson.D{{"$group", bson.D{{"_id", "$tags"}, {"count", bson.D{{"$sum", 1}}}}}},
+			bson.D{{"$sort", bson.D{{"count", -1}, {"_id", 1}}}},
+			bson.D{{"$limit", maxFacetValues}},
+		}},
+		{"categories", bson.A{
+			bson.D{{"$group", bson.D{{"_id", "$category_id"}, {"count", bson.D{{"$sum", 1}}}}}},
+			bson.D{{"$sort", bson.D{{"count", -1}, {"_id", 1}}}},
+			bson.D{{"$limit", maxFacetValues}},
+		}},
+	}}})
+
+	cursor, err := col.Aggregate(context.Background(), pipeline)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/search_impl.go",
+				Method: "SearchCourses",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	type dbFacetCount struct {
+		Value interface{} `bson:"_id"`
+		Count int         `bson:"count"`
+	}
+
+	var facets []struct {
+		Courses []*DbCourse `bson:"courses"`
+		Total   []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Tags       []*dbFacetCount `bson:"tags"`
+		Categories []*dbFacetCount `bson:"categories"`
+	}
+
+	err = cursor.All(context.Background(), &facets)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/search_impl.go",
+				Method: "SearchCourses",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	result := &common.SearchResult{
+		Courses: []*common.Course{},
+		Facets:  &common.SearchFacets{Tags: []*common.FacetCount{}, Categories: []*common.FacetCount{}},
+	}
+
+	if len(facets) == 0 {
+		return result, nil
+	}
+
+	if len(facets[0].Total) > 0 {
+		result.Total = facets[0].Total[0].Count
+	}
+
+	for _, dbCourse := range facets[0].Courses {
+		course, err := dbCourse.ToCourse()
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/search_impl.go",
+					Method: "SearchCourses",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		result.Courses = append(result.Courses, course)
+	}
+
+	for _, tag := range facets[0].Tags {
+		if value, ok := tag.Value.(string); ok {
+			result.Facets.Tags = append(result.Facets.Tags, &common.FacetCount{Value: value, Count: tag.Count})
+		}
+	}
+
+	for _, category := range facets[0].Categories {
+		if value, ok := category.Value.(primitive.ObjectID); ok {
+			result.Facets.Categories = append(result.Facets.Categories,
+				&common.FacetCount{Value: value.Hex(), Count: category.Count})
+		}
+	}
+
+	return result, nil
+}
+
+// searchFilter return filter of courses by search query fields except text
+func searchFilter(query *common.SearchQuery) (bson.D, error) {
+	match := bson.D{}
+
+	if len(query.Lang) > 0 {
+		match = append(match, bson.E{Key: "lang", Value: query.Lang})
+	}
+
+	if len(query.CategoryId) > 0 {
+		objectCategoryId, err := primitive.ObjectIDFromHex(query.CategoryId)
+
+		if err != nil {
+			return nil, openerrors.InvalidIdErr{
+				Id:        query.CategoryId,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/search_impl.go",
+						Method: "searchFilter",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		match = append(match, bson.E{Key: "category_id", Value: objectCategoryId})
+	}
+
+	if len(query.Tags) > 0 {
+		match = append(match, bson.E{Key: "tags", Value: bson.D{{"$all", query.Tags}}})
+	}
+
+	rating := bson.D{}
+
+	if query.MinRating != nil {
+		rating = append(rating, bson.E{Key: "$gte", Value: *query.MinRating})
+	}
+
+	if query.MaxRating != nil {
+		rating = append(rating, bson.E{Key: "$lte", Value: *query.MaxRating})
+	}
+
+	if len(rating) > 0 {
+		match = append(match, bson.E{Key: "rating", Value: rating})
+	}
+
+	date := bson.D{}
+
+	if !query.DateFrom.IsZero() {
+		date = append(date, bson.E{Key: "$gte", Value: primitive.NewDateTimeFromTime(query.DateFrom)})
+	}
+
+	if !query.DateTo.IsZero() {
+		date = append(date, bson.E{Key: "$lt", Value: primitive.NewDateTimeFromTime(query.DateTo)})
+	}
+
+	if len(date) > 0 {
+		match = append(match, bson.E{Key: "date_create", Value: date})
+	}
+
+	return match, nil
+}
@@ -0,0 +1,568 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/events"
+	"strconv"
+)
+
+/*
+This file contains discussion threads of stages. Thread is root comment with replies, reply of reply is added
+to the same thread. Deleted comment keeps its place in thread without body.
+*/
+
+// maxCommentLength max length of comment body
+const maxCommentLength = 10000
+
+// commentsSorts sorts of thread lists
+var commentsSorts = map[string]bson.D{
+	common.CommentsNewest: {{"_id", -1}},
+	common.CommentsVotes:  {{"votes", -1}, {"_id", -1}},
+}
+
+// ClearStageComments remove all data from stage comments collection
+func (ctx *DbContext) ClearStageComments() error {
+	col := ctx.Client.Database(DbName).Collection(StageCommentCollection)
+
+	_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: "ClearStageComments",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+AddStageComment add thread to stage or reply to thread. Hidden or deleted thread isn't replied. Parameters:
+userId - comment author id;
+userName - comment author name;
+stageId - stage id;
+query - model for add comment;
+*/
+func (ctx *DbContext) AddStageComment(userId string, userName string, stageId string,
+	query *common.AddStageCommentQuery) (*common.StageComment, error) {
+
+	col := ctx.Client.Database(DbName).Collection(StageCommentCollection)
+
+	if query == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: "AddStageComment",
+			},
+			Model: "query",
+		}
+	}
+
+	err := checkCommentBody(query.Body, "AddStageComment")
+
+	if err != nil {
+		return nil, err
+	}
+
+	objectUserId, err := commentObjectId(userId, "AddStageComment")
+
+	if err != nil {
+		return nil, err
+	}
+
+	stage, err := ctx.GetStage(stageId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if stage == nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: "AddStageComment",
+			},
+			Msg: "stage " + stageId + " isn't found",
+		}
+	}
+
+	objectStageId, _ := primitive.ObjectIDFromHex(stage.Id)
+	objectCourseId, _ := primitive.ObjectIDFromHex(stage.CourseId)
+	dateNow := primitive.NewDateTimeFromTime(ctx.Now())
+
+	dbComment := DbStageComment{
+		Id:         primitive.NewObjectID(),
+		StageId:    objectStageId,
+		CourseId:   objectCourseId,
+		UserId:     objectUserId,
+		UserName:   userName,
+		Body:       query.Body,
+		Voters:     []primitive.ObjectID{},
+		DateCreate: dateNow,
+		DateUpdate: dateNow,
+	}
+
+	if len(query.ParentId) > 0 {
+		parent, err := ctx.getDbStageComment(query.ParentId, "AddStageComment")
+
+		if err != nil {
+			return nil, err
+		}
+
+		if parent == nil || parent.StageId != objectStageId {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/stage_comment_impl.go",
+					Method: "AddStageComment",
+				},
+				Msg: "comment " + query.ParentId + " of stage isn't found",
+			}
+		}
+
+		dbComment.ParentId = parent.Id
+		closed := parent.Hidden || parent.Deleted
+
+		// Reply of reply is added to thread, so root must be open too
+		if !parent.ParentId.IsZero() {
+			dbComment.ParentId = parent.ParentId
+
+			root, err := ctx.getDbStageComment(parent.ParentId.Hex(), "AddStageComment")
+
+			if err != nil {
+				return nil, err
+			}
+
+			closed = closed || root == nil || root.Hidden || root.Deleted
+		}
+
+		if closed {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/stage_comment_impl.go",
+					Method: "AddStageComment",
+				},
+				Msg: "comment " + query.ParentId + " is hidden or deleted",
+			}
+		}
+	}
+
+	session, err := ctx.Client.StartSession()
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: "AddStageComment",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		_, err := col.InsertOne(sc, dbComment)
+
+		if err != nil || dbComment.ParentId.IsZero() {
+			return nil, err
+		}
+
+		return col.UpdateOne(sc, bson.D{{"_id", dbComment.ParentId}}, bson.D{{"$inc", bson.D{{"reply_count", 1}}}})
+	})
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: "AddStageComment",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	if len(query.ParentId) > 0 {
+		ctx.publish(events.Event{Type: events.CommentReplied, UserId: userId, CourseId: stage.CourseId,
+			StageId: stage.Id, TargetId: query.ParentId})
+	}
+
+	return dbComment.ToStageComment(), nil
+}
+
+/*
+GetStageComment return comment. Returns nil, if comment isn't found. Parameters:
+commentId - comment id;
+*/
+func (ctx *DbContext) GetStageComment(commentId string) (*common.StageComment, error) {
+	dbComment, err := ctx.getDbStageComment(commentId, "GetStageComment")
+
+	if err != nil || dbComment == nil {
+		return nil, err
+	}
+
+	return dbComment.ToStageComment(), nil
+}
+
+/*
+GetStageCommentsPage return page of visible threads of stage. Parameters:
+stageId - stage id;
+sort - threads sort, the newest threads or threads with more upvotes first;
+query - page query;
+*/
+func (ctx *DbContext) GetStageCommentsPage(stageId string, sort string,
+	query *common.PageQuery) ([]*common.StageComment, *common.Page, error) {
+
+	objectStageId, err := commentObjectId(stageId, "GetStageCommentsPage")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sortFields, ok := commentsSorts[sort]
+
+	if !ok {
+		return nil, nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: "GetStageCommentsPage",
+			},
+			Msg: "unknown comments sort " + sort,
+		}
+	}
+
+	// Root comment hasn't parent field, it's matched by null
+	filter := bson.D{{"stage_id", objectStageId}, {"parent_id", nil}, {"hidden", false}}
+
+	return ctx.findCommentsPage(filter, sortFields, query, "GetStageCommentsPage")
+}
+
+/*
+GetCommentRepliesPage return page of visible thread replies, the oldest first. Parameters:
+commentId - root comment id;
+query - page query;
+*/
+func (ctx *DbContext) GetCommentRepliesPage(commentId string, query *common.PageQuery) ([]*common.StageComment, *common.Page, error) {
+	objectCommentId, err := commentObjectId(commentId, "GetCommentRepliesPage")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter := bson.D{{"parent_id", objectCommentId}, {"hidden", false}}
+
+	return ctx.findCommentsPage(filter, bson.D{{"_id", 1}}, query, "GetCommentRepliesPage")
+}
+
+/*
+UpdateStageComment replace body of comment. Deleted comment isn't changed. Returns false, if comment isn't found.
+Parameters:
+commentId - comment id;
+body - markdown body;
+*/
+func (ctx *DbContext) UpdateStageComment(commentId string, body string) (bool, error) {
+	err := checkCommentBody(body, "UpdateStageComment")
+
+	if err != nil {
+		return false, err
+	}
+
+	update := bson.D{{"$set", bson.D{{"body", body}, {"date_update", primitive.NewDateTimeFromTime(ctx.Now())}}}}
+
+	return ctx.updateStageComment(commentId, bson.D{{"deleted", false}}, update, "UpdateStageComment")
+}
+
+/*
+DeleteStageComment remove body of comment. Comment stays in thread, so replies aren't lost.
+Returns false, if comment isn't found. Parameters:
+commentId - comment id;
+*/
+func (ctx *DbContext) DeleteStageComment(commentId string) (bool, error) {
+	update := bson.D{{"$set", bson.D{
+		{"deleted", true},
+		{"body", ""},
+		{"date_update", primitive.NewDateTimeFromTime(ctx.Now())},
+	}}}
+
+	return ctx.updateStageComment(commentId, bson.D{}, update, "DeleteStageComment")
+}
+
+/*
+VoteStageComment add or remove upvote of user. User upvotes comment once. Returns false, if vote isn't changed.
+Parameters:
+commentId - comment id;
+userId - user id;
+up - true for add upvote, false for remove upvote;
+*/
+func (ctx *DbContext) VoteStageComment(commentId string, userId string, up bool) (bool, error) {
+	objectUserId, err := commentObjectId(userId, "VoteStageComment")
+
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.D{{"deleted", false}, {"voters", bson.D{{"$ne", objectUserId}}}}
+	update := bson.D{{"$addToSet", bson.D{{"voters", objectUserId}}}, {"$inc", bson.D{{"votes", 1}}}}
+
+	if !up {
+		filter = bson.D{{"voters", objectUserId}}
+		update = bson.D{{"$pull", bson.D{{"voters", objectUserId}}}, {"$inc", bson.D{{"votes", -1}}}}
+	}
+
+	return ctx.updateStageComment(commentId, filter, update, "VoteStageComment")
+}
+
+/*
+HideStageComment hide comment or show hidden comment. Replies of hidden thread aren't listed with it.
+Returns false, if comment isn't found. Parameters:
+commentId - comment id;
+hidden - comment is hidden;
+*/
+func (ctx *DbContext) HideStageComment(commentId string, hidden bool) (bool, error) {
+	return ctx.updateStageComment(commentId, bson.D{}, bson.D{{"$set", bson.D{{"hidden", hidden}}}}, "HideStageComment")
+}
+
+/*
+MarkCommentAnswer mark reply as answer of thread or remove mark. Thread has one answer, the previous mark is removed.
+Mark is removed only from reply, which is answer. Returns false, if reply isn't found. Parameters:
+commentId - reply id;
+answer - reply is answer;
+*/
+func (ctx *DbContext) MarkCommentAnswer(commentId string, answer bool) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(StageCommentCollection)
+
+	reply, err := ctx.getDbStageComment(commentId, "MarkCommentAnswer")
+
+	if err != nil || reply == nil {
+		return false, err
+	}
+
+	if reply.ParentId.IsZero() {
+		return false, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: "MarkCommentAnswer",
+			},
+			Msg: "root comment can't be answer",
+		}
+	}
+
+	// Removing mark of other reply would remove answer of thread
+	if !answer && !reply.IsAnswer {
+		return true, nil
+	}
+
+	session, err := ctx.Client.StartSession()
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: "MarkCommentAnswer",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer session.EndSession(context.Background())
+
+	rootUpdate := bson.D{{"$unset", bson.D{{"answer_id", ""}}}}
+
+	if answer {
+		rootUpdate = bson.D{{"$set", bson.D{{"answer_id", reply.Id}}}}
+	}
+
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		_, err := col.UpdateMany(sc, bson.D{{"parent_id", reply.ParentId}, {"is_answer", true}},
+			bson.D{{"$set", bson.D{{"is_answer", false}}}})
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = col.UpdateOne(sc, bson.D{{"_id", reply.Id}}, bson.D{{"$set", bson.D{{"is_answer", answer}}}})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return col.UpdateOne(sc, bson.D{{"_id", reply.ParentId}}, rootUpdate)
+	})
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: "MarkCommentAnswer",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return true, nil
+}
+
+/*
+findCommentsPage return page of comments. Parameters:
+filter - comments filter;
+sort - comments sort;
+query - page query;
+method - method name for errors;
+*/
+func (ctx *DbContext) findCommentsPage(filter bson.D, sort bson.D, query *common.PageQuery,
+	method string) ([]*common.StageComment, *common.Page, error) {
+
+	col := ctx.Client.Database(DbName).Collection(StageCommentCollection)
+
+	docs, page, err := ctx.findPage(col, filter, sort, bson.D{{"voters", 0}}, query)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comments := make([]*common.StageComment, 0, len(docs))
+
+	for _, doc := range docs {
+		var dbComment DbStageComment
+
+		err = bson.Unmarshal(doc, &dbComment)
+
+		if err != nil {
+			return nil, nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/stage_comment_impl.go",
+					Method: method,
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		comments = append(comments, dbComment.ToStageComment())
+	}
+
+	return comments, page, nil
+}
+
+/*
+updateStageComment update comment, which is matched by id and filter. Returns false, if comment isn't matched.
+Parameters:
+commentId - comment id;
+filter - additional filter of comment;
+update - comment update;
+method - method name for errors;
+*/
+func (ctx *DbContext) updateStageComment(commentId string, filter bson.D, update bson.D, method string) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(StageCommentCollection)
+
+	objectCommentId, err := commentObjectId(commentId, method)
+
+	if err != nil {
+		return false, err
+	}
+
+	result, err := col.UpdateOne(context.Background(), append(bson.D{{"_id", objectCommentId}}, filter...), update)
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: method,
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+/*
+getDbStageComment return comment. Returns nil, if comment isn't found. Parameters:
+commentId - comment id;
+method - method name for errors;
+*/
+func (ctx *DbContext) getDbStageComment(commentId string, method string) (*DbStageComment, error) {
+	col := ctx.Client.Database(DbName).Collection(StageCommentCollection)
+
+	objectCommentId, err := commentObjectId(commentId, method)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var dbComment DbStageComment
+
+	err = col.FindOne(context.Background(), bson.D{{"_id", objectCommentId}}).Decode(&dbComment)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: method,
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return &dbComment, nil
+}
+
+// checkCommentBody check that body isn't empty and isn't too long
+func checkCommentBody(body string, method string) error {
+	if len(body) == 0 || len(body) > maxCommentLength {
+		return openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_comment_impl.go",
+				Method: method,
+			},
+			Msg: "comment body must be not empty and shorter than " + strconv.Itoa(maxCommentLength),
+		}
+	}
+
+	return nil
+}
+
+// commentObjectId convert id to object id
+func commentObjectId(id string, method string) (primitive.ObjectID, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		return objectId, openerrors.InvalidIdErr{
+			Id:        id,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/stage_comment_impl.go",
+					Method: method,
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	return objectId, nil
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo"
 	"opencourse/common"
 	"opencourse/common/openerrors"
+	"opencourse/events"
 )
 
 // ClearStages remove all data from stages collection
@@ -31,7 +32,7 @@ func (ctx *DbContext) ClearStages() error {
 }
 
 /*
-GetStage return stage by id. Parameters:
+GetStage return stage by id. Returns nil, if stage isn't found. Parameters:
 stageId - stage id;
 */
 func (ctx *DbContext) GetStage(stageId string) (*common.Stage, error) {
@@ -59,6 +60,10 @@ func (ctx *DbContext) GetStage(stageId string) (*common.Stage, error) {
 	var dbStage DbStage
 	err = col.FindOne(context.Background(), filter).Decode(&dbStage)
 
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
 	if err != nil {
 		return nil, openerrors.DbErr{
 			BaseErr: openerrors.BaseErr{
@@ -93,68 +98,66 @@ take - how much records take;
 skip - how much records skip;
 */
 func (ctx *DbContext) GetStages(courseId string, take int64, skip int64) ([]*common.StagePreview, error) {
+	stages, _, err := ctx.GetStagesPage(courseId, &common.PageQuery{Take: take, Skip: skip})
+
+	return stages, err
+}
+
+/*
+GetStagesPage return page of course stages ordered by order number and position of next page. Parameters:
+courseId - course id;
+query - page query;
+*/
+func (ctx *DbContext) GetStagesPage(courseId string, query *common.PageQuery) ([]*common.StagePreview, *common.Page, error) {
 	col := ctx.Client.Database(DbName).Collection(StageCollection)
 
 	objectCourseId, err := primitive.ObjectIDFromHex(courseId)
 
 	if err != nil {
-		return nil, openerrors.InvalidIdErr{
+		return nil, nil, openerrors.InvalidIdErr{
 			Id:        courseId,
 			Converter: "ObjectIDFromHex",
 			Default: openerrors.DefaultErr{
 				BaseErr: openerrors.BaseErr{
 					File:   "database/stage_impl.go",
-					Method: "GetStages",
+					Method: "GetStagesPage",
 				},
 				Msg: err.Error(),
 			},
 		}
 	}
 
-	ops := options.Find().SetLimit(take).SetSkip(skip).
-		SetSort(bson.D{{"order_number", 1}}).SetProjection(bson.D{{"content", -1}})
-
-	cursor, err := col.Find(context.Background(), bson.D{{"course_id", objectCourseId}}, ops)
+	docs, page, err := ctx.findPage(col, bson.D{{"course_id", objectCourseId}},
+		bson.D{{"order_number", 1}, {"_id", 1}}, bson.D{{"content", 0}}, query)
 
 	if err != nil {
-		return nil, openerrors.DbErr{
-			BaseErr: openerrors.BaseErr{
-				File:   "database/stage_impl.go",
-				Method: "GetStages",
-			},
-			DbName: ctx.DbName,
-			ConStr: ctx.ConStr,
-			DbErr:  err.Error(),
-		}
+		return nil, nil, err
 	}
 
-	var dbStages []*DbStage
-
-	err = cursor.All(context.Background(), &dbStages)
+	var stages []*common.StagePreview
 
-	if err != nil {
-		return nil, openerrors.DbErr{
-			BaseErr: openerrors.BaseErr{
-				File:   "database/stage_impl.go",
-				Method: "GetStages",
-			},
-			DbName: ctx.DbName,
-			ConStr: ctx.ConStr,
-			DbErr:  err.Error(),
-		}
-	}
+	for _, doc := range docs {
+		var dbStage DbStage
 
-	var stages []*common.StagePreview
+		err = bson.Unmarshal(doc, &dbStage)
 
-	for _, dbStage := range dbStages {
+		if err != nil {
+			return nil, nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/stage_impl.go",
+					Method: "GetStagesPage",
+				},
+				Msg: err.Error(),
+			}
+		}
 
 		stagePreview, err := dbStage.ToStagePreview()
 
 		if err != nil {
-			return nil, openerrors.DefaultErr{
+			return nil, nil, openerrors.DefaultErr{
 				BaseErr: openerrors.BaseErr{
 					File:   "database/stage_impl.go",
-					Method: "GetStages",
+					Method: "GetStagesPage",
 				},
 				Msg: err.Error(),
 			}
@@ -163,7 +166,7 @@ func (ctx *DbContext) GetStages(courseId string, take int64, skip int64) ([]*com
 		stages = append(stages, stagePreview)
 	}
 
-	return stages, nil
+	return stages, page, nil
 }
 
 /*
@@ -217,11 +220,23 @@ func (ctx *DbContext) AddStage(query *common.AddStageQuery) (string, error) {
 		}
 	}
 
+	if !validPools(query.Pools) {
+		return "", openerrors.FieldEmptyErr{
+			Field: "query.Pools",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_impl.go",
+				Method: "AddStage",
+			},
+		}
+	}
+
 	var dbStage DbStage
 
 	dbStage.Name = query.Name
 	dbStage.HeaderImg = query.HeaderImg
 	dbStage.OrderNumber = query.OrderNumber
+	dbStage.TimeLimit = query.TimeLimit
+	dbStage.Pools = ToDbQuestionPools(query.Pools)
 
 	objectCourseId, err := primitive.ObjectIDFromHex(query.CourseId)
 
@@ -260,6 +275,8 @@ func (ctx *DbContext) AddStage(query *common.AddStageQuery) (string, error) {
 
 	newId := result.InsertedID.(primitive.ObjectID)
 
+	ctx.publishContent(events.StageSaved, query.CourseId, newId.Hex())
+
 	return newId.Hex(), nil
 }
 
@@ -314,6 +331,16 @@ func (ctx *DbContext) UpdateStage(query *common.UpdateStageQuery) error {
 		}
 	}
 
+	if !validPools(query.Pools) {
+		return openerrors.FieldEmptyErr{
+			Field: "query.Pools",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/stage_impl.go",
+				Method: "UpdateStage",
+			},
+		}
+	}
+
 	objectStageId, err := primitive.ObjectIDFromHex(query.StageId)
 
 	if err != nil {
@@ -349,6 +376,7 @@ func (ctx *DbContext) UpdateStage(query *common.UpdateStageQuery) error {
 	var dbContent *DbPostContent = nil
 
 	if query.Content != nil {
+		dbContent = &DbPostContent{}
 		dbContent.Body = query.Content.Body
 		dbContent.MediaItems = query.Content.MediaItems
 	}
@@ -360,7 +388,9 @@ func (ctx *DbContext) UpdateStage(query *common.UpdateStageQuery) error {
 			{"course_id", objectCourseId},
 			{"name", query.Name},
 			{"header_img", query.HeaderImg},
-			{"order_number", query.HeaderImg},
+			{"order_number", query.OrderNumber},
+			{"time_limit", query.TimeLimit},
+			{"pools", ToDbQuestionPools(query.Pools)},
 			{"content", dbContent},
 		}},
 	}
@@ -379,6 +409,8 @@ func (ctx *DbContext) UpdateStage(query *common.UpdateStageQuery) error {
 		}
 	}
 
+	ctx.publishContent(events.StageSaved, query.CourseId, query.StageId)
+
 	return nil
 }
 
@@ -419,5 +451,22 @@ func (ctx *DbContext) DeleteStage(stageId string) error {
 		}
 	}
 
+	ctx.publishContent(events.StageDeleted, "", stageId)
+
 	return nil
 }
+
+// validPools check that pools have unique names and not negative draw counts
+func validPools(pools []*common.QuestionPool) bool {
+	names := make(map[string]bool, len(pools))
+
+	for _, pool := range pools {
+		if pool == nil || len(pool.Name) == 0 || pool.DrawCount < 0 || names[pool.Name] {
+			return false
+		}
+
+		names[pool.Name] = true
+	}
+
+	return true
+}
@@ -0,0 +1,363 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"time"
+)
+
+// ClearTestAttempts remove all data from test_attempts collection
+func (ctx *DbContext) ClearTestAttempts() error {
+	col := ctx.Client.Database(DbName).Collection(TestAttemptCollection)
+
+	_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_attempt_impl.go",
+				Method: "ClearTestAttempts",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+CheckAttempt check that user can answer test now. Returns openerrors.AttemptDeniedErr,
+if attempts limit is reached or cooldown isn't over. Parameters:
+userId - user id;
+test - test for answer;
+*/
+func (ctx *DbContext) CheckAttempt(userId string, test *common.Test) error {
+	col := ctx.Client.Database(DbName).Collection(UserTestCollection)
+
+	if test == nil {
+		return openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_attempt_impl.go",
+				Method: "CheckAttempt",
+			},
+			Model: "test",
+		}
+	}
+
+	// Test without limits doesn't need user_tests document
+	if test.MaxAttempts == 0 && test.Cooldown == 0 {
+		return nil
+	}
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_attempt_impl.go",
+					Method: "CheckAttempt",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	objectTestId, err := primitive.ObjectIDFromHex(test.Id)
+
+	if err != nil {
+		return openerrors.InvalidIdErr{
+			Id:        test.Id,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_attempt_impl.go",
+					Method: "CheckAttempt",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbUserTest DbUserTest
+
+	err = col.FindOne(context.Background(), bson.D{{"user_id", objectUserId}, {"test_id", objectTestId}}).
+		Decode(&dbUserTest)
+
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_attempt_impl.go",
+				Method: "CheckAttempt",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return checkAttempt(&dbUserTest, test, ctx.Now(), "CheckAttempt")
+}
+
+/*
+GetAttempts return user attempts for test ordered by number. Parameters:
+userId - user id;
+testId - test id;
+*/
+func (ctx *DbContext) GetAttempts(userId string, testId string) ([]*common.TestAttempt, error) {
+	col := ctx.Client.Database(DbName).Collection(TestAttemptCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_attempt_impl.go",
+					Method: "GetAttempts",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	objectTestId, err := primitive.ObjectIDFromHex(testId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        testId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_attempt_impl.go",
+					Method: "GetAttempts",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	ops := options.Find().SetSort(bson.D{{"number", 1}})
+
+	cursor, err := col.Find(context.Background(), bson.D{{"user_id", objectUserId}, {"test_id", objectTestId}}, ops)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_attempt_impl.go",
+				Method: "GetAttempts",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbAttempts []*DbTestAttempt
+
+	err = cursor.All(context.Background(), &dbAttempts)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_attempt_impl.go",
+				Method: "GetAttempts",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var attempts []*common.TestAttempt
+
+	for _, dbAttempt := range dbAttempts {
+		attempt, err := dbAttempt.ToTestAttempt()
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_attempt_impl.go",
+					Method: "GetAttempts",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
+
+/*
+GetAttemptCounts return count of user attempts for every answered test of stage. Parameters:
+userId - user id;
+stageId - stage id;
+*/
+func (ctx *DbContext) GetAttemptCounts(userId string, stageId string) (map[string]int, error) {
+	col := ctx.Client.Database(DbName).Collection(UserTestCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_attempt_impl.go",
+					Method: "GetAttemptCounts",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	objectStageId, err := primitive.ObjectIDFromHex(stageId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        stageId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_attempt_impl.go",
+					Method: "GetAttemptCounts",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	ops := options.Find().SetProjection(bson.D{{"test_id", 1}, {"attempts", 1}})
+
+	cursor, err := col.Find(context.Background(), bson.D{{"user_id", objectUserId}, {"stage_id", objectStageId}}, ops)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_attempt_impl.go",
+				Method: "GetAttemptCounts",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbUserTests []*DbUserTest
+
+	err = cursor.All(context.Background(), &dbUserTests)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_attempt_impl.go",
+				Method: "GetAttemptCounts",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	counts := make(map[string]int, len(dbUserTests))
+
+	for _, dbUserTest := range dbUserTests {
+		counts[dbUserTest.TestId.Hex()] = dbUserTest.Attempts
+	}
+
+	return counts, nil
+}
+
+/*
+GetActivityDays return days in UTC, when user answered tests, ordered by date. Day format is 2006-01-02. Parameters:
+userId - user id;
+from - start date of period;
+*/
+func (ctx *DbContext) GetActivityDays(userId string, from time.Time) ([]string, error) {
+	col := ctx.Client.Database(DbName).Collection(TestAttemptCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_attempt_impl.go",
+					Method: "GetActivityDays",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{
+			{"user_id", objectUserId},
+			{"date_create", bson.D{{"$gte", primitive.NewDateTimeFromTime(from)}}},
+		}}},
+		{{"$group", bson.D{
+			{"_id", bson.D{{"$dateToString", bson.D{{"format", "%Y-%m-%d"}, {"date", "$date_create"}}}}},
+		}}},
+		{{"$sort", bson.D{{"_id", 1}}}},
+	}
+
+	cursor, err := col.Aggregate(context.Background(), pipeline)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_attempt_impl.go",
+				Method: "GetActivityDays",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var groups []struct {
+		Day string `bson:"_id"`
+	}
+
+	err = cursor.All(context.Background(), &groups)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_attempt_impl.go",
+				Method: "GetActivityDays",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	days := make([]string, 0, len(groups))
+
+	for _, group := range groups {
+		days = append(days, group.Day)
+	}
+
+	return days, nil
+}
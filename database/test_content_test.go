@@ -0,0 +1,48 @@
+package database
+
+import (
+	"opencourse/common"
+	"testing"
+)
+
+// TestTestContentNilOption nil option is rejected instead of panic on save
+func TestTestContentNilOption(t *testing.T) {
+	queries := []*common.AddTestQuery{
+		{
+			TestType: common.TestOption,
+			OptionTest: &common.OptionTest{
+				Question: "Question",
+				Options:  []*common.Option{nil, {Answer: "a", IsRight: true}, {Answer: "b"}},
+			},
+		},
+		{
+			TestType: common.TestMultiSelect,
+			MultiSelectTest: &common.MultiSelectTest{
+				Question: "Question",
+				Options:  []*common.Option{{Answer: "a", IsRight: true}, nil, {Answer: "b"}},
+			},
+		},
+	}
+
+	for _, query := range queries {
+		if test, field := testContent(query); test != nil || field == "" {
+			t.Errorf("%s test with nil option must be rejected", query.TestType)
+		}
+	}
+}
+
+// TestToDbTestNilOption
+func TestToDbTestNilOption(t *testing.T) {
+	dbTest, err := ToDbTest(&common.Test{
+		TestType:   common.TestOption,
+		OptionTest: &common.OptionTest{Question: "Question", Options: []*common.Option{nil, {Answer: "a", IsRight: true}}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dbTest.OptionTest.Options) != 1 || dbTest.OptionTest.Options[0].Answer != "a" {
+		t.Errorf("nil option must be skipped, got %+v", dbTest.OptionTest.Options)
+	}
+}
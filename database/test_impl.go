@@ -2,11 +2,16 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"opencourse/common"
 	"opencourse/common/openerrors"
+	"opencourse/sandbox"
+	"regexp"
+	"strings"
 )
 
 // ClearTests remove all data from tests collection
@@ -31,7 +36,7 @@ func (ctx *DbContext) ClearTests() error {
 }
 
 /*
-GetTest return test. Parameters:
+GetTest return test. Returns nil, if test isn't found. Parameters:
 testId - test id;
 */
 func (ctx *DbContext) GetTest(testId string) (*common.Test, error) {
@@ -59,6 +64,10 @@ func (ctx *DbContext) GetTest(testId string) (*common.Test, error) {
 	var dbTest DbTest
 	err = col.FindOne(context.Background(), filter).Decode(&dbTest)
 
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
 	if err != nil {
 		return nil, openerrors.DbErr{
 			BaseErr: openerrors.BaseErr{
@@ -93,6 +102,82 @@ take - how much records take;
 skip - how much records skip;
 */
 func (ctx *DbContext) GetTests(stageId string, take int64, skip int64) ([]*common.TestPreview, error) {
+	tests, _, err := ctx.GetTestsPage(stageId, &common.PageQuery{Take: take, Skip: skip})
+
+	return tests, err
+}
+
+/*
+GetTestsPage return page of stage tests ordered by order number and position of next page. Parameters:
+stageId - stage id;
+query - page query;
+*/
+func (ctx *DbContext) GetTestsPage(stageId string, query *common.PageQuery) ([]*common.TestPreview, *common.Page, error) {
+	col := ctx.Client.Database(DbName).Collection(TestCollection)
+
+	objectStageId, err := primitive.ObjectIDFromHex(stageId)
+
+	if err != nil {
+		return nil, nil, openerrors.InvalidIdErr{
+			Id:        stageId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_impl.go",
+					Method: "GetTestsPage",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	docs, page, err := ctx.findPage(col, bson.D{{"stage_id", objectStageId}},
+		bson.D{{"order_number", 1}, {"_id", 1}}, nil, query)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tests []*common.TestPreview
+
+	for _, doc := range docs {
+		var dbTest DbTest
+
+		err = bson.Unmarshal(doc, &dbTest)
+
+		if err != nil {
+			return nil, nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_impl.go",
+					Method: "GetTestsPage",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		test, err := dbTest.ToTestPreview()
+
+		if err != nil {
+			return nil, nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_impl.go",
+					Method: "GetTestsPage",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		tests = append(tests, test)
+	}
+
+	return tests, page, nil
+}
+
+/*
+GetStageTests return all stage tests with right answers ordered by order number. Parameters:
+stageId - stage id;
+*/
+func (ctx *DbContext) GetStageTests(stageId string) ([]*common.Test, error) {
 	col := ctx.Client.Database(DbName).Collection(TestCollection)
 
 	objectStageId, err := primitive.ObjectIDFromHex(stageId)
@@ -104,23 +189,22 @@ func (ctx *DbContext) GetTests(stageId string, take int64, skip int64) ([]*commo
 			Default: openerrors.DefaultErr{
 				BaseErr: openerrors.BaseErr{
 					File:   "database/test_impl.go",
-					Method: "GetTests",
+					Method: "GetStageTests",
 				},
 				Msg: err.Error(),
 			},
 		}
 	}
 
-	ops := options.Find().SetLimit(take).SetSkip(skip).
-		SetSort(bson.D{{"order_number", 1}}).SetProjection(bson.D{{"option_test", -1}, {"rewrite_test", -1}})
+	ops := options.Find().SetSort(bson.D{{"order_number", 1}})
 
-	cursor, err := col.Find(context.Background(), bson.D{{"course_id", objectStageId}}, ops)
+	cursor, err := col.Find(context.Background(), bson.D{{"stage_id", objectStageId}}, ops)
 
 	if err != nil {
 		return nil, openerrors.DbErr{
 			BaseErr: openerrors.BaseErr{
 				File:   "database/test_impl.go",
-				Method: "GetTests",
+				Method: "GetStageTests",
 			},
 			DbName: ctx.DbName,
 			ConStr: ctx.ConStr,
@@ -136,7 +220,7 @@ func (ctx *DbContext) GetTests(stageId string, take int64, skip int64) ([]*commo
 		return nil, openerrors.DbErr{
 			BaseErr: openerrors.BaseErr{
 				File:   "database/test_impl.go",
-				Method: "GetTests",
+				Method: "GetStageTests",
 			},
 			DbName: ctx.DbName,
 			ConStr: ctx.ConStr,
@@ -144,17 +228,16 @@ func (ctx *DbContext) GetTests(stageId string, take int64, skip int64) ([]*commo
 		}
 	}
 
-	var tests []*common.TestPreview
+	var tests []*common.Test
 
 	for _, dbTest := range dbTests {
-
-		test, err := dbTest.ToTestPreview()
+		test, err := dbTest.ToTest()
 
 		if err != nil {
 			return nil, openerrors.DefaultErr{
 				BaseErr: openerrors.BaseErr{
 					File:   "database/test_impl.go",
-					Method: "GetTests",
+					Method: "GetStageTests",
 				},
 				Msg: err.Error(),
 			}
@@ -173,103 +256,97 @@ query - model for create test;
 func (ctx *DbContext) AddTest(query *common.AddTestQuery) (string, error) {
 	col := ctx.Client.Database(DbName).Collection(TestCollection)
 
-	if query.OrderNumber < 0 {
-		return "", openerrors.FieldEmptyErr{
-			Field: "query.OrderNumber",
-			BaseErr: openerrors.BaseErr{
-				File:   "database/test_impl.go",
-				Method: "AddTest",
-			},
-		}
-	}
+	dbTest, err := buildDbTest(query, "AddTest")
 
-	if len(query.TestType) < 2 {
-		return "", openerrors.FieldEmptyErr{
-			Field: "query.TestType",
-			BaseErr: openerrors.BaseErr{
-				File:   "database/test_impl.go",
-				Method: "AddTest",
-			},
-		}
+	if err != nil {
+		return "", err
 	}
 
-	if query.LemmingsCount < 1 {
-		return "", openerrors.FieldEmptyErr{
-			Field: "query.LemmingsCount",
+	result, err := col.InsertOne(context.Background(), dbTest)
+
+	if err != nil {
+		return "", openerrors.DbErr{
 			BaseErr: openerrors.BaseErr{
 				File:   "database/test_impl.go",
 				Method: "AddTest",
 			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
 		}
 	}
 
-	objectStageId, err := primitive.ObjectIDFromHex(query.StageId)
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+
+}
+
+/*
+AddTests add tests to the end of stage. Order numbers of queries are shifted after the last stage test. Parameters:
+stageId - stage id;
+queries - models for create tests;
+*/
+func (ctx *DbContext) AddTests(stageId string, queries []*common.AddTestQuery) ([]string, error) {
+	col := ctx.Client.Database(DbName).Collection(TestCollection)
+
+	objectStageId, err := primitive.ObjectIDFromHex(stageId)
 
 	if err != nil {
-		return "", openerrors.InvalidIdErr{
-			Id:        query.StageId,
+		return nil, openerrors.InvalidIdErr{
+			Id:        stageId,
 			Converter: "ObjectIDFromHex",
 			Default: openerrors.DefaultErr{
 				BaseErr: openerrors.BaseErr{
 					File:   "database/test_impl.go",
-					Method: "AddTest",
+					Method: "AddTests",
 				},
 				Msg: err.Error(),
 			},
 		}
 	}
 
-	var dbTest DbTest
-
-	dbTest.StageId = objectStageId
-	dbTest.TestType = query.TestType
-	dbTest.LemmingsCount = query.LemmingsCount
-	dbTest.OrderNumber = query.OrderNumber
+	if len(queries) == 0 {
+		return nil, nil
+	}
 
-	if query.TestType == common.TestOption {
+	var lastTest DbTest
 
-		if query.OptionTest == nil {
-			return "", openerrors.FieldEmptyErr{
-				Field: "query.OptionTest",
-				BaseErr: openerrors.BaseErr{
-					File:   "database/test_impl.go",
-					Method: "AddTest",
-				},
-			}
-		}
+	ops := options.FindOne().SetSort(bson.D{{"order_number", -1}}).SetProjection(bson.D{{"order_number", 1}})
+	err = col.FindOne(context.Background(), bson.D{{"stage_id", objectStageId}}, ops).Decode(&lastTest)
 
-		dbTest.OptionTest = &DbOptionTest{}
-		dbTest.OptionTest.Question = query.OptionTest.Question
-		for _, option := range query.OptionTest.Options {
-			dbTest.OptionTest.Options =
-				append(dbTest.OptionTest.Options, &DbOption{Answer: option.Answer, IsRight: option.IsRight})
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_impl.go",
+				Method: "AddTests",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
 		}
 	}
 
-	if query.TestType == common.TestRewrite {
+	var dbTests []interface{}
 
-		if query.RewriteTest == nil {
-			return "", openerrors.FieldEmptyErr{
-				Field: "query.RewriteTest",
-				BaseErr: openerrors.BaseErr{
-					File:   "database/test_impl.go",
-					Method: "AddTest",
-				},
-			}
+	for _, query := range queries {
+		query.StageId = stageId
+		query.OrderNumber += lastTest.OrderNumber
+
+		dbTest, err := buildDbTest(query, "AddTests")
+
+		if err != nil {
+			return nil, err
 		}
 
-		dbTest.RewriteTest = &DbRewriteTest{}
-		dbTest.RewriteTest.Question = query.RewriteTest.Question
-		dbTest.RewriteTest.RightAnswer = query.RewriteTest.RightAnswer
+		dbTests = append(dbTests, dbTest)
 	}
 
-	result, err := col.InsertOne(context.Background(), dbTest)
+	result, err := col.InsertMany(context.Background(), dbTests)
 
 	if err != nil {
-		return "", openerrors.DbErr{
+		return nil, openerrors.DbErr{
 			BaseErr: openerrors.BaseErr{
 				File:   "database/test_impl.go",
-				Method: "AddTest",
+				Method: "AddTests",
 			},
 			DbName: ctx.DbName,
 			ConStr: ctx.ConStr,
@@ -277,8 +354,13 @@ func (ctx *DbContext) AddTest(query *common.AddTestQuery) (string, error) {
 		}
 	}
 
-	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+	var ids []string
 
+	for _, id := range result.InsertedIDs {
+		ids = append(ids, id.(primitive.ObjectID).Hex())
+	}
+
+	return ids, nil
 }
 
 /*
@@ -320,3 +402,233 @@ func (ctx *DbContext) DeleteTest(testId string) error {
 
 	return nil
 }
+
+/*
+buildDbTest validate query and build test document. Parameters:
+query - model for create test;
+method - caller method name for errors;
+*/
+func buildDbTest(query *common.AddTestQuery, method string) (*DbTest, error) {
+	if query == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			Model: "query",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_impl.go",
+				Method: method,
+			},
+		}
+	}
+
+	if query.OrderNumber < 0 {
+		return nil, openerrors.FieldEmptyErr{
+			Field: "query.OrderNumber",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_impl.go",
+				Method: method,
+			},
+		}
+	}
+
+	if query.MaxAttempts < 0 || query.Cooldown < 0 {
+		return nil, openerrors.FieldEmptyErr{
+			Field: "query.MaxAttempts or query.Cooldown",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_impl.go",
+				Method: method,
+			},
+		}
+	}
+
+	if len(query.TestType) < 2 {
+		return nil, openerrors.FieldEmptyErr{
+			Field: "query.TestType",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_impl.go",
+				Method: method,
+			},
+		}
+	}
+
+	if query.LemmingsCount < 1 {
+		return nil, openerrors.FieldEmptyErr{
+			Field: "query.LemmingsCount",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_impl.go",
+				Method: method,
+			},
+		}
+	}
+
+	objectStageId, err := primitive.ObjectIDFromHex(query.StageId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        query.StageId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_impl.go",
+					Method: method,
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	test, field := testContent(query)
+
+	if test == nil {
+		return nil, openerrors.FieldEmptyErr{
+			Field: field,
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_impl.go",
+				Method: method,
+			},
+		}
+	}
+
+	dbTest, err := ToDbTest(test)
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_impl.go",
+				Method: method,
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	dbTest.StageId = objectStageId
+
+	return dbTest, nil
+}
+
+/*
+testContent validate query content for test type and return test with sub document only for this type.
+If content is invalid, return nil and invalid field name. Parameters:
+query - model for create test;
+*/
+func testContent(query *common.AddTestQuery) (*common.Test, string) {
+	test := &common.Test{
+		TestType:      query.TestType,
+		LemmingsCount: query.LemmingsCount,
+		OrderNumber:   query.OrderNumber,
+		MaxAttempts:   query.MaxAttempts,
+		Cooldown:      query.Cooldown,
+		Pool:          query.Pool,
+	}
+
+	switch query.TestType {
+	case common.TestOption:
+		if query.OptionTest == nil || len(query.OptionTest.Options) < 2 || hasNilOption(query.OptionTest.Options) ||
+			countRight(query.OptionTest.Options) != 1 {
+			return nil, "query.OptionTest"
+		}
+
+		test.OptionTest = query.OptionTest
+	case common.TestRewrite:
+		if query.RewriteTest == nil {
+			return nil, "query.RewriteTest"
+		}
+
+		if matching := query.RewriteTest.Matching; matching != nil {
+			if matching.MaxDistance < 0 {
+				return nil, "query.RewriteTest.Matching.MaxDistance"
+			}
+
+			for _, pattern := range matching.Patterns {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return nil, "query.RewriteTest.Matching.Patterns"
+				}
+			}
+		}
+
+		test.RewriteTest = query.RewriteTest
+	case common.TestMultiSelect:
+		if query.MultiSelectTest == nil || len(query.MultiSelectTest.Options) < 2 ||
+			hasNilOption(query.MultiSelectTest.Options) || countRight(query.MultiSelectTest.Options) < 1 {
+			return nil, "query.MultiSelectTest"
+		}
+
+		test.MultiSelectTest = query.MultiSelectTest
+	case common.TestOrdering:
+		if query.OrderingTest == nil || len(query.OrderingTest.Items) < 2 {
+			return nil, "query.OrderingTest"
+		}
+
+		test.OrderingTest = query.OrderingTest
+	case common.TestMatching:
+		if query.MatchingTest == nil || len(query.MatchingTest.Pairs) < 2 {
+			return nil, "query.MatchingTest"
+		}
+
+		for _, pair := range query.MatchingTest.Pairs {
+			if pair == nil || len(pair.Left) == 0 || len(pair.Right) == 0 {
+				return nil, "query.MatchingTest.Pairs"
+			}
+		}
+
+		test.MatchingTest = query.MatchingTest
+	case common.TestCloze:
+		if query.ClozeTest == nil || len(query.ClozeTest.Blanks) == 0 {
+			return nil, "query.ClozeTest"
+		}
+
+		for i, blank := range query.ClozeTest.Blanks {
+			placeholder := fmt.Sprintf("{{%d}}", i+1)
+
+			if blank == nil || len(blank.Answers) == 0 || !strings.Contains(query.ClozeTest.Text, placeholder) {
+				return nil, "query.ClozeTest.Blanks"
+			}
+		}
+
+		test.ClozeTest = query.ClozeTest
+	case common.TestNumeric:
+		if query.NumericTest == nil || query.NumericTest.Tolerance < 0 {
+			return nil, "query.NumericTest"
+		}
+
+		test.NumericTest = query.NumericTest
+	case common.TestCode:
+		if query.CodeTest == nil || query.CodeTest.Language != sandbox.LanguageGo || len(query.CodeTest.Cases) == 0 {
+			return nil, "query.CodeTest"
+		}
+
+		for _, codeCase := range query.CodeTest.Cases {
+			if codeCase == nil {
+				return nil, "query.CodeTest.Cases"
+			}
+		}
+
+		test.CodeTest = query.CodeTest
+	default:
+		return nil, "query.TestType"
+	}
+
+	return test, ""
+}
+
+// hasNilOption return true, if some option is nil
+func hasNilOption(options []*common.Option) bool {
+	for _, option := range options {
+		if option == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// countRight return count of right options
+func countRight(options []*common.Option) int {
+	count := 0
+
+	for _, option := range options {
+		if option != nil && option.IsRight {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,413 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+)
+
+// ClearTestSelections remove all data from test_selections collection
+func (ctx *DbContext) ClearTestSelections() error {
+	col := ctx.Client.Database(DbName).Collection(TestSelectionCollection)
+
+	_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "ClearTestSelections",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+SaveTestSelection save selection if user hasn't selection for stage or session. Stored selection is never replaced,
+so the saved selection is returned. Parameters:
+selection - drawn tests. Id and DateCreate are set on save;
+*/
+func (ctx *DbContext) SaveTestSelection(selection *common.TestSelection) (*common.TestSelection, error) {
+	col := ctx.Client.Database(DbName).Collection(TestSelectionCollection)
+
+	if selection == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "SaveTestSelection",
+			},
+			Model: "selection",
+		}
+	}
+
+	ids := append([]string{selection.UserId, selection.StageId}, selection.TestIds...)
+
+	if len(selection.SessionId) > 0 {
+		ids = append(ids, selection.SessionId)
+	}
+
+	objectIds := make(map[string]primitive.ObjectID, len(ids))
+
+	for _, id := range ids {
+		objectId, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			return nil, openerrors.InvalidIdErr{
+				Id:        id,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/test_selection_impl.go",
+						Method: "SaveTestSelection",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		objectIds[id] = objectId
+	}
+
+	dbSelection := DbTestSelection{
+		UserId:     objectIds[selection.UserId],
+		StageId:    objectIds[selection.StageId],
+		SessionId:  objectIds[selection.SessionId],
+		Seed:       selection.Seed,
+		TestIds:    []primitive.ObjectID{},
+		DateCreate: primitive.NewDateTimeFromTime(ctx.Now()),
+	}
+
+	for _, testId := range selection.TestIds {
+		dbSelection.TestIds = append(dbSelection.TestIds, objectIds[testId])
+	}
+
+	filter := selectionFilter(dbSelection.UserId, dbSelection.StageId, dbSelection.SessionId)
+	update := bson.D{{"$setOnInsert", dbSelection}}
+	ops := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	err := col.FindOneAndUpdate(context.Background(), filter, update, ops).Decode(&dbSelection)
+
+	// Concurrent insert of the same selection, the first one is kept
+	if mongo.IsDuplicateKeyError(err) {
+		err = col.FindOne(context.Background(), filter).Decode(&dbSelection)
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "SaveTestSelection",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	saved, err := dbSelection.ToTestSelection()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "SaveTestSelection",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return saved, nil
+}
+
+/*
+GetTestSelection return selection by id. Parameters:
+selectionId - selection id;
+*/
+func (ctx *DbContext) GetTestSelection(selectionId string) (*common.TestSelection, error) {
+	col := ctx.Client.Database(DbName).Collection(TestSelectionCollection)
+
+	objectSelectionId, err := primitive.ObjectIDFromHex(selectionId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        selectionId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_selection_impl.go",
+					Method: "GetTestSelection",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbSelection DbTestSelection
+
+	err = col.FindOne(context.Background(), bson.D{{"_id", objectSelectionId}}).Decode(&dbSelection)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "GetTestSelection",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	selection, err := dbSelection.ToTestSelection()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "GetTestSelection",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return selection, nil
+}
+
+/*
+GetTestPreviews return tests without right answers in order of ids. Parameters:
+testIds - tests ids;
+*/
+func (ctx *DbContext) GetTestPreviews(testIds []string) ([]*common.TestPreview, error) {
+	col := ctx.Client.Database(DbName).Collection(TestCollection)
+
+	objectTestIds := make([]primitive.ObjectID, 0, len(testIds))
+
+	for _, testId := range testIds {
+		objectTestId, err := primitive.ObjectIDFromHex(testId)
+
+		if err != nil {
+			return nil, openerrors.InvalidIdErr{
+				Id:        testId,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/test_selection_impl.go",
+						Method: "GetTestPreviews",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		objectTestIds = append(objectTestIds, objectTestId)
+	}
+
+	cursor, err := col.Find(context.Background(), bson.D{{"_id", bson.D{{"$in", objectTestIds}}}})
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "GetTestPreviews",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbTests []*DbTest
+
+	err = cursor.All(context.Background(), &dbTests)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "GetTestPreviews",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	previews := make(map[string]*common.TestPreview, len(dbTests))
+
+	for _, dbTest := range dbTests {
+		preview, err := dbTest.ToTestPreview()
+
+		if err != nil {
+			return nil, openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/test_selection_impl.go",
+					Method: "GetTestPreviews",
+				},
+				Msg: err.Error(),
+			}
+		}
+
+		previews[preview.Id] = preview
+	}
+
+	// Deleted tests are skipped
+	var tests []*common.TestPreview
+
+	for _, testId := range testIds {
+		if preview, ok := previews[testId]; ok {
+			tests = append(tests, preview)
+		}
+	}
+
+	return tests, nil
+}
+
+/*
+FindTestSelection return stored selection of user for stage or session. Returns nil, if selection isn't drawn yet.
+Parameters:
+userId - user id;
+stageId - stage id;
+sessionId - quiz session id. Empty for stage without time limit;
+*/
+func (ctx *DbContext) FindTestSelection(userId string, stageId string, sessionId string) (*common.TestSelection, error) {
+	col := ctx.Client.Database(DbName).Collection(TestSelectionCollection)
+
+	ids := []string{userId, stageId}
+
+	if len(sessionId) > 0 {
+		ids = append(ids, sessionId)
+	}
+
+	objectIds := make(map[string]primitive.ObjectID, len(ids))
+
+	for _, id := range ids {
+		objectId, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			return nil, openerrors.InvalidIdErr{
+				Id:        id,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/test_selection_impl.go",
+						Method: "FindTestSelection",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		objectIds[id] = objectId
+	}
+
+	var dbSelection DbTestSelection
+
+	filter := selectionFilter(objectIds[userId], objectIds[stageId], objectIds[sessionId])
+	err := col.FindOne(context.Background(), filter).Decode(&dbSelection)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "FindTestSelection",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	selection, err := dbSelection.ToTestSelection()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "FindTestSelection",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return selection, nil
+}
+
+// selectionFilter return filter of selection for user and stage. Session id is zero for stage without time limit
+func selectionFilter(userId primitive.ObjectID, stageId primitive.ObjectID, sessionId primitive.ObjectID) bson.D {
+	filter := bson.D{{"user_id", userId}, {"stage_id", stageId}}
+
+	if sessionId.IsZero() {
+		return append(filter, bson.E{Key: "session_id", Value: bson.D{{"$exists", false}}})
+	}
+
+	return append(filter, bson.E{Key: "session_id", Value: sessionId})
+}
+
+/*
+AddSelectionTests add tests to the end of selection. Tests which are in selection are skipped. Parameters:
+selectionId - selection id;
+testIds - tests ids;
+*/
+func (ctx *DbContext) AddSelectionTests(selectionId string, testIds []string) error {
+	col := ctx.Client.Database(DbName).Collection(TestSelectionCollection)
+
+	ids := append([]string{selectionId}, testIds...)
+	objectIds := make(map[string]primitive.ObjectID, len(ids))
+
+	for _, id := range ids {
+		objectId, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			return openerrors.InvalidIdErr{
+				Id:        id,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/test_selection_impl.go",
+						Method: "AddSelectionTests",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		objectIds[id] = objectId
+	}
+
+	objectTestIds := make([]primitive.ObjectID, 0, len(testIds))
+
+	for _, testId := range testIds {
+		objectTestIds = append(objectTestIds, objectIds[testId])
+	}
+
+	update := bson.D{{"$addToSet", bson.D{{"test_ids", bson.D{{"$each", objectTestIds}}}}}}
+
+	_, err := col.UpdateOne(context.Background(), bson.D{{"_id", objectIds[selectionId]}}, update)
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/test_selection_impl.go",
+				Method: "AddSelectionTests",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
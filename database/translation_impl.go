@@ -0,0 +1,359 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+)
+
+/*
+This file contains translation groups. Translations of course, category, stage or test have the same group id,
+so the best variant for languages of user is found in group.
+*/
+
+// translationCollections collections of translated content by translation kind
+var translationCollections = map[string]string{
+	common.TranslationCourse:   CourseCollection,
+	common.TranslationCategory: CategoryCollection,
+	common.TranslationStage:    StageCollection,
+	common.TranslationTest:     TestCollection,
+}
+
+// dbTranslation group and language of content
+type dbTranslation struct {
+	Id    primitive.ObjectID `bson:"_id"`             // Content id
+	Group primitive.ObjectID `bson:"group,omitempty"` // Translation group
+	Lang  string             `bson:"lang"`            // Content language
+}
+
+/*
+GetTranslations return variants of content from translation group. Content without group is the only variant.
+Returns nil, if content isn't found. Parameters:
+kind - translation kind;
+id - content id;
+*/
+func (ctx *DbContext) GetTranslations(kind string, id string) ([]*common.Translation, error) {
+	dbTranslations, err := ctx.findGroup(kind, id, "GetTranslations")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var translations []*common.Translation
+
+	for _, dbTranslation := range dbTranslations {
+		translations = append(translations, &common.Translation{Id: dbTranslation.Id.Hex(), Lang: dbTranslation.Lang})
+	}
+
+	return translations, nil
+}
+
+/*
+ResolveTranslation return id of the best variant of content for languages. If group hasn't variant
+of languages, content id is returned. Parameters:
+kind - translation kind;
+id - content id;
+langs - languages in order of preference;
+*/
+func (ctx *DbContext) ResolveTranslation(kind string, id string, langs []string) (string, error) {
+	dbTranslations, err := ctx.findGroup(kind, id, "ResolveTranslation")
+
+	if err != nil {
+		return "", err
+	}
+
+	best := id
+	bestRank := len(langs)
+
+	for _, dbTranslation := range dbTranslations {
+		rank := langRank(langs, dbTranslation.Lang)
+
+		// Content is kept, if variant has the same rank
+		if rank < bestRank || (rank == bestRank && dbTranslation.Id.Hex() == id) {
+			best = dbTranslation.Id.Hex()
+			bestRank = rank
+		}
+	}
+
+	return best, nil
+}
+
+/*
+LinkTranslation add translated content with its group to group of source content. Group can't have two
+variants of one language, unique index rejects concurrent link of courses and categories. Returns group id. Parameters:
+kind - translation kind;
+sourceId - source content id;
+translationId - translated content id;
+*/
+func (ctx *DbContext) LinkTranslation(kind string, sourceId string, translationId string) (string, error) {
+	variants, err := ctx.findGroup(kind, sourceId, "LinkTranslation")
+
+	if err != nil {
+		return "", err
+	}
+
+	translations, err := ctx.findGroup(kind, translationId, "LinkTranslation")
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(variants) == 0 || len(translations) == 0 {
+		return "", openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/translation_impl.go",
+				Method: "LinkTranslation",
+			},
+			Msg: "content isn't found",
+		}
+	}
+
+	langs := make(map[string]bool, len(variants))
+	group := primitive.NilObjectID
+	filter := bson.A{}
+
+	for _, variant := range variants {
+		langs[variant.Lang] = true
+		group = variant.Group
+		filter = append(filter, bson.D{{"_id", variant.Id}})
+	}
+
+	for _, translation := range translations {
+		// Content is already in group
+		if translation.Group == group && !group.IsZero() {
+			return group.Hex(), nil
+		}
+
+		if langs[translation.Lang] {
+			return "", openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/translation_impl.go",
+					Method: "LinkTranslation",
+				},
+				Msg: "translation group already has variant of language " + translation.Lang,
+			}
+		}
+
+		filter = append(filter, bson.D{{"_id", translation.Id}})
+	}
+
+	// Group id is id of the first content of group
+	if group.IsZero() {
+		group = variants[0].Id
+	}
+
+	col := ctx.Client.Database(DbName).Collection(translationCollections[kind])
+
+	session, err := ctx.Client.StartSession()
+
+	if err != nil {
+		return "", openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/translation_impl.go",
+				Method: "LinkTranslation",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer session.EndSession(context.Background())
+
+	// Groups are merged completely or aren't changed
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		return col.UpdateMany(sc, bson.D{{"$or", filter}}, bson.D{{"$set", bson.D{{"group", group}}}})
+	})
+
+	if mongo.IsDuplicateKeyError(err) {
+		return "", openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/translation_impl.go",
+				Method: "LinkTranslation",
+			},
+			Msg: "translation group already has variant of language",
+		}
+	}
+
+	if err != nil {
+		return "", openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/translation_impl.go",
+				Method: "LinkTranslation",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return group.Hex(), nil
+}
+
+/*
+translatedCourses return pipeline of courses, where every translation group is replaced with its best variant
+for languages. Variants are resolved before pagination, so group is listed once. Course is replaced only
+with variant of better language. Parameters:
+filter - courses filter;
+langs - languages in order of preference;
+*/
+func translatedCourses(filter bson.D, langs []string) mongo.Pipeline {
+	rank := bson.D{{"$let", bson.D{
+		{"vars", bson.D{{"i", bson.D{{"$indexOfArray", bson.A{langs, "$lang"}}}}}},
+		{"in", bson.D{{"$cond", bson.A{bson.D{{"$eq", bson.A{"$$i", -1}}}, len(langs), "$$i"}}}},
+	}}}
+
+	return mongo.Pipeline{
+		{{"$match", filter}},
+		{{"$addFields", bson.D{{"lang_rank", rank}}}},
+		{{"$sort", bson.D{{"lang_rank", 1}, {"_id", 1}}}},
+		{{"$group", bson.D{{"_id", bson.D{{"$ifNull", bson.A{"$group", "$_id"}}}}, {"doc", bson.D{{"$first", "$$ROOT"}}}}}},
+		{{"$replaceRoot", bson.D{{"newRoot", "$doc"}}}},
+		{{"$lookup", bson.D{
+			{"from", CourseCollection},
+			{"let", bson.D{{"group", "$group"}}},
+			{"pipeline", mongo.Pipeline{
+				{{"$match", bson.D{{"$expr", bson.D{{"$and", bson.A{
+					bson.D{{"$gt", bson.A{"$$group", nil}}},
+					bson.D{{"$eq", bson.A{"$group", "$$group"}}},
+				}}}}}}},
+				{{"$addFields", bson.D{{"lang_rank", rank}}}},
+				{{"$sort", bson.D{{"lang_rank", 1}, {"_id", 1}}}},
+				{{"$limit", 1}},
+			}},
+			{"as", "variants"},
+		}}},
+		{{"$replaceRoot", bson.D{{"newRoot", bson.D{{"$let", bson.D{
+			{"vars", bson.D{{"variant", bson.D{{"$arrayElemAt", bson.A{"$variants", 0}}}}}},
+			{"in", bson.D{{"$cond", bson.A{
+				bson.D{{"$and", bson.A{
+					bson.D{{"$gt", bson.A{bson.D{{"$size", "$variants"}}, 0}}},
+					bson.D{{"$lt", bson.A{"$$variant.lang_rank", "$lang_rank"}}},
+				}}},
+				"$$variant",
+				"$$ROOT",
+			}}}},
+		}}}}}}},
+		{{"$project", bson.D{{"variants", 0}, {"lang_rank", 0}}}},
+	}
+}
+
+/*
+findGroup return content and other variants of its translation group with languages. Parameters:
+kind - translation kind;
+id - content id;
+method - method name for errors;
+*/
+func (ctx *DbContext) findGroup(kind string, id string, method string) ([]*dbTranslation, error) {
+	collection, ok := translationCollections[kind]
+
+	if !ok {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/translation_impl.go",
+				Method: method,
+			},
+			Msg: "unknown translation kind " + kind,
+		}
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        id,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/translation_impl.go",
+					Method: method,
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	dbTranslations, err := ctx.findTranslations(collection, kind, bson.D{{"_id", objectId}})
+
+	if err != nil || len(dbTranslations) == 0 || dbTranslations[0].Group.IsZero() {
+		return dbTranslations, err
+	}
+
+	// Content is the first variant
+	dbVariants, err := ctx.findTranslations(collection, kind,
+		bson.D{{"group", dbTranslations[0].Group}, {"_id", bson.D{{"$ne", objectId}}}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(dbTranslations, dbVariants...), nil
+}
+
+/*
+findTranslations return groups and languages of matched content. Parameters:
+collection - collection of content;
+kind - translation kind;
+match - content filter;
+*/
+func (ctx *DbContext) findTranslations(collection string, kind string, match bson.D) ([]*dbTranslation, error) {
+	col := ctx.Client.Database(DbName).Collection(collection)
+
+	pipeline := append(mongo.Pipeline{bson.D{{"$match", match}}}, langStages(kind)...)
+	pipeline = append(pipeline, bson.D{{"$project", bson.D{{"group", 1}, {"lang", 1}}}})
+
+	cursor, err := col.Aggregate(context.Background(), pipeline)
+
+	var dbTranslations []*dbTranslation
+
+	if err == nil {
+		err = cursor.All(context.Background(), &dbTranslations)
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/translation_impl.go",
+				Method: "findTranslations",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return dbTranslations, nil
+}
+
+// langStages return pipeline stages, which add language of course to stages and tests
+func langStages(kind string) mongo.Pipeline {
+	pipeline := mongo.Pipeline{}
+
+	switch kind {
+	case common.TranslationTest:
+		pipeline = append(pipeline,
+			bson.D{{"$lookup", bson.D{{"from", StageCollection}, {"localField", "stage_id"}, {"foreignField", "_id"}, {"as", "stage"}}}},
+			bson.D{{"$addFields", bson.D{{"course_id", bson.D{{"$arrayElemAt", bson.A{"$stage.course_id", 0}}}}}}})
+		fallthrough
+	case common.TranslationStage:
+		pipeline = append(pipeline,
+			bson.D{{"$lookup", bson.D{{"from", CourseCollection}, {"localField", "course_id"}, {"foreignField", "_id"}, {"as", "course"}}}},
+			bson.D{{"$addFields", bson.D{{"lang", bson.D{{"$arrayElemAt", bson.A{"$course.lang", 0}}}}}}})
+	}
+
+	return pipeline
+}
+
+// langRank return position of language in languages of preference. Other languages have the lowest rank
+func langRank(langs []string, lang string) int {
+	for i, preferred := range langs {
+		if preferred == lang {
+			return i
+		}
+	}
+
+	return len(langs)
+}
@@ -202,3 +202,131 @@ func (ctx *DbContext) GetUserByLogin(login string) (*common.User, error) {
 
 	return user, nil
 }
+
+/*
+GetUser return user by id. If user doesn't exist, return nil. Parameters:
+userId - user id;
+*/
+func (ctx *DbContext) GetUser(userId string) (*common.User, error) {
+	col := ctx.Client.Database(DbName).Collection(UserCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/mongodb/user_impl.go",
+					Method: "GetUser",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbUser DbUser
+	err = col.FindOne(context.Background(), bson.D{{"_id", objectUserId}}).Decode(&dbUser)
+
+	if err != nil && err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/user_impl.go",
+				Method: "GetUser",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	user, err := dbUser.ToUser()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/mongodb/user_impl.go",
+				Method: "GetUser",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return user, nil
+}
+
+/*
+AddUserRating add points to user rating and save them for leaderboards. Parameters:
+userId - user id;
+courseId - course id, where points are earned. Empty id is used for points out of course;
+points - added points, negative points decrease rating;
+*/
+func (ctx *DbContext) AddUserRating(userId string, courseId string, points int) error {
+	col := ctx.Client.Database(DbName).Collection(UserCollection)
+
+	objectId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/user_impl.go",
+					Method: "AddUserRating",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	dbScoreEvent := DbScoreEvent{
+		UserId: objectId,
+		Metric: common.MetricRating,
+		Points: points,
+		Date:   primitive.NewDateTimeFromTime(ctx.Now()),
+	}
+
+	if len(courseId) > 0 {
+		dbScoreEvent.CourseId, err = primitive.ObjectIDFromHex(courseId)
+
+		if err != nil {
+			return openerrors.InvalidIdErr{
+				Id:        courseId,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/user_impl.go",
+						Method: "AddUserRating",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+	}
+
+	_, err = col.UpdateOne(context.Background(), bson.D{{"_id", objectId}}, bson.D{{"$inc", bson.D{{"rating", points}}}})
+
+	if err == nil {
+		_, err = ctx.Client.Database(DbName).Collection(ScoreEventCollection).InsertOne(context.Background(), dbScoreEvent)
+	}
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_impl.go",
+				Method: "AddUserRating",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package database
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"time"
+)
+
+// ClearUserProgress remove all data from user_progress collection
+func (ctx *DbContext) ClearUserProgress() error {
+	col := ctx.Client.Database(DbName).Collection(UserProgressCollection)
+
+	_, err := col.DeleteMany(context.Background(), bson.D{{}})
+
+	if err != nil {
+		return openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_progress_impl.go",
+				Method: "ClearUserProgress",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return nil
+}
+
+/*
+GetUserProgress return progress of user. If user has no progress, return nil. Parameters:
+userId - user id;
+*/
+func (ctx *DbContext) GetUserProgress(userId string) (*common.UserProgress, error) {
+	col := ctx.Client.Database(DbName).Collection(UserProgressCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/user_progress_impl.go",
+					Method: "GetUserProgress",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbProgress DbUserProgress
+
+	err = col.FindOne(context.Background(), bson.D{{"user_id", objectUserId}}).Decode(&dbProgress)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_progress_impl.go",
+				Method: "GetUserProgress",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	progress, err := dbProgress.ToUserProgress()
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_progress_impl.go",
+				Method: "GetUserProgress",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return progress, nil
+}
+
+/*
+IncEventCounter increment count of events of user and return new count. Progress is created
+with the first event. Parameters:
+userId - user id;
+eventType - event type;
+*/
+func (ctx *DbContext) IncEventCounter(userId string, eventType string) (int, error) {
+	col := ctx.Client.Database(DbName).Collection(UserProgressCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return 0, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/user_progress_impl.go",
+					Method: "IncEventCounter",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	if len(eventType) == 0 {
+		return 0, openerrors.FieldEmptyErr{
+			Field: "eventType",
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_progress_impl.go",
+				Method: "IncEventCounter",
+			},
+		}
+	}
+
+	update := bson.D{
+		{"$inc", bson.D{{"counters." + eventType, 1}}},
+		{"$setOnInsert", bson.D{
+			{"streak", 0},
+			{"longest_streak", 0},
+			{"last_active", primitive.DateTime(0)},
+			{"achievements", bson.A{}},
+		}},
+	}
+
+	ops := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var dbProgress DbUserProgress
+
+	err = col.FindOneAndUpdate(context.Background(), bson.D{{"user_id", objectUserId}}, update, ops).Decode(&dbProgress)
+
+	// The first events of user are saved concurrently, the counter is incremented again
+	if mongo.IsDuplicateKeyError(err) {
+		err = col.FindOneAndUpdate(context.Background(), bson.D{{"user_id", objectUserId}}, update, ops).Decode(&dbProgress)
+	}
+
+	if err != nil {
+		return 0, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_progress_impl.go",
+				Method: "IncEventCounter",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return dbProgress.Counters[eventType], nil
+}
+
+/*
+KeepStreak save activity of user in day. Returns current streak and true, if it's the first activity of day.
+Streak continues, if the last activity was the day before, otherwise it starts again. Parameters:
+userId - user id;
+date - activity date;
+*/
+func (ctx *DbContext) KeepStreak(userId string, date time.Time) (int, bool, error) {
+	col := ctx.Client.Database(DbName).Collection(UserProgressCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return 0, false, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/user_progress_impl.go",
+					Method: "KeepStreak",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	var dbProgress DbUserProgress
+
+	err = col.FindOne(context.Background(), bson.D{{"user_id", objectUserId}}).Decode(&dbProgress)
+
+	if err == mongo.ErrNoDocuments {
+		err = nil
+		dbProgress.UserId = objectUserId
+	}
+
+	if err != nil {
+		return 0, false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_progress_impl.go",
+				Method: "KeepStreak",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	day := date.UTC().Truncate(24 * time.Hour)
+	lastActive := dbProgress.LastActive.Time().UTC()
+
+	if dbProgress.LastActive > 0 && !lastActive.Before(day) {
+		return dbProgress.Streak, false, nil
+	}
+
+	streak := 1
+
+	if dbProgress.LastActive > 0 && lastActive.Equal(day.AddDate(0, 0, -1)) {
+		streak = dbProgress.Streak + 1
+	}
+
+	longest := dbProgress.LongestStreak
+
+	if streak > longest {
+		longest = streak
+	}
+
+	// The last active day is in filter, so concurrent activity of the same day is counted once
+	filter := bson.D{{"user_id", objectUserId}, {"last_active", dbProgress.LastActive}}
+	update := bson.D{
+		{"$set", bson.D{
+			{"streak", streak},
+			{"longest_streak", longest},
+			{"last_active", primitive.NewDateTimeFromTime(day)},
+		}},
+		{"$setOnInsert", bson.D{
+			{"counters", bson.D{}},
+			{"achievements", bson.A{}},
+		}},
+	}
+
+	ops := options.Update().SetUpsert(dbProgress.Id.IsZero())
+
+	result, err := col.UpdateOne(context.Background(), filter, update, ops)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return streak, false, nil
+	}
+
+	if err != nil {
+		return 0, false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_progress_impl.go",
+				Method: "KeepStreak",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return streak, result.ModifiedCount+result.UpsertedCount == 1, nil
+}
+
+/*
+AddAchievement save earned achievement of user. Returns false, if user earned it before. Parameters:
+userId - user id;
+achievementId - achievement id from config;
+*/
+func (ctx *DbContext) AddAchievement(userId string, achievementId string) (bool, error) {
+	col := ctx.Client.Database(DbName).Collection(UserProgressCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return false, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/user_progress_impl.go",
+					Method: "AddAchievement",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	filter := bson.D{{"user_id", objectUserId}, {"achievements", bson.D{{"$ne", achievementId}}}}
+	update := bson.D{{"$push", bson.D{{"achievements", achievementId}}}}
+
+	result, err := col.UpdateOne(context.Background(), filter, update)
+
+	if err != nil {
+		return false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_progress_impl.go",
+				Method: "AddAchievement",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	return result.ModifiedCount == 1, nil
+}
@@ -3,7 +3,12 @@ package database
 import (
 	"context"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"opencourse/common"
 	"opencourse/common/openerrors"
+	"time"
 )
 
 // ClearUserTests remove all data from user_tests collection
@@ -26,3 +31,389 @@ func (ctx *DbContext) ClearUserTests() error {
 
 	return nil
 }
+
+/*
+SaveTestResult save user attempt for test, update best score and credit lemmings for the first pass.
+Attempts limit and cooldown of test are checked, openerrors.AttemptDeniedErr is returned if attempt isn't allowed.
+Returns saved attempt and true, if test is passed by user first time. Attempt of quiz session is saved once,
+repeated save returns nil attempt. Parameters:
+userId - user id;
+courseId - course id of test stage;
+test - answered test;
+answer - user answer;
+result - grade result of answer;
+*/
+func (ctx *DbContext) SaveTestResult(userId string, courseId string, test *common.Test, answer *common.TestAnswer,
+	result *common.GradeResult) (*common.TestAttempt, bool, error) {
+
+	if test == nil || answer == nil || result == nil {
+		return nil, false, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_test_impl.go",
+				Method: "SaveTestResult",
+			},
+			Model: "test, answer or result",
+		}
+	}
+
+	ids := []string{userId, courseId, test.StageId, test.Id}
+
+	// Selection is set for tests drawn from question pools
+	if len(result.SelectionId) > 0 {
+		ids = append(ids, result.SelectionId)
+	}
+
+	if len(result.SessionId) > 0 {
+		ids = append(ids, result.SessionId)
+	}
+
+	objectIds := make(map[string]primitive.ObjectID, len(ids))
+
+	for _, id := range ids {
+		objectId, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			return nil, false, openerrors.InvalidIdErr{
+				Id:        id,
+				Converter: "ObjectIDFromHex",
+				Default: openerrors.DefaultErr{
+					BaseErr: openerrors.BaseErr{
+						File:   "database/user_test_impl.go",
+						Method: "SaveTestResult",
+					},
+					Msg: err.Error(),
+				},
+			}
+		}
+
+		objectIds[id] = objectId
+	}
+
+	session, err := ctx.Client.StartSession()
+
+	if err != nil {
+		return nil, false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_test_impl.go",
+				Method: "SaveTestResult",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	defer session.EndSession(context.Background())
+
+	var dbAttempt DbTestAttempt
+	firstPass, saved := false, false
+
+	// Concurrent attempts update the same user_tests document, so one of transactions is retried
+	// and sees the attempt of another
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		db := ctx.Client.Database(DbName)
+		filter := bson.D{{"user_id", objectIds[userId]}, {"test_id", objectIds[test.Id]}}
+		dateNow := ctx.Now()
+
+		// Session is submitted again after failure or concurrently, its saved attempts are skipped
+		if len(result.SessionId) > 0 {
+			sessionFilter := append(filter, bson.E{Key: "session_id", Value: objectIds[result.SessionId]})
+
+			count, err := db.Collection(TestAttemptCollection).CountDocuments(sc, sessionFilter)
+
+			if err != nil {
+				return nil, err
+			}
+
+			saved = count > 0
+
+			if saved {
+				return nil, nil
+			}
+		}
+
+		var dbUserTest DbUserTest
+		err := db.Collection(UserTestCollection).FindOne(sc, filter).Decode(&dbUserTest)
+
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+
+		err = checkAttempt(&dbUserTest, test, dateNow, "SaveTestResult")
+
+		if err != nil {
+			return nil, err
+		}
+
+		set := bson.D{
+			{"stage_id", objectIds[test.StageId]},
+			{"course_id", objectIds[courseId]},
+			{"date_update", primitive.NewDateTimeFromTime(dateNow)},
+		}
+
+		setOnInsert := bson.D{}
+
+		// Passed test stays passed after wrong answers
+		if result.IsPassed {
+			set = append(set, bson.E{Key: "is_passed", Value: true})
+		} else {
+			setOnInsert = append(setOnInsert, bson.E{Key: "is_passed", Value: false})
+		}
+
+		update := bson.D{
+			{"$max", bson.D{{"score", result.Score}}},
+			{"$inc", bson.D{{"attempts", 1}}},
+			{"$set", set},
+		}
+
+		if len(setOnInsert) > 0 {
+			update = append(update, bson.E{Key: "$setOnInsert", Value: setOnInsert})
+		}
+
+		_, err = db.Collection(UserTestCollection).UpdateOne(sc, filter, update, options.Update().SetUpsert(true))
+
+		if err != nil {
+			return nil, err
+		}
+
+		dbAttempt = DbTestAttempt{
+			Id:          primitive.NewObjectID(),
+			UserId:      objectIds[userId],
+			TestId:      objectIds[test.Id],
+			StageId:     objectIds[test.StageId],
+			CourseId:    objectIds[courseId],
+			Number:      dbUserTest.Attempts + 1,
+			Answer:      ToDbTestAnswer(answer),
+			Score:       result.Score,
+			IsPassed:    result.IsPassed,
+			DateCreate:  primitive.NewDateTimeFromTime(dateNow),
+			SelectionId: objectIds[result.SelectionId],
+			SessionId:   objectIds[result.SessionId],
+			Question:    result.Question,
+			Options:     result.Options,
+		}
+
+		_, err = db.Collection(TestAttemptCollection).InsertOne(sc, dbAttempt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		firstPass = result.IsPassed && !dbUserTest.IsPassed
+
+		if !firstPass {
+			return nil, nil
+		}
+
+		_, err = db.Collection(UserCollection).UpdateOne(sc, bson.D{{"_id", objectIds[userId]}},
+			bson.D{{"$inc", bson.D{{"lemmings", test.LemmingsCount}}}})
+
+		if err != nil || test.LemmingsCount == 0 {
+			return nil, err
+		}
+
+		_, err = db.Collection(ScoreEventCollection).InsertOne(sc, DbScoreEvent{
+			UserId:   objectIds[userId],
+			CourseId: objectIds[courseId],
+			Metric:   common.MetricLemmings,
+			Points:   test.LemmingsCount,
+			Date:     primitive.NewDateTimeFromTime(dateNow),
+		})
+
+		return nil, err
+	})
+
+	if denied, ok := err.(openerrors.AttemptDeniedErr); ok {
+		return nil, false, denied
+	}
+
+	if err != nil {
+		return nil, false, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_test_impl.go",
+				Method: "SaveTestResult",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	if saved {
+		return nil, false, nil
+	}
+
+	attempt, err := dbAttempt.ToTestAttempt()
+
+	if err != nil {
+		return nil, false, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_test_impl.go",
+				Method: "SaveTestResult",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	return attempt, firstPass, nil
+}
+
+// checkAttempt return openerrors.AttemptDeniedErr if attempts limit of test is reached or cooldown isn't over
+func checkAttempt(dbUserTest *DbUserTest, test *common.Test, dateNow time.Time, method string) error {
+	if test.MaxAttempts > 0 && dbUserTest.Attempts >= test.MaxAttempts {
+		return openerrors.AttemptDeniedErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_test_impl.go",
+				Method: method,
+			},
+			Attempts:    dbUserTest.Attempts,
+			MaxAttempts: test.MaxAttempts,
+		}
+	}
+
+	retryAfter := dbUserTest.DateUpdate.Time().Add(time.Duration(test.Cooldown) * time.Second)
+
+	if test.Cooldown > 0 && dbUserTest.Attempts > 0 && dateNow.Before(retryAfter) {
+		return openerrors.AttemptDeniedErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_test_impl.go",
+				Method: method,
+			},
+			Attempts:    dbUserTest.Attempts,
+			MaxAttempts: test.MaxAttempts,
+			RetryAfter:  retryAfter.UTC(),
+		}
+	}
+
+	return nil
+}
+
+/*
+GetPassedTests return ids of tests passed by user in course. Parameters:
+userId - user id;
+courseId - course id;
+*/
+func (ctx *DbContext) GetPassedTests(userId string, courseId string) (map[string]bool, error) {
+	col := ctx.Client.Database(DbName).Collection(UserTestCollection)
+
+	objectUserId, err := primitive.ObjectIDFromHex(userId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        userId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/user_test_impl.go",
+					Method: "GetPassedTests",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	objectCourseId, err := primitive.ObjectIDFromHex(courseId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        courseId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/user_test_impl.go",
+					Method: "GetPassedTests",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	filter := bson.D{{"user_id", objectUserId}, {"course_id", objectCourseId}, {"is_passed", true}}
+	ops := options.Find().SetProjection(bson.D{{"test_id", 1}})
+
+	cursor, err := col.Find(context.Background(), filter, ops)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_test_impl.go",
+				Method: "GetPassedTests",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	var dbUserTests []*DbUserTest
+
+	err = cursor.All(context.Background(), &dbUserTests)
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_test_impl.go",
+				Method: "GetPassedTests",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	passed := make(map[string]bool, len(dbUserTests))
+
+	for _, dbUserTest := range dbUserTests {
+		passed[dbUserTest.TestId.Hex()] = true
+	}
+
+	return passed, nil
+}
+
+/*
+GetCourseLearners return ids of users, who answered at least one test of course. Parameters:
+courseId - course id;
+*/
+func (ctx *DbContext) GetCourseLearners(courseId string) ([]string, error) {
+	col := ctx.Client.Database(DbName).Collection(UserTestCollection)
+
+	objectCourseId, err := primitive.ObjectIDFromHex(courseId)
+
+	if err != nil {
+		return nil, openerrors.InvalidIdErr{
+			Id:        courseId,
+			Converter: "ObjectIDFromHex",
+			Default: openerrors.DefaultErr{
+				BaseErr: openerrors.BaseErr{
+					File:   "database/user_test_impl.go",
+					Method: "GetCourseLearners",
+				},
+				Msg: err.Error(),
+			},
+		}
+	}
+
+	values, err := col.Distinct(context.Background(), "user_id", bson.D{{"course_id", objectCourseId}})
+
+	if err != nil {
+		return nil, openerrors.DbErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "database/user_test_impl.go",
+				Method: "GetCourseLearners",
+			},
+			DbName: ctx.DbName,
+			ConStr: ctx.ConStr,
+			DbErr:  err.Error(),
+		}
+	}
+
+	userIds := make([]string, 0, len(values))
+
+	for _, value := range values {
+		if objectUserId, ok := value.(primitive.ObjectID); ok {
+			userIds = append(userIds, objectUserId.Hex())
+		}
+	}
+
+	return userIds, nil
+}
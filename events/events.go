@@ -0,0 +1,100 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+This file contains in-process bus of domain events. Events are handled synchronously in order of subscription,
+so a handler sees changes, which are saved before publishing.
+*/
+
+// Event types of learner progress
+const (
+	TestAnswered   = "test_answered"   // Answer of test is saved
+	TestPassed     = "test_passed"     // Test is passed the first time
+	StageCompleted = "stage_completed" // Every test of stage is passed or quiz session of stage is passed
+	StreakKept     = "streak_kept"     // The first activity of day continues or starts streak. Value is streak days
+	CourseFinished = "course_finished" // Certificate of course is issued
+)
+
+// Event types of course content
+const (
+	CourseSaved  = "course_saved"  // Course is created or changed
+	StageSaved   = "stage_saved"   // Stage is created or changed
+	StageDeleted = "stage_deleted" // Stage is removed
+)
+
+// Event types of discussions, reviews and rewards
+const (
+	CommentReplied  = "comment_replied"  // Reply is added. Target is replied comment
+	ReviewReplied   = "review_replied"   // Course author replied to review. Target is review
+	ReviewModerated = "review_moderated" // Review is hidden or shown by moderator. Target is review, value is 1 for hidden
+	BadgeAwarded    = "badge_awarded"    // Badge is awarded to user. Target is badge assertion
+)
+
+// Event domain event of user
+type Event struct {
+	Type     string    // Event type
+	UserId   string    // User id
+	CourseId string    // Course id, if event is related to course
+	StageId  string    // Stage id, if event is related to stage
+	TestId   string    // Test id, if event is related to test
+	TargetId string    // Id of comment, review or badge assertion, if event is related to it
+	Value    int       // Value of event, for example streak days
+	Date     time.Time // Event date
+}
+
+// Handler handle event
+type Handler func(event Event) error
+
+// Bus dispatch events to subscribed handlers. Nil bus ignores events
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus return bus without handlers
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+/*
+Subscribe add handler of events. Parameters:
+eventType - event type. Empty type subscribes to every event;
+handler - event handler;
+*/
+func (bus *Bus) Subscribe(eventType string, handler Handler) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.handlers[eventType] = append(bus.handlers[eventType], handler)
+}
+
+/*
+Publish call handlers of event. Every handler is called, the first error is returned. Parameters:
+event - published event;
+*/
+func (bus *Bus) Publish(event Event) error {
+	if bus == nil {
+		return nil
+	}
+
+	// Handlers are copied, so handler can publish events
+	bus.mu.RLock()
+	handlers := append(append([]Handler(nil), bus.handlers[event.Type]...), bus.handlers[""]...)
+	bus.mu.RUnlock()
+
+	var result error
+
+	for _, handler := range handlers {
+		err := handler(event)
+
+		if err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
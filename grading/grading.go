@@ -0,0 +1,289 @@
+package grading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/sandbox"
+	"strings"
+)
+
+/*
+This file contains grading rules for test types. Score is from 0 to 1, test is passed only with full score.
+Lemmings are credited only for passed test. Code tests are graded by GradeCode, because they need sandbox.
+*/
+
+/*
+Grade check user answer for test and return result. Parameters:
+test - test with right answers;
+answer - user answer;
+*/
+func Grade(test *common.Test, answer *common.TestAnswer) (*common.GradeResult, error) {
+	if test == nil || answer == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "grading/grading.go",
+				Method: "Grade",
+			},
+			Model: "test or answer",
+		}
+	}
+
+	var score float64
+	var rule, matched string
+
+	switch {
+	case test.TestType == common.TestOption && test.OptionTest != nil:
+		score = gradeOptions(test.OptionTest.Options, answer.Options, false)
+	case test.TestType == common.TestMultiSelect && test.MultiSelectTest != nil:
+		score = gradeOptions(test.MultiSelectTest.Options, answer.Options, test.MultiSelectTest.PartialCredit)
+	case test.TestType == common.TestRewrite && test.RewriteTest != nil:
+		rule, matched = matchRewrite(test.RewriteTest, answer.Text)
+		score = boolScore(len(rule) > 0)
+	case test.TestType == common.TestOrdering && test.OrderingTest != nil:
+		score = gradeOrdering(test.OrderingTest.Items, answer.Order)
+	case test.TestType == common.TestMatching && test.MatchingTest != nil:
+		score = gradeMatching(test.MatchingTest.Pairs, answer.Pairs)
+	case test.TestType == common.TestCloze && test.ClozeTest != nil:
+		score = gradeCloze(test.ClozeTest.Blanks, answer.Blanks)
+	case test.TestType == common.TestNumeric && test.NumericTest != nil:
+		score = boolScore(math.Abs(answer.Number-test.NumericTest.Answer) <= test.NumericTest.Tolerance)
+	default:
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "grading/grading.go",
+				Method: "Grade",
+			},
+			Msg: fmt.Sprintf("test %s of type %s can't be graded", test.Id, test.TestType),
+		}
+	}
+
+	result := &common.GradeResult{Score: score, IsPassed: score >= 1, MatchedRule: rule, MatchedAnswer: matched}
+
+	if result.IsPassed {
+		result.LemmingsCount = test.LemmingsCount
+	}
+
+	return result, nil
+}
+
+// gradeOptions grade chosen options. Without partial credit only exact set of right options is accepted.
+// With partial credit score is (right chosen - wrong chosen) / right count, but not less than 0
+func gradeOptions(options []*common.Option, chosen []string, partialCredit bool) float64 {
+	isChosen := make(map[string]bool)
+
+	for _, answer := range chosen {
+		isChosen[answer] = true
+	}
+
+	rightCount, rightChosen, wrongChosen := 0, 0, 0
+
+	for _, option := range options {
+		if option.IsRight {
+			rightCount++
+		}
+
+		if !isChosen[option.Answer] {
+			continue
+		}
+
+		if option.IsRight {
+			rightChosen++
+		} else {
+			wrongChosen++
+		}
+	}
+
+	if rightCount == 0 {
+		return 0
+	}
+
+	if !partialCredit {
+		return boolScore(rightChosen == rightCount && wrongChosen == 0 && len(isChosen) == rightCount)
+	}
+
+	return math.Max(0, float64(rightChosen-wrongChosen)/float64(rightCount))
+}
+
+// gradeOrdering return part of items which are in the right position
+func gradeOrdering(items []string, order []string) float64 {
+	if len(items) == 0 || len(order) != len(items) {
+		return 0
+	}
+
+	right := 0
+
+	for i, item := range items {
+		if order[i] == item {
+			right++
+		}
+	}
+
+	return float64(right) / float64(len(items))
+}
+
+// gradeMatching return part of pairs which are matched right
+func gradeMatching(pairs []*common.MatchPair, answer map[string]string) float64 {
+	if len(pairs) == 0 {
+		return 0
+	}
+
+	right := 0
+
+	for _, pair := range pairs {
+		if value, ok := answer[pair.Left]; ok && value == pair.Right {
+			right++
+		}
+	}
+
+	return float64(right) / float64(len(pairs))
+}
+
+// gradeCloze return part of blanks which are filled right. Answers are compared without case and surrounding spaces
+func gradeCloze(blanks []*common.ClozeBlank, answers []string) float64 {
+	if len(blanks) == 0 {
+		return 0
+	}
+
+	right := 0
+
+	for i, blank := range blanks {
+		if i >= len(answers) {
+			break
+		}
+
+		for _, accepted := range blank.Answers {
+			if strings.EqualFold(strings.TrimSpace(answers[i]), strings.TrimSpace(accepted)) {
+				right++
+				break
+			}
+		}
+	}
+
+	return float64(right) / float64(len(blanks))
+}
+
+// boolScore return 1 for true and 0 for false
+func boolScore(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+/*
+GradeCode run user program for every case of code test and return result. Score is a part of passed cases.
+Input and output of hidden cases are not returned. Parameters:
+ctx - context, cancel it to stop program;
+executor - sandbox executor;
+test - code test;
+answer - user answer with program;
+*/
+func GradeCode(ctx context.Context, executor sandbox.Executor, test *common.Test, answer *common.TestAnswer) (*common.GradeResult, error) {
+	if test == nil || test.CodeTest == nil || answer == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "grading/grading.go",
+				Method: "GradeCode",
+			},
+			Model: "test or answer",
+		}
+	}
+
+	inputs := make([]string, 0, len(test.CodeTest.Cases))
+
+	for _, codeCase := range test.CodeTest.Cases {
+		inputs = append(inputs, codeCase.Input)
+	}
+
+	report, err := executor.Run(ctx, &sandbox.Program{Language: test.CodeTest.Language, Source: answer.Code}, inputs)
+
+	if err != nil {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "grading/grading.go",
+				Method: "GradeCode",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	result := &common.GradeResult{CompileOutput: report.CompileOutput}
+
+	if !report.Compiled {
+		return result, nil
+	}
+
+	if len(report.Results) != len(test.CodeTest.Cases) {
+		return nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "grading/grading.go",
+				Method: "GradeCode",
+			},
+			Msg: fmt.Sprintf("executor returned %d results for %d cases", len(report.Results), len(test.CodeTest.Cases)),
+		}
+	}
+
+	passed := 0
+
+	for i, codeCase := range test.CodeTest.Cases {
+		run := report.Results[i]
+
+		caseResult := &common.CaseResult{
+			Passed:   run.ExitCode == 0 && sameOutput(run.Stdout, codeCase.ExpectedOutput),
+			Hidden:   codeCase.Hidden,
+			Duration: run.Duration.Milliseconds(),
+		}
+
+		switch {
+		case run.TimedOut:
+			caseResult.Error = "time limit exceeded"
+		case run.Truncated:
+			caseResult.Error = "output limit exceeded"
+		case run.ExitCode != 0:
+			caseResult.Error = fmt.Sprintf("exit code %d", run.ExitCode)
+		}
+
+		if !codeCase.Hidden {
+			caseResult.Input = codeCase.Input
+			caseResult.Output = run.Stdout
+
+			if len(run.Stderr) > 0 {
+				caseResult.Error = strings.TrimSpace(caseResult.Error + "\n" + run.Stderr)
+			}
+		}
+
+		if caseResult.Passed {
+			passed++
+		}
+
+		result.Cases = append(result.Cases, caseResult)
+	}
+
+	result.Score = float64(passed) / float64(len(test.CodeTest.Cases))
+	result.IsPassed = result.Score >= 1
+
+	if result.IsPassed {
+		result.LemmingsCount = test.LemmingsCount
+	}
+
+	return result, nil
+}
+
+// sameOutput compare program outputs without trailing spaces of lines and trailing empty lines
+func sameOutput(actual string, expected string) bool {
+	normalize := func(output string) string {
+		lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " \t")
+		}
+
+		return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	}
+
+	return normalize(actual) == normalize(expected)
+}
@@ -0,0 +1,283 @@
+package grading
+
+import (
+	"context"
+	"errors"
+	"opencourse/common"
+	"opencourse/sandbox"
+	"testing"
+)
+
+// TestGradeMultiSelect without partial credit only exact set of right options is accepted
+func TestGradeMultiSelect(t *testing.T) {
+	multiSelect := &common.MultiSelectTest{Options: []*common.Option{
+		{Answer: "a", IsRight: true},
+		{Answer: "b", IsRight: true},
+		{Answer: "c"},
+	}}
+
+	test := &common.Test{TestType: common.TestMultiSelect, LemmingsCount: 2, MultiSelectTest: multiSelect}
+
+	for _, item := range []struct {
+		options []string
+		partial bool
+		score   float64
+	}{
+		{[]string{"a", "b"}, false, 1},
+		{[]string{"b", "a"}, false, 1},
+		{[]string{"a"}, false, 0},
+		{[]string{"a", "b", "c"}, false, 0},
+		{[]string{"a"}, true, 0.5},
+		{[]string{"a", "b", "c"}, true, 0.5},
+		{[]string{"a", "c"}, true, 0},
+		{[]string{"c"}, true, 0},
+	} {
+		multiSelect.PartialCredit = item.partial
+
+		result, err := Grade(test, &common.TestAnswer{Options: item.options})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Score != item.score || result.IsPassed != (item.score == 1) {
+			t.Errorf("options %v, partial %v: expected score %v, got %+v", item.options, item.partial, item.score, result)
+		}
+
+		if result.IsPassed != (result.LemmingsCount == 2) {
+			t.Errorf("lemmings must be credited only for passed test, got %+v", result)
+		}
+	}
+}
+
+// TestGradeOrdering score is part of items in the right position, order of other length isn't graded
+func TestGradeOrdering(t *testing.T) {
+	test := &common.Test{TestType: common.TestOrdering, OrderingTest: &common.OrderingTest{Items: []string{"a", "b", "c", "d"}}}
+
+	for _, item := range []struct {
+		order []string
+		score float64
+	}{
+		{[]string{"a", "b", "c", "d"}, 1},
+		{[]string{"a", "b", "d", "c"}, 0.5},
+		{[]string{"d", "c", "b", "a"}, 0},
+		{[]string{"a", "b", "c"}, 0},
+	} {
+		result, err := Grade(test, &common.TestAnswer{Order: item.order})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Score != item.score {
+			t.Errorf("order %v: expected score %v, got %v", item.order, item.score, result.Score)
+		}
+	}
+}
+
+// TestGradeMatching score is part of pairs, which are matched right
+func TestGradeMatching(t *testing.T) {
+	test := &common.Test{TestType: common.TestMatching, MatchingTest: &common.MatchingTest{Pairs: []*common.MatchPair{
+		{Left: "go", Right: "gopher"},
+		{Left: "rust", Right: "crab"},
+	}}}
+
+	for _, item := range []struct {
+		pairs map[string]string
+		score float64
+	}{
+		{map[string]string{"go": "gopher", "rust": "crab"}, 1},
+		{map[string]string{"go": "gopher", "rust": "gopher"}, 0.5},
+		{map[string]string{"go": "crab"}, 0},
+		{nil, 0},
+	} {
+		result, err := Grade(test, &common.TestAnswer{Pairs: item.pairs})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Score != item.score {
+			t.Errorf("pairs %v: expected score %v, got %v", item.pairs, item.score, result.Score)
+		}
+	}
+}
+
+// TestGradeCloze blanks are compared without case and surrounding spaces, missing blanks aren't right
+func TestGradeCloze(t *testing.T) {
+	test := &common.Test{TestType: common.TestCloze, ClozeTest: &common.ClozeTest{Blanks: []*common.ClozeBlank{
+		{Answers: []string{"func"}},
+		{Answers: []string{"return", "ret"}},
+	}}}
+
+	for _, item := range []struct {
+		blanks []string
+		score  float64
+	}{
+		{[]string{" FUNC ", "ret"}, 1},
+		{[]string{"func", "break"}, 0.5},
+		{[]string{"func"}, 0.5},
+		{nil, 0},
+	} {
+		result, err := Grade(test, &common.TestAnswer{Blanks: item.blanks})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Score != item.score {
+			t.Errorf("blanks %v: expected score %v, got %v", item.blanks, item.score, result.Score)
+		}
+	}
+}
+
+// TestGradeNumeric answer is right within tolerance
+func TestGradeNumeric(t *testing.T) {
+	test := &common.Test{TestType: common.TestNumeric, NumericTest: &common.NumericTest{Answer: 3.14, Tolerance: 0.01}}
+
+	for number, passed := range map[float64]bool{3.14: true, 3.145: true, 3.135: true, 3.16: false, 0: false} {
+		result, err := Grade(test, &common.TestAnswer{Number: number})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.IsPassed != passed {
+			t.Errorf("number %v: expected passed %v, got %v", number, passed, result.IsPassed)
+		}
+	}
+}
+
+// TestGradeErr test without content of its type and nil answer aren't graded
+func TestGradeErr(t *testing.T) {
+	for _, test := range []*common.Test{
+		{TestType: common.TestOrdering},
+		{TestType: "unknown"},
+		nil,
+	} {
+		_, err := Grade(test, &common.TestAnswer{})
+
+		if err == nil {
+			t.Errorf("test %+v mustn't be graded", test)
+		}
+	}
+
+	_, err := Grade(&common.Test{TestType: common.TestNumeric, NumericTest: &common.NumericTest{}}, nil)
+
+	if err == nil {
+		t.Error("nil answer mustn't be graded")
+	}
+}
+
+// fakeExecutor executor, which returns prepared report
+type fakeExecutor struct {
+	report *sandbox.Report
+	err    error
+	inputs []string
+}
+
+func (executor *fakeExecutor) Run(ctx context.Context, program *sandbox.Program, inputs []string) (*sandbox.Report, error) {
+	executor.inputs = inputs
+
+	return executor.report, executor.err
+}
+
+func getCodeTest() *common.Test {
+	return &common.Test{
+		TestType:      common.TestCode,
+		LemmingsCount: 3,
+		CodeTest: &common.CodeTest{
+			Language: sandbox.LanguageGo,
+			Cases: []*common.CodeCase{
+				{Input: "1 2", ExpectedOutput: "3"},
+				{Input: "2 2", ExpectedOutput: "4", Hidden: true},
+			},
+		},
+	}
+}
+
+// TestGradeCode
+func TestGradeCode(t *testing.T) {
+	executor := &fakeExecutor{report: &sandbox.Report{
+		Compiled: true,
+		Results: []*sandbox.Result{
+			{Stdout: "3  \n\n"},
+			{Stdout: "4\n", Stderr: "debug"},
+		},
+	}}
+
+	result, err := GradeCode(context.Background(), executor, getCodeTest(), &common.TestAnswer{Code: "package main"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(executor.inputs) != 2 || executor.inputs[1] != "2 2" {
+		t.Errorf("executor got wrong inputs %v", executor.inputs)
+	}
+
+	if !result.IsPassed || result.Score != 1 || result.LemmingsCount != 3 {
+		t.Errorf("right program isn't passed: %+v", result)
+	}
+
+	// Input, output and errors of hidden case aren't returned
+	if hidden := result.Cases[1]; !hidden.Hidden || hidden.Input != "" || hidden.Output != "" || hidden.Error != "" {
+		t.Errorf("hidden case is returned: %+v", hidden)
+	}
+
+	if shown := result.Cases[0]; shown.Input != "1 2" || shown.Output != "3  \n\n" {
+		t.Errorf("shown case isn't returned: %+v", shown)
+	}
+}
+
+// TestGradeCodeFailed
+func TestGradeCodeFailed(t *testing.T) {
+	executor := &fakeExecutor{report: &sandbox.Report{
+		Compiled: true,
+		Results: []*sandbox.Result{
+			{Stdout: "3", ExitCode: 2, Stderr: "panic"},
+			{TimedOut: true, ExitCode: -1},
+		},
+	}}
+
+	result, err := GradeCode(context.Background(), executor, getCodeTest(), &common.TestAnswer{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.IsPassed || result.Score != 0 || result.LemmingsCount != 0 {
+		t.Errorf("failed program is passed: %+v", result)
+	}
+
+	if result.Cases[0].Error != "exit code 2\npanic" || result.Cases[1].Error != "time limit exceeded" {
+		t.Errorf("unexpected case errors %q and %q", result.Cases[0].Error, result.Cases[1].Error)
+	}
+
+	// Compile error is returned without cases
+	executor.report = &sandbox.Report{CompileOutput: "syntax error"}
+
+	result, err = GradeCode(context.Background(), executor, getCodeTest(), &common.TestAnswer{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.IsPassed || result.CompileOutput != "syntax error" || len(result.Cases) != 0 {
+		t.Errorf("compile error is graded wrong: %+v", result)
+	}
+}
+
+// TestGradeCodeErr executor errors and incomplete reports are returned as errors
+func TestGradeCodeErr(t *testing.T) {
+	executors := []*fakeExecutor{
+		{err: errors.New("jail setup")},
+		{report: &sandbox.Report{Compiled: true, Results: []*sandbox.Result{{Stdout: "3"}}}},
+	}
+
+	for _, executor := range executors {
+		if _, err := GradeCode(context.Background(), executor, getCodeTest(), &common.TestAnswer{}); err == nil {
+			t.Errorf("error must be returned for report %+v", executor.report)
+		}
+	}
+}
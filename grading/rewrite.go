@@ -0,0 +1,176 @@
+package grading
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"opencourse/common"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+This file contains answer matching of rewrite tests. Rules are checked in order: exact, normalized, pattern, fuzzy.
+The first matched rule is reported. Empty answer is never matched.
+*/
+
+/*
+matchRewrite check answer of rewrite test and return matched rule and accepted answer or pattern.
+Rule is empty if answer doesn't match. Parameters:
+test - rewrite test;
+answer - user answer;
+*/
+func matchRewrite(test *common.RewriteTest, answer string) (string, string) {
+	matching := test.Matching
+
+	if matching == nil {
+		matching = &common.RewriteMatching{}
+	}
+
+	accepted := append([]string{test.RightAnswer}, matching.AcceptedAnswers...)
+	answer = strings.TrimSpace(answer)
+	normalized := normalizeAnswer(answer, matching)
+
+	// Empty answer isn't matched even by pattern or distance
+	if len(normalized) == 0 {
+		return "", ""
+	}
+
+	for _, value := range accepted {
+		if answer == strings.TrimSpace(value) {
+			return common.MatchExact, value
+		}
+	}
+
+	for _, value := range accepted {
+		if normalized == normalizeAnswer(value, matching) {
+			return common.MatchNormalized, value
+		}
+	}
+
+	for _, pattern := range matching.Patterns {
+		expression := "^(?:" + pattern + ")$"
+
+		if matching.IgnoreCase {
+			expression = "(?i)" + expression
+		}
+
+		re, err := regexp.Compile(expression)
+
+		// Patterns are checked on test creation, so invalid pattern is skipped
+		if err != nil {
+			continue
+		}
+
+		if re.MatchString(answer) || re.MatchString(normalized) {
+			return common.MatchPattern, pattern
+		}
+	}
+
+	if matching.MaxDistance > 0 {
+		for _, value := range accepted {
+			expected := normalizeAnswer(value, matching)
+
+			if distance := levenshtein(normalized, expected); distance <= fuzzyDistance(expected, matching.MaxDistance) {
+				return common.MatchFuzzy, value
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// fuzzyDistance return max distance for accepted answer. It is not more than a third of answer length,
+// so short answers aren't matched by other short words
+func fuzzyDistance(expected string, maxDistance int) int {
+	return minInt(maxDistance, utf8.RuneCountInString(expected)/3)
+}
+
+// normalizeAnswer apply normalization settings to answer
+func normalizeAnswer(answer string, matching *common.RewriteMatching) string {
+	if matching.UnicodeNormalize {
+		answer = norm.NFKC.String(answer)
+	}
+
+	if matching.FoldDiacritics {
+		answer = foldDiacritics(answer)
+	}
+
+	if matching.IgnoreCase {
+		answer = strings.ToLower(answer)
+	}
+
+	if matching.IgnorePunctuation {
+		answer = strings.Map(func(r rune) rune {
+			if unicode.IsPunct(r) {
+				return -1
+			}
+
+			return r
+		}, answer)
+	}
+
+	if matching.IgnoreWhitespace {
+		answer = strings.Join(strings.Fields(answer), " ")
+	}
+
+	return strings.TrimSpace(answer)
+}
+
+// foldDiacritics remove combining marks from decomposed text and replace "ß" by "ss"
+func foldDiacritics(text string) string {
+	var builder strings.Builder
+
+	for _, r := range norm.NFD.String(text) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r == 'ß':
+			builder.WriteString("ss")
+		case r == 'ẞ':
+			builder.WriteString("SS")
+		default:
+			builder.WriteRune(r)
+		}
+	}
+
+	return norm.NFC.String(builder.String())
+}
+
+// levenshtein return edit distance between strings in runes
+func levenshtein(a string, b string) int {
+	first, second := []rune(a), []rune(b)
+	previous := make([]int, len(second)+1)
+	current := make([]int, len(second)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(first); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(second); j++ {
+			cost := 1
+
+			if first[i-1] == second[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(second)]
+}
+
+// minInt return the smallest of two numbers
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
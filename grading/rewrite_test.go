@@ -0,0 +1,116 @@
+package grading
+
+import (
+	"opencourse/common"
+	"testing"
+)
+
+// TestLevenshtein
+func TestLevenshtein(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"", "func", 4},
+		{"func", "func", 0},
+		{"func", "fucn", 2},
+		{"kitten", "sitting", 3},
+		{"функция", "фукция", 1},
+		{"ß", "ss", 2},
+	}
+
+	for _, c := range cases {
+		if distance := levenshtein(c.a, c.b); distance != c.distance {
+			t.Errorf("levenshtein(%q, %q) = %d, expected %d", c.a, c.b, distance, c.distance)
+		}
+
+		if distance := levenshtein(c.b, c.a); distance != c.distance {
+			t.Errorf("levenshtein(%q, %q) = %d, expected %d", c.b, c.a, distance, c.distance)
+		}
+	}
+}
+
+// TestFoldDiacritics
+func TestFoldDiacritics(t *testing.T) {
+	cases := map[string]string{
+		"café":   "cafe",
+		"Ёлка":   "Елка",
+		"über":   "uber",
+		"Straße": "Strasse",
+		"STRAẞE": "STRASSE",
+		"naïve":  "naive",
+		"plain":  "plain",
+		"é":     "e",
+	}
+
+	for text, expected := range cases {
+		if folded := foldDiacritics(text); folded != expected {
+			t.Errorf("foldDiacritics(%q) = %q, expected %q", text, folded, expected)
+		}
+	}
+}
+
+// TestNormalizeAnswer
+func TestNormalizeAnswer(t *testing.T) {
+	cases := []struct {
+		answer   string
+		matching common.RewriteMatching
+		expected string
+	}{
+		{"  Func ", common.RewriteMatching{}, "Func"},
+		{"Func", common.RewriteMatching{IgnoreCase: true}, "func"},
+		{"a \t b\n c", common.RewriteMatching{IgnoreWhitespace: true}, "a b c"},
+		{"a, b!", common.RewriteMatching{IgnorePunctuation: true}, "a b"},
+		{"ﬁ ①", common.RewriteMatching{UnicodeNormalize: true}, "fi 1"},
+		{"Ёж", common.RewriteMatching{FoldDiacritics: true, IgnoreCase: true}, "еж"},
+		{"...", common.RewriteMatching{IgnorePunctuation: true}, ""},
+	}
+
+	for _, c := range cases {
+		if normalized := normalizeAnswer(c.answer, &c.matching); normalized != c.expected {
+			t.Errorf("normalizeAnswer(%q, %+v) = %q, expected %q", c.answer, c.matching, normalized, c.expected)
+		}
+	}
+}
+
+// TestMatchRewrite
+func TestMatchRewrite(t *testing.T) {
+	test := &common.RewriteTest{
+		RightAnswer: "function",
+		Matching: &common.RewriteMatching{
+			IgnoreCase:        true,
+			IgnorePunctuation: true,
+			AcceptedAnswers:   []string{"func", "fn"},
+			Patterns:          []string{`f.*`},
+			MaxDistance:       5,
+		},
+	}
+
+	cases := []struct {
+		answer string
+		rule   string
+	}{
+		{"func", common.MatchExact},
+		{"FUNC!", common.MatchNormalized},
+		{"fun", common.MatchPattern},
+		{"unction", common.MatchFuzzy},
+		// Distance is capped by answer length: 8 runes allow only 2 edits
+		{"action", ""},
+		{"", ""},
+		{"?!", ""},
+	}
+
+	for _, c := range cases {
+		if rule, _ := matchRewrite(test, c.answer); rule != c.rule {
+			t.Errorf("answer %q matched rule %q, expected %q", c.answer, rule, c.rule)
+		}
+	}
+
+	// Empty answer isn't matched by pattern, which matches empty string
+	anything := &common.RewriteTest{RightAnswer: "a", Matching: &common.RewriteMatching{Patterns: []string{`.*`}}}
+
+	if rule, _ := matchRewrite(anything, " "); rule != "" {
+		t.Errorf("empty answer matched rule %q", rule)
+	}
+}
@@ -0,0 +1,95 @@
+package leaderboards
+
+import (
+	"context"
+	"golang.org/x/exp/slices"
+	"opencourse/common"
+	"opencourse/database"
+	"time"
+)
+
+/*
+This file contains refresh of leaderboards. Leaderboards are read from snapshots, which are computed by
+background job, so requests don't aggregate users and score events. Every refresh computes all leaderboards
+into new generation and makes it current after the last one is computed.
+*/
+
+// Metrics, scopes and windows of computed leaderboards
+var (
+	Metrics = []string{common.MetricRating, common.MetricLemmings}
+	Scopes  = []string{common.ScopeGlobal, common.ScopeCategory, common.ScopeCourse}
+	Windows = []string{common.WindowAll, common.WindowWeek, common.WindowMonth}
+)
+
+/*
+WindowStart return start date of time window. Zero date is returned for all time. Parameters:
+window - time window;
+now - current date;
+*/
+func WindowStart(window string, now time.Time) time.Time {
+	switch window {
+	case common.WindowWeek:
+		return now.AddDate(0, 0, -7)
+	case common.WindowMonth:
+		return now.AddDate(0, 0, -30)
+	}
+
+	return time.Time{}
+}
+
+/*
+Valid check that leaderboard is computed. Parameters:
+metric - ranking metric;
+scope - leaderboard scope;
+window - time window;
+*/
+func Valid(metric string, scope string, window string) bool {
+	return slices.Contains(Metrics, metric) && slices.Contains(Scopes, scope) && slices.Contains(Windows, window)
+}
+
+/*
+Refresh compute every leaderboard and make snapshot current. Parameters:
+db - database context;
+*/
+func Refresh(db *database.DbContext) error {
+	now := db.Now()
+	generation := now.UnixNano()
+
+	for _, metric := range Metrics {
+		for _, scope := range Scopes {
+			for _, window := range Windows {
+				_, err := db.RefreshLeaderboard(generation, metric, scope, window, WindowStart(window, now))
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return db.CommitLeaderboards(generation, now)
+}
+
+/*
+RunRefresh refresh leaderboards every interval until context is canceled. Waiting uses clock of database
+context. Parameters:
+ctx - context, cancel it to stop job;
+db - database context;
+interval - interval between refreshes;
+onErr - error handler. Job isn't stopped by errors;
+*/
+func RunRefresh(ctx context.Context, db *database.DbContext, interval time.Duration, onErr func(error)) {
+	for {
+		err := Refresh(db)
+
+		if err != nil {
+			onErr(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-db.Clock.After(interval):
+		}
+	}
+}
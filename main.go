@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httplog"
 	"github.com/go-chi/jwtauth/v5"
 	"net/http"
+	"opencourse/achievements"
+	"opencourse/badges"
 	"opencourse/database"
+	"opencourse/leaderboards"
+	"opencourse/notifications"
 	v1 "opencourse/openrouters/v1"
+	"opencourse/quizsession"
+	"opencourse/sandbox"
+	"opencourse/search"
 	"os"
+	"time"
 )
 
 func main() {
@@ -19,9 +28,15 @@ func main() {
 	smtpAccount := os.Getenv("OPENCOURSE_SMTP_ACCOUNT")
 	smtpAccountPass := os.Getenv("OPENCOURSE_SMTP_ACCOUNT_PASS")
 	baseEndpoint := os.Getenv("OPENCOURSE_ENDPOINT")
+	mediaDir := os.Getenv("OPENCOURSE_MEDIA_DIR")
+	sandboxDir := os.Getenv("OPENCOURSE_SANDBOX_DIR")
+	achievementsPath := os.Getenv("OPENCOURSE_ACHIEVEMENTS")
+	searchIndexPath := os.Getenv("OPENCOURSE_SEARCH_INDEX")
+	badgeKeyPath := os.Getenv("OPENCOURSE_BADGE_KEY")
 
 	dbContext := database.DbContext{}
 	dbContext.Defaults(conStr, smtpAccount, smtpAccountPass, baseEndpoint)
+	dbContext.MediaDir = mediaDir
 
 	tokenAuth := jwtauth.New("HS256", []byte(sign), nil)
 
@@ -37,6 +52,20 @@ func main() {
 		}
 	}()
 
+	err = dbContext.EnsureIndexes()
+	if err != nil {
+		panic(err)
+	}
+
+	// Run CLI subcommand if it's set
+	if len(os.Args) > 1 {
+		err := runCommand(&dbContext, os.Args[1:])
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	logger := httplog.NewLogger("openlog", httplog.Options{
 		JSON:    true,
 		Concise: true,
@@ -45,11 +74,79 @@ func main() {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
+	// Secrets of url parameters aren't written to log
+	r.Use(v1.RedactQuery("jwt", "ticket"))
 	r.Use(httplog.RequestLogger(logger))
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Heartbeat("/ping"))
 
-	r.Mount("/v1", v1.RouteTable(dbContext, tokenAuth))
+	executor := sandbox.NewLocalExecutor(sandboxDir, sandbox.DefaultLimits)
+
+	achievementsConfig, err := achievements.LoadConfig(achievementsPath)
+	if err != nil {
+		panic(err)
+	}
+
+	// Engine rewards learners on events, which are published by routes and quiz sessions
+	engine := achievements.NewEngine(&dbContext, achievementsConfig)
+	engine.Subscribe(dbContext.Events)
+
+	// Courses are searched by text indexes of db, if local index isn't set
+	var searchIndex search.Index = search.NewMongoIndex(&dbContext)
+
+	if len(searchIndexPath) > 0 {
+		bleveIndex, created, err := search.OpenBleveIndex(&dbContext, searchIndexPath, func(err error) {
+			logger.Error().Err(err).Msg("update of search index")
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		defer func() {
+			_ = bleveIndex.Close()
+		}()
+
+		bleveIndex.Subscribe(dbContext.Events)
+
+		if created {
+			go func() {
+				err := bleveIndex.Reindex()
+				if err != nil {
+					logger.Error().Err(err).Msg("reindex of search index")
+				}
+			}()
+		}
+
+		searchIndex = bleveIndex
+	}
+
+	// Notifications are saved on events and sent to connected streams
+	hub := notifications.NewHub()
+	notifications.NewService(&dbContext, hub, func(err error) {
+		logger.Error().Err(err).Msg("notification of users")
+	}).Subscribe(dbContext.Events)
+
+	// Open Badges 3.0 credentials are signed by issuer key, they aren't issued without it
+	var badgeSigner *badges.Signer
+
+	if len(badgeKeyPath) > 0 {
+		badgeSigner, err = badges.LoadSigner(badgeKeyPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	r.Mount("/v1", v1.RouteTable(dbContext, tokenAuth, executor, engine, searchIndex, hub, badgeSigner))
+
+	// Grade quiz sessions which deadline is passed
+	go quizsession.RunAutoSubmit(context.Background(), &dbContext, executor, time.Minute, func(err error) {
+		logger.Error().Err(err).Msg("auto-submit of quiz sessions")
+	})
+
+	// Leaderboards are read from snapshots
+	go leaderboards.RunRefresh(context.Background(), &dbContext, 10*time.Minute, func(err error) {
+		logger.Error().Err(err).Msg("refresh of leaderboards")
+	})
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write([]byte("Welcome to OpenCourses REST API"))
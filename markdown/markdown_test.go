@@ -0,0 +1,229 @@
+package markdown
+
+import (
+	"fmt"
+	"opencourse/common"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func getTests() []*common.Test {
+	return []*common.Test{
+		{
+			TestType: common.TestOption,
+			OptionTest: &common.OptionTest{
+				Question: "What is goroutine?",
+				Options:  []*common.Option{{Answer: "Lightweight thread", IsRight: true}, {Answer: "Package manager"}},
+			},
+		},
+		{
+			TestType:    common.TestMultiSelect,
+			MaxAttempts: 3,
+			Cooldown:    60,
+			MultiSelectTest: &common.MultiSelectTest{
+				Question:      "Reference types",
+				Options:       []*common.Option{{Answer: "map", IsRight: true}, {Answer: "int"}},
+				PartialCredit: true,
+			},
+		},
+		{
+			TestType: common.TestRewrite,
+			Pool:     "basics",
+			RewriteTest: &common.RewriteTest{
+				Question:    "Keyword of function\nin Go",
+				RightAnswer: "func",
+				Matching: &common.RewriteMatching{
+					IgnoreCase:      true,
+					FoldDiacritics:  true,
+					AcceptedAnswers: []string{"функция"},
+					Patterns:        []string{`^fu?nc$`},
+					MaxDistance:     1,
+				},
+			},
+		},
+		{
+			TestType:     common.TestOrdering,
+			OrderingTest: &common.OrderingTest{Question: "Order", Items: []string{"package", "import", "func"}},
+		},
+		{
+			TestType: common.TestMatching,
+			MatchingTest: &common.MatchingTest{
+				Question: "Match",
+				Pairs:    []*common.MatchPair{{Left: "int", Right: "0"}, {Left: "string", Right: `""`}},
+			},
+		},
+		{
+			TestType: common.TestCloze,
+			ClozeTest: &common.ClozeTest{
+				Text:   "{{1}} main\n\nfunc {{2}}() {}",
+				Blanks: []*common.ClozeBlank{{Answers: []string{"package"}}, {Answers: []string{"main", "Main"}}},
+			},
+		},
+		{
+			TestType:    common.TestNumeric,
+			NumericTest: &common.NumericTest{Question: "Bits in byte", Answer: 8, Tolerance: 0.5},
+		},
+		{
+			TestType: common.TestCode,
+			CodeTest: &common.CodeTest{
+				Question:    "Print sum",
+				Language:    "go",
+				StarterCode: "package main\n\nfunc main() {\n\t// sum\n}\n",
+				Cases: []*common.CodeCase{
+					{Input: "1 2", ExpectedOutput: "3"},
+					{Input: "", ExpectedOutput: "0\n", Hidden: true},
+				},
+			},
+		},
+	}
+}
+
+func getMarkdownBundle(stagesCount int) *common.CourseBundle {
+	courseBundle := &common.CourseBundle{
+		Version:  common.BundleVersion,
+		Id:       "golang",
+		Course:   &common.Course{Id: "golang", Name: "The greatest golang", Lang: common.LangEn},
+		Category: &common.CategoryRef{Name: "Programming", Lang: common.LangEn},
+	}
+
+	for i := 0; i < stagesCount; i++ {
+		stageId := fmt.Sprintf("stage%d", i+1)
+
+		courseBundle.Stages = append(courseBundle.Stages, &common.BundleStage{
+			Stage: &common.Stage{
+				Id:          stageId,
+				Name:        fmt.Sprintf("Stage %d", i+1),
+				OrderNumber: i + 1,
+				Content:     &common.PostContent{Body: "package main"},
+			},
+		})
+	}
+
+	return courseBundle
+}
+
+// TestWriteReadDir every test type is written and read back without changes
+func TestWriteReadDir(t *testing.T) {
+	courseBundle := getMarkdownBundle(1)
+
+	for i, test := range getTests() {
+		test.Id = fmt.Sprintf("stage1-q%d", i+1)
+		test.StageId = "stage1"
+		test.LemmingsCount = i + 1
+		test.OrderNumber = i + 1
+		courseBundle.Stages[0].Tests = append(courseBundle.Stages[0].Tests, test)
+	}
+
+	dir := t.TempDir()
+
+	err := WriteDir(dir, courseBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Stages) != 1 || len(result.Stages[0].Tests) != len(courseBundle.Stages[0].Tests) {
+		t.Fatalf("expected %d tests", len(courseBundle.Stages[0].Tests))
+	}
+
+	for i, expected := range courseBundle.Stages[0].Tests {
+		if actual := result.Stages[0].Tests[i]; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("test %s is changed: expected %+v, got %+v", expected.TestType, expected, actual)
+		}
+	}
+}
+
+// TestWriteDirInvalid test which can't be written fails export and nothing is written
+func TestWriteDirInvalid(t *testing.T) {
+	courseBundle := getMarkdownBundle(1)
+
+	courseBundle.Stages[0].Tests = []*common.Test{{
+		Id:           "multiline",
+		TestType:     common.TestOrdering,
+		OrderingTest: &common.OrderingTest{Question: "Order", Items: []string{"first\nline", "second"}},
+	}}
+
+	dir := filepath.Join(t.TempDir(), "course")
+
+	err := WriteDir(dir, courseBundle)
+
+	if err == nil || !strings.Contains(err.Error(), "multiline") {
+		t.Fatalf("expected error with test id, got %v", err)
+	}
+
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("directory mustn't be written")
+	}
+}
+
+// TestReadDirOrder stages are ordered by number of file name
+func TestReadDirOrder(t *testing.T) {
+	dir := t.TempDir()
+
+	err := WriteDir(dir, getMarkdownBundle(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files of other authors may be not padded
+	err = os.Rename(filepath.Join(dir, "02-stage-2.md"), filepath.Join(dir, "2-stage-2.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, bundleStage := range result.Stages {
+		if bundleStage.Stage.Id != fmt.Sprintf("stage%d", i+1) || bundleStage.Stage.OrderNumber != i+1 {
+			t.Fatalf("stage %d has id %s", i+1, bundleStage.Stage.Id)
+		}
+	}
+}
+
+// TestParseQuiz test type is detected by lines without "type" line
+func TestParseQuiz(t *testing.T) {
+	cases := map[string][]string{
+		common.TestOption:      {"? Question", "- [x] a", "- [ ] b"},
+		common.TestMultiSelect: {"? Question", "- [x] a", "- [x] b"},
+		common.TestRewrite:     {"? Question", "= answer"},
+		common.TestOrdering:    {"? Question", "1. a", "2. b"},
+		common.TestMatching:    {"? Question", "- a => b", "- c => d"},
+		common.TestCloze:       {"? {{1}} text", "{{1}} = a"},
+		common.TestCode:        {"? Question", "case:", "| 1", "expect:", "| 1"},
+	}
+
+	for testType, lines := range cases {
+		test, err := parseQuiz(lines, "id", 1)
+
+		if err != nil {
+			t.Fatalf("%s: %v", testType, err)
+		}
+
+		if test.TestType != testType {
+			t.Errorf("expected type %s, got %s", testType, test.TestType)
+		}
+	}
+
+	for _, lines := range [][]string{
+		{"- [x] a"},
+		{"? Question", "- [x] a", "= b"},
+		{"? Question", "| text"},
+		{"? Question", "unknown line"},
+		{"? Question", "type: numeric", "= eight"},
+		{"? {{1}} text", "{{1000}} = a"},
+	} {
+		if _, err := parseQuiz(lines, "id", 1); err == nil {
+			t.Errorf("quiz %v must be rejected", lines)
+		}
+	}
+}
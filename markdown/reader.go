@@ -0,0 +1,661 @@
+package markdown
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"opencourse/bundle"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/sandbox"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+This file contains importer of courses written as Markdown directory tree:
+
+	course.md      - course front-matter, body is a course description;
+	01-intro.md    - stage front-matter and content, one file per stage, ordered by file name number;
+	02-basics.md
+
+Stage quizzes are written in fenced blocks with "quiz" info string:
+
+	```quiz
+	lemmings: 5
+	? What is goroutine?
+	- [x] Lightweight thread
+	- [ ] Package manager
+	```
+
+Quiz with "= answer" lines instead of options is a rewrite test, the first answer is the right answer
+and the others are accepted answers. Quiz with many right options is a multi-select test. Other test types
+are set by "type" line:
+
+	ordering      - "1. item" lines in the right order;
+	matching      - "- left => right" pair lines;
+	cloze         - "?" lines are text with {{1}} placeholders, "{{1}} = answer" lines are accepted answers;
+	numeric       - "= 42" answer and "tolerance: 0.5" line;
+	code          - "starter:", "case:" or "hidden case:" and "expect:" blocks of "| text" lines.
+
+Settings are written as "key: value" lines: max_attempts, cooldown, pool, partial_credit, rewrite matching
+settings and "pattern: regexp" lines. Stage files are ordered by number prefix of file name, then by name.
+*/
+
+const (
+	CourseFile      = "course.md" // Course description file name
+	QuizFence       = "```quiz"   // Opening fence for quiz block
+	Fence           = "```"       // Closing fence
+	FrontMatter     = "---"       // Front-matter delimiter
+	PairSeparator   = "=>"        // Separator of left and right items of matching pair
+	defaultLemmings = 1           // Lemmings count if it's not set in quiz block
+	maxBlanks       = 100         // Max number of cloze blank
+)
+
+/*
+ReadDir read course from Markdown directory tree and return validated bundle. Parameters:
+dir - course directory;
+*/
+func ReadDir(dir string) (*common.CourseBundle, error) {
+	meta, description, err := readFile(filepath.Join(dir, CourseFile))
+
+	if err != nil {
+		return nil, readErr("ReadDir", err)
+	}
+
+	courseId := meta.get("id", filepath.Base(filepath.Clean(dir)))
+
+	courseBundle := &common.CourseBundle{
+		Version:    common.BundleVersion,
+		Id:         courseId,
+		ExportDate: time.Now().UTC(),
+		Course: &common.Course{
+			Id:          courseId,
+			Name:        meta.get("name", ""),
+			CategoryId:  meta.get("category_id", ""),
+			Lang:        meta.get("lang", ""),
+			Tags:        meta.list("tags"),
+			Description: strings.TrimSpace(description),
+			IconImg:     meta.get("icon_img", ""),
+			HeaderImg:   meta.get("header_img", ""),
+		},
+		Category: &common.CategoryRef{
+			Id:   meta.get("category_id", ""),
+			Name: meta.get("category", ""),
+			Lang: meta.get("category_lang", meta.get("lang", "")),
+		},
+	}
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return nil, readErr("ReadDir", err)
+	}
+
+	var names []string
+
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == CourseFile || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+
+		names = append(names, entry.Name())
+	}
+
+	// "10-" is after "2-", files without number are the last
+	sort.SliceStable(names, func(i, j int) bool {
+		left, right := fileNumber(names[i]), fileNumber(names[j])
+
+		if left != right {
+			return left < right
+		}
+
+		return names[i] < names[j]
+	})
+
+	for i, name := range names {
+		bundleStage, err := readStage(filepath.Join(dir, name), i+1)
+
+		if err != nil {
+			return nil, readErr("ReadDir", fmt.Errorf("%s: %w", name, err))
+		}
+
+		courseBundle.Stages = append(courseBundle.Stages, bundleStage)
+	}
+
+	err = bundle.Validate(courseBundle)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return courseBundle, nil
+}
+
+// fileNumber return number prefix of stage file name. File without number is ordered after numbered files
+func fileNumber(name string) int {
+	end := 0
+
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+
+	number, err := strconv.Atoi(name[:end])
+
+	if err != nil {
+		return math.MaxInt
+	}
+
+	return number
+}
+
+// readStage read stage file. Quiz blocks are removed from stage content and converted to tests
+func readStage(fileName string, orderNumber int) (*common.BundleStage, error) {
+	meta, body, err := readFile(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stageId := meta.get("id", strings.TrimSuffix(filepath.Base(fileName), ".md"))
+
+	content, quizzes, err := splitQuizzes(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bundleStage := &common.BundleStage{
+		Stage: &common.Stage{
+			Id:          stageId,
+			Name:        meta.get("name", ""),
+			HeaderImg:   meta.get("header_img", ""),
+			OrderNumber: orderNumber,
+			Content: &common.PostContent{
+				Body:       strings.TrimSpace(content),
+				MediaItems: meta.list("media"),
+			},
+		},
+	}
+
+	for i, quiz := range quizzes {
+		test, err := parseQuiz(quiz, fmt.Sprintf("%s-q%d", stageId, i+1), i+1)
+
+		if err != nil {
+			return nil, fmt.Errorf("quiz %d: %w", i+1, err)
+		}
+
+		test.StageId = stageId
+		bundleStage.Tests = append(bundleStage.Tests, test)
+	}
+
+	return bundleStage, nil
+}
+
+// splitQuizzes separate stage content and quiz blocks
+func splitQuizzes(body string) (string, [][]string, error) {
+	var content strings.Builder
+	var quizzes [][]string
+	var quiz []string
+	inQuiz := false
+
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case !inQuiz && trimmed == QuizFence:
+			inQuiz = true
+			quiz = nil
+		case inQuiz && trimmed == Fence:
+			inQuiz = false
+			quizzes = append(quizzes, quiz)
+		case inQuiz:
+			quiz = append(quiz, line)
+		default:
+			content.WriteString(line)
+			content.WriteString("\n")
+		}
+	}
+
+	if inQuiz {
+		return "", nil, fmt.Errorf("quiz block is not closed")
+	}
+
+	return content.String(), quizzes, nil
+}
+
+// quizBlock parsed lines of quiz block
+type quizBlock struct {
+	testType   string
+	question   []string
+	options    []*common.Option
+	answers    []string
+	items      []string
+	pairs      []*common.MatchPair
+	blanks     []*common.ClozeBlank
+	settings   map[string]string
+	patterns   []string
+	starter    []string
+	cases      []*codeCase
+	hasStarter bool
+}
+
+// codeCase lines of code test case
+type codeCase struct {
+	input  []string
+	output []string
+	hidden bool
+}
+
+var (
+	orderingItem = regexp.MustCompile(`^(\d+)\.\s+(.*)$`)
+	clozeAnswer  = regexp.MustCompile(`^\{\{(\d+)\}\}\s*=\s*(.*)$`)
+)
+
+// quizSettings keys of "key: value" lines in quiz block
+var quizSettings = map[string]bool{
+	"ignore_case": true, "ignore_whitespace": true, "ignore_punctuation": true, "unicode_normalize": true,
+	"fold_diacritics": true, "max_distance": true, "partial_credit": true, "tolerance": true, "language": true,
+	"max_attempts": true, "cooldown": true, "pool": true,
+}
+
+// parseQuiz convert quiz block lines to test. Test type is set by "type" line or is detected by lines
+func parseQuiz(lines []string, defaultId string, orderNumber int) (*common.Test, error) {
+	test := &common.Test{
+		Id:            defaultId,
+		LemmingsCount: defaultLemmings,
+		OrderNumber:   orderNumber,
+	}
+
+	quiz := &quizBlock{settings: make(map[string]string)}
+
+	// Text lines "| text" are added to the last started block: starter code, case input or expected output
+	var block *[]string
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "|") {
+			if block == nil {
+				return nil, fmt.Errorf("text line %q isn't in starter, case or expect block", trimmed)
+			}
+
+			text := strings.TrimLeft(line, " \t")[1:]
+			*block = append(*block, strings.TrimPrefix(text, " "))
+			continue
+		}
+
+		block = nil
+
+		if len(trimmed) == 0 {
+			continue
+		}
+
+		if match := orderingItem.FindStringSubmatch(trimmed); match != nil {
+			quiz.items = append(quiz.items, match[2])
+			continue
+		}
+
+		if match := clozeAnswer.FindStringSubmatch(trimmed); match != nil {
+			number, _ := strconv.Atoi(match[1])
+
+			if number < 1 || number > maxBlanks {
+				return nil, fmt.Errorf("invalid blank number %q", trimmed)
+			}
+
+			for len(quiz.blanks) < number {
+				quiz.blanks = append(quiz.blanks, &common.ClozeBlank{})
+			}
+
+			quiz.blanks[number-1].Answers = append(quiz.blanks[number-1].Answers, strings.TrimSpace(match[2]))
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "?"):
+			quiz.question = append(quiz.question, strings.TrimSpace(trimmed[1:]))
+		case strings.HasPrefix(trimmed, "- [x]"), strings.HasPrefix(trimmed, "- [X]"):
+			quiz.options = append(quiz.options, &common.Option{Answer: strings.TrimSpace(trimmed[5:]), IsRight: true})
+		case strings.HasPrefix(trimmed, "- [ ]"):
+			quiz.options = append(quiz.options, &common.Option{Answer: strings.TrimSpace(trimmed[5:])})
+		case strings.HasPrefix(trimmed, "- ") && strings.Contains(trimmed, PairSeparator):
+			left, right, _ := strings.Cut(trimmed[2:], PairSeparator)
+			quiz.pairs = append(quiz.pairs, &common.MatchPair{Left: strings.TrimSpace(left), Right: strings.TrimSpace(right)})
+		case strings.HasPrefix(trimmed, "="):
+			quiz.answers = append(quiz.answers, strings.TrimSpace(trimmed[1:]))
+		case trimmed == "starter:":
+			quiz.hasStarter = true
+			block = &quiz.starter
+		case trimmed == "case:", trimmed == "hidden case:":
+			quiz.cases = append(quiz.cases, &codeCase{hidden: trimmed == "hidden case:"})
+			block = &quiz.cases[len(quiz.cases)-1].input
+		case trimmed == "expect:":
+			if len(quiz.cases) == 0 {
+				return nil, fmt.Errorf("expect block without case")
+			}
+
+			block = &quiz.cases[len(quiz.cases)-1].output
+		default:
+			key, value, ok := strings.Cut(trimmed, ":")
+			key = strings.TrimSpace(key)
+			value = strings.TrimSpace(value)
+
+			switch {
+			case !ok:
+				return nil, fmt.Errorf("unknown quiz line %q", trimmed)
+			case key == "id":
+				test.Id = value
+			case key == "type":
+				quiz.testType = value
+			case key == "pattern":
+				quiz.patterns = append(quiz.patterns, value)
+			case key == "lemmings":
+				count, err := strconv.Atoi(value)
+
+				if err != nil {
+					return nil, fmt.Errorf("invalid lemmings count: %w", err)
+				}
+
+				test.LemmingsCount = count
+			case quizSettings[key]:
+				quiz.settings[key] = value
+			default:
+				return nil, fmt.Errorf("unknown quiz line %q", trimmed)
+			}
+		}
+	}
+
+	if len(quiz.question) == 0 {
+		return nil, fmt.Errorf("question is empty")
+	}
+
+	err := quiz.fill(test)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return test, nil
+}
+
+// detectType return test type by lines of quiz block without "type" line
+func (quiz *quizBlock) detectType() (string, error) {
+	switch {
+	case len(quiz.answers) > 0 && len(quiz.options) > 0:
+		return "", fmt.Errorf("quiz has both options and answer")
+	case len(quiz.answers) > 0:
+		return common.TestRewrite, nil
+	case len(quiz.options) > 0 && rightCount(quiz.options) > 1:
+		return common.TestMultiSelect, nil
+	case len(quiz.options) > 0:
+		return common.TestOption, nil
+	case len(quiz.items) > 0:
+		return common.TestOrdering, nil
+	case len(quiz.pairs) > 0:
+		return common.TestMatching, nil
+	case len(quiz.blanks) > 0:
+		return common.TestCloze, nil
+	case len(quiz.cases) > 0 || quiz.hasStarter:
+		return common.TestCode, nil
+	default:
+		return "", fmt.Errorf("quiz hasn't options or answer")
+	}
+}
+
+// fill set test type, content and settings of test
+func (quiz *quizBlock) fill(test *common.Test) error {
+	var err error
+
+	test.TestType = quiz.testType
+
+	if len(test.TestType) == 0 {
+		test.TestType, err = quiz.detectType()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	question := strings.Join(quiz.question, "\n")
+	test.Pool = quiz.settings["pool"]
+
+	test.MaxAttempts, err = quiz.intSetting("max_attempts")
+
+	if err != nil {
+		return err
+	}
+
+	test.Cooldown, err = quiz.intSetting("cooldown")
+
+	if err != nil {
+		return err
+	}
+
+	switch test.TestType {
+	case common.TestOption:
+		test.OptionTest = &common.OptionTest{Question: question, Options: quiz.options}
+	case common.TestMultiSelect:
+		test.MultiSelectTest = &common.MultiSelectTest{
+			Question:      question,
+			Options:       quiz.options,
+			PartialCredit: quiz.settings["partial_credit"] == "true",
+		}
+	case common.TestRewrite:
+		if len(quiz.answers) == 0 {
+			return fmt.Errorf("rewrite quiz hasn't answer")
+		}
+
+		test.RewriteTest = &common.RewriteTest{Question: question, RightAnswer: quiz.answers[0]}
+
+		matching := &common.RewriteMatching{
+			IgnoreCase:        quiz.settings["ignore_case"] == "true",
+			IgnoreWhitespace:  quiz.settings["ignore_whitespace"] == "true",
+			IgnorePunctuation: quiz.settings["ignore_punctuation"] == "true",
+			UnicodeNormalize:  quiz.settings["unicode_normalize"] == "true",
+			FoldDiacritics:    quiz.settings["fold_diacritics"] == "true",
+			AcceptedAnswers:   quiz.answers[1:],
+			Patterns:          quiz.patterns,
+		}
+
+		matching.MaxDistance, err = quiz.intSetting("max_distance")
+
+		if err != nil {
+			return err
+		}
+
+		if len(matching.AcceptedAnswers) == 0 {
+			matching.AcceptedAnswers = nil
+		}
+
+		// Exact matching hasn't settings
+		if !reflect.DeepEqual(*matching, common.RewriteMatching{}) {
+			test.RewriteTest.Matching = matching
+		}
+	case common.TestOrdering:
+		test.OrderingTest = &common.OrderingTest{Question: question, Items: quiz.items}
+	case common.TestMatching:
+		test.MatchingTest = &common.MatchingTest{Question: question, Pairs: quiz.pairs}
+	case common.TestCloze:
+		test.ClozeTest = &common.ClozeTest{Text: question, Blanks: quiz.blanks}
+	case common.TestNumeric:
+		if len(quiz.answers) != 1 {
+			return fmt.Errorf("numeric quiz must have one answer")
+		}
+
+		test.NumericTest = &common.NumericTest{Question: question}
+
+		test.NumericTest.Answer, err = strconv.ParseFloat(quiz.answers[0], 64)
+
+		if err != nil {
+			return fmt.Errorf("invalid numeric answer: %w", err)
+		}
+
+		if tolerance, ok := quiz.settings["tolerance"]; ok {
+			test.NumericTest.Tolerance, err = strconv.ParseFloat(tolerance, 64)
+
+			if err != nil {
+				return fmt.Errorf("invalid tolerance: %w", err)
+			}
+		}
+	case common.TestCode:
+		test.CodeTest = &common.CodeTest{
+			Question:    question,
+			Language:    quiz.settings["language"],
+			StarterCode: strings.Join(quiz.starter, "\n"),
+		}
+
+		if len(test.CodeTest.Language) == 0 {
+			test.CodeTest.Language = sandbox.LanguageGo
+		}
+
+		for _, codeCase := range quiz.cases {
+			test.CodeTest.Cases = append(test.CodeTest.Cases, &common.CodeCase{
+				Input:          strings.Join(codeCase.input, "\n"),
+				ExpectedOutput: strings.Join(codeCase.output, "\n"),
+				Hidden:         codeCase.hidden,
+			})
+		}
+	default:
+		return fmt.Errorf("unknown test type %q", test.TestType)
+	}
+
+	return nil
+}
+
+// intSetting return integer setting or 0, if it isn't set
+func (quiz *quizBlock) intSetting(key string) (int, error) {
+	value, ok := quiz.settings[key]
+
+	if !ok {
+		return 0, nil
+	}
+
+	result, err := strconv.Atoi(value)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return result, nil
+}
+
+// rightCount return count of right options
+func rightCount(options []*common.Option) int {
+	count := 0
+
+	for _, option := range options {
+		if option.IsRight {
+			count++
+		}
+	}
+
+	return count
+}
+
+// readFile read Markdown file and split front-matter and body
+func readFile(fileName string) (frontMatter, string, error) {
+	file, err := os.Open(fileName)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	meta := frontMatter{}
+	var body strings.Builder
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	lineNumber := 0
+	inMeta := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNumber++
+
+		if lineNumber == 1 && strings.TrimSpace(line) == FrontMatter {
+			inMeta = true
+			continue
+		}
+
+		if inMeta && strings.TrimSpace(line) == FrontMatter {
+			inMeta = false
+			continue
+		}
+
+		if inMeta {
+			key, value, ok := strings.Cut(line, ":")
+
+			if !ok {
+				return nil, "", fmt.Errorf("line %d: invalid front-matter %q", lineNumber, line)
+			}
+
+			meta[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			continue
+		}
+
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if inMeta {
+		return nil, "", fmt.Errorf("front-matter is not closed")
+	}
+
+	return meta, body.String(), nil
+}
+
+// frontMatter simple "key: value" metadata of Markdown file
+type frontMatter map[string]string
+
+// get return value by key or default value
+func (meta frontMatter) get(key string, defaultValue string) string {
+	value, ok := meta[key]
+
+	if !ok || len(value) == 0 {
+		return defaultValue
+	}
+
+	return strings.Trim(value, `"'`)
+}
+
+// list return comma separated values. Values may be wrapped in square brackets
+func (meta frontMatter) list(key string) []string {
+	value := strings.TrimSuffix(strings.TrimPrefix(meta.get(key, ""), "["), "]")
+
+	var result []string
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.Trim(strings.TrimSpace(item), `"'`)
+
+		if len(item) > 0 {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+func readErr(method string, err error) error {
+	return openerrors.DefaultErr{
+		BaseErr: openerrors.BaseErr{
+			File:   "markdown/reader.go",
+			Method: method,
+		},
+		Msg: err.Error(),
+	}
+}
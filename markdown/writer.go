@@ -0,0 +1,376 @@
+package markdown
+
+import (
+	"fmt"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+/*
+WriteDir write course bundle as Markdown directory tree. The layout is the same as ReadDir expects.
+If some tests can't be written without changes, nothing is written and error lists these tests. Parameters:
+dir - target directory. Created if it doesn't exist;
+bundle - course bundle;
+*/
+func WriteDir(dir string, bundle *common.CourseBundle) error {
+	if bundle == nil || bundle.Course == nil {
+		return openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "markdown/writer.go",
+				Method: "WriteDir",
+			},
+			Model: "bundle",
+		}
+	}
+
+	// Import of directory removes tests which are missing in it, so every test must be written
+	var invalid []string
+
+	for _, bundleStage := range bundle.Stages {
+		for _, test := range bundleStage.Tests {
+			if reason := checkQuiz(test); len(reason) > 0 {
+				invalid = append(invalid, fmt.Sprintf("%s (%s)", test.Id, reason))
+			}
+		}
+	}
+
+	if len(invalid) > 0 {
+		return writeErr(fmt.Errorf("tests can't be written to Markdown: %s", strings.Join(invalid, ", ")))
+	}
+
+	err := os.MkdirAll(dir, 0755)
+
+	if err != nil {
+		return writeErr(err)
+	}
+
+	var course strings.Builder
+
+	writeFrontMatter(&course, [][2]string{
+		{"id", bundle.Course.Id},
+		{"name", bundle.Course.Name},
+		{"lang", bundle.Course.Lang},
+		{"tags", strings.Join(bundle.Course.Tags, ", ")},
+		{"category_id", categoryValue(bundle.Category, func(ref *common.CategoryRef) string { return ref.Id })},
+		{"category", categoryValue(bundle.Category, func(ref *common.CategoryRef) string { return ref.Name })},
+		{"category_lang", categoryValue(bundle.Category, func(ref *common.CategoryRef) string { return ref.Lang })},
+		{"icon_img", bundle.Course.IconImg},
+		{"header_img", bundle.Course.HeaderImg},
+	})
+
+	course.WriteString(bundle.Course.Description)
+	course.WriteString("\n")
+
+	err = os.WriteFile(filepath.Join(dir, CourseFile), []byte(course.String()), 0644)
+
+	if err != nil {
+		return writeErr(err)
+	}
+
+	width := len(strconv.Itoa(len(bundle.Stages)))
+
+	if width < 2 {
+		width = 2
+	}
+
+	for i, bundleStage := range bundle.Stages {
+		var stage strings.Builder
+
+		writeFrontMatter(&stage, [][2]string{
+			{"id", bundleStage.Stage.Id},
+			{"name", bundleStage.Stage.Name},
+			{"header_img", bundleStage.Stage.HeaderImg},
+			{"media", mediaValue(bundleStage.Stage.Content)},
+		})
+
+		if bundleStage.Stage.Content != nil {
+			stage.WriteString(bundleStage.Stage.Content.Body)
+			stage.WriteString("\n")
+		}
+
+		for _, test := range bundleStage.Tests {
+			stage.WriteString("\n")
+			writeQuiz(&stage, test)
+		}
+
+		// Index prefix keeps stages order. It's padded, so stages are ordered by name in file managers too
+		name := fmt.Sprintf("%0*d-%s.md", width, i+1, slug(bundleStage.Stage.Name))
+
+		err = os.WriteFile(filepath.Join(dir, name), []byte(stage.String()), 0644)
+
+		if err != nil {
+			return writeErr(err)
+		}
+	}
+
+	return nil
+}
+
+func writeFrontMatter(builder *strings.Builder, values [][2]string) {
+	builder.WriteString(FrontMatter + "\n")
+
+	for _, value := range values {
+		if len(value[1]) == 0 {
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf("%s: %s\n", value[0], value[1]))
+	}
+
+	builder.WriteString(FrontMatter + "\n")
+}
+
+func writeQuiz(builder *strings.Builder, test *common.Test) {
+	builder.WriteString(QuizFence + "\n")
+	builder.WriteString(fmt.Sprintf("id: %s\n", test.Id))
+	builder.WriteString(fmt.Sprintf("type: %s\n", test.TestType))
+	builder.WriteString(fmt.Sprintf("lemmings: %d\n", test.LemmingsCount))
+
+	writeSetting(builder, "max_attempts", test.MaxAttempts != 0, strconv.Itoa(test.MaxAttempts))
+	writeSetting(builder, "cooldown", test.Cooldown != 0, strconv.Itoa(test.Cooldown))
+	writeSetting(builder, "pool", len(test.Pool) > 0, test.Pool)
+
+	switch test.TestType {
+	case common.TestOption:
+		writeQuestion(builder, test.OptionTest.Question)
+		writeOptions(builder, test.OptionTest.Options)
+	case common.TestMultiSelect:
+		writeSetting(builder, "partial_credit", test.MultiSelectTest.PartialCredit, "true")
+		writeQuestion(builder, test.MultiSelectTest.Question)
+		writeOptions(builder, test.MultiSelectTest.Options)
+	case common.TestRewrite:
+		writeQuestion(builder, test.RewriteTest.Question)
+		builder.WriteString(fmt.Sprintf("= %s\n", test.RewriteTest.RightAnswer))
+
+		if matching := test.RewriteTest.Matching; matching != nil {
+			for _, answer := range matching.AcceptedAnswers {
+				builder.WriteString(fmt.Sprintf("= %s\n", answer))
+			}
+
+			for _, pattern := range matching.Patterns {
+				builder.WriteString(fmt.Sprintf("pattern: %s\n", pattern))
+			}
+
+			writeSetting(builder, "ignore_case", matching.IgnoreCase, "true")
+			writeSetting(builder, "ignore_whitespace", matching.IgnoreWhitespace, "true")
+			writeSetting(builder, "ignore_punctuation", matching.IgnorePunctuation, "true")
+			writeSetting(builder, "unicode_normalize", matching.UnicodeNormalize, "true")
+			writeSetting(builder, "fold_diacritics", matching.FoldDiacritics, "true")
+			writeSetting(builder, "max_distance", matching.MaxDistance != 0, strconv.Itoa(matching.MaxDistance))
+		}
+	case common.TestOrdering:
+		writeQuestion(builder, test.OrderingTest.Question)
+
+		for i, item := range test.OrderingTest.Items {
+			builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, item))
+		}
+	case common.TestMatching:
+		writeQuestion(builder, test.MatchingTest.Question)
+
+		for _, pair := range test.MatchingTest.Pairs {
+			builder.WriteString(fmt.Sprintf("- %s %s %s\n", pair.Left, PairSeparator, pair.Right))
+		}
+	case common.TestCloze:
+		writeQuestion(builder, test.ClozeTest.Text)
+
+		for i, blank := range test.ClozeTest.Blanks {
+			for _, answer := range blank.Answers {
+				builder.WriteString(fmt.Sprintf("{{%d}} = %s\n", i+1, answer))
+			}
+		}
+	case common.TestNumeric:
+		writeQuestion(builder, test.NumericTest.Question)
+		builder.WriteString(fmt.Sprintf("= %s\n", strconv.FormatFloat(test.NumericTest.Answer, 'g', -1, 64)))
+		writeSetting(builder, "tolerance", test.NumericTest.Tolerance != 0,
+			strconv.FormatFloat(test.NumericTest.Tolerance, 'g', -1, 64))
+	case common.TestCode:
+		writeQuestion(builder, test.CodeTest.Question)
+		builder.WriteString(fmt.Sprintf("language: %s\n", test.CodeTest.Language))
+		builder.WriteString("starter:\n")
+		writeText(builder, test.CodeTest.StarterCode)
+
+		for _, codeCase := range test.CodeTest.Cases {
+			if codeCase.Hidden {
+				builder.WriteString("hidden ")
+			}
+
+			builder.WriteString("case:\n")
+			writeText(builder, codeCase.Input)
+			builder.WriteString("expect:\n")
+			writeText(builder, codeCase.ExpectedOutput)
+		}
+	}
+
+	builder.WriteString(Fence + "\n")
+}
+
+/*
+checkQuiz return reason, why test can't be written to quiz block and read back without changes.
+Returns empty string, if test can be written. Parameters:
+test - test;
+*/
+func checkQuiz(test *common.Test) string {
+	var lines []string
+	var question string
+
+	switch {
+	case test.TestType == common.TestOption && test.OptionTest != nil:
+		question = test.OptionTest.Question
+		lines = optionAnswers(test.OptionTest.Options)
+	case test.TestType == common.TestMultiSelect && test.MultiSelectTest != nil:
+		question = test.MultiSelectTest.Question
+		lines = optionAnswers(test.MultiSelectTest.Options)
+	case test.TestType == common.TestRewrite && test.RewriteTest != nil:
+		question = test.RewriteTest.Question
+		lines = append(lines, test.RewriteTest.RightAnswer)
+
+		if test.RewriteTest.Matching != nil {
+			lines = append(lines, test.RewriteTest.Matching.AcceptedAnswers...)
+			lines = append(lines, test.RewriteTest.Matching.Patterns...)
+		}
+	case test.TestType == common.TestOrdering && test.OrderingTest != nil:
+		question = test.OrderingTest.Question
+		lines = test.OrderingTest.Items
+	case test.TestType == common.TestMatching && test.MatchingTest != nil:
+		question = test.MatchingTest.Question
+
+		for _, pair := range test.MatchingTest.Pairs {
+			if pair == nil || strings.Contains(pair.Left, PairSeparator) {
+				return "matching pair contains " + PairSeparator
+			}
+
+			lines = append(lines, pair.Left, pair.Right)
+		}
+	case test.TestType == common.TestCloze && test.ClozeTest != nil:
+		question = test.ClozeTest.Text
+
+		for _, blank := range test.ClozeTest.Blanks {
+			if blank == nil {
+				return "blank is empty"
+			}
+
+			lines = append(lines, blank.Answers...)
+		}
+	case test.TestType == common.TestNumeric && test.NumericTest != nil:
+		question = test.NumericTest.Question
+	case test.TestType == common.TestCode && test.CodeTest != nil:
+		question = test.CodeTest.Question
+		lines = append(lines, test.CodeTest.Language)
+
+		for _, codeCase := range test.CodeTest.Cases {
+			if codeCase == nil {
+				return "code case is empty"
+			}
+		}
+	default:
+		return "unknown test type " + test.TestType
+	}
+
+	if len(strings.TrimSpace(question)) == 0 {
+		return "question is empty"
+	}
+
+	// Line values are trimmed on read
+	for _, line := range append(lines, test.Id, test.Pool) {
+		if strings.Contains(line, "\n") || strings.TrimSpace(line) != line {
+			return fmt.Sprintf("value %q isn't one line", line)
+		}
+	}
+
+	return ""
+}
+
+// optionAnswers return answers of options. Nil option is returned as line with line break, so it's rejected
+func optionAnswers(options []*common.Option) []string {
+	answers := make([]string, 0, len(options))
+
+	for _, option := range options {
+		if option == nil {
+			answers = append(answers, "\n")
+			continue
+		}
+
+		answers = append(answers, option.Answer)
+	}
+
+	return answers
+}
+
+func writeSetting(builder *strings.Builder, key string, ok bool, value string) {
+	if ok {
+		builder.WriteString(fmt.Sprintf("%s: %s\n", key, value))
+	}
+}
+
+// writeText write multiline text as "| line" lines
+func writeText(builder *strings.Builder, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		if len(line) == 0 {
+			builder.WriteString("|\n")
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf("| %s\n", line))
+	}
+}
+
+func writeOptions(builder *strings.Builder, options []*common.Option) {
+	for _, option := range options {
+		mark := " "
+		if option.IsRight {
+			mark = "x"
+		}
+
+		builder.WriteString(fmt.Sprintf("- [%s] %s\n", mark, option.Answer))
+	}
+}
+
+func writeQuestion(builder *strings.Builder, question string) {
+	for _, line := range strings.Split(question, "\n") {
+		builder.WriteString(fmt.Sprintf("? %s\n", line))
+	}
+}
+
+func categoryValue(ref *common.CategoryRef, value func(ref *common.CategoryRef) string) string {
+	if ref == nil {
+		return ""
+	}
+
+	return value(ref)
+}
+
+func mediaValue(content *common.PostContent) string {
+	if content == nil {
+		return ""
+	}
+
+	return strings.Join(content.MediaItems, ", ")
+}
+
+// slug make file name part from stage name
+func slug(name string) string {
+	result := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+
+	if len(result) == 0 {
+		return "stage"
+	}
+
+	return result
+}
+
+func writeErr(err error) error {
+	return openerrors.DefaultErr{
+		BaseErr: openerrors.BaseErr{
+			File:   "markdown/writer.go",
+			Method: "WriteDir",
+		},
+		Msg: err.Error(),
+	}
+}
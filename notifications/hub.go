@@ -0,0 +1,144 @@
+package notifications
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"opencourse/common"
+	"sync"
+	"time"
+)
+
+// streamBuffer count of notifications, which wait for slow stream
+const streamBuffer = 16
+
+// ticketTtl lifetime of stream ticket. Ticket is redeemed by stream request right after it's issued
+const ticketTtl = 30 * time.Second
+
+// ticket issued stream ticket
+type ticket struct {
+	userId  string    // Owner of ticket
+	expires time.Time // Ticket isn't redeemed after this time
+}
+
+/*
+Hub deliver saved notifications to connected streams of users. Browser EventSource can't set headers, so stream
+is opened by short-lived single-use ticket instead of token in url, which would stay in logs and history.
+*/
+type Hub struct {
+	mu      sync.Mutex
+	streams map[string]map[chan *common.Notification]bool
+	tickets map[string]ticket
+	now     func() time.Time // Clock of ticket expiration
+}
+
+// NewHub return hub without streams
+func NewHub() *Hub {
+	return &Hub{
+		streams: make(map[string]map[chan *common.Notification]bool),
+		tickets: make(map[string]ticket),
+		now:     time.Now,
+	}
+}
+
+/*
+Ticket return new stream ticket of user. Expired tickets are removed. Parameters:
+userId - owner of ticket;
+*/
+func (hub *Hub) Ticket(userId string) (string, error) {
+	data := make([]byte, 32)
+
+	_, err := rand.Read(data)
+
+	if err != nil {
+		return "", err
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(data)
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	now := hub.now()
+
+	for key, issued := range hub.tickets {
+		if now.After(issued.expires) {
+			delete(hub.tickets, key)
+		}
+	}
+
+	hub.tickets[value] = ticket{userId: userId, expires: now.Add(ticketTtl)}
+
+	return value, nil
+}
+
+/*
+Redeem return owner of ticket and remove ticket. Returns false, if ticket isn't found or expired. Parameters:
+value - stream ticket;
+*/
+func (hub *Hub) Redeem(value string) (string, bool) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	issued, ok := hub.tickets[value]
+
+	if !ok {
+		return "", false
+	}
+
+	delete(hub.tickets, value)
+
+	if hub.now().After(issued.expires) {
+		return "", false
+	}
+
+	return issued.userId, true
+}
+
+/*
+Subscribe return stream of user notifications and function, which closes stream. Parameters:
+userId - recipient id;
+*/
+func (hub *Hub) Subscribe(userId string) (<-chan *common.Notification, func()) {
+	stream := make(chan *common.Notification, streamBuffer)
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if hub.streams[userId] == nil {
+		hub.streams[userId] = make(map[chan *common.Notification]bool)
+	}
+
+	hub.streams[userId][stream] = true
+
+	return stream, func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+
+		delete(hub.streams[userId], stream)
+
+		if len(hub.streams[userId]) == 0 {
+			delete(hub.streams, userId)
+		}
+	}
+}
+
+/*
+Send send notification to streams of recipient. Slow stream misses notification, it's read from inbox.
+Parameters:
+notification - saved notification;
+*/
+func (hub *Hub) Send(notification *common.Notification) {
+	if hub == nil {
+		return
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for stream := range hub.streams[notification.UserId] {
+		select {
+		case stream <- notification:
+		default:
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRedeem ticket is redeemed once by its owner
+func TestRedeem(t *testing.T) {
+	hub := NewHub()
+
+	value, err := hub.Ticket("user")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userId, ok := hub.Redeem(value)
+
+	if !ok || userId != "user" {
+		t.Errorf("ticket must be redeemed by its owner, got %q", userId)
+	}
+
+	if _, ok = hub.Redeem(value); ok {
+		t.Error("ticket must be redeemed once")
+	}
+
+	if _, ok = hub.Redeem("unknown"); ok {
+		t.Error("unknown ticket mustn't be redeemed")
+	}
+}
+
+// TestRedeemExpired expired ticket is rejected and removed
+func TestRedeemExpired(t *testing.T) {
+	hub := NewHub()
+	now := time.Now()
+	hub.now = func() time.Time { return now }
+
+	expired, err := hub.Ticket("user")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(ticketTtl + time.Second)
+
+	if _, ok := hub.Redeem(expired); ok {
+		t.Error("expired ticket mustn't be redeemed")
+	}
+
+	_, err = hub.Ticket("user")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, _ = hub.Ticket("other")
+	now = now.Add(ticketTtl + time.Second)
+
+	_, _ = hub.Ticket("user")
+
+	if _, ok := hub.tickets[expired]; ok {
+		t.Error("expired tickets must be removed on issue")
+	}
+}
@@ -0,0 +1,265 @@
+package notifications
+
+import (
+	"opencourse/common"
+	"opencourse/database"
+	"opencourse/events"
+)
+
+/*
+This file contains notifications of users. Service saves notifications about replies, reviews, badges and
+changes of courses, which user learns, to inbox and sends them to connected streams of recipients.
+*/
+
+// Service creates notifications on events
+type Service struct {
+	db    *database.DbContext
+	hub   *Hub
+	onErr func(error)
+}
+
+/*
+NewService return notifications service. Parameters:
+db - database context;
+hub - hub of connected streams;
+onErr - handler of errors, which happen in event handlers;
+*/
+func NewService(db *database.DbContext, hub *Hub, onErr func(error)) *Service {
+	return &Service{db: db, hub: hub, onErr: onErr}
+}
+
+/*
+Subscribe subscribe service to events of bus. Parameters:
+bus - events bus;
+*/
+func (service *Service) Subscribe(bus *events.Bus) {
+	for _, eventType := range []string{events.CommentReplied, events.ReviewReplied, events.ReviewModerated,
+		events.BadgeAwarded, events.CourseSaved, events.StageSaved} {
+
+		bus.Subscribe(eventType, service.Handle)
+	}
+}
+
+/*
+Handle create notifications of event. Errors are passed to error handler, because change is saved. Parameters:
+event - domain event;
+*/
+func (service *Service) Handle(event events.Event) error {
+	var err error
+
+	switch event.Type {
+	case events.CommentReplied:
+		err = service.commentReplied(event)
+	case events.ReviewReplied:
+		err = service.reviewReplied(event)
+	case events.ReviewModerated:
+		err = service.reviewModerated(event)
+	case events.BadgeAwarded:
+		err = service.badgeAwarded(event)
+	case events.CourseSaved, events.StageSaved:
+		// Course can have many learners, so author doesn't wait for notifications
+		go func() {
+			service.report(service.courseUpdated(event))
+		}()
+	}
+
+	service.report(err)
+
+	return nil
+}
+
+// commentReplied notify authors of replied comment and thread
+func (service *Service) commentReplied(event events.Event) error {
+	comment, err := service.db.GetStageComment(event.TargetId)
+
+	if err != nil || comment == nil {
+		return err
+	}
+
+	user, err := service.db.GetUser(event.UserId)
+
+	if err != nil || user == nil {
+		return err
+	}
+
+	threadId := comment.Id
+	recipients := map[string]string{comment.UserId: user.Name + " replied to your comment"}
+
+	if len(comment.ParentId) > 0 {
+		threadId = comment.ParentId
+
+		thread, err := service.db.GetStageComment(comment.ParentId)
+
+		if err != nil {
+			return err
+		}
+
+		if thread != nil && thread.UserId != comment.UserId {
+			recipients[thread.UserId] = user.Name + " replied in your discussion"
+		}
+	}
+
+	// User isn't notified about own reply
+	delete(recipients, event.UserId)
+
+	for userId, message := range recipients {
+		err = service.notify(&common.Notification{
+			UserId:   userId,
+			Kind:     common.NotificationCommentReply,
+			Message:  message,
+			CourseId: comment.CourseId,
+			StageId:  comment.StageId,
+			TargetId: threadId,
+		}, false)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reviewReplied notify author of review about reply of course author
+func (service *Service) reviewReplied(event events.Event) error {
+	review, course, err := service.review(event.TargetId)
+
+	if err != nil || review == nil || review.UserId == event.UserId {
+		return err
+	}
+
+	return service.notify(&common.Notification{
+		UserId:   review.UserId,
+		Kind:     common.NotificationReviewReply,
+		Message:  "Author of course " + course.Name + " replied to your review",
+		CourseId: review.CourseId,
+		TargetId: review.Id,
+	}, false)
+}
+
+// reviewModerated notify author of review about decision of moderator
+func (service *Service) reviewModerated(event events.Event) error {
+	review, course, err := service.review(event.TargetId)
+
+	if err != nil || review == nil {
+		return err
+	}
+
+	message := "Your review of course " + course.Name + " is shown again"
+
+	if event.Value == 1 {
+		message = "Your review of course " + course.Name + " is hidden by moderator"
+	}
+
+	return service.notify(&common.Notification{
+		UserId:   review.UserId,
+		Kind:     common.NotificationReviewDecision,
+		Message:  message,
+		CourseId: review.CourseId,
+		TargetId: review.Id,
+	}, false)
+}
+
+// badgeAwarded notify user about awarded badge
+func (service *Service) badgeAwarded(event events.Event) error {
+	assertion, err := service.db.GetBadgeAssertion(event.TargetId)
+
+	if err != nil || assertion == nil {
+		return err
+	}
+
+	badgeClass, err := service.db.GetBadgeClass(assertion.BadgeClassId)
+
+	if err != nil || badgeClass == nil {
+		return err
+	}
+
+	return service.notify(&common.Notification{
+		UserId:   assertion.UserId,
+		Kind:     common.NotificationBadge,
+		Message:  "You earned badge " + badgeClass.Name,
+		TargetId: assertion.Id,
+	}, false)
+}
+
+// courseUpdated notify learners of enabled course about change. Unread notification of course is refreshed
+func (service *Service) courseUpdated(event events.Event) error {
+	course, err := service.db.GetCourse(event.CourseId)
+
+	if err != nil || course == nil || !course.Enabled {
+		return err
+	}
+
+	learners, err := service.db.GetCourseLearners(course.Id)
+
+	if err != nil {
+		return err
+	}
+
+	for _, userId := range learners {
+		if userId == course.AuthorId {
+			continue
+		}
+
+		err = service.notify(&common.Notification{
+			UserId:   userId,
+			Kind:     common.NotificationCourseUpdate,
+			Message:  "Course " + course.Name + " is updated",
+			CourseId: course.Id,
+			StageId:  event.StageId,
+		}, true)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// review return review with its course. Returns nil, if review or course isn't found
+func (service *Service) review(reviewId string) (*common.CourseReview, *common.Course, error) {
+	review, err := service.db.GetCourseReview(reviewId)
+
+	if err != nil || review == nil {
+		return nil, nil, err
+	}
+
+	course, err := service.db.GetCourse(review.CourseId)
+
+	if err != nil || course == nil {
+		return nil, nil, err
+	}
+
+	return review, course, nil
+}
+
+/*
+notify save notification and send it to streams of recipient. Parameters:
+notification - notification;
+refresh - unread notification of the same kind and course is refreshed instead of adding new one;
+*/
+func (service *Service) notify(notification *common.Notification, refresh bool) error {
+	var err error
+
+	if refresh {
+		notification, err = service.db.RefreshNotification(notification)
+	} else {
+		notification, err = service.db.AddNotification(notification)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	service.hub.Send(notification)
+
+	return nil
+}
+
+// report pass error to error handler
+func (service *Service) report(err error) {
+	if err != nil && service.onErr != nil {
+		service.onErr(err)
+	}
+}
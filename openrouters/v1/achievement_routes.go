@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"net/http"
+	"opencourse/common"
+)
+
+func (ctx *RouteContext) GetAchievements(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	userAchievements, err := ctx.Engine.Achievements(userId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get achievements."}, 400)
+		return
+	}
+
+	WriteResponse[[]*common.Achievement](writer, request, &userAchievements)
+}
+
+func (ctx *RouteContext) GetProgress(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	progress, err := ctx.DbContext.GetUserProgress(userId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get progress."}, 400)
+		return
+	}
+
+	user, err := ctx.DbContext.GetUser(userId)
+
+	if err != nil || user == nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get user."}, 400)
+		return
+	}
+
+	// User without events has empty progress
+	if progress == nil {
+		progress = &common.UserProgress{UserId: userId, Counters: map[string]int{}}
+	}
+
+	progress.Rating = user.Rating
+
+	WriteResponse[common.UserProgress](writer, request, progress)
+}
@@ -40,6 +40,7 @@ func (ctx *RouteContext) Login(writer http.ResponseWriter, request *http.Request
 
 	_, tokenString, err := ctx.TokenAuth.Encode(
 		map[string]interface{}{
+			"id":    user.Id,
 			"login": openRequest.Payload.Login,
 			"roles": strings.Join(user.Credential.Roles, ","),
 			"exp":   time.Now().Add(time.Minute * 60).Unix(),
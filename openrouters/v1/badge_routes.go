@@ -0,0 +1,243 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"opencourse/badges"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+)
+
+func (ctx *RouteContext) PostBadgeClass(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.AddBadgeClassQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	badgeClassId, err := ctx.DbContext.AddBadgeClass(&openRequest.Payload)
+
+	var fieldErr openerrors.FieldEmptyErr
+
+	if errors.As(err, &fieldErr) {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrValid, Message: "Invalid badge class. Check " + fieldErr.Field + "."}, 400)
+		return
+	}
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't create badge class."}, 400)
+		return
+	}
+
+	WriteResponse[string](writer, request, &badgeClassId)
+}
+
+func (ctx *RouteContext) RevokeBadge(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.RevokeBadgeQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	found, err := ctx.DbContext.RevokeBadge(chi.URLParam(request, "assertionId"), openRequest.Payload.Reason)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't revoke badge."}, 400)
+		return
+	}
+
+	if !found {
+		WriteErrResponse(writer, request, errors.New("assertion not found"),
+			&ResponseError{Code: ErrParameter, Message: "Assertion not found."}, 404)
+		return
+	}
+
+	result := "success"
+	WriteResponse[string](writer, request, &result)
+}
+
+func (ctx *RouteContext) GetBadgeWallet(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	// Earlier milestones, for example badge classes created after them, are awarded on open of wallet
+	_, err := badges.AwardEarned(&ctx.DbContext, userId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't award badges."}, 400)
+		return
+	}
+
+	assertions, err := ctx.DbContext.GetUserBadges(userId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get badges."}, 400)
+		return
+	}
+
+	badgeClasses, err := ctx.DbContext.GetBadgeClasses()
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get badges."}, 400)
+		return
+	}
+
+	classes := make(map[string]*common.BadgeClass, len(badgeClasses))
+
+	for _, badgeClass := range badgeClasses {
+		classes[badgeClass.Id] = badgeClass
+	}
+
+	wallet := make([]*common.WalletBadge, 0, len(assertions))
+
+	for _, assertion := range assertions {
+		badge := &common.WalletBadge{
+			Assertion:    assertion,
+			BadgeClass:   classes[assertion.BadgeClassId],
+			AssertionUrl: badges.AssertionUrl(ctx.DbContext.Endpoint, assertion.Id),
+		}
+
+		if ctx.BadgeSigner != nil {
+			badge.CredentialUrl = badges.CredentialUrl(ctx.DbContext.Endpoint, assertion.Id)
+		}
+
+		wallet = append(wallet, badge)
+	}
+
+	WriteResponse[[]*common.WalletBadge](writer, request, &wallet)
+}
+
+func (ctx *RouteContext) GetBadgeIssuer(writer http.ResponseWriter, request *http.Request) {
+	render.JSON(writer, request, badges.NewIssuer(ctx.DbContext.Endpoint))
+}
+
+func (ctx *RouteContext) GetBadgeIssuerKeys(writer http.ResponseWriter, request *http.Request) {
+	if ctx.BadgeSigner == nil {
+		WriteErrResponse(writer, request, errors.New("issuer key isn't set"),
+			&ResponseError{Code: ErrParameter, Message: "Open Badges 3.0 credentials aren't issued."}, 404)
+		return
+	}
+
+	render.JSON(writer, request, ctx.BadgeSigner.KeySet())
+}
+
+func (ctx *RouteContext) GetBadgeClass(writer http.ResponseWriter, request *http.Request) {
+	badgeClass, err := ctx.DbContext.GetBadgeClass(chi.URLParam(request, "badgeClassId"))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrParameter, Message: "Wrong badge class id."}, 400)
+		return
+	}
+
+	if badgeClass == nil {
+		WriteErrResponse(writer, request, errors.New("badge class not found"),
+			&ResponseError{Code: ErrParameter, Message: "Badge class not found."}, 404)
+		return
+	}
+
+	render.JSON(writer, request, badges.NewBadgeClass(ctx.DbContext.Endpoint, badgeClass))
+}
+
+func (ctx *RouteContext) GetBadgeAssertion(writer http.ResponseWriter, request *http.Request) {
+	assertion, ok := ctx.badgeAssertion(writer, request)
+	if !ok {
+		return
+	}
+
+	// Hosted verification treats 410 Gone as revoked assertion
+	if assertion.Revoked {
+		render.Status(request, http.StatusGone)
+	}
+
+	render.JSON(writer, request, badges.NewAssertion(ctx.DbContext.Endpoint, assertion))
+}
+
+func (ctx *RouteContext) GetBadgeCredential(writer http.ResponseWriter, request *http.Request) {
+	// Unsigned credential isn't verifiable, so it isn't served
+	if ctx.BadgeSigner == nil {
+		WriteErrResponse(writer, request, errors.New("issuer key isn't set"),
+			&ResponseError{Code: ErrParameter, Message: "Open Badges 3.0 credentials aren't issued."}, 404)
+		return
+	}
+
+	assertion, ok := ctx.badgeAssertion(writer, request)
+	if !ok {
+		return
+	}
+
+	badgeClass, err := ctx.DbContext.GetBadgeClass(assertion.BadgeClassId)
+
+	if err != nil || badgeClass == nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get badge class."}, 400)
+		return
+	}
+
+	credential, err := ctx.BadgeSigner.Sign(badges.NewCredential(ctx.DbContext.Endpoint, assertion, badgeClass))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't sign credential."}, 400)
+		return
+	}
+
+	status := http.StatusOK
+
+	if assertion.Revoked {
+		status = http.StatusGone
+	}
+
+	writer.Header().Set("Content-Type", badges.CredentialMediaType)
+	writer.WriteHeader(status)
+	_, _ = writer.Write([]byte(credential))
+}
+
+// badgeAssertion return assertion from url. If assertion doesn't exist, error response is written
+func (ctx *RouteContext) badgeAssertion(writer http.ResponseWriter, request *http.Request) (*common.BadgeAssertion, bool) {
+	assertion, err := ctx.DbContext.GetBadgeAssertion(chi.URLParam(request, "assertionId"))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrParameter, Message: "Wrong assertion id."}, 400)
+		return nil, false
+	}
+
+	if assertion == nil {
+		WriteErrResponse(writer, request, errors.New("assertion not found"),
+			&ResponseError{Code: ErrParameter, Message: "Assertion not found."}, 404)
+		return nil, false
+	}
+
+	return assertion, true
+}
@@ -7,20 +7,31 @@ import (
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-chi/render"
 	"golang.org/x/exp/slices"
+	"golang.org/x/text/language"
 	"net/http"
+	"net/url"
+	"opencourse/achievements"
+	"opencourse/badges"
+	"opencourse/common"
 	"opencourse/database"
+	"opencourse/notifications"
+	"opencourse/sandbox"
+	"opencourse/search"
+	"strconv"
 	"strings"
 )
 
 const (
-	ErrInternal          = 1 // ErrInternal - internal business logic
-	ErrBinding           = 2 // ErrBinding - data binding error
-	ErrParameter         = 3 // ErrParameter - parameter wrong error
-	ErrLoginOrPassword   = 4 // ErrLoginOrPassword - login or password is incorrect
-	ErrUserAlreadyExists = 5 // ErrUserAlreadyExists - user with same login already exist
-	ErrValid             = 6 // ErrValid - validation error
-	ErrAuth              = 7 // ErrAuth authentication error
-	ErrForbidden         = 8 // ErrForbidden access forbidden
+	ErrInternal          = 1  // ErrInternal - internal business logic
+	ErrBinding           = 2  // ErrBinding - data binding error
+	ErrParameter         = 3  // ErrParameter - parameter wrong error
+	ErrLoginOrPassword   = 4  // ErrLoginOrPassword - login or password is incorrect
+	ErrUserAlreadyExists = 5  // ErrUserAlreadyExists - user with same login already exist
+	ErrValid             = 6  // ErrValid - validation error
+	ErrAuth              = 7  // ErrAuth authentication error
+	ErrForbidden         = 8  // ErrForbidden access forbidden
+	ErrAttempts          = 9  // ErrAttempts attempts limit is reached or cooldown isn't over
+	ErrSessionClosed     = 10 // ErrSessionClosed quiz session is submitted or deadline is passed
 )
 
 // ResponseError model with error description
@@ -31,14 +42,21 @@ type ResponseError struct {
 
 // RouteContext contains data for request handlers
 type RouteContext struct {
-	DbContext database.DbContext // DbContext, contains methods and properties for work with db
-	TokenAuth *jwtauth.JWTAuth   // TokenAuth contains methods for decode and encode jwt tokens
+	DbContext     database.DbContext   // DbContext, contains methods and properties for work with db
+	TokenAuth     *jwtauth.JWTAuth     // TokenAuth contains methods for decode and encode jwt tokens
+	Executor      sandbox.Executor     // Executor runs programs of code tests
+	Engine        *achievements.Engine // Engine of achievements, which are declared in config
+	Search        search.Index         // Search index of courses
+	Notifications *notifications.Hub   // Hub of notification streams
+	BadgeSigner   *badges.Signer       // Signer of Open Badges 3.0 credentials. Credentials aren't issued, if it's nil
 }
 
 // Response is model for http handler response. Contains properties with user data and error
 type Response[T any] struct {
-	Payload *T             `json:"payload,omitempty"` // Payload is a user model with data
-	Error   *ResponseError `json:"error,omitempty"`   // Error field contains a description of the error
+	Payload    *T             `json:"payload,omitempty"`     // Payload is a user model with data
+	NextCursor string         `json:"next_cursor,omitempty"` // NextCursor is a cursor of next page of list
+	HasMore    *bool          `json:"has_more,omitempty"`    // HasMore is set for lists. List has next page, if it's true
+	Error      *ResponseError `json:"error,omitempty"`       // Error field contains a description of the error
 }
 
 // Request is a user request model
@@ -68,6 +86,23 @@ func WriteResponse[T any](writer http.ResponseWriter, request *http.Request, pay
 	}
 }
 
+// WriteListResponse function for build and write data for success response with list page
+func WriteListResponse[T any](writer http.ResponseWriter, request *http.Request, payload *[]T, page *common.Page) {
+
+	response := &Response[[]T]{Payload: payload}
+
+	if page != nil {
+		response.NextCursor = page.NextCursor
+		response.HasMore = &page.HasMore
+	}
+
+	err := render.Render(writer, request, response)
+
+	if err != nil {
+		writer.WriteHeader(500)
+	}
+}
+
 // WriteErrResponse function for build and write data for error response
 func WriteErrResponse(writer http.ResponseWriter, request *http.Request, internalError error, responseError *ResponseError, httpStatus int) {
 
@@ -98,7 +133,8 @@ func WriteErrResponse(writer http.ResponseWriter, request *http.Request, interna
 	}
 }
 
-func InRole(writer http.ResponseWriter, request *http.Request, role string) bool {
+// InRole check that user from token has at least one of the roles. If not, write error response and return false
+func InRole(writer http.ResponseWriter, request *http.Request, roles ...string) bool {
 	_, claims, err := jwtauth.FromContext(request.Context())
 
 	if err != nil {
@@ -115,15 +151,159 @@ func InRole(writer http.ResponseWriter, request *http.Request, role string) bool
 		return false
 	}
 
-	roles := strings.Split(roleStr.(string), ",")
+	userRoles := strings.Split(roleStr.(string), ",")
 
-	if !slices.Contains[string](roles, role) {
-		err = errors.New("user is not in role, access forbidden")
-		WriteErrResponse(writer, request, err,
-			&ResponseError{Code: ErrAuth, Message: "Forbidden"}, 403)
+	for _, role := range roles {
+		if slices.Contains[string](userRoles, role) {
+			return true
+		}
+	}
+
+	err = errors.New("user is not in role, access forbidden")
+	WriteErrResponse(writer, request, err,
+		&ResponseError{Code: ErrAuth, Message: "Forbidden"}, 403)
+
+	return false
+}
+
+// HasRole check that user from token has at least one of the roles. Unlike InRole, response isn't written
+func HasRole(request *http.Request, roles ...string) bool {
+	_, claims, err := jwtauth.FromContext(request.Context())
 
+	if err != nil {
 		return false
 	}
 
-	return true
+	roleStr, ok := claims["roles"].(string)
+
+	if !ok {
+		return false
+	}
+
+	userRoles := strings.Split(roleStr, ",")
+
+	for _, role := range roles {
+		if slices.Contains[string](userRoles, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UserId return user id from token. If token hasn't user id, write error response and return false
+func UserId(writer http.ResponseWriter, request *http.Request) (string, bool) {
+	_, claims, err := jwtauth.FromContext(request.Context())
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrAuth, Message: "Invalid token"}, 401)
+		return "", false
+	}
+
+	id, ok := claims["id"].(string)
+
+	if !ok || len(id) == 0 {
+		WriteErrResponse(writer, request, errors.New("token hasn't claim id"),
+			&ResponseError{Code: ErrAuth, Message: "Invalid token"}, 401)
+		return "", false
+	}
+
+	return id, true
+}
+
+// ListPage return page query from url parameters cursor, take and skip. If parameters are wrong, write error response
+// and return false. Take is limited by max page size
+func ListPage(writer http.ResponseWriter, request *http.Request, defaultTake int) (*common.PageQuery, bool) {
+	urlValues := request.URL.Query()
+
+	take := defaultTake
+	skip := 0
+	var err error = nil
+
+	if urlValues.Has("take") {
+		take, err = strconv.Atoi(urlValues.Get("take"))
+
+		if err != nil || take <= 0 {
+			WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong take parameter."}, 400)
+			return nil, false
+		}
+	}
+
+	if urlValues.Has("skip") {
+		skip, err = strconv.Atoi(urlValues.Get("skip"))
+
+		if err != nil || skip < 0 {
+			WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong skip parameter."}, 400)
+			return nil, false
+		}
+	}
+
+	if take > common.MaxPageSize {
+		take = common.MaxPageSize
+	}
+
+	return &common.PageQuery{Cursor: urlValues.Get("cursor"), Take: int64(take), Skip: int64(skip)}, true
+}
+
+// RequestLangs return supported languages of request in order of preference: preferred languages, url parameter
+// "lang", Accept-Language header and fallback language
+func RequestLangs(request *http.Request, preferred ...string) []string {
+	var langs []string
+
+	add := func(lang string) {
+		if slices.Contains(common.Langs, lang) && !slices.Contains(langs, lang) {
+			langs = append(langs, lang)
+		}
+	}
+
+	for _, lang := range preferred {
+		add(lang)
+	}
+
+	add(request.URL.Query().Get("lang"))
+
+	// Tags are sorted by quality, wrong header is ignored
+	tags, _, _ := language.ParseAcceptLanguage(request.Header.Get("Accept-Language"))
+
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		add(base.String())
+	}
+
+	add(common.FallbackLang)
+
+	return langs
+}
+
+// RedactQuery replace values of url parameters in request uri, which is written to log. Url of handlers isn't changed
+func RedactQuery(params ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			request.RequestURI = redactUri(request.RequestURI, params)
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// redactUri replace values of url parameters in request uri
+func redactUri(uri string, params []string) string {
+	path, query, found := strings.Cut(uri, "?")
+
+	if !found {
+		return uri
+	}
+
+	pairs := strings.Split(query, "&")
+
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(key)
+
+		if err == nil && slices.Contains(params, key) {
+			pairs[i] = url.QueryEscape(key) + "=REDACTED"
+		}
+	}
+
+	return path + "?" + strings.Join(pairs, "&")
 }
@@ -0,0 +1,192 @@
+package v1
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"net/http"
+	"opencourse/bundle"
+	"opencourse/common"
+	"opencourse/scorm"
+	"strings"
+)
+
+// MaxBundleSize maximum size of imported bundle
+const MaxBundleSize = 64 << 20
+
+func (ctx *RouteContext) ExportCourse(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin, common.RoleAuthor)
+	if !ok {
+		return
+	}
+
+	// Bundle contains right answers, so only author of course exports it
+	course, ok := ctx.courseAccess(writer, request, chi.URLParam(request, "courseId"), false)
+	if !ok {
+		return
+	}
+
+	courseId := course.Id
+	format := request.URL.Query().Get("format")
+
+	courseBundle, err := ctx.DbContext.ExportCourse(courseId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't export course."}, 400)
+		return
+	}
+
+	var buffer bytes.Buffer
+	contentType := "application/zip"
+	fileName := fmt.Sprintf("course-%s.zip", courseId)
+
+	switch format {
+	case "json":
+		contentType = "application/json"
+		fileName = fmt.Sprintf("course-%s.json", courseId)
+		err = bundle.WriteJSON(&buffer, courseBundle)
+	case "", "zip":
+		err = bundle.WriteZip(&buffer, courseBundle, bundle.CourseStore(ctx.DbContext.MediaDir, courseId))
+	default:
+		WriteErrResponse(writer, request, nil,
+			&ResponseError{Code: ErrParameter, Message: "Wrong format parameter."}, 400)
+		return
+	}
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't export course."}, 400)
+		return
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	_, _ = writer.Write(buffer.Bytes())
+}
+
+func (ctx *RouteContext) ImportCourse(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin, common.RoleAuthor)
+	if !ok {
+		return
+	}
+
+	authorId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	categoryId := request.URL.Query().Get("category_id")
+
+	if request.ContentLength > MaxBundleSize {
+		WriteErrResponse(writer, request, errors.New("bundle is too large"),
+			&ResponseError{Code: ErrValid, Message: "Bundle is too large."}, 413)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, MaxBundleSize))
+
+	// Body without length is limited while reading
+	if err != nil && len(data) >= MaxBundleSize {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrValid, Message: "Bundle is too large."}, 413)
+		return
+	}
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrBinding, Message: "Invalid bundle"}, 400)
+		return
+	}
+
+	var courseBundle *common.CourseBundle
+
+	isJSON := strings.HasPrefix(request.Header.Get("Content-Type"), "application/json")
+
+	if isJSON {
+		courseBundle, err = bundle.ReadJSON(bytes.NewReader(data))
+	} else {
+		courseBundle, err = bundle.ReadZip(bytes.NewReader(data), int64(len(data)))
+	}
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrValid, Message: "Invalid bundle"}, 400)
+		return
+	}
+
+	id, err := ctx.DbContext.ImportCourse(courseBundle, categoryId, authorId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't import course."}, 400)
+		return
+	}
+
+	// Media files are saved to folder of course only after bundle is validated and imported
+	if !isJSON {
+		err = bundle.SaveMedia(bytes.NewReader(data), int64(len(data)), courseBundle,
+			bundle.CourseStore(ctx.DbContext.MediaDir, id))
+
+		if err != nil {
+			WriteErrResponse(writer, request, err,
+				&ResponseError{Code: ErrInternal, Message: "Internal error. Can't save media files."}, 400)
+			return
+		}
+	}
+
+	WriteResponse[string](writer, request, &id)
+}
+
+func (ctx *RouteContext) ExportScorm(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin, common.RoleAuthor)
+	if !ok {
+		return
+	}
+
+	course, ok := ctx.courseAccess(writer, request, chi.URLParam(request, "courseId"), false)
+	if !ok {
+		return
+	}
+
+	courseId := course.Id
+
+	version := scorm.Version2004
+	if request.URL.Query().Has("version") {
+		version = request.URL.Query().Get("version")
+	}
+
+	if version != scorm.Version12 && version != scorm.Version2004 {
+		WriteErrResponse(writer, request, nil,
+			&ResponseError{Code: ErrParameter, Message: "Wrong version parameter."}, 400)
+		return
+	}
+
+	courseBundle, err := ctx.DbContext.ExportCourse(courseId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't export course."}, 400)
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	err = scorm.Export(&buffer, courseBundle, version, bundle.CourseStore(ctx.DbContext.MediaDir, courseId))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't export SCORM package."}, 400)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/zip")
+	writer.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("course-%s-scorm-%s.zip", courseId, version)))
+	_, _ = writer.Write(buffer.Bytes())
+}
@@ -9,9 +9,16 @@ import (
 
 func (ctx *RouteContext) GetCategories(writer http.ResponseWriter, request *http.Request) {
 
-	lang := chi.URLParam(request, "lang")
+	// Categories without translation to language of url are returned in fallback languages
+	langs := RequestLangs(request, chi.URLParam(request, "lang"))
 
-	categories, err := ctx.DbContext.GetCategories(lang)
+	getCategories := ctx.DbContext.GetCategories
+
+	if request.URL.Query().Get("tree") == "true" {
+		getCategories = ctx.DbContext.GetCategoryTree
+	}
+
+	categories, err := getCategories(langs...)
 
 	if err != nil {
 		WriteErrResponse(writer, request, err,
@@ -78,3 +85,61 @@ func (ctx *RouteContext) PutCategory(writer http.ResponseWriter, request *http.R
 	result := "success"
 	WriteResponse[string](writer, request, &result)
 }
+
+func (ctx *RouteContext) DeleteCategory(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	categoryId := chi.URLParam(request, "categoryId")
+	targetId := request.URL.Query().Get("target_id")
+
+	if len(targetId) == 0 || targetId == categoryId {
+		WriteErrResponse(writer, request, nil,
+			&ResponseError{Code: ErrParameter, Message: "Target category for courses is required."}, 400)
+		return
+	}
+
+	err := ctx.DbContext.DeleteCategory(categoryId, targetId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't delete category."}, 400)
+		return
+	}
+
+	result := "success"
+	WriteResponse[string](writer, request, &result)
+}
+
+func (ctx *RouteContext) MoveCategory(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	categoryId := chi.URLParam(request, "categoryId")
+
+	openRequest := &Request[common.MoveCategoryQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	err = ctx.DbContext.MoveCategory(categoryId, openRequest.Payload.ParentId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't move category."}, 400)
+		return
+	}
+
+	result := "success"
+	WriteResponse[string](writer, request, &result)
+}
@@ -0,0 +1,116 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"opencourse/certificates"
+	"opencourse/common"
+	"strings"
+)
+
+func (ctx *RouteContext) GetCertificates(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	userCertificates, err := ctx.DbContext.GetUserCertificates(userId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get certificates."}, 400)
+		return
+	}
+
+	WriteResponse[[]*common.Certificate](writer, request, &userCertificates)
+}
+
+func (ctx *RouteContext) PostCertificate(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	courseId := chi.URLParam(request, "courseId")
+
+	certificate, err := certificates.IssueIfCompleted(&ctx.DbContext, userId, courseId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't issue certificate."}, 400)
+		return
+	}
+
+	if certificate == nil {
+		WriteErrResponse(writer, request, errors.New("course isn't completed"),
+			&ResponseError{Code: ErrValid, Message: "Course isn't completed."}, 400)
+		return
+	}
+
+	WriteResponse[common.Certificate](writer, request, certificate)
+}
+
+func (ctx *RouteContext) VerifyCertificate(writer http.ResponseWriter, request *http.Request) {
+	code := chi.URLParam(request, "code")
+
+	certificate, err := ctx.DbContext.GetCertificateByCode(code)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrParameter, Message: "Wrong verification code."}, 400)
+		return
+	}
+
+	verification := common.CertificateVerification{Code: code}
+
+	if certificate != nil {
+		verification = common.CertificateVerification{
+			Valid:      true,
+			Code:       certificate.Code,
+			UserName:   certificate.UserName,
+			CourseName: certificate.CourseName,
+			DateIssue:  certificate.DateIssue,
+		}
+	}
+
+	WriteResponse[common.CertificateVerification](writer, request, &verification)
+}
+
+func (ctx *RouteContext) GetCertificatePdf(writer http.ResponseWriter, request *http.Request) {
+	code := chi.URLParam(request, "code")
+
+	certificate, err := ctx.DbContext.GetCertificateByCode(code)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrParameter, Message: "Wrong verification code."}, 400)
+		return
+	}
+
+	if certificate == nil {
+		WriteErrResponse(writer, request, errors.New("certificate not found"),
+			&ResponseError{Code: ErrParameter, Message: "Certificate not found."}, 404)
+		return
+	}
+
+	verifyUrl := ""
+
+	if len(ctx.DbContext.Endpoint) > 0 {
+		verifyUrl = fmt.Sprintf("%s/v1/certificates/%s/verify", strings.TrimRight(ctx.DbContext.Endpoint, "/"), certificate.Code)
+	}
+
+	content, err := certificates.Render(certificate, verifyUrl)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't render certificate."}, 400)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/pdf")
+	writer.Header().Set("Content-Disposition",
+		fmt.Sprintf("inline; filename=%q", fmt.Sprintf("certificate-%s.pdf", certificate.Code)))
+	_, _ = writer.Write(content)
+}
@@ -0,0 +1,333 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"opencourse/common"
+)
+
+func (ctx *RouteContext) GetStageComments(writer http.ResponseWriter, request *http.Request) {
+	query, ok := ListPage(writer, request, 20)
+	if !ok {
+		return
+	}
+
+	sort := request.URL.Query().Get("sort")
+
+	if len(sort) == 0 {
+		sort = common.CommentsNewest
+	}
+
+	comments, page, err := ctx.DbContext.GetStageCommentsPage(chi.URLParam(request, "stageId"), sort, query)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get comments."}, 400)
+		return
+	}
+
+	WriteListResponse[*common.StageComment](writer, request, &comments, page)
+}
+
+func (ctx *RouteContext) GetCommentReplies(writer http.ResponseWriter, request *http.Request) {
+	query, ok := ListPage(writer, request, 20)
+	if !ok {
+		return
+	}
+
+	replies, page, err := ctx.DbContext.GetCommentRepliesPage(chi.URLParam(request, "commentId"), query)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get replies."}, 400)
+		return
+	}
+
+	WriteListResponse[*common.StageComment](writer, request, &replies, page)
+}
+
+func (ctx *RouteContext) PostStageComment(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.AddStageCommentQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	user, err := ctx.DbContext.GetUser(userId)
+
+	if err != nil || user == nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't add comment."}, 400)
+		return
+	}
+
+	comment, err := ctx.DbContext.AddStageComment(userId, user.Name, chi.URLParam(request, "stageId"), &openRequest.Payload)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't add comment."}, 400)
+		return
+	}
+
+	WriteResponse[common.StageComment](writer, request, comment)
+}
+
+func (ctx *RouteContext) PutStageComment(writer http.ResponseWriter, request *http.Request) {
+	openRequest := &Request[common.UpdateStageCommentQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	// Only author edits comment
+	comment, ok := ctx.commentAccess(writer, request, false)
+	if !ok {
+		return
+	}
+
+	found, err := ctx.DbContext.UpdateStageComment(comment.Id, openRequest.Payload.Body)
+
+	ctx.writeCommentResult(writer, request, found, err, "Internal error. Can't edit comment.")
+}
+
+func (ctx *RouteContext) DeleteStageComment(writer http.ResponseWriter, request *http.Request) {
+	comment, ok := ctx.commentAccess(writer, request, true)
+	if !ok {
+		return
+	}
+
+	found, err := ctx.DbContext.DeleteStageComment(comment.Id)
+
+	ctx.writeCommentResult(writer, request, found, err, "Internal error. Can't delete comment.")
+}
+
+func (ctx *RouteContext) UpvoteStageComment(writer http.ResponseWriter, request *http.Request) {
+	ctx.voteStageComment(writer, request, true)
+}
+
+func (ctx *RouteContext) DeleteStageCommentUpvote(writer http.ResponseWriter, request *http.Request) {
+	ctx.voteStageComment(writer, request, false)
+}
+
+func (ctx *RouteContext) HideStageComment(writer http.ResponseWriter, request *http.Request) {
+	openRequest := &Request[common.HideStageCommentQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	comment, ok := ctx.stageComment(writer, request)
+	if !ok {
+		return
+	}
+
+	// Author can't hide or show own comment, only moderator
+	if !ctx.canModerate(writer, request, comment.CourseId) {
+		return
+	}
+
+	found, err := ctx.DbContext.HideStageComment(comment.Id, openRequest.Payload.Hidden)
+
+	ctx.writeCommentResult(writer, request, found, err, "Internal error. Can't hide comment.")
+}
+
+func (ctx *RouteContext) MarkCommentAnswer(writer http.ResponseWriter, request *http.Request) {
+	ctx.markCommentAnswer(writer, request, true)
+}
+
+func (ctx *RouteContext) DeleteCommentAnswer(writer http.ResponseWriter, request *http.Request) {
+	ctx.markCommentAnswer(writer, request, false)
+}
+
+/*
+voteStageComment add or remove upvote of user. Parameters:
+up - true for add upvote, false for remove upvote;
+*/
+func (ctx *RouteContext) voteStageComment(writer http.ResponseWriter, request *http.Request, up bool) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	comment, ok := ctx.stageComment(writer, request)
+	if !ok {
+		return
+	}
+
+	// Vote of hidden comment is rejected as vote of missing comment
+	if comment.Hidden {
+		WriteErrResponse(writer, request, errors.New("comment is hidden"),
+			&ResponseError{Code: ErrParameter, Message: "Comment isn't found."}, 404)
+		return
+	}
+
+	if comment.UserId == userId {
+		WriteErrResponse(writer, request, errors.New("user can't vote own comment"),
+			&ResponseError{Code: ErrValid, Message: "User can't vote own comment."}, 400)
+		return
+	}
+
+	// Repeated vote doesn't change comment and isn't error
+	_, err := ctx.DbContext.VoteStageComment(comment.Id, userId, up)
+
+	ctx.writeCommentResult(writer, request, true, err, "Internal error. Can't vote comment.")
+}
+
+/*
+markCommentAnswer mark reply as answer of thread or remove mark. Answer is marked by thread author
+or moderator. Parameters:
+answer - reply is answer;
+*/
+func (ctx *RouteContext) markCommentAnswer(writer http.ResponseWriter, request *http.Request, answer bool) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	comment, ok := ctx.stageComment(writer, request)
+	if !ok {
+		return
+	}
+
+	if len(comment.ParentId) == 0 {
+		WriteErrResponse(writer, request, errors.New("root comment can't be answer"),
+			&ResponseError{Code: ErrValid, Message: "Only reply can be answer."}, 400)
+		return
+	}
+
+	thread, err := ctx.DbContext.GetStageComment(comment.ParentId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't mark answer."}, 400)
+		return
+	}
+
+	// Thread author marks answer to own question, moderator marks answer to any question
+	if thread == nil || thread.UserId != userId {
+		if !ctx.canModerate(writer, request, comment.CourseId) {
+			return
+		}
+	}
+
+	found, err := ctx.DbContext.MarkCommentAnswer(comment.Id, answer)
+
+	ctx.writeCommentResult(writer, request, found, err, "Internal error. Can't mark answer.")
+}
+
+/*
+commentAccess return comment from url, if user is comment author. If moderate is true, moderator has access too.
+If comment isn't found or access is forbidden, write error response and return false. Parameters:
+moderate - moderator has access to comment;
+*/
+func (ctx *RouteContext) commentAccess(writer http.ResponseWriter, request *http.Request,
+	moderate bool) (*common.StageComment, bool) {
+
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return nil, false
+	}
+
+	comment, ok := ctx.stageComment(writer, request)
+	if !ok {
+		return nil, false
+	}
+
+	if comment.UserId == userId {
+		return comment, true
+	}
+
+	if !moderate {
+		WriteErrResponse(writer, request, errors.New("user isn't author of comment"),
+			&ResponseError{Code: ErrForbidden, Message: "Forbidden"}, 403)
+		return nil, false
+	}
+
+	return comment, ctx.canModerate(writer, request, comment.CourseId)
+}
+
+// stageComment return comment from url. If comment isn't found, write error response and return false
+func (ctx *RouteContext) stageComment(writer http.ResponseWriter, request *http.Request) (*common.StageComment, bool) {
+	comment, err := ctx.DbContext.GetStageComment(chi.URLParam(request, "commentId"))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get comment."}, 400)
+		return nil, false
+	}
+
+	if comment == nil {
+		WriteErrResponse(writer, request, errors.New("comment isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Comment isn't found."}, 404)
+		return nil, false
+	}
+
+	return comment, true
+}
+
+/*
+canModerate check that user is admin, moderator or owner of course. If not, write error response and return false.
+Parameters:
+courseId - course id of moderated content;
+*/
+func (ctx *RouteContext) canModerate(writer http.ResponseWriter, request *http.Request, courseId string) bool {
+	if HasRole(request, common.RoleAdmin, common.RoleModerator) {
+		return true
+	}
+
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return false
+	}
+
+	course, err := ctx.DbContext.GetCourse(courseId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get course."}, 400)
+		return false
+	}
+
+	if course == nil || course.AuthorId != userId {
+		WriteErrResponse(writer, request, errors.New("user isn't moderator of course"),
+			&ResponseError{Code: ErrForbidden, Message: "Forbidden"}, 403)
+		return false
+	}
+
+	return true
+}
+
+// writeCommentResult write success response or error response, if comment update failed or comment isn't found
+func (ctx *RouteContext) writeCommentResult(writer http.ResponseWriter, request *http.Request, found bool, err error,
+	message string) {
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrInternal, Message: message}, 400)
+		return
+	}
+
+	if !found {
+		WriteErrResponse(writer, request, errors.New("comment isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Comment isn't found."}, 404)
+		return
+	}
+
+	result := "success"
+	WriteResponse[string](writer, request, &result)
+}
@@ -0,0 +1,173 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"opencourse/common"
+)
+
+func (ctx *RouteContext) GetCourseReviews(writer http.ResponseWriter, request *http.Request) {
+	query, ok := ListPage(writer, request, 20)
+	if !ok {
+		return
+	}
+
+	reviews, page, err := ctx.DbContext.GetCourseReviewsPage(chi.URLParam(request, "courseId"), query)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get reviews."}, 400)
+		return
+	}
+
+	WriteListResponse[*common.CourseReview](writer, request, &reviews, page)
+}
+
+func (ctx *RouteContext) PostCourseReview(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.AddCourseReviewQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	courseId := chi.URLParam(request, "courseId")
+
+	// Only learners of course can rate it
+	hasProgress, err := ctx.DbContext.HasCourseProgress(userId, courseId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't save review."}, 400)
+		return
+	}
+
+	if !hasProgress {
+		WriteErrResponse(writer, request, errors.New("user hasn't progress in course"),
+			&ResponseError{Code: ErrForbidden, Message: "Only learners of course can review it."}, 403)
+		return
+	}
+
+	user, err := ctx.DbContext.GetUser(userId)
+
+	if err != nil || user == nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't save review."}, 400)
+		return
+	}
+
+	review, err := ctx.DbContext.SaveCourseReview(userId, user.Name, courseId, &openRequest.Payload)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't save review."}, 400)
+		return
+	}
+
+	WriteResponse[common.CourseReview](writer, request, review)
+}
+
+func (ctx *RouteContext) ReplyCourseReview(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.ReplyCourseReviewQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	review, err := ctx.DbContext.GetCourseReview(chi.URLParam(request, "reviewId"))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't reply to review."}, 400)
+		return
+	}
+
+	if review == nil {
+		WriteErrResponse(writer, request, errors.New("review isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Review isn't found."}, 404)
+		return
+	}
+
+	course, err := ctx.DbContext.GetCourse(review.CourseId)
+
+	if err != nil || course == nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't reply to review."}, 400)
+		return
+	}
+
+	// Only author of course replies to reviews
+	if course.AuthorId != userId && !HasRole(request, common.RoleAdmin) {
+		WriteErrResponse(writer, request, errors.New("user isn't author of course"),
+			&ResponseError{Code: ErrForbidden, Message: "Forbidden"}, 403)
+		return
+	}
+
+	found, err := ctx.DbContext.ReplyCourseReview(review.Id, userId, openRequest.Payload.Text)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't reply to review."}, 400)
+		return
+	}
+
+	if !found {
+		WriteErrResponse(writer, request, errors.New("review isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Review isn't found."}, 404)
+		return
+	}
+
+	result := "success"
+	WriteResponse[string](writer, request, &result)
+}
+
+func (ctx *RouteContext) HideCourseReview(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin, common.RoleModerator)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.HideCourseReviewQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	found, err := ctx.DbContext.HideCourseReview(chi.URLParam(request, "reviewId"), openRequest.Payload.Hidden)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't hide review."}, 400)
+		return
+	}
+
+	if !found {
+		WriteErrResponse(writer, request, errors.New("review isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Review isn't found."}, 404)
+		return
+	}
+
+	result := "success"
+	WriteResponse[string](writer, request, &result)
+}
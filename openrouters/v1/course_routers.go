@@ -1,50 +1,48 @@
 package v1
 
 import (
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"golang.org/x/exp/slices"
 	"net/http"
 	"opencourse/common"
-	"strconv"
 )
 
 func (ctx *RouteContext) GetCourses(writer http.ResponseWriter, request *http.Request) {
 
 	categoryId := chi.URLParam(request, "categoryId")
 
-	urlValues := request.URL.Query()
-
-	take := 5
-	skip := 0
-	var err error = nil
-
-	if urlValues.Has("take") {
-		take, err = strconv.Atoi(urlValues.Get("take"))
-		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong take parameter."}, 400)
+	query, ok := ListPage(writer, request, 5)
+	if !ok {
 		return
 	}
 
-	if urlValues.Has("skip") {
-		skip, err = strconv.Atoi(urlValues.Get("skip"))
+	courses, page, err := ctx.DbContext.GetCoursesPage(categoryId, RequestLangs(request), query)
 
-		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong skip parameter."}, 400)
-		return
+	if err == nil {
+		err = ctx.DbContext.AttachPromotionLabels(courses)
 	}
 
-	courses, err := ctx.DbContext.GetCourses(categoryId, int64(take), int64(skip))
-
 	if err != nil {
 		WriteErrResponse(writer, request, err,
 			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get courses."}, 400)
 		return
 	}
 
-	WriteResponse[[]*common.Course](writer, request, &courses)
+	WriteListResponse[*common.Course](writer, request, &courses, page)
 }
 
 func (ctx *RouteContext) GetCourse(writer http.ResponseWriter, request *http.Request) {
 
-	courseId := chi.URLParam(request, "courseId")
+	courseId, err := ctx.DbContext.ResolveTranslation(common.TranslationCourse,
+		chi.URLParam(request, "courseId"), RequestLangs(request))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get course."}, 400)
+		return
+	}
 
 	course, err := ctx.DbContext.GetCourse(courseId)
 
@@ -54,6 +52,12 @@ func (ctx *RouteContext) GetCourse(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
+	if course == nil {
+		WriteErrResponse(writer, request, errors.New("course isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Course isn't found."}, 404)
+		return
+	}
+
 	WriteResponse[common.Course](writer, request, course)
 
 }
@@ -75,6 +79,13 @@ func (ctx *RouteContext) PostCourse(writer http.ResponseWriter, request *http.Re
 		return
 	}
 
+	authorId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	openRequest.Payload.AuthorId = authorId
+
 	id, err := ctx.DbContext.AddCourse(&openRequest.Payload)
 
 	if err != nil {
@@ -85,3 +96,85 @@ func (ctx *RouteContext) PostCourse(writer http.ResponseWriter, request *http.Re
 
 	WriteResponse[string](writer, request, &id)
 }
+
+func (ctx *RouteContext) CloneCourse(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin, common.RoleAuthor)
+	if !ok {
+		return
+	}
+
+	authorId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.CloneCourseQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	if len(openRequest.Payload.Lang) > 0 && !slices.Contains(common.Langs, openRequest.Payload.Lang) {
+		WriteErrResponse(writer, request, errors.New("language isn't supported"),
+			&ResponseError{Code: ErrValid, Message: "Language isn't supported."}, 400)
+		return
+	}
+
+	// Author clones own courses and published courses of other authors
+	course, ok := ctx.courseAccess(writer, request, chi.URLParam(request, "courseId"), true)
+	if !ok {
+		return
+	}
+
+	id, err := ctx.DbContext.CloneCourse(course.Id, authorId, &openRequest.Payload)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't clone course."}, 400)
+		return
+	}
+
+	WriteResponse[string](writer, request, &id)
+}
+
+/*
+courseAccess return course, if user is admin or author of course. If enabled is true, enabled course is accessible
+to every user. If course isn't found or access is forbidden, write error response and return false. Parameters:
+courseId - course id;
+enabled - enabled course is accessible to every user;
+*/
+func (ctx *RouteContext) courseAccess(writer http.ResponseWriter, request *http.Request, courseId string,
+	enabled bool) (*common.Course, bool) {
+
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return nil, false
+	}
+
+	course, err := ctx.DbContext.GetCourse(courseId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get course."}, 400)
+		return nil, false
+	}
+
+	if course == nil {
+		WriteErrResponse(writer, request, errors.New("course isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Course isn't found."}, 404)
+		return nil, false
+	}
+
+	if course.AuthorId != userId && !HasRole(request, common.RoleAdmin) && !(enabled && course.Enabled) {
+		WriteErrResponse(writer, request, errors.New("user isn't author of course"),
+			&ResponseError{Code: ErrForbidden, Message: "Forbidden"}, 403)
+		return nil, false
+	}
+
+	return course, true
+}
@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"opencourse/common"
+	"opencourse/leaderboards"
+	"strconv"
+)
+
+func (ctx *RouteContext) GetLeaderboard(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	urlValues := request.URL.Query()
+
+	metric := chi.URLParam(request, "metric")
+	scope := common.ScopeGlobal
+	scopeId := urlValues.Get("scope_id")
+	window := common.WindowAll
+	take := 20
+	skip := 0
+	var err error = nil
+
+	if urlValues.Has("scope") {
+		scope = urlValues.Get("scope")
+	}
+
+	if urlValues.Has("window") {
+		window = urlValues.Get("window")
+	}
+
+	if !leaderboards.Valid(metric, scope, window) {
+		WriteErrResponse(writer, request, errors.New("unknown leaderboard"),
+			&ResponseError{Code: ErrParameter, Message: "Wrong metric, scope or window parameter."}, 400)
+		return
+	}
+
+	// Category and course leaderboards are separated by scope id
+	if (scope == common.ScopeGlobal) != (len(scopeId) == 0) {
+		WriteErrResponse(writer, request, errors.New("scope id doesn't match scope"),
+			&ResponseError{Code: ErrParameter, Message: "Wrong scope_id parameter."}, 400)
+		return
+	}
+
+	if urlValues.Has("take") {
+		take, err = strconv.Atoi(urlValues.Get("take"))
+
+		if err != nil || take <= 0 {
+			WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong take parameter."}, 400)
+			return
+		}
+	}
+
+	if urlValues.Has("skip") {
+		skip, err = strconv.Atoi(urlValues.Get("skip"))
+
+		if err != nil || skip < 0 {
+			WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong skip parameter."}, 400)
+			return
+		}
+	}
+
+	if take > common.MaxPageSize {
+		take = common.MaxPageSize
+	}
+
+	leaderboard, err := ctx.DbContext.GetLeaderboard(metric, scope, scopeId, window, userId, int64(take), int64(skip))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get leaderboard."}, 400)
+		return
+	}
+
+	WriteResponse[common.Leaderboard](writer, request, leaderboard)
+}
@@ -0,0 +1,215 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/render"
+	"net/http"
+	"opencourse/common"
+	"opencourse/database"
+	"time"
+)
+
+// streamHeartbeat period of comments, which keep idle stream open through proxies
+const streamHeartbeat = 30 * time.Second
+
+func (ctx *RouteContext) GetNotifications(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	query, ok := ListPage(writer, request, 20)
+	if !ok {
+		return
+	}
+
+	unread := request.URL.Query().Get("unread") == "true"
+
+	notifications, page, err := ctx.DbContext.GetNotificationsPage(userId, unread, query)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get notifications."}, 400)
+		return
+	}
+
+	WriteListResponse[*common.Notification](writer, request, &notifications, page)
+}
+
+func (ctx *RouteContext) GetUnreadNotifications(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	count, err := ctx.DbContext.CountUnreadNotifications(userId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't count notifications."}, 400)
+		return
+	}
+
+	WriteResponse[int64](writer, request, &count)
+}
+
+func (ctx *RouteContext) ReadNotifications(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.ReadNotificationsQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	count, err := ctx.DbContext.ReadNotifications(userId, openRequest.Payload.Ids)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't read notifications."}, 400)
+		return
+	}
+
+	WriteResponse[int64](writer, request, &count)
+}
+
+// PostStreamTicket return single-use ticket, which opens notifications stream of user
+func (ctx *RouteContext) PostStreamTicket(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	ticket, err := ctx.Notifications.Ticket(userId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't issue ticket."}, 400)
+		return
+	}
+
+	WriteResponse[string](writer, request, &ticket)
+}
+
+/*
+StreamNotifications send new notifications of user as server-sent events until client disconnects. Stream is opened
+by "ticket" url parameter. Notifications after Last-Event-ID header or "last_event_id" url parameter are sent first,
+so reconnected client doesn't miss them.
+*/
+func (ctx *RouteContext) StreamNotifications(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := ctx.Notifications.Redeem(request.URL.Query().Get("ticket"))
+
+	if !ok {
+		WriteErrResponse(writer, request, errors.New("stream ticket isn't valid"),
+			&ResponseError{Code: ErrAuth, Message: "Invalid ticket"}, 401)
+		return
+	}
+
+	flusher, ok := writer.(http.Flusher)
+
+	if !ok {
+		WriteErrResponse(writer, request, errors.New("response writer isn't flusher"),
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Streaming isn't supported."}, 500)
+		return
+	}
+
+	// Stream is subscribed before missed notifications are read, so notification between them isn't lost
+	stream, closeStream := ctx.Notifications.Subscribe(userId)
+	defer closeStream()
+
+	lastEventId := request.Header.Get("Last-Event-ID")
+
+	if len(lastEventId) == 0 {
+		lastEventId = request.URL.Query().Get("last_event_id")
+	}
+
+	var missed []*common.Notification
+
+	if len(lastEventId) > 0 {
+		var err error
+
+		missed, err = ctx.DbContext.GetNotificationsAfter(userId, lastEventId)
+
+		if err != nil {
+			WriteErrResponse(writer, request, err,
+				&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get notifications."}, 400)
+			return
+		}
+	}
+
+	sent := make(map[string]bool, len(missed))
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for _, notification := range missed {
+		eventId, err := writeNotificationEvent(writer, notification)
+
+		if err != nil {
+			return
+		}
+
+		sent[eventId] = true
+	}
+
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-request.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(writer, ": ping\n\n")
+		case notification := <-stream:
+			var eventId string
+
+			// Notification, which is saved while missed are read, is sent once
+			eventId, err = database.NotificationEventId(notification)
+
+			if err == nil && !sent[eventId] {
+				_, err = writeNotificationEvent(writer, notification)
+			}
+		}
+
+		if err != nil {
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+// writeNotificationEvent write notification as server-sent event and return its event id
+func writeNotificationEvent(writer http.ResponseWriter, notification *common.Notification) (string, error) {
+	eventId, err := database.NotificationEventId(notification)
+
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(notification)
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = fmt.Fprintf(writer, "id: %s\nevent: notification\ndata: %s\n\n", eventId, data)
+
+	return eventId, err
+}
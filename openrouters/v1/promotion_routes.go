@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"opencourse/common"
+	"strconv"
+)
+
+func (ctx *RouteContext) GetPromotions(writer http.ResponseWriter, request *http.Request) {
+	urlValues := request.URL.Query()
+
+	take := 20
+	var err error = nil
+
+	if urlValues.Has("take") {
+		take, err = strconv.Atoi(urlValues.Get("take"))
+
+		if err != nil || take <= 0 {
+			WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong take parameter."}, 400)
+			return
+		}
+	}
+
+	if take > common.MaxPageSize {
+		take = common.MaxPageSize
+	}
+
+	promotions, err := ctx.DbContext.GetPromotionFeed(int64(take))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get promotions."}, 400)
+		return
+	}
+
+	WriteResponse[[]*common.DbCoursePromotion](writer, request, &promotions)
+}
+
+func (ctx *RouteContext) PostPromotion(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.AddPromotionQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	promotionId, err := ctx.DbContext.AddPromotion(&openRequest.Payload)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't create promotion."}, 400)
+		return
+	}
+
+	WriteResponse[string](writer, request, &promotionId)
+}
+
+func (ctx *RouteContext) DeletePromotion(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	deleted, err := ctx.DbContext.DeletePromotion(chi.URLParam(request, "promotionId"))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't delete promotion."}, 400)
+		return
+	}
+
+	if !deleted {
+		WriteErrResponse(writer, request, errors.New("promotion isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Promotion isn't found."}, 404)
+		return
+	}
+
+	result := "success"
+	WriteResponse[string](writer, request, &result)
+}
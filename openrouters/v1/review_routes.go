@@ -0,0 +1,178 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"opencourse/common"
+	"opencourse/grading"
+	"opencourse/reviews"
+	"strconv"
+)
+
+func (ctx *RouteContext) GetDueReviews(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	urlValues := request.URL.Query()
+
+	take := 20
+	var err error = nil
+
+	if urlValues.Has("take") {
+		take, err = strconv.Atoi(urlValues.Get("take"))
+
+		if err != nil || take <= 0 {
+			WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong take parameter."}, 400)
+			return
+		}
+	}
+
+	if take > common.MaxPageSize {
+		take = common.MaxPageSize
+	}
+
+	// Tests passed since the last request get schedules
+	err = ctx.DbContext.SyncReviewSchedules(userId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't sync review schedules."}, 400)
+		return
+	}
+
+	schedules, err := ctx.DbContext.GetDueReviews(userId, ctx.DbContext.Now(), int64(take))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get due reviews."}, 400)
+		return
+	}
+
+	testIds := make([]string, 0, len(schedules))
+
+	for _, schedule := range schedules {
+		testIds = append(testIds, schedule.TestId)
+	}
+
+	previews, err := ctx.DbContext.GetTestPreviews(testIds)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get tests."}, 400)
+		return
+	}
+
+	tests := make(map[string]*common.TestPreview, len(previews))
+
+	for _, preview := range previews {
+		tests[preview.Id] = preview
+	}
+
+	items := make([]*common.ReviewItem, 0, len(schedules))
+
+	// Schedules of deleted tests are skipped
+	for _, schedule := range schedules {
+		if test, ok := tests[schedule.TestId]; ok {
+			items = append(items, &common.ReviewItem{Schedule: schedule, Test: test})
+		}
+	}
+
+	WriteResponse[[]*common.ReviewItem](writer, request, &items)
+}
+
+func (ctx *RouteContext) AnswerReview(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	testId := chi.URLParam(request, "testId")
+
+	openRequest := &Request[common.TestAnswer]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	schedule, err := ctx.DbContext.GetReviewSchedule(userId, testId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get review schedule."}, 400)
+		return
+	}
+
+	if schedule == nil {
+		WriteErrResponse(writer, request, errors.New("test isn't scheduled"),
+			&ResponseError{Code: ErrParameter, Message: "Test isn't scheduled for review."}, 404)
+		return
+	}
+
+	dateNow := ctx.DbContext.Now()
+
+	if schedule.DateDue.After(dateNow) {
+		WriteErrResponse(writer, request, errors.New("review isn't due"),
+			&ResponseError{Code: ErrValid, Message: "Test isn't due for review."}, 400)
+		return
+	}
+
+	test, err := ctx.DbContext.GetTest(testId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get test."}, 400)
+		return
+	}
+
+	if test == nil {
+		WriteErrResponse(writer, request, errors.New("test isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Test isn't found."}, 404)
+		return
+	}
+
+	var grade *common.GradeResult
+
+	if test.TestType == common.TestCode {
+		grade, err = grading.GradeCode(request.Context(), ctx.Executor, test, &openRequest.Payload)
+	} else {
+		grade, err = grading.Grade(test, &openRequest.Payload)
+	}
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrValid, Message: "Test can't be graded."}, 400)
+		return
+	}
+
+	// Reviews don't credit lemmings and aren't attempts of test
+	grade.LemmingsCount = 0
+
+	quality := reviews.Quality(grade.Score)
+	next := reviews.Next(schedule, quality, dateNow)
+
+	updated, err := ctx.DbContext.UpdateReviewSchedule(schedule, next)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't update review schedule."}, 400)
+		return
+	}
+
+	if !updated {
+		WriteErrResponse(writer, request, errors.New("review is answered concurrently"),
+			&ResponseError{Code: ErrValid, Message: "Review is already answered."}, 409)
+		return
+	}
+
+	result := common.ReviewResult{Grade: grade, Quality: quality, Schedule: next}
+
+	WriteResponse[common.ReviewResult](writer, request, &result)
+}
@@ -4,12 +4,19 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth/v5"
 	"net/http"
+	"opencourse/achievements"
+	"opencourse/badges"
 	"opencourse/database"
+	"opencourse/notifications"
+	"opencourse/sandbox"
+	"opencourse/search"
 )
 
-func RouteTable(dbContext database.DbContext, tokenAuth *jwtauth.JWTAuth) http.Handler {
+func RouteTable(dbContext database.DbContext, tokenAuth *jwtauth.JWTAuth, executor sandbox.Executor,
+	engine *achievements.Engine, index search.Index, hub *notifications.Hub, signer *badges.Signer) http.Handler {
 	r := chi.NewRouter()
-	rtx := RouteContext{DbContext: dbContext, TokenAuth: tokenAuth}
+	rtx := RouteContext{DbContext: dbContext, TokenAuth: tokenAuth, Executor: executor, Engine: engine, Search: index,
+		Notifications: hub, BadgeSigner: signer}
 
 	r.Group(func(r chi.Router) {
 
@@ -17,22 +24,97 @@ func RouteTable(dbContext database.DbContext, tokenAuth *jwtauth.JWTAuth) http.H
 		r.Use(jwtauth.Authenticator)
 
 		r.Get("/courses/{categoryId}/list", rtx.GetCourses)
-		r.Get("/courses/{courseId}", rtx.GetCourses)
+		r.Get("/courses/search", rtx.SearchCourses)
+		r.Get("/courses/{courseId}", rtx.GetCourse)
 		r.Post("/courses", rtx.PostCourse)
+		r.Post("/courses/{courseId}/clone", rtx.CloneCourse)
+		r.Get("/courses/{courseId}/export", rtx.ExportCourse)
+		r.Get("/courses/{courseId}/scorm", rtx.ExportScorm)
+		r.Post("/courses/import", rtx.ImportCourse)
+
+		r.Get("/courses/{courseId}/reviews", rtx.GetCourseReviews)
+		r.Post("/courses/{courseId}/reviews", rtx.PostCourseReview)
+		r.Post("/reviews/{reviewId}/reply", rtx.ReplyCourseReview)
+		r.Post("/reviews/{reviewId}/hide", rtx.HideCourseReview)
 
 		r.Get("/stages/{courseId}/list", rtx.GetStages)
 		r.Get("/stages/{stageId}", rtx.GetStage)
 		r.Post("/stages", rtx.PostStage)
 		r.Put("/stages", rtx.PutStage)
+		r.Post("/stages/{stageId}/tests/import", rtx.ImportTests)
+
+		r.Get("/stages/{stageId}/comments", rtx.GetStageComments)
+		r.Post("/stages/{stageId}/comments", rtx.PostStageComment)
+		r.Get("/comments/{commentId}/replies", rtx.GetCommentReplies)
+		r.Put("/comments/{commentId}", rtx.PutStageComment)
+		r.Delete("/comments/{commentId}", rtx.DeleteStageComment)
+		r.Post("/comments/{commentId}/upvote", rtx.UpvoteStageComment)
+		r.Delete("/comments/{commentId}/upvote", rtx.DeleteStageCommentUpvote)
+		r.Post("/comments/{commentId}/answer", rtx.MarkCommentAnswer)
+		r.Delete("/comments/{commentId}/answer", rtx.DeleteCommentAnswer)
+		r.Post("/comments/{commentId}/hide", rtx.HideStageComment)
+
+		r.Get("/tests/{stageId}/list", rtx.GetTests)
+		r.Post("/tests", rtx.PostTest)
+		r.Post("/tests/{testId}/answer", rtx.AnswerTest)
+		r.Get("/tests/{testId}/attempts", rtx.GetAttempts)
+
+		r.Post("/stages/{stageId}/sessions", rtx.StartSession)
+		r.Get("/sessions/{sessionId}", rtx.GetSession)
+		r.Get("/sessions/{sessionId}/tests", rtx.GetSessionTests)
+		r.Put("/sessions/{sessionId}/answers/{testId}", rtx.PutSessionAnswer)
+		r.Post("/sessions/{sessionId}/submit", rtx.SubmitSession)
+
+		r.Get("/certificates", rtx.GetCertificates)
+		r.Post("/courses/{courseId}/certificate", rtx.PostCertificate)
+
+		r.Post("/badges/classes", rtx.PostBadgeClass)
+		r.Post("/badges/assertions/{assertionId}/revoke", rtx.RevokeBadge)
+		r.Get("/badges/wallet", rtx.GetBadgeWallet)
+
+		r.Get("/achievements", rtx.GetAchievements)
+		r.Get("/progress", rtx.GetProgress)
+
+		r.Get("/leaderboards/{metric}", rtx.GetLeaderboard)
+
+		r.Get("/me/notifications", rtx.GetNotifications)
+		r.Get("/me/notifications/unread", rtx.GetUnreadNotifications)
+		r.Post("/me/notifications/read", rtx.ReadNotifications)
+		r.Post("/me/notifications/ticket", rtx.PostStreamTicket)
+
+		r.Get("/me/reviews/due", rtx.GetDueReviews)
+		r.Post("/me/reviews/{testId}/answer", rtx.AnswerReview)
 
 		r.Get("/categories/{lang}", rtx.GetCategories)
 		r.Post("/categories", rtx.PostCategory)
+		r.Delete("/categories/{categoryId}", rtx.DeleteCategory)
+		r.Post("/categories/{categoryId}/move", rtx.MoveCategory)
+
+		r.Post("/promotions", rtx.PostPromotion)
+		r.Delete("/promotions/{promotionId}", rtx.DeletePromotion)
+
+		r.Post("/translations", rtx.PostTranslation)
+		r.Get("/translations/{kind}/{id}", rtx.GetTranslations)
 	})
 
 	r.Group(func(r chi.Router) {
+		// Browser EventSource can't set headers, so stream is opened by single-use ticket in url
+		r.Get("/me/notifications/stream", rtx.StreamNotifications)
+
 		r.Post("/auth/login", rtx.Login)
 		r.Post("/auth/register", rtx.Register)
 		r.Get("/auth/confirm/{id}/{code}", rtx.Confirm)
+
+		r.Get("/certificates/{code}/verify", rtx.VerifyCertificate)
+		r.Get("/certificates/{code}/pdf", rtx.GetCertificatePdf)
+
+		r.Get("/promotions", rtx.GetPromotions)
+
+		r.Get("/badges/issuer", rtx.GetBadgeIssuer)
+		r.Get("/badges/issuer/jwks", rtx.GetBadgeIssuerKeys)
+		r.Get("/badges/classes/{badgeClassId}", rtx.GetBadgeClass)
+		r.Get("/badges/assertions/{assertionId}", rtx.GetBadgeAssertion)
+		r.Get("/badges/assertions/{assertionId}/credential", rtx.GetBadgeCredential)
 	})
 
 	return r
@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"net/http"
+	"opencourse/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (ctx *RouteContext) SearchCourses(writer http.ResponseWriter, request *http.Request) {
+	urlValues := request.URL.Query()
+
+	query := common.SearchQuery{
+		Text:       strings.TrimSpace(urlValues.Get("q")),
+		Lang:       urlValues.Get("lang"),
+		CategoryId: urlValues.Get("category_id"),
+		Take:       20,
+	}
+
+	if urlValues.Has("tags") {
+		for _, tag := range strings.Split(urlValues.Get("tags"), ",") {
+			if tag = strings.TrimSpace(tag); len(tag) > 0 {
+				query.Tags = append(query.Tags, tag)
+			}
+		}
+	}
+
+	for _, param := range []string{"min_rating", "max_rating", "take", "skip"} {
+		if !urlValues.Has(param) {
+			continue
+		}
+
+		value, err := strconv.Atoi(urlValues.Get(param))
+
+		if err != nil || (param == "take" && value <= 0) || (param == "skip" && value < 0) {
+			WriteErrResponse(writer, request, err,
+				&ResponseError{Code: ErrParameter, Message: "Wrong " + param + " parameter."}, 400)
+			return
+		}
+
+		switch param {
+		case "min_rating":
+			query.MinRating = &value
+		case "max_rating":
+			query.MaxRating = &value
+		case "take":
+			if value > common.MaxPageSize {
+				value = common.MaxPageSize
+			}
+
+			query.Take = int64(value)
+		case "skip":
+			query.Skip = int64(value)
+		}
+	}
+
+	for _, param := range []string{"date_from", "date_to"} {
+		if !urlValues.Has(param) {
+			continue
+		}
+
+		date, err := parseDate(urlValues.Get(param))
+
+		if err != nil {
+			WriteErrResponse(writer, request, err,
+				&ResponseError{Code: ErrParameter, Message: "Wrong " + param + " parameter."}, 400)
+			return
+		}
+
+		if param == "date_from" {
+			query.DateFrom = date
+		} else {
+			query.DateTo = date
+		}
+	}
+
+	result, err := ctx.Search.Search(&query)
+
+	if err == nil {
+		err = ctx.DbContext.AttachPromotionLabels(result.Courses)
+	}
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't search courses."}, 400)
+		return
+	}
+
+	WriteResponse[common.SearchResult](writer, request, result)
+}
+
+// parseDate parse date in format 2006-01-02 or RFC 3339
+func parseDate(value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+
+	if err != nil {
+		return time.Parse(time.RFC3339, value)
+	}
+
+	return date, nil
+}
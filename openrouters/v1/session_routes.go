@@ -0,0 +1,219 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog"
+	"github.com/go-chi/render"
+	"net/http"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/quizsession"
+	"opencourse/selection"
+)
+
+func (ctx *RouteContext) StartSession(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	stageId := chi.URLParam(request, "stageId")
+
+	session, err := quizsession.Start(request.Context(), &ctx.DbContext, ctx.Executor, userId, stageId,
+		sessionErrHandler(request))
+
+	var fieldErr openerrors.FieldEmptyErr
+
+	if errors.As(err, &fieldErr) {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrValid, Message: "Stage isn't timed."}, 400)
+		return
+	}
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't start session."}, 400)
+		return
+	}
+
+	WriteResponse[common.QuizSession](writer, request, session)
+}
+
+func (ctx *RouteContext) GetSession(writer http.ResponseWriter, request *http.Request) {
+	session, ok := ctx.sessionOf(writer, request, true)
+	if !ok {
+		return
+	}
+
+	WriteResponse[common.QuizSession](writer, request, session)
+}
+
+func (ctx *RouteContext) GetSessionTests(writer http.ResponseWriter, request *http.Request) {
+	session, ok := ctx.sessionOf(writer, request, true)
+	if !ok {
+		return
+	}
+
+	drawn, ok := ctx.sessionSelection(writer, request, session)
+	if !ok {
+		return
+	}
+
+	previews, err := selection.Previews(&ctx.DbContext, drawn)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get tests."}, 400)
+		return
+	}
+
+	WriteResponse[[]*common.TestPreview](writer, request, &previews)
+}
+
+func (ctx *RouteContext) PutSessionAnswer(writer http.ResponseWriter, request *http.Request) {
+	session, ok := ctx.sessionOf(writer, request, false)
+	if !ok {
+		return
+	}
+
+	testId := chi.URLParam(request, "testId")
+
+	openRequest := &Request[common.TestAnswer]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	drawn, ok := ctx.sessionSelection(writer, request, session)
+	if !ok {
+		return
+	}
+
+	if !selection.Contains(drawn, testId) {
+		WriteErrResponse(writer, request, errors.New("test isn't drawn for session"),
+			&ResponseError{Code: ErrParameter, Message: "Test isn't from session."}, 400)
+		return
+	}
+
+	err = ctx.DbContext.SaveSessionAnswer(session.Id, testId, &openRequest.Payload)
+
+	var closedErr openerrors.SessionClosedErr
+
+	if errors.As(err, &closedErr) {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrSessionClosed, Message: "Session is closed. Answer isn't saved."}, 409)
+		return
+	}
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't save answer."}, 400)
+		return
+	}
+
+	session, err = ctx.DbContext.GetQuizSession(session.Id)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get session."}, 400)
+		return
+	}
+
+	WriteResponse[common.QuizSession](writer, request, session)
+}
+
+func (ctx *RouteContext) SubmitSession(writer http.ResponseWriter, request *http.Request) {
+	session, ok := ctx.sessionOf(writer, request, false)
+	if !ok {
+		return
+	}
+
+	session, err := quizsession.Submit(request.Context(), &ctx.DbContext, ctx.Executor, session.Id,
+		sessionErrHandler(request))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't submit session."}, 400)
+		return
+	}
+
+	WriteResponse[common.QuizSession](writer, request, session)
+}
+
+// sessionOf return session from url of request. Session is available for its user,
+// admins and authors can read any session, if readOnly is true
+func (ctx *RouteContext) sessionOf(writer http.ResponseWriter, request *http.Request, readOnly bool) (*common.QuizSession, bool) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return nil, false
+	}
+
+	sessionId := chi.URLParam(request, "sessionId")
+
+	session, err := ctx.DbContext.GetQuizSession(sessionId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get session."}, 400)
+		return nil, false
+	}
+
+	if session.UserId == userId {
+		return session, true
+	}
+
+	if !readOnly {
+		WriteErrResponse(writer, request, errors.New("session of another user"),
+			&ResponseError{Code: ErrForbidden, Message: "Session of another user."}, 403)
+		return nil, false
+	}
+
+	ok = InRole(writer, request, common.RoleAdmin, common.RoleAuthor)
+	if !ok {
+		return nil, false
+	}
+
+	return session, true
+}
+
+// sessionSelection return tests selection of session
+func (ctx *RouteContext) sessionSelection(writer http.ResponseWriter, request *http.Request,
+	session *common.QuizSession) (*common.TestSelection, bool) {
+
+	stage, err := ctx.DbContext.GetStage(session.StageId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get stage."}, 400)
+		return nil, false
+	}
+
+	if stage == nil {
+		WriteErrResponse(writer, request, errors.New("stage isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Stage isn't found."}, 404)
+		return nil, false
+	}
+
+	drawn, _, err := selection.Select(&ctx.DbContext, session.UserId, stage, session.Id)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get tests selection."}, 400)
+		return nil, false
+	}
+
+	return drawn, true
+}
+
+// sessionErrHandler return handler, which logs errors of events, certificate and badges after submit of session.
+// Results are saved, so these errors don't fail request
+func sessionErrHandler(request *http.Request) func(error) {
+	return func(err error) {
+		httplog.LogEntrySetField(request.Context(), "achievement_error", err.Error())
+	}
+}
@@ -1,37 +1,31 @@
 package v1
 
 import (
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"net/http"
 	"opencourse/common"
-	"strconv"
 )
 
 func (ctx *RouteContext) GetStages(writer http.ResponseWriter, request *http.Request) {
 
-	courseId := chi.URLParam(request, "courseId")
-
-	urlValues := request.URL.Query()
-
-	take := 5
-	skip := 0
-	var err error = nil
-
-	if urlValues.Has("take") {
-		take, err = strconv.Atoi(urlValues.Get("take"))
-		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong take parameter."}, 400)
+	query, ok := ListPage(writer, request, 5)
+	if !ok {
 		return
 	}
 
-	if urlValues.Has("skip") {
-		skip, err = strconv.Atoi(urlValues.Get("skip"))
+	// Stages of course translation are returned
+	courseId, err := ctx.DbContext.ResolveTranslation(common.TranslationCourse,
+		chi.URLParam(request, "courseId"), RequestLangs(request))
 
-		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong skip parameter."}, 400)
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get stages."}, 400)
 		return
 	}
 
-	stagePreviews, err := ctx.DbContext.GetStages(courseId, int64(take), int64(skip))
+	stagePreviews, page, err := ctx.DbContext.GetStagesPage(courseId, query)
 
 	if err != nil {
 		WriteErrResponse(writer, request, err,
@@ -39,12 +33,19 @@ func (ctx *RouteContext) GetStages(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
-	WriteResponse[[]*common.StagePreview](writer, request, &stagePreviews)
+	WriteListResponse[*common.StagePreview](writer, request, &stagePreviews, page)
 }
 
 func (ctx *RouteContext) GetStage(writer http.ResponseWriter, request *http.Request) {
 
-	stageId := chi.URLParam(request, "stageId")
+	stageId, err := ctx.DbContext.ResolveTranslation(common.TranslationStage,
+		chi.URLParam(request, "stageId"), RequestLangs(request))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get stage."}, 400)
+		return
+	}
 
 	stage, err := ctx.DbContext.GetStage(stageId)
 
@@ -54,6 +55,12 @@ func (ctx *RouteContext) GetStage(writer http.ResponseWriter, request *http.Requ
 		return
 	}
 
+	if stage == nil {
+		WriteErrResponse(writer, request, errors.New("stage isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Stage isn't found."}, 404)
+		return
+	}
+
 	WriteResponse[common.Stage](writer, request, stage)
 
 }
@@ -114,3 +121,31 @@ func (ctx *RouteContext) PutStage(writer http.ResponseWriter, request *http.Requ
 	result := "success"
 	WriteResponse[string](writer, request, &result)
 }
+
+/*
+stageAccess return stage, if user is admin or author of its course. If stage isn't found or access is forbidden,
+write error response and return false. Parameters:
+stageId - stage id;
+*/
+func (ctx *RouteContext) stageAccess(writer http.ResponseWriter, request *http.Request, stageId string) (*common.Stage, bool) {
+	stage, err := ctx.DbContext.GetStage(stageId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get stage."}, 400)
+		return nil, false
+	}
+
+	if stage == nil {
+		WriteErrResponse(writer, request, errors.New("stage isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Stage isn't found."}, 404)
+		return nil, false
+	}
+
+	_, ok := ctx.courseAccess(writer, request, stage.CourseId, false)
+	if !ok {
+		return nil, false
+	}
+
+	return stage, true
+}
@@ -0,0 +1,521 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog"
+	"github.com/go-chi/render"
+	"math"
+	"net/http"
+	"opencourse/badges"
+	"opencourse/certificates"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/database"
+	"opencourse/events"
+	"opencourse/grading"
+	"opencourse/quizimport"
+	"opencourse/selection"
+	"strconv"
+)
+
+func (ctx *RouteContext) GetTests(writer http.ResponseWriter, request *http.Request) {
+
+	query, ok := ListPage(writer, request, 20)
+	if !ok {
+		return
+	}
+
+	// Tests of stage translation are returned
+	stageId, err := ctx.DbContext.ResolveTranslation(common.TranslationStage,
+		chi.URLParam(request, "stageId"), RequestLangs(request))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get tests."}, 400)
+		return
+	}
+
+	// Authors see all tests, learners see tests drawn for them
+	if HasRole(request, common.RoleAdmin, common.RoleAuthor) {
+		testPreviews, page, err := ctx.DbContext.GetTestsPage(stageId, query)
+
+		if err != nil {
+			WriteErrResponse(writer, request, err,
+				&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get tests."}, 400)
+			return
+		}
+
+		WriteListResponse[*common.TestPreview](writer, request, &testPreviews, page)
+		return
+	}
+
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	stage, err := ctx.DbContext.GetStage(stageId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get stage."}, 400)
+		return
+	}
+
+	if stage == nil {
+		WriteErrResponse(writer, request, errors.New("stage isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Stage isn't found."}, 404)
+		return
+	}
+
+	if stage.TimeLimit > 0 {
+		WriteErrResponse(writer, request, errors.New("stage is timed"),
+			&ResponseError{Code: ErrValid, Message: "Tests of timed stage are shown in quiz session."}, 400)
+		return
+	}
+
+	drawn, _, err := selection.Select(&ctx.DbContext, userId, stage, "")
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get tests selection."}, 400)
+		return
+	}
+
+	testPreviews, err := selection.Previews(&ctx.DbContext, drawn)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get tests."}, 400)
+		return
+	}
+
+	// Drawn tests are paged in memory, cursor contains id of the last test of page
+	start := int(query.Skip)
+
+	if len(query.Cursor) > 0 {
+		values, err := database.DecodeCursor(query.Cursor)
+
+		if err == nil && len(values) != 1 {
+			err = errors.New("cursor doesn't match list of tests")
+		}
+
+		if err != nil {
+			WriteErrResponse(writer, request, err, &ResponseError{Code: ErrParameter, Message: "Wrong cursor parameter."}, 400)
+			return
+		}
+
+		start = len(testPreviews)
+
+		for i, testPreview := range testPreviews {
+			if testPreview.Id == values[0] {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	if start > len(testPreviews) {
+		start = len(testPreviews)
+	}
+
+	end := start + int(query.Take)
+	page := &common.Page{HasMore: end < len(testPreviews)}
+
+	if !page.HasMore {
+		end = len(testPreviews)
+	}
+
+	testPreviews = testPreviews[start:end]
+
+	if page.HasMore {
+		page.NextCursor, err = database.EncodeCursor(testPreviews[len(testPreviews)-1].Id)
+
+		if err != nil {
+			WriteErrResponse(writer, request, err,
+				&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get tests."}, 400)
+			return
+		}
+	}
+
+	WriteListResponse[*common.TestPreview](writer, request, &testPreviews, page)
+}
+
+func (ctx *RouteContext) PostTest(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin, common.RoleAuthor)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.AddTestQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	// Authors add tests only to stages of own courses
+	_, ok = ctx.stageAccess(writer, request, openRequest.Payload.StageId)
+	if !ok {
+		return
+	}
+
+	id, err := ctx.DbContext.AddTest(&openRequest.Payload)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't add test."}, 400)
+		return
+	}
+
+	WriteResponse[string](writer, request, &id)
+}
+
+func (ctx *RouteContext) AnswerTest(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	testId := chi.URLParam(request, "testId")
+
+	openRequest := &Request[common.TestAnswer]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	test, err := ctx.DbContext.GetTest(testId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get test."}, 400)
+		return
+	}
+
+	if test == nil {
+		WriteErrResponse(writer, request, errors.New("test isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Test isn't found."}, 404)
+		return
+	}
+
+	stage, err := ctx.DbContext.GetStage(test.StageId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get stage."}, 400)
+		return
+	}
+
+	if stage == nil {
+		WriteErrResponse(writer, request, errors.New("stage isn't found"),
+			&ResponseError{Code: ErrParameter, Message: "Stage isn't found."}, 404)
+		return
+	}
+
+	// Tests of timed stage are answered only in quiz session
+	if stage.TimeLimit > 0 {
+		WriteErrResponse(writer, request, errors.New("stage is timed"),
+			&ResponseError{Code: ErrValid, Message: "Test of timed stage is answered in quiz session."}, 400)
+		return
+	}
+
+	drawn, _, err := selection.Select(&ctx.DbContext, userId, stage, "")
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get tests selection."}, 400)
+		return
+	}
+
+	if !selection.Contains(drawn, test.Id) {
+		WriteErrResponse(writer, request, errors.New("test isn't drawn for user"),
+			&ResponseError{Code: ErrValid, Message: "Test isn't drawn for user."}, 400)
+		return
+	}
+
+	// Check limits before grading, because code tests are expensive
+	err = ctx.DbContext.CheckAttempt(userId, test)
+
+	if err != nil {
+		ctx.writeAttemptErr(writer, request, err)
+		return
+	}
+
+	var result *common.GradeResult
+
+	if test.TestType == common.TestCode {
+		result, err = grading.GradeCode(request.Context(), ctx.Executor, test, &openRequest.Payload)
+	} else {
+		result, err = grading.Grade(test, &openRequest.Payload)
+	}
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrValid, Message: "Test can't be graded."}, 400)
+		return
+	}
+
+	result.SelectionId = drawn.Id
+
+	// Previews shuffle options for the next attempt, so it's the order shown to learner
+	counts, err := ctx.DbContext.GetAttemptCounts(userId, stage.Id)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get attempts."}, 400)
+		return
+	}
+
+	selection.Snapshot(result, test, drawn, counts[test.Id]+1)
+
+	attempt, firstPass, err := ctx.DbContext.SaveTestResult(userId, stage.CourseId, test, &openRequest.Payload, result)
+
+	if err != nil {
+		ctx.writeAttemptErr(writer, request, err)
+		return
+	}
+
+	result.Attempt = attempt.Number
+
+	// Lemmings are credited only once
+	if !firstPass {
+		result.LemmingsCount = 0
+	}
+
+	// Answer is saved, so errors of achievements, certificate and badges are only logged
+	err = ctx.publishAnswer(userId, stage, test.Id, firstPass)
+
+	if err != nil {
+		httplog.LogEntrySetField(request.Context(), "achievement_error", err.Error())
+	}
+
+	// The first pass of the last test completes course
+	if firstPass {
+		result.Certificate, err = certificates.IssueIfCompleted(&ctx.DbContext, userId, stage.CourseId)
+
+		if err != nil {
+			httplog.LogEntrySetField(request.Context(), "certificate_error", err.Error())
+		}
+	}
+
+	_, err = badges.AwardEarned(&ctx.DbContext, userId)
+
+	if err != nil {
+		httplog.LogEntrySetField(request.Context(), "badge_error", err.Error())
+	}
+
+	WriteResponse[common.GradeResult](writer, request, result)
+}
+
+func (ctx *RouteContext) GetAttempts(writer http.ResponseWriter, request *http.Request) {
+	userId, ok := UserId(writer, request)
+	if !ok {
+		return
+	}
+
+	testId := chi.URLParam(request, "testId")
+
+	test, err := ctx.DbContext.GetTest(testId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get test."}, 400)
+		return
+	}
+
+	// Authors can see attempts of other users only for tests of own courses
+	if otherUserId := request.URL.Query().Get("user_id"); len(otherUserId) > 0 && otherUserId != userId {
+		ok = InRole(writer, request, common.RoleAdmin, common.RoleAuthor)
+		if !ok {
+			return
+		}
+
+		if test == nil {
+			WriteErrResponse(writer, request, errors.New("test isn't found"),
+				&ResponseError{Code: ErrParameter, Message: "Test isn't found."}, 404)
+			return
+		}
+
+		stage, err := ctx.DbContext.GetStage(test.StageId)
+
+		if err != nil {
+			WriteErrResponse(writer, request, err,
+				&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get stage."}, 400)
+			return
+		}
+
+		if stage == nil {
+			WriteErrResponse(writer, request, errors.New("stage isn't found"),
+				&ResponseError{Code: ErrParameter, Message: "Stage isn't found."}, 404)
+			return
+		}
+
+		_, ok = ctx.courseAccess(writer, request, stage.CourseId, false)
+		if !ok {
+			return
+		}
+
+		userId = otherUserId
+	}
+
+	attempts, err := ctx.DbContext.GetAttempts(userId, testId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get attempts."}, 400)
+		return
+	}
+
+	// Attempts saved without snapshot restore options order from their selection
+	var options []string
+
+	// Attempts of deleted test keep their snapshots
+	if test != nil {
+		options = selection.OptionAnswers(test)
+	}
+
+	selections := make(map[string]*common.TestSelection)
+
+	for _, attempt := range attempts {
+		if len(attempt.SelectionId) == 0 || len(options) == 0 || len(attempt.Options) > 0 {
+			continue
+		}
+
+		drawn, ok := selections[attempt.SelectionId]
+
+		if !ok {
+			drawn, err = ctx.DbContext.GetTestSelection(attempt.SelectionId)
+
+			if err != nil {
+				WriteErrResponse(writer, request, err,
+					&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get tests selection."}, 400)
+				return
+			}
+
+			selections[attempt.SelectionId] = drawn
+		}
+
+		attempt.Options = selection.Shuffle(options, selection.AttemptSeed(drawn.Seed, testId, attempt.Number))
+	}
+
+	WriteResponse[[]*common.TestAttempt](writer, request, &attempts)
+}
+
+func (ctx *RouteContext) ImportTests(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin, common.RoleAuthor)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.ImportTestsQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	// Authors import tests only to stages of own courses
+	stage, ok := ctx.stageAccess(writer, request, chi.URLParam(request, "stageId"))
+	if !ok {
+		return
+	}
+
+	query := openRequest.Payload
+
+	tests, issues, err := quizimport.Parse(query.Format, query.Content, query.LemmingsCount)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrValid, Message: "Invalid quiz file."}, 400)
+		return
+	}
+
+	result := common.ImportTestsResult{Tests: tests, Issues: issues}
+
+	if !query.DryRun {
+		result.TestIds, err = ctx.DbContext.AddTests(stage.Id, tests)
+
+		if err != nil {
+			WriteErrResponse(writer, request, err,
+				&ResponseError{Code: ErrInternal, Message: "Internal error. Can't import tests."}, 400)
+			return
+		}
+	}
+
+	WriteResponse[common.ImportTestsResult](writer, request, &result)
+}
+
+// writeAttemptErr write error response for attempt. Denied attempt is 429 error with Retry-After header for cooldown
+func (ctx *RouteContext) writeAttemptErr(writer http.ResponseWriter, request *http.Request, err error) {
+	var denied openerrors.AttemptDeniedErr
+
+	if !errors.As(err, &denied) {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't save test result."}, 400)
+		return
+	}
+
+	if denied.RetryAfter.IsZero() {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrAttempts, Message: fmt.Sprintf("Attempts limit %d is reached.", denied.MaxAttempts)}, 429)
+		return
+	}
+
+	seconds := int(math.Ceil(denied.RetryAfter.Sub(ctx.DbContext.Now()).Seconds()))
+	writer.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	WriteErrResponse(writer, request, err,
+		&ResponseError{Code: ErrAttempts, Message: fmt.Sprintf("The next attempt is allowed in %d seconds.", seconds)}, 429)
+}
+
+// publishAnswer publish events of saved answer. The first pass publishes passed test and completed stage
+func (ctx *RouteContext) publishAnswer(userId string, stage *common.Stage, testId string, firstPass bool) error {
+	event := events.Event{
+		Type:     events.TestAnswered,
+		UserId:   userId,
+		CourseId: stage.CourseId,
+		StageId:  stage.Id,
+		TestId:   testId,
+		Date:     ctx.DbContext.Now(),
+	}
+
+	err := ctx.DbContext.Events.Publish(event)
+
+	if err != nil || !firstPass {
+		return err
+	}
+
+	event.Type = events.TestPassed
+
+	err = ctx.DbContext.Events.Publish(event)
+
+	if err != nil {
+		return err
+	}
+
+	completed, err := certificates.StageCompleted(&ctx.DbContext, userId, stage)
+
+	if err != nil || !completed {
+		return err
+	}
+
+	event.Type = events.StageCompleted
+	event.TestId = ""
+
+	return ctx.DbContext.Events.Publish(event)
+}
@@ -0,0 +1,120 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"opencourse/common"
+)
+
+func (ctx *RouteContext) PostTranslation(writer http.ResponseWriter, request *http.Request) {
+	// Check user role. If user is not in role, return.
+	ok := InRole(writer, request, common.RoleAdmin, common.RoleAuthor)
+	if !ok {
+		return
+	}
+
+	openRequest := &Request[common.LinkTranslationQuery]{}
+
+	err := render.Bind(request, openRequest)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err, &ResponseError{Code: ErrBinding, Message: "Invalid model"}, 400)
+		return
+	}
+
+	query := openRequest.Payload
+
+	// Categories are shared, so only admins translate them. Other content is linked by author of both sides
+	if query.Kind == common.TranslationCategory {
+		ok = InRole(writer, request, common.RoleAdmin)
+		if !ok {
+			return
+		}
+	} else {
+		for _, id := range []string{query.SourceId, query.TranslationId} {
+			ok = ctx.translationAccess(writer, request, query.Kind, id)
+			if !ok {
+				return
+			}
+		}
+	}
+
+	group, err := ctx.DbContext.LinkTranslation(query.Kind, query.SourceId, query.TranslationId)
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't link translation."}, 400)
+		return
+	}
+
+	WriteResponse[string](writer, request, &group)
+}
+
+func (ctx *RouteContext) GetTranslations(writer http.ResponseWriter, request *http.Request) {
+	translations, err := ctx.DbContext.GetTranslations(chi.URLParam(request, "kind"), chi.URLParam(request, "id"))
+
+	if err != nil {
+		WriteErrResponse(writer, request, err,
+			&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get translations."}, 400)
+		return
+	}
+
+	if translations == nil {
+		WriteErrResponse(writer, request, nil,
+			&ResponseError{Code: ErrParameter, Message: "Content isn't found."}, 404)
+		return
+	}
+
+	WriteResponse[[]*common.Translation](writer, request, &translations)
+}
+
+// translationAccess check that user can change course of translated course, stage or test
+func (ctx *RouteContext) translationAccess(writer http.ResponseWriter, request *http.Request, kind string, id string) bool {
+	courseId := id
+
+	if kind == common.TranslationTest {
+		test, err := ctx.DbContext.GetTest(id)
+
+		if err != nil {
+			WriteErrResponse(writer, request, err,
+				&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get test."}, 400)
+			return false
+		}
+
+		if test == nil {
+			WriteErrResponse(writer, request, errors.New("test isn't found"),
+				&ResponseError{Code: ErrParameter, Message: "Content isn't found."}, 404)
+			return false
+		}
+
+		kind, id = common.TranslationStage, test.StageId
+	}
+
+	if kind == common.TranslationStage {
+		stage, err := ctx.DbContext.GetStage(id)
+
+		if err != nil {
+			WriteErrResponse(writer, request, err,
+				&ResponseError{Code: ErrInternal, Message: "Internal error. Can't get stage."}, 400)
+			return false
+		}
+
+		if stage == nil {
+			WriteErrResponse(writer, request, errors.New("stage isn't found"),
+				&ResponseError{Code: ErrParameter, Message: "Content isn't found."}, 404)
+			return false
+		}
+
+		courseId = stage.CourseId
+	} else if kind != common.TranslationCourse {
+		WriteErrResponse(writer, request, errors.New("unknown translation kind"),
+			&ResponseError{Code: ErrParameter, Message: "Wrong kind parameter."}, 400)
+		return false
+	}
+
+	_, ok := ctx.courseAccess(writer, request, courseId, false)
+
+	return ok
+}
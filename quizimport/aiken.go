@@ -0,0 +1,73 @@
+package quizimport
+
+import (
+	"opencourse/common"
+	"regexp"
+	"strings"
+)
+
+/*
+This file contains Aiken parser. Item is a question, options "A. text" or "A) text"
+and "ANSWER: A" line. Items are separated by empty lines.
+*/
+
+var aikenOption = regexp.MustCompile(`^([A-Z])[.)]\s+(.*)$`)
+
+// parseAiken parse Aiken content
+func parseAiken(content string) ([]*item, []*common.ImportIssue) {
+	var items []*item
+	var issues []*common.ImportIssue
+
+	for _, b := range splitBlocks(content, nil) {
+		var question []string
+		var options []*common.Option
+		letters := make(map[string]int)
+		answer := ""
+
+		for _, line := range b.Lines {
+			line = strings.TrimSpace(line)
+
+			if strings.HasPrefix(strings.ToUpper(line), "ANSWER:") {
+				answer = strings.ToUpper(strings.TrimSpace(line[len("ANSWER:"):]))
+				continue
+			}
+
+			if match := aikenOption.FindStringSubmatch(line); match != nil && len(question) > 0 {
+				letters[match[1]] = len(options)
+				options = append(options, &common.Option{Answer: strings.TrimSpace(match[2])})
+				continue
+			}
+
+			if len(options) > 0 {
+				// Question text after options is invalid, it's a part of option in other formats
+				options[len(options)-1].Answer += " " + line
+				continue
+			}
+
+			question = append(question, line)
+		}
+
+		if len(question) == 0 {
+			issues = append(issues, issue(b.Line, "question is empty"))
+			continue
+		}
+
+		if len(options) < 2 {
+			issues = append(issues, issue(b.Line, "item must have at least two options"))
+			continue
+		}
+
+		index, ok := letters[answer]
+
+		if !ok {
+			issues = append(issues, issue(b.Line, "answer %q is not found in options", answer))
+			continue
+		}
+
+		options[index].IsRight = true
+
+		items = append(items, &item{Line: b.Line, Query: optionQuery(strings.Join(question, "\n"), options)})
+	}
+
+	return items, issues
+}
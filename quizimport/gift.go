@@ -0,0 +1,292 @@
+package quizimport
+
+import (
+	"opencourse/common"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+This file contains Moodle GIFT parser. Supported items:
+multiple choice {=right ~wrong}, multiple answers {~%50%right ~%-50%wrong}, true-false {T},
+short answer {=answer =other} and numeric {#answer:tolerance} or {#min..max}.
+Matching, essay and description items are reported as issues.
+*/
+
+var giftWeight = regexp.MustCompile(`^%(-?[0-9.]+)%`)
+
+// parseGift parse GIFT content
+func parseGift(content string) ([]*item, []*common.ImportIssue) {
+	var items []*item
+	var issues []*common.ImportIssue
+
+	blocks := splitBlocks(content, func(line string) bool {
+		return strings.HasPrefix(line, "//") || strings.HasPrefix(line, "$CATEGORY:")
+	})
+
+	for _, b := range blocks {
+		text := strings.Join(b.Lines, "\n")
+
+		// Remove title ::title::
+		if strings.HasPrefix(strings.TrimSpace(text), "::") {
+			text = strings.TrimSpace(text)[2:]
+			end := indexUnescaped(text, "::")
+
+			if end < 0 {
+				issues = append(issues, issue(b.Line, "question title is not closed"))
+				continue
+			}
+
+			text = text[end+2:]
+		}
+
+		open := indexUnescaped(text, "{")
+
+		if open < 0 {
+			issues = append(issues, issue(b.Line, "description items are not supported"))
+			continue
+		}
+
+		closeIndex := indexUnescaped(text[open:], "}")
+
+		if closeIndex < 0 {
+			issues = append(issues, issue(b.Line, "answers block is not closed"))
+			continue
+		}
+
+		closeIndex += open
+
+		question := giftQuestion(text[:open], text[closeIndex+1:])
+		answers := strings.TrimSpace(text[open+1 : closeIndex])
+
+		if len(question) == 0 {
+			issues = append(issues, issue(b.Line, "question is empty"))
+			continue
+		}
+
+		query, message := giftAnswers(question, answers)
+
+		if query == nil {
+			issues = append(issues, issue(b.Line, message))
+			continue
+		}
+
+		if len(message) > 0 {
+			issues = append(issues, issue(b.Line, message))
+		}
+
+		items = append(items, &item{Line: b.Line, Query: query})
+	}
+
+	return items, issues
+}
+
+// giftQuestion join question text before and after answers block
+func giftQuestion(before string, after string) string {
+	before = strings.TrimSpace(before)
+
+	// Remove text format [html], [moodle], [plain], [markdown]
+	if strings.HasPrefix(before, "[") {
+		if end := strings.Index(before, "]"); end > 0 {
+			before = strings.TrimSpace(before[end+1:])
+		}
+	}
+
+	after = strings.TrimSpace(after)
+
+	if len(after) > 0 {
+		before = before + " _____ " + after
+	}
+
+	return unescapeGift(before)
+}
+
+// giftAnswers convert answers block to test query. Returns nil query and reason for unsupported items.
+// Message for supported query is a warning
+func giftAnswers(question string, answers string) (*common.AddTestQuery, string) {
+	if len(answers) == 0 {
+		return nil, "essay items are not supported"
+	}
+
+	if strings.HasPrefix(answers, "#") {
+		return giftNumeric(question, strings.TrimSpace(answers[1:]))
+	}
+
+	// True-false answer may have feedbacks {T#feedback for wrong answer#feedback for right answer}
+	switch strings.ToUpper(giftAnswerText(answers)) {
+	case "T", "TRUE":
+		return optionQuery(question, []*common.Option{{Answer: "True", IsRight: true}, {Answer: "False"}}), ""
+	case "F", "FALSE":
+		return optionQuery(question, []*common.Option{{Answer: "True"}, {Answer: "False", IsRight: true}}), ""
+	}
+
+	tokens := splitGiftAnswers(answers)
+
+	if len(tokens) == 0 {
+		return nil, "answers are not found"
+	}
+
+	hasWrong := false
+
+	for _, token := range tokens {
+		if indexUnescaped(token[1:], "->") >= 0 {
+			return nil, "matching items are not supported"
+		}
+
+		if token[0] == '~' {
+			hasWrong = true
+		}
+	}
+
+	// Short answer: all answers are right. Moodle compares short answers without case by default
+	if !hasWrong {
+		query := rewriteQuery(question, giftAnswerText(tokens[0][1:]))
+		query.RewriteTest.Matching = &common.RewriteMatching{IgnoreCase: true, IgnoreWhitespace: true}
+
+		for _, token := range tokens[1:] {
+			query.RewriteTest.Matching.AcceptedAnswers =
+				append(query.RewriteTest.Matching.AcceptedAnswers, giftAnswerText(token[1:]))
+		}
+
+		return query, ""
+	}
+
+	var options []*common.Option
+	hasRight := false
+
+	for _, token := range tokens {
+		text := token[1:]
+		isRight := token[0] == '='
+
+		if match := giftWeight.FindStringSubmatch(text); match != nil {
+			weight, err := strconv.ParseFloat(match[1], 64)
+
+			if err != nil {
+				return nil, "invalid answer weight " + match[1]
+			}
+
+			isRight = weight > 0
+			text = text[len(match[0]):]
+		}
+
+		hasRight = hasRight || isRight
+		options = append(options, &common.Option{Answer: giftAnswerText(text), IsRight: isRight})
+	}
+
+	if !hasRight {
+		return nil, "multiple choice item hasn't right answer"
+	}
+
+	return optionQuery(question, options), ""
+}
+
+// giftNumeric convert numeric answer "answer", "answer:tolerance" or "min..max" to numeric test query
+func giftNumeric(question string, answer string) (*common.AddTestQuery, string) {
+	if strings.HasPrefix(answer, "=") {
+		return nil, "numeric items with many answers are not supported"
+	}
+
+	answer = giftAnswerText(answer)
+
+	if bounds := strings.SplitN(answer, "..", 2); len(bounds) == 2 {
+		low, lowErr := strconv.ParseFloat(strings.TrimSpace(bounds[0]), 64)
+		high, highErr := strconv.ParseFloat(strings.TrimSpace(bounds[1]), 64)
+
+		if lowErr != nil || highErr != nil || low > high {
+			return nil, "invalid numeric range " + answer
+		}
+
+		return numericQuery(question, (low+high)/2, (high-low)/2), ""
+	}
+
+	value, tolerance := answer, "0"
+
+	if colon := strings.Index(answer, ":"); colon >= 0 {
+		value, tolerance = answer[:colon], answer[colon+1:]
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+
+	if err != nil {
+		return nil, "invalid numeric answer " + value
+	}
+
+	delta, err := strconv.ParseFloat(strings.TrimSpace(tolerance), 64)
+
+	if err != nil || delta < 0 {
+		return nil, "invalid numeric tolerance " + tolerance
+	}
+
+	return numericQuery(question, number, delta), ""
+}
+
+// splitGiftAnswers split answers block to tokens started with = or ~
+func splitGiftAnswers(answers string) []string {
+	var tokens []string
+	start := -1
+
+	for i := 0; i < len(answers); i++ {
+		switch answers[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			if start >= 0 {
+				tokens = append(tokens, strings.TrimSpace(answers[start:i]))
+			}
+
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, strings.TrimSpace(answers[start:]))
+	}
+
+	return tokens
+}
+
+// giftAnswerText remove feedback and unescape answer
+func giftAnswerText(text string) string {
+	if feedback := indexUnescaped(text, "#"); feedback >= 0 {
+		text = text[:feedback]
+	}
+
+	return unescapeGift(strings.TrimSpace(text))
+}
+
+// indexUnescaped return index of substring which is not escaped by backslash
+func indexUnescaped(text string, substr string) int {
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(text[i:], substr) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// unescapeGift replace GIFT escape sequences
+func unescapeGift(text string) string {
+	var builder strings.Builder
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			i++
+
+			if text[i] == 'n' {
+				builder.WriteByte('\n')
+				continue
+			}
+		}
+
+		builder.WriteByte(text[i])
+	}
+
+	return builder.String()
+}
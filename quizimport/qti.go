@@ -0,0 +1,241 @@
+package quizimport
+
+import (
+	"encoding/xml"
+	"io"
+	"opencourse/common"
+	"strings"
+)
+
+/*
+This file contains IMS QTI 2.1 parser. Content may contain one or many assessmentItem elements.
+Items with choiceInteraction are mapped to option or multi-select tests, items with textEntryInteraction to rewrite tests.
+Items with other interactions are reported as issues.
+*/
+
+// qtiInteraction parsed interaction of item body
+type qtiInteraction struct {
+	Kind               string       // Interaction element name
+	ResponseIdentifier string       // Response declaration identifier
+	Prompt             string       // Interaction prompt
+	Choices            []*qtiChoice // Choices of choiceInteraction
+}
+
+// qtiChoice choice of choiceInteraction
+type qtiChoice struct {
+	Identifier string // Choice identifier
+	Text       string // Choice text
+}
+
+// qtiItem parsed assessment item
+type qtiItem struct {
+	Line         int                 // Line number of item start
+	Identifier   string              // Item identifier
+	Title        string              // Item title
+	Text         string              // Item body text outside of interactions
+	Correct      map[string][]string // Correct response values by response identifier
+	Interactions []*qtiInteraction   // Item interactions
+}
+
+// parseQti parse QTI 2.1 XML content
+func parseQti(content string) ([]*item, []*common.ImportIssue, error) {
+	var items []*item
+	var issues []*common.ImportIssue
+
+	decoder := xml.NewDecoder(strings.NewReader(content))
+
+	// Item bodies are XHTML, authoring tools write HTML entities like &nbsp;
+	decoder.Entity = xml.HTMLEntity
+
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+
+		if !ok || start.Name.Local != "assessmentItem" {
+			continue
+		}
+
+		qti := &qtiItem{
+			Line:    lineAt(content, offset),
+			Correct: make(map[string][]string),
+		}
+
+		qti.Identifier = attr(start, "identifier")
+		qti.Title = attr(start, "title")
+
+		err = readQtiItem(decoder, qti)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		query, message := qtiQuery(qti)
+
+		if query == nil {
+			issues = append(issues, issue(qti.Line, "item %s: %s", qti.Identifier, message))
+			continue
+		}
+
+		items = append(items, &item{Line: qti.Line, Query: query})
+	}
+
+	return items, issues, nil
+}
+
+// readQtiItem read assessmentItem content until its end element
+func readQtiItem(decoder *xml.Decoder, qti *qtiItem) error {
+	var text strings.Builder
+	var interaction *qtiInteraction
+	var choice *qtiChoice
+	responseId := ""
+	inBody, inPrompt, inCorrect, inValue := false, false, false, false
+
+	for {
+		token, err := decoder.Token()
+
+		if err != nil {
+			return err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch name := element.Name.Local; {
+			case name == "responseDeclaration":
+				responseId = attr(element, "identifier")
+			case name == "correctResponse":
+				inCorrect = true
+			case name == "value" && inCorrect:
+				inValue = true
+			case name == "itemBody":
+				inBody = true
+			case name == "prompt" && interaction != nil:
+				inPrompt = true
+			case name == "simpleChoice" && interaction != nil:
+				choice = &qtiChoice{Identifier: attr(element, "identifier")}
+				interaction.Choices = append(interaction.Choices, choice)
+			case inBody && strings.HasSuffix(name, "Interaction"):
+				interaction = &qtiInteraction{Kind: name, ResponseIdentifier: attr(element, "responseIdentifier")}
+				qti.Interactions = append(qti.Interactions, interaction)
+
+				// textEntryInteraction is inline and empty, so text after it belongs to item text
+				if name == "textEntryInteraction" {
+					text.WriteString(" _____ ")
+				}
+			}
+		case xml.EndElement:
+			switch name := element.Name.Local; {
+			case name == "assessmentItem":
+				qti.Text = strings.Join(strings.Fields(text.String()), " ")
+				return nil
+			case name == "correctResponse":
+				inCorrect = false
+			case name == "value":
+				inValue = false
+			case name == "itemBody":
+				inBody = false
+			case name == "prompt":
+				inPrompt = false
+			case name == "simpleChoice":
+				choice = nil
+			case strings.HasSuffix(name, "Interaction"):
+				interaction = nil
+			}
+		case xml.CharData:
+			value := string(element)
+
+			switch {
+			case inValue:
+				qti.Correct[responseId] = append(qti.Correct[responseId], strings.TrimSpace(value))
+			case choice != nil:
+				choice.Text += value
+			case inPrompt:
+				interaction.Prompt += value
+			case inBody && interaction == nil:
+				text.WriteString(value)
+				text.WriteString(" ")
+			}
+		}
+	}
+}
+
+// qtiQuery convert parsed item to test query. Returns nil query and reason for unsupported items
+func qtiQuery(qti *qtiItem) (*common.AddTestQuery, string) {
+	if len(qti.Interactions) != 1 {
+		return nil, "items must have exactly one interaction"
+	}
+
+	interaction := qti.Interactions[0]
+
+	switch interaction.Kind {
+	case "choiceInteraction", "textEntryInteraction", "extendedTextInteraction":
+	default:
+		return nil, interaction.Kind + " is not supported"
+	}
+
+	question := strings.TrimSpace(strings.Join(strings.Fields(qti.Text+" "+interaction.Prompt), " "))
+
+	if len(question) == 0 {
+		question = qti.Title
+	}
+
+	correct := qti.Correct[interaction.ResponseIdentifier]
+
+	if len(correct) == 0 {
+		return nil, "correct response is not found"
+	}
+
+	switch interaction.Kind {
+	case "choiceInteraction":
+		var options []*common.Option
+
+		for _, choice := range interaction.Choices {
+			option := &common.Option{Answer: strings.Join(strings.Fields(choice.Text), " ")}
+
+			for _, value := range correct {
+				if value == choice.Identifier {
+					option.IsRight = true
+				}
+			}
+
+			options = append(options, option)
+		}
+
+		if len(options) < 2 {
+			return nil, "choice interaction must have at least two choices"
+		}
+
+		return optionQuery(question, options), ""
+	default:
+		return rewriteQuery(question, correct[0]), ""
+	}
+}
+
+// attr return attribute value by local name
+func attr(element xml.StartElement, name string) string {
+	for _, attribute := range element.Attr {
+		if attribute.Name.Local == name {
+			return attribute.Value
+		}
+	}
+
+	return ""
+}
+
+// lineAt return line number of byte offset
+func lineAt(content string, offset int64) int {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+
+	return strings.Count(content[:offset], "\n") + 1
+}
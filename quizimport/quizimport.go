@@ -0,0 +1,153 @@
+package quizimport
+
+import (
+	"fmt"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"strings"
+)
+
+/*
+This file contains quiz import entry point. Parsers map multiple-choice items to option tests,
+multiple-answer items to multi-select tests, short-answer items to rewrite tests and numeric items to numeric tests.
+Other items are reported as issues with line numbers.
+*/
+
+// item parsed quiz item
+type item struct {
+	Line  int                  // Line number of item start
+	Query *common.AddTestQuery // Test query
+}
+
+/*
+Parse parse quiz content and return test queries and issues. Parameters:
+format - quiz format: common.QuizFormatGift, common.QuizFormatAiken or common.QuizFormatQti;
+content - quiz file content;
+lemmingsCount - count of lemmings for every test. If less than 1, 1 is used;
+*/
+func Parse(format string, content string, lemmingsCount int) ([]*common.AddTestQuery, []*common.ImportIssue, error) {
+	var items []*item
+	var issues []*common.ImportIssue
+	var err error
+
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	switch format {
+	case common.QuizFormatGift:
+		items, issues = parseGift(content)
+	case common.QuizFormatAiken:
+		items, issues = parseAiken(content)
+	case common.QuizFormatQti:
+		items, issues, err = parseQti(content)
+	default:
+		return nil, nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "quizimport/quizimport.go",
+				Method: "Parse",
+			},
+			Msg: fmt.Sprintf("unknown quiz format %s", format),
+		}
+	}
+
+	if err != nil {
+		return nil, nil, openerrors.DefaultErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "quizimport/quizimport.go",
+				Method: "Parse",
+			},
+			Msg: err.Error(),
+		}
+	}
+
+	if lemmingsCount < 1 {
+		lemmingsCount = 1
+	}
+
+	var queries []*common.AddTestQuery
+
+	for i, parsed := range items {
+		parsed.Query.LemmingsCount = lemmingsCount
+		parsed.Query.OrderNumber = i + 1
+		queries = append(queries, parsed.Query)
+	}
+
+	return queries, issues, nil
+}
+
+// optionQuery create option test query. If many options are right, multi-select test query with partial credit is created
+func optionQuery(question string, options []*common.Option) *common.AddTestQuery {
+	rightCount := 0
+
+	for _, option := range options {
+		if option.IsRight {
+			rightCount++
+		}
+	}
+
+	if rightCount > 1 {
+		return &common.AddTestQuery{
+			TestType:        common.TestMultiSelect,
+			MultiSelectTest: &common.MultiSelectTest{Question: question, Options: options, PartialCredit: true},
+		}
+	}
+
+	return &common.AddTestQuery{
+		TestType:   common.TestOption,
+		OptionTest: &common.OptionTest{Question: question, Options: options},
+	}
+}
+
+// numericQuery create numeric test query
+func numericQuery(question string, answer float64, tolerance float64) *common.AddTestQuery {
+	return &common.AddTestQuery{
+		TestType:    common.TestNumeric,
+		NumericTest: &common.NumericTest{Question: question, Answer: answer, Tolerance: tolerance},
+	}
+}
+
+// rewriteQuery create rewrite test query
+func rewriteQuery(question string, answer string) *common.AddTestQuery {
+	return &common.AddTestQuery{
+		TestType:    common.TestRewrite,
+		RewriteTest: &common.RewriteTest{Question: question, RightAnswer: answer},
+	}
+}
+
+// issue create import issue
+func issue(line int, format string, args ...interface{}) *common.ImportIssue {
+	return &common.ImportIssue{Line: line, Message: fmt.Sprintf(format, args...)}
+}
+
+// block text lines separated by empty lines
+type block struct {
+	Line  int      // Line number of the first block line
+	Lines []string // Block lines
+}
+
+// splitBlocks split content by empty lines. Lines for which skip returns true are ignored
+func splitBlocks(content string, skip func(line string) bool) []*block {
+	var blocks []*block
+	var current *block
+
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if skip != nil && skip(trimmed) {
+			continue
+		}
+
+		if len(trimmed) == 0 {
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			current = &block{Line: i + 1}
+			blocks = append(blocks, current)
+		}
+
+		current.Lines = append(current.Lines, line)
+	}
+
+	return blocks
+}
@@ -0,0 +1,147 @@
+package quizimport
+
+import (
+	"opencourse/common"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func parseFixture(t *testing.T, format string, fileName string) ([]*common.AddTestQuery, []*common.ImportIssue) {
+	content, err := os.ReadFile(filepath.Join("testdata", fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries, issues, err := Parse(format, string(content), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, query := range queries {
+		if query.LemmingsCount != 2 || query.OrderNumber != i+1 {
+			t.Errorf("query %d has lemmings %d and order %d", i, query.LemmingsCount, query.OrderNumber)
+		}
+	}
+
+	return queries, issues
+}
+
+func issueLines(issues []*common.ImportIssue) []int {
+	var lines []int
+
+	for _, item := range issues {
+		lines = append(lines, item.Line)
+	}
+
+	return lines
+}
+
+// TestParseGift
+func TestParseGift(t *testing.T) {
+	queries, issues := parseFixture(t, common.QuizFormatGift, "quiz.gift")
+
+	if len(queries) != 7 {
+		t.Fatalf("expected 7 tests, got %d, issues %v", len(queries), issueLines(issues))
+	}
+
+	goroutine := queries[0].OptionTest
+
+	if goroutine == nil || goroutine.Question != "What is goroutine?" || len(goroutine.Options) != 3 ||
+		!goroutine.Options[0].IsRight || goroutine.Options[1].Answer != "Package manager" {
+
+		t.Errorf("multiple choice is parsed wrong: %+v", queries[0])
+	}
+
+	// True-false answer with feedbacks
+	generics := queries[1].OptionTest
+
+	if generics == nil || !generics.Options[0].IsRight || generics.Options[1].IsRight {
+		t.Errorf("true-false with feedback is parsed wrong: %+v", queries[1])
+	}
+
+	if interpreted := queries[2].OptionTest; interpreted == nil || !interpreted.Options[1].IsRight {
+		t.Errorf("false answer is parsed wrong: %+v", queries[2])
+	}
+
+	keyword := queries[3].RewriteTest
+
+	if keyword == nil || keyword.RightAnswer != "func" || !keyword.Matching.IgnoreCase ||
+		!reflect.DeepEqual(keyword.Matching.AcceptedAnswers, []string{"FUNC"}) {
+
+		t.Errorf("short answer is parsed wrong: %+v", queries[3])
+	}
+
+	if bits := queries[4].NumericTest; bits == nil || bits.Answer != 8 || bits.Tolerance != 0 {
+		t.Errorf("numeric answer is parsed wrong: %+v", queries[4])
+	}
+
+	if pi := queries[5].NumericTest; pi == nil || pi.Answer != 3.1 || pi.Tolerance < 0.099 || pi.Tolerance > 0.101 {
+		t.Errorf("numeric range is parsed wrong: %+v", queries[5])
+	}
+
+	references := queries[6].MultiSelectTest
+
+	if references == nil || !references.PartialCredit || !references.Options[1].IsRight || references.Options[2].IsRight {
+		t.Errorf("multiple answers are parsed wrong: %+v", queries[6])
+	}
+
+	// Matching and essay items are reported with line numbers
+	if !reflect.DeepEqual(issueLines(issues), []int{18, 20}) {
+		t.Errorf("expected issues at lines 18 and 20, got %v", issueLines(issues))
+	}
+}
+
+// TestParseAiken
+func TestParseAiken(t *testing.T) {
+	queries, issues := parseFixture(t, common.QuizFormatAiken, "quiz.txt")
+
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 tests, got %d", len(queries))
+	}
+
+	if format := queries[1].OptionTest; format == nil || format.Question != "Which command formats code?" ||
+		!format.Options[1].IsRight || format.Options[1].Answer != "go fmt" {
+
+		t.Errorf("item is parsed wrong: %+v", queries[1])
+	}
+
+	if !reflect.DeepEqual(issueLines(issues), []int{12}) {
+		t.Errorf("expected issue at line 12, got %v", issueLines(issues))
+	}
+}
+
+// TestParseQti HTML entities don't reject file
+func TestParseQti(t *testing.T) {
+	queries, issues := parseFixture(t, common.QuizFormatQti, "quiz.xml")
+
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 tests, got %d", len(queries))
+	}
+
+	goroutine := queries[0].OptionTest
+
+	if goroutine == nil || goroutine.Question != "What is goroutine?" || !goroutine.Options[0].IsRight ||
+		goroutine.Options[0].Answer != "Lightweight thread" || goroutine.Options[1].Answer != "Package & manager" {
+
+		t.Errorf("choice item is parsed wrong: %+v", goroutine)
+	}
+
+	if keyword := queries[1].RewriteTest; keyword == nil || keyword.RightAnswer != "func" ||
+		keyword.Question != "Keyword of function is _____ — always." {
+
+		t.Errorf("text entry item is parsed wrong: %+v", keyword)
+	}
+
+	if !reflect.DeepEqual(issueLines(issues), []int{23}) {
+		t.Errorf("expected issue at line 23, got %v", issueLines(issues))
+	}
+}
+
+// TestParseUnknownFormat
+func TestParseUnknownFormat(t *testing.T) {
+	if _, _, err := Parse("moodle-xml", "", 1); err == nil {
+		t.Error("unknown format must be rejected")
+	}
+}
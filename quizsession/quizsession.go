@@ -0,0 +1,393 @@
+package quizsession
+
+import (
+	"context"
+	"errors"
+	"opencourse/badges"
+	"opencourse/certificates"
+	"opencourse/clock"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/database"
+	"opencourse/events"
+	"opencourse/grading"
+	"opencourse/sandbox"
+	"opencourse/selection"
+	"time"
+)
+
+/*
+This file contains submit of quiz sessions. Session is graded once: results of answered tests are saved
+once for session, and session is closed the last, so failed submit is repeated by user or auto-submit job.
+Answer, which can't be graded, gets zero score, so broken test doesn't keep session active. Errors of grading,
+events, certificate and badges are passed to error handler.
+*/
+
+// expiredBatch max count of expired sessions submitted in one auto-submit iteration
+const expiredBatch = 100
+
+/*
+Start start quiz session for stage and draw its tests. If user has active session for stage, it's returned.
+Active session after deadline is submitted and new session is started. Parameters:
+ctx - context, cancel it to stop code tests of expired session;
+db - database context;
+executor - sandbox executor for code tests;
+userId - user id;
+stageId - stage id;
+onErr - handler of errors, which don't fail submit of expired session;
+*/
+func Start(ctx context.Context, db *database.DbContext, executor sandbox.Executor, userId string, stageId string,
+	onErr func(error)) (*common.QuizSession, error) {
+
+	session, err := db.StartQuizSession(userId, stageId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isExpired(session, db.Now()) {
+		_, err = Submit(ctx, db, executor, session.Id, onErr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		session, err = db.StartQuizSession(userId, stageId)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stage, err := db.GetStage(stageId)
+
+	if err == nil && stage == nil {
+		err = stageNotFound(stageId, "Start")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Every session is a new attempt, so it gets its own selection
+	_, _, err = selection.Select(db, userId, stage, session.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+/*
+Submit grade all drawn tests of session, save results and close session. Session is expired, if it's submitted
+after deadline. Closed session is returned without changes. Parameters:
+ctx - context, cancel it to stop code tests;
+db - database context;
+executor - sandbox executor for code tests;
+sessionId - session id;
+onErr - handler of errors of grading, events, certificate and badges. They don't fail submit;
+*/
+func Submit(ctx context.Context, db *database.DbContext, executor sandbox.Executor, sessionId string,
+	onErr func(error)) (*common.QuizSession, error) {
+
+	session, err := db.GetQuizSession(sessionId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status != common.SessionActive {
+		return session, nil
+	}
+
+	stage, err := db.GetStage(session.StageId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Session of deleted stage is closed without results, otherwise auto-submit repeats it
+	if stage == nil {
+		onErr(stageNotFound(session.StageId, "Submit"))
+
+		_, err = completeSession(db, session, nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return db.GetQuizSession(sessionId)
+	}
+
+	drawn, tests, err := selection.Select(db, session.UserId, stage, session.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Session previews shuffle options for the next attempt of every test
+	counts, err := db.GetAttemptCounts(session.UserId, session.StageId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	answers := make(map[string]*common.TestAnswer, len(session.Answers))
+
+	for _, answer := range session.Answers {
+		answers[answer.TestId] = answer.Answer
+	}
+
+	results, grades, err := gradeAnswers(ctx, executor, tests, answers, onErr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	answered := make([]*common.Test, 0, len(grades))
+
+	for _, test := range tests {
+		grade, ok := grades[test.Id]
+
+		if ok {
+			grade.SelectionId = drawn.Id
+			grade.SessionId = session.Id
+			selection.Snapshot(grade, test, drawn, counts[test.Id]+1)
+			answered = append(answered, test)
+		}
+	}
+
+	// Stage is completed by the first passed session
+	passedBefore, err := db.HasPassedSession(session.UserId, session.StageId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, test := range answered {
+		attempt, firstPass, err := db.SaveTestResult(session.UserId, session.CourseId, test, answers[test.Id],
+			grades[test.Id])
+
+		var denied openerrors.AttemptDeniedErr
+
+		// Session result is saved even if attempts of test are over
+		if errors.As(err, &denied) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Attempt is saved by previous or concurrent submit, which publishes its events
+		if attempt == nil {
+			continue
+		}
+
+		publish(db, events.Event{
+			Type:     events.TestAnswered,
+			UserId:   session.UserId,
+			CourseId: session.CourseId,
+			StageId:  session.StageId,
+			TestId:   test.Id,
+			Date:     db.Now(),
+		}, firstPass, onErr)
+	}
+
+	closed, err := completeSession(db, session, results)
+
+	if err != nil {
+		return nil, err
+	}
+
+	session, err = db.GetQuizSession(sessionId)
+
+	// Session is closed by concurrent submit, which completes stage
+	if err != nil || !closed {
+		return session, err
+	}
+
+	if session.IsPassed && !passedBefore {
+		err = db.Events.Publish(events.Event{
+			Type:     events.StageCompleted,
+			UserId:   session.UserId,
+			CourseId: session.CourseId,
+			StageId:  session.StageId,
+			Date:     db.Now(),
+		})
+
+		if err != nil {
+			onErr(err)
+		}
+	}
+
+	if session.IsPassed {
+		_, err = certificates.IssueIfCompleted(db, session.UserId, session.CourseId)
+
+		if err != nil {
+			onErr(err)
+		}
+	}
+
+	_, err = badges.AwardEarned(db, session.UserId)
+
+	if err != nil {
+		onErr(err)
+	}
+
+	return session, nil
+}
+
+/*
+RunAutoSubmit submit expired sessions every interval until context is canceled. Waiting uses clock of database
+context. Parameters:
+ctx - context, cancel it to stop job;
+db - database context;
+executor - sandbox executor for code tests;
+interval - interval between checks;
+onErr - error handler. Job isn't stopped by errors;
+*/
+func RunAutoSubmit(ctx context.Context, db *database.DbContext, executor sandbox.Executor, interval time.Duration,
+	onErr func(error)) {
+
+	expired := func() ([]*common.QuizSession, error) {
+		return db.GetExpiredQuizSessions(expiredBatch)
+	}
+
+	submit := func(sessionId string) error {
+		_, err := Submit(ctx, db, executor, sessionId, onErr)
+
+		return err
+	}
+
+	runAutoSubmit(ctx, db.Clock, interval, expired, submit, onErr)
+}
+
+// runAutoSubmit submit sessions returned by expired every interval of clock until context is canceled
+func runAutoSubmit(ctx context.Context, clock clock.Clock, interval time.Duration,
+	expired func() ([]*common.QuizSession, error), submit func(sessionId string) error, onErr func(error)) {
+
+	for {
+		sessions, err := expired()
+
+		if err != nil {
+			onErr(err)
+		}
+
+		for _, session := range sessions {
+			err = submit(session.Id)
+
+			if err != nil {
+				onErr(err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+		}
+	}
+}
+
+// isExpired check that deadline of session is passed
+func isExpired(session *common.QuizSession, now time.Time) bool {
+	return !now.Before(session.Deadline)
+}
+
+// publish publish event of answered test and event of passed test for the first pass. Errors are passed to onErr
+func publish(db *database.DbContext, event events.Event, firstPass bool, onErr func(error)) {
+	err := db.Events.Publish(event)
+
+	if err == nil && firstPass {
+		event.Type = events.TestPassed
+		err = db.Events.Publish(event)
+	}
+
+	if err != nil {
+		onErr(err)
+	}
+}
+
+/*
+gradeAnswers grade answers for tests. Answer, which can't be graded, gets zero score and error is passed to onErr.
+Returns results for every test and grades of graded answers by test id. Error is returned only if context
+is canceled, so session is submitted again. Parameters:
+ctx - context, cancel it to stop code tests;
+executor - sandbox executor for code tests;
+tests - drawn tests of session;
+answers - answers by test id;
+onErr - handler of grading errors;
+*/
+func gradeAnswers(ctx context.Context, executor sandbox.Executor, tests []*common.Test,
+	answers map[string]*common.TestAnswer, onErr func(error)) ([]*common.SessionResult, map[string]*common.GradeResult, error) {
+
+	results := make([]*common.SessionResult, 0, len(tests))
+	grades := make(map[string]*common.GradeResult, len(answers))
+
+	for _, test := range tests {
+		result := &common.SessionResult{TestId: test.Id}
+		answer, ok := answers[test.Id]
+
+		if ok {
+			result.Answered = true
+			grade, err := gradeTest(ctx, executor, test, answer)
+
+			if err != nil && ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+
+			if err != nil {
+				onErr(err)
+			} else {
+				grades[test.Id] = grade
+				result.Score = grade.Score
+				result.IsPassed = grade.IsPassed
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, grades, nil
+}
+
+/*
+completeSession close active session with results. Session is expired, if deadline is passed.
+Returns false, if session is closed by concurrent submit. Parameters:
+db - database context;
+session - active session;
+results - results for every test;
+*/
+func completeSession(db *database.DbContext, session *common.QuizSession, results []*common.SessionResult) (bool, error) {
+	status := common.SessionSubmitted
+
+	if isExpired(session, db.Now()) {
+		status = common.SessionExpired
+	}
+
+	return db.CompleteQuizSession(session.Id, status, results)
+}
+
+// gradeTest grade answer for test. Code tests are run in sandbox
+func gradeTest(ctx context.Context, executor sandbox.Executor, test *common.Test,
+	answer *common.TestAnswer) (*common.GradeResult, error) {
+
+	if test.TestType == common.TestCode {
+		return grading.GradeCode(ctx, executor, test, answer)
+	}
+
+	return grading.Grade(test, answer)
+}
+
+// stageNotFound return error of stage, which isn't found
+func stageNotFound(stageId string, method string) error {
+	return openerrors.DefaultErr{
+		BaseErr: openerrors.BaseErr{
+			File:   "quizsession/quizsession.go",
+			Method: method,
+		},
+		Msg: "stage " + stageId + " isn't found",
+	}
+}
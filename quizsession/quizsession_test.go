@@ -0,0 +1,216 @@
+package quizsession
+
+import (
+	"context"
+	"errors"
+	"opencourse/clock"
+	"opencourse/common"
+	"opencourse/sandbox"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestIsExpired session is expired from deadline
+func TestIsExpired(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	manual := clock.NewManualClock(start)
+	session := &common.QuizSession{Deadline: start.Add(time.Minute)}
+
+	if isExpired(session, manual.Now()) {
+		t.Error("session is expired before deadline")
+	}
+
+	manual.Advance(time.Minute - time.Nanosecond)
+
+	if isExpired(session, manual.Now()) {
+		t.Error("session is expired before deadline")
+	}
+
+	manual.Advance(time.Nanosecond)
+
+	if !isExpired(session, manual.Now()) {
+		t.Error("session isn't expired at deadline")
+	}
+}
+
+// TestRunAutoSubmit sessions are submitted on every tick of clock until context is canceled
+func TestRunAutoSubmit(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	manual := clock.NewManualClock(start)
+	sessions := []*common.QuizSession{
+		{Id: "a", Deadline: start.Add(time.Minute)},
+		{Id: "b", Deadline: start.Add(3 * time.Minute)},
+	}
+
+	var mutex sync.Mutex
+	var submitted, errs []string
+
+	// Job sees only sessions, which deadline is passed by clock
+	expired := func() ([]*common.QuizSession, error) {
+		var result []*common.QuizSession
+
+		for _, session := range sessions {
+			if isExpired(session, manual.Now()) {
+				result = append(result, session)
+			}
+		}
+
+		return result, nil
+	}
+
+	submit := func(sessionId string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		submitted = append(submitted, sessionId)
+
+		// Submitted session isn't returned again
+		for i, session := range sessions {
+			if session.Id == sessionId {
+				sessions = append(sessions[:i], sessions[i+1:]...)
+				break
+			}
+		}
+
+		if sessionId == "b" {
+			return errors.New("grading error")
+		}
+
+		return nil
+	}
+
+	onErr := func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		errs = append(errs, err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		runAutoSubmit(ctx, manual, time.Minute, expired, submit, onErr)
+		close(done)
+	}()
+
+	// tick moves clock, when job waits, and waits for the next wait
+	tick := func() {
+		waitFor(t, func() bool { return manual.Waiters() == 1 })
+		manual.Advance(time.Minute)
+		waitFor(t, func() bool { return manual.Waiters() == 1 })
+	}
+
+	waitFor(t, func() bool { return manual.Waiters() == 1 })
+
+	if len(submitted) != 0 {
+		t.Fatalf("sessions are submitted before deadline: %v", submitted)
+	}
+
+	tick()
+
+	mutex.Lock()
+
+	if len(submitted) != 1 || submitted[0] != "a" {
+		t.Fatalf("expected submit of a, got %v", submitted)
+	}
+
+	mutex.Unlock()
+
+	tick()
+	tick()
+
+	cancel()
+	manual.Advance(time.Minute)
+	<-done
+
+	if len(submitted) != 2 || submitted[1] != "b" {
+		t.Errorf("expected submit of b, got %v", submitted)
+	}
+
+	// Job isn't stopped by error of submit
+	if len(errs) != 1 || errs[0] != "grading error" {
+		t.Errorf("expected submit error, got %v", errs)
+	}
+}
+
+// waitFor wait until condition is true
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition isn't met")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// failExecutor executor, which can't run programs
+type failExecutor struct{}
+
+func (executor failExecutor) Run(ctx context.Context, program *sandbox.Program, inputs []string) (*sandbox.Report, error) {
+	return nil, errors.New("sandbox is unavailable")
+}
+
+// TestGradeAnswers answer, which can't be graded, gets zero score and error is passed to handler
+func TestGradeAnswers(t *testing.T) {
+	tests := []*common.Test{
+		{Id: "code", TestType: common.TestCode, CodeTest: &common.CodeTest{
+			Language: sandbox.LanguageGo,
+			Cases:    []*common.CodeCase{{Input: "1 2", ExpectedOutput: "3"}},
+		}},
+		{Id: "numeric", TestType: common.TestNumeric, NumericTest: &common.NumericTest{Answer: 4}},
+		{Id: "skipped", TestType: common.TestNumeric, NumericTest: &common.NumericTest{Answer: 4}},
+	}
+
+	answers := map[string]*common.TestAnswer{
+		"code":    {Code: "package main"},
+		"numeric": {Number: 4},
+	}
+
+	var errs []error
+
+	results, grades, err := gradeAnswers(context.Background(), failExecutor{}, tests, answers,
+		func(err error) { errs = append(errs, err) })
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(errs) != 1 {
+		t.Errorf("expected grading error, got %v", errs)
+	}
+
+	expected := []common.SessionResult{
+		{TestId: "code", Answered: true},
+		{TestId: "numeric", Answered: true, Score: 1, IsPassed: true},
+		{TestId: "skipped"},
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+
+	for i, result := range results {
+		if *result != expected[i] {
+			t.Errorf("expected result %+v, got %+v", expected[i], *result)
+		}
+	}
+
+	// Attempt isn't saved for answer, which isn't graded
+	if _, ok := grades["code"]; ok || len(grades) != 1 {
+		t.Errorf("expected grade of numeric test only, got %v", grades)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err = gradeAnswers(ctx, failExecutor{}, tests, answers, func(err error) {})
+
+	if err == nil {
+		t.Error("canceled submit must fail, so session is submitted again")
+	}
+}
@@ -0,0 +1,56 @@
+package reviews
+
+import (
+	"math"
+	"opencourse/common"
+	"time"
+)
+
+/*
+This file contains SM-2 spaced repetition. Recall quality from 0 to 5 is derived from score of review answer.
+Quality 3 and more continues repetitions: the first interval is 1 day, the second is 6 days and the next ones
+are multiplied by ease factor. Lower quality starts repetitions again. Ease factor is changed by quality
+and isn't less than 1.3.
+*/
+
+/*
+Quality return recall quality of answer score. Parameters:
+score - score from 0 to 1;
+*/
+func Quality(score float64) int {
+	return int(math.Round(math.Max(0, math.Min(1, score)) * 5))
+}
+
+/*
+Next return schedule after review. Parameters:
+schedule - schedule before review;
+quality - recall quality from 0 to 5;
+dateNow - date of review;
+*/
+func Next(schedule *common.ReviewSchedule, quality int, dateNow time.Time) *common.ReviewSchedule {
+	next := *schedule
+
+	if quality >= 3 {
+		switch next.Repetitions {
+		case 0:
+			next.Interval = 1
+		case 1:
+			next.Interval = 6
+		default:
+			next.Interval = int(math.Round(float64(next.Interval) * next.Ease))
+		}
+
+		next.Repetitions++
+	} else {
+		next.Repetitions = 0
+		next.Interval = 1
+	}
+
+	lapse := float64(5 - quality)
+	next.Ease = math.Max(common.ReviewMinEase, next.Ease+0.1-lapse*(0.08+lapse*0.02))
+	next.LastQuality = quality
+	next.DateReview = dateNow
+	next.DateDue = dateNow.AddDate(0, 0, next.Interval)
+
+	return &next
+}
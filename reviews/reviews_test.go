@@ -0,0 +1,48 @@
+package reviews
+
+import (
+	"opencourse/common"
+	"testing"
+	"time"
+)
+
+// TestQuality score is scaled to quality from 0 to 5
+func TestQuality(t *testing.T) {
+	for score, quality := range map[float64]int{-1: 0, 0: 0, 0.5: 3, 0.59: 3, 0.8: 4, 1: 5, 2: 5} {
+		if result := Quality(score); result != quality {
+			t.Errorf("score %v: expected quality %d, got %d", score, quality, result)
+		}
+	}
+}
+
+// TestNext intervals are 1, 6 and multiplied by ease, low quality starts repetitions again
+func TestNext(t *testing.T) {
+	dateNow := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	schedule := &common.ReviewSchedule{Ease: 2.5}
+
+	for i, interval := range []int{1, 6, 15} {
+		schedule = Next(schedule, 4, dateNow)
+
+		if schedule.Interval != interval || schedule.Repetitions != i+1 {
+			t.Errorf("review %d: expected interval %d, got %d", i, interval, schedule.Interval)
+		}
+	}
+
+	if schedule.Ease != 2.5 || !schedule.DateDue.Equal(dateNow.AddDate(0, 0, 15)) || schedule.LastQuality != 4 {
+		t.Errorf("quality 4 mustn't change ease, got %v due %v", schedule.Ease, schedule.DateDue)
+	}
+
+	schedule = Next(schedule, 1, dateNow)
+
+	if schedule.Interval != 1 || schedule.Repetitions != 0 || schedule.Ease >= 2.5 {
+		t.Errorf("low quality must start repetitions again and lower ease, got %+v", schedule)
+	}
+
+	for i := 0; i < 10; i++ {
+		schedule = Next(schedule, 0, dateNow)
+	}
+
+	if schedule.Ease != common.ReviewMinEase {
+		t.Errorf("ease mustn't be less than %v, got %v", common.ReviewMinEase, schedule.Ease)
+	}
+}
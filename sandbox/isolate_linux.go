@@ -0,0 +1,428 @@
+//go:build linux && (amd64 || arm64)
+
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+/*
+Isolated command is started by this binary with initArg in new user, mount, pid, network, IPC and UTS namespaces
+as unprivileged user. Init process mounts read-only root of jail, sets resource limits and seccomp filter
+and replaces itself with the command.
+*/
+
+const (
+	initArg   = "opencourse-sandbox-init" // First argument of init process
+	configEnv = "OPENCOURSE_SANDBOX"      // Environment variable with config of init process
+	statusFd  = 3                         // Init process writes setup error to this file
+	nobodyId  = 65534                     // User and group of jail
+)
+
+// Seccomp constants, which aren't declared in unix package
+const (
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetAllow       = 0x7fff0000
+	seccompDataNr         = 0
+	seccompDataArch       = 4
+	seccompDataArg0       = 16
+	x32SyscallBit         = 0x40000000
+)
+
+// namespaceFlags flags of clone, which create namespaces
+const namespaceFlags = unix.CLONE_NEWNS | unix.CLONE_NEWUTS | unix.CLONE_NEWIPC | unix.CLONE_NEWUSER |
+	unix.CLONE_NEWPID | unix.CLONE_NEWNET | unix.CLONE_NEWCGROUP
+
+// deniedSyscalls syscalls, which fail with EPERM in jail. They change system or escape namespaces
+var deniedSyscalls = []uint32{
+	unix.SYS_MOUNT, unix.SYS_UMOUNT2, unix.SYS_PIVOT_ROOT, unix.SYS_CHROOT, unix.SYS_UNSHARE, unix.SYS_SETNS,
+	unix.SYS_OPEN_TREE, unix.SYS_MOVE_MOUNT, unix.SYS_FSOPEN, unix.SYS_FSCONFIG, unix.SYS_FSMOUNT, unix.SYS_FSPICK,
+	unix.SYS_MOUNT_SETATTR, unix.SYS_PTRACE, unix.SYS_PROCESS_VM_READV, unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_PIDFD_GETFD, unix.SYS_KEYCTL, unix.SYS_ADD_KEY, unix.SYS_REQUEST_KEY, unix.SYS_BPF,
+	unix.SYS_PERF_EVENT_OPEN, unix.SYS_USERFAULTFD, unix.SYS_IO_URING_SETUP, unix.SYS_IO_URING_ENTER,
+	unix.SYS_IO_URING_REGISTER, unix.SYS_KEXEC_LOAD, unix.SYS_KEXEC_FILE_LOAD, unix.SYS_INIT_MODULE,
+	unix.SYS_FINIT_MODULE, unix.SYS_DELETE_MODULE, unix.SYS_REBOOT, unix.SYS_SWAPON, unix.SYS_SWAPOFF,
+	unix.SYS_SYSLOG, unix.SYS_ACCT, unix.SYS_QUOTACTL, unix.SYS_SETTIMEOFDAY, unix.SYS_CLOCK_SETTIME,
+	unix.SYS_CLOCK_ADJTIME, unix.SYS_ADJTIMEX, unix.SYS_SETHOSTNAME, unix.SYS_SETDOMAINNAME, unix.SYS_PERSONALITY,
+	unix.SYS_OPEN_BY_HANDLE_AT, unix.SYS_NAME_TO_HANDLE_AT, unix.SYS_LOOKUP_DCOOKIE, unix.SYS_VHANGUP,
+}
+
+// jailConfig config of init process
+type jailConfig struct {
+	Jail *jail    // File system and limits
+	Path string   // Command path
+	Args []string // Command arguments
+	Env  []string // Command environment
+	Dir  string   // Command working directory
+}
+
+func init() {
+	if len(os.Args) > 0 && os.Args[0] == initArg {
+		runInit()
+	}
+}
+
+/*
+isolate replace command with init process, which runs command in jail. Returned function waits until
+command is started in jail and returns setup error. It must be called after start of command. Parameters:
+cmd - command, which isn't started;
+jail - file system and limits of command;
+*/
+func isolate(cmd *exec.Cmd, jail *jail) (func() error, error) {
+	config, err := json.Marshal(&jailConfig{Jail: jail, Path: cmd.Path, Args: cmd.Args, Env: cmd.Env, Dir: cmd.Dir})
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer, err := os.Pipe()
+
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Path = "/proc/self/exe"
+	cmd.Args = []string{initArg}
+	cmd.Env = []string{configEnv + "=" + string(config)}
+	cmd.Dir = ""
+	cmd.ExtraFiles = []*os.File{writer}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: nobodyId, HostID: os.Getuid(), Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: nobodyId, HostID: os.Getgid(), Size: 1},
+		},
+		// Init process mounts root of jail. Capability is valid only in own namespaces and is dropped before command
+		AmbientCaps: []uintptr{unix.CAP_SYS_ADMIN},
+	}
+
+	started := func() error {
+		_ = writer.Close()
+
+		defer func() {
+			_ = reader.Close()
+		}()
+
+		// Status file is closed on exec of command
+		status, err := io.ReadAll(reader)
+
+		if err != nil {
+			return err
+		}
+
+		if len(status) > 0 {
+			return fmt.Errorf("jail setup: %s", status)
+		}
+
+		return nil
+	}
+
+	return started, nil
+}
+
+// runInit set up jail and execute command in it. Setup error is written to status file
+func runInit() {
+	runtime.LockOSThread()
+
+	err := startJail()
+
+	_, _ = os.NewFile(statusFd, "status").WriteString(err.Error())
+	os.Exit(1)
+}
+
+// startJail set up jail and execute command in it. It returns only error
+func startJail() error {
+	syscall.CloseOnExec(statusFd)
+
+	var config jailConfig
+
+	err := json.Unmarshal([]byte(os.Getenv(configEnv)), &config)
+
+	if err != nil {
+		return err
+	}
+
+	if config.Jail == nil {
+		return errors.New("jail config is empty")
+	}
+
+	err = mountRoot(config.Jail)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Chdir(config.Dir)
+
+	if err != nil {
+		return err
+	}
+
+	err = setLimits(config.Jail.Limits)
+
+	if err != nil {
+		return err
+	}
+
+	// Command is executed by unprivileged user, so it gets no capabilities without ambient ones
+	err = unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0)
+
+	if err != nil {
+		return err
+	}
+
+	err = unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+
+	if err != nil {
+		return err
+	}
+
+	// Filter is set for this thread only, which executes command
+	filter, err := seccompFilter()
+
+	if err != nil {
+		return err
+	}
+
+	program := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+
+	err = unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&program)), 0, 0)
+
+	if err != nil {
+		return err
+	}
+
+	return syscall.Exec(config.Path, config.Args, config.Env)
+}
+
+// mountRoot mount root of jail with temporary directory and binds, and make it root of process
+func mountRoot(jail *jail) error {
+	// Mounts of jail aren't propagated to host
+	err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, "")
+
+	if err != nil {
+		return err
+	}
+
+	err = unix.Mount("tmpfs", jail.Root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=1m,mode=0755")
+
+	if err != nil {
+		return err
+	}
+
+	// Temporary directory is mounted before binds, because work directory may be in host temporary directory
+	tmp := filepath.Join(jail.Root, "tmp")
+
+	err = os.Mkdir(tmp, 0755)
+
+	if err != nil {
+		return err
+	}
+
+	options := "mode=1777"
+
+	if jail.Limits.FileBytes > 0 {
+		options = fmt.Sprintf("size=%d,%s", jail.Limits.FileBytes, options)
+	}
+
+	err = unix.Mount("tmpfs", tmp, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, options)
+
+	if err != nil {
+		return err
+	}
+
+	for _, bind := range jail.Binds {
+		err = mountBind(jail.Root, bind)
+
+		if err != nil {
+			return fmt.Errorf("bind %s: %w", bind.Path, err)
+		}
+	}
+
+	// Old root is detached, so host file system isn't reachable from jail
+	err = unix.Chdir(jail.Root)
+
+	if err != nil {
+		return err
+	}
+
+	err = unix.PivotRoot(".", ".")
+
+	if err != nil {
+		return err
+	}
+
+	err = unix.Unmount(".", unix.MNT_DETACH)
+
+	if err != nil {
+		return err
+	}
+
+	err = unix.Chdir("/")
+
+	if err != nil {
+		return err
+	}
+
+	return unix.Mount("", "/", "", unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, "")
+}
+
+// mountBind mount host path to the same path in root of jail. Not writable path is read-only
+func mountBind(root string, bind bind) error {
+	target := filepath.Join(root, bind.Path)
+
+	info, err := os.Stat(bind.Path)
+
+	if err != nil {
+		return err
+	}
+
+	// Mount point is directory or empty file like host path
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else {
+		err = os.MkdirAll(filepath.Dir(target), 0755)
+
+		if err == nil {
+			err = os.WriteFile(target, nil, 0644)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = unix.Mount(bind.Path, target, "", unix.MS_BIND, "")
+
+	if err != nil || bind.Writable {
+		return err
+	}
+
+	var stat unix.Statfs_t
+
+	err = unix.Statfs(target, &stat)
+
+	if err != nil {
+		return err
+	}
+
+	// Flags of host mount are locked in user namespace, so they must be kept on remount
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY | unix.MS_NOSUID)
+
+	for statFlag, mountFlag := range map[int64]uintptr{
+		0x4:    unix.MS_NODEV,
+		0x8:    unix.MS_NOEXEC,
+		0x400:  unix.MS_NOATIME,
+		0x800:  unix.MS_NODIRATIME,
+		0x1000: unix.MS_RELATIME,
+	} {
+		if int64(stat.Flags)&statFlag != 0 {
+			flags |= mountFlag
+		}
+	}
+
+	return unix.Mount("", target, "", flags, "")
+}
+
+// setLimits set resource limits of process. Zero limit isn't set
+func setLimits(limits Limits) error {
+	for resource, value := range map[int]uint64{
+		unix.RLIMIT_DATA:   uint64(limits.MemoryBytes),
+		unix.RLIMIT_FSIZE:  uint64(limits.FileBytes),
+		unix.RLIMIT_NOFILE: limits.OpenFiles,
+		unix.RLIMIT_NPROC:  limits.Processes,
+	} {
+		if value == 0 {
+			continue
+		}
+
+		// syscall package is used, because it doesn't restore open files limit on exec after own change
+		err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: value, Max: value})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+seccompFilter return BPF program, which kills process of other architecture, denies syscalls from deniedSyscalls
+and creation of namespaces by clone. clone3 fails with ENOSYS, because its flags can't be checked,
+so programs use clone.
+*/
+func seccompFilter() ([]unix.SockFilter, error) {
+	var arch uint32
+
+	switch runtime.GOARCH {
+	case "amd64":
+		arch = unix.AUDIT_ARCH_X86_64
+	case "arm64":
+		arch = unix.AUDIT_ARCH_AARCH64
+	default:
+		return nil, fmt.Errorf("seccomp filter isn't supported on %s", runtime.GOARCH)
+	}
+
+	load := func(offset uint32) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: offset}
+	}
+
+	jump := func(operation uint16, value uint32, jumpTrue uint8, jumpFalse uint8) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_JMP | operation | unix.BPF_K, Jt: jumpTrue, Jf: jumpFalse, K: value}
+	}
+
+	ret := func(value uint32) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: value}
+	}
+
+	filter := []unix.SockFilter{
+		load(seccompDataArch),
+		jump(unix.BPF_JEQ, arch, 1, 0),
+		ret(seccompRetKillProcess),
+		load(seccompDataNr),
+		// x32 syscalls of amd64 have the same architecture
+		jump(unix.BPF_JGE, x32SyscallBit, 0, 1),
+		ret(seccompRetKillProcess),
+		jump(unix.BPF_JEQ, unix.SYS_CLONE3, 0, 1),
+		ret(seccompRetErrno | uint32(unix.ENOSYS)),
+	}
+
+	for _, number := range deniedSyscalls {
+		filter = append(filter, jump(unix.BPF_JEQ, number, 0, 1), ret(seccompRetErrno|uint32(unix.EPERM)))
+	}
+
+	// Flags are the first argument of clone on amd64 and arm64. Namespace flags are in the lower half
+	filter = append(filter,
+		jump(unix.BPF_JEQ, unix.SYS_CLONE, 0, 3),
+		load(seccompDataArg0),
+		jump(unix.BPF_JSET, namespaceFlags, 0, 1),
+		ret(seccompRetErrno|uint32(unix.EPERM)),
+		ret(seccompRetAllow),
+	)
+
+	return filter, nil
+}
+
+// kill kill command process group
+func kill(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux || !(amd64 || arm64)
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// isolate return error, because programs can't be isolated on this platform
+func isolate(cmd *exec.Cmd, jail *jail) (func() error, error) {
+	return nil, errors.New("sandbox is supported only on linux amd64 and arm64")
+}
+
+// kill kill command process
+func kill(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		_ = cmd.Process.Kill()
+	}
+}
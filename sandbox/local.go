@@ -0,0 +1,342 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"opencourse/common/openerrors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// LocalExecutor runs programs on the local machine. Every process runs in own namespaces as unprivileged user
+// with read-only root, without network, with seccomp filter, timeout, memory, file, process and output limits
+type LocalExecutor struct {
+	WorkDir string        // Directory for temporary program directories and build cache. Temp dir is used if empty
+	Limits  Limits        // Resource limits
+	slots   chan struct{} // Limits count of parallel executions
+}
+
+/*
+NewLocalExecutor create local executor which runs as many programs in parallel as CPU count. Parameters:
+workDir - directory for temporary files. Temp dir is used if empty;
+limits - resource limits;
+*/
+func NewLocalExecutor(workDir string, limits Limits) *LocalExecutor {
+	if len(workDir) == 0 {
+		workDir = filepath.Join(os.TempDir(), "opencourse-sandbox")
+	}
+
+	return &LocalExecutor{
+		WorkDir: workDir,
+		Limits:  limits,
+		slots:   make(chan struct{}, runtime.NumCPU()),
+	}
+}
+
+// Run compile program and run it for every input
+func (executor *LocalExecutor) Run(ctx context.Context, program *Program, inputs []string) (*Report, error) {
+	if program == nil {
+		return nil, openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "sandbox/local.go",
+				Method: "Run",
+			},
+			Model: "program",
+		}
+	}
+
+	if program.Language != LanguageGo {
+		return nil, runErr(fmt.Errorf("language %s is not supported", program.Language))
+	}
+
+	select {
+	case executor.slots <- struct{}{}:
+		defer func() { <-executor.slots }()
+	case <-ctx.Done():
+		return nil, runErr(ctx.Err())
+	}
+
+	cacheDir := filepath.Join(executor.WorkDir, "gocache")
+
+	err := os.MkdirAll(cacheDir, 0700)
+
+	if err != nil {
+		return nil, runErr(err)
+	}
+
+	goRoot, err := findGoRoot()
+
+	if err != nil {
+		return nil, runErr(err)
+	}
+
+	runDir, err := os.MkdirTemp(executor.WorkDir, "run-")
+
+	if err != nil {
+		return nil, runErr(err)
+	}
+
+	defer func() {
+		_ = os.RemoveAll(runDir)
+	}()
+
+	// Program directory and empty directory for root of isolated file system
+	dir := filepath.Join(runDir, "work")
+	root := filepath.Join(runDir, "root")
+
+	for _, path := range []string{dir, root} {
+		err = os.Mkdir(path, 0700)
+
+		if err != nil {
+			return nil, runErr(err)
+		}
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(program.Source), 0600)
+
+	if err != nil {
+		return nil, runErr(err)
+	}
+
+	// Telemetry of go command is off, because its process needs /proc, which isn't mounted in jail
+	telemetryDir := filepath.Join(dir, ".config", "go", "telemetry")
+
+	err = os.MkdirAll(telemetryDir, 0700)
+
+	if err != nil {
+		return nil, runErr(err)
+	}
+
+	err = os.WriteFile(filepath.Join(telemetryDir, "mode"), []byte("off"), 0600)
+
+	if err != nil {
+		return nil, runErr(err)
+	}
+
+	report := &Report{}
+
+	// Compiler is isolated too, so build can't download anything and sees only go root and own directories
+	build := exec.Command(filepath.Join(goRoot, "bin", "go"), "build", "-o", "program", "main.go")
+	build.Dir = dir
+	build.Env = []string{
+		"PATH=" + filepath.Join(goRoot, "bin"),
+		"HOME=" + dir,
+		"XDG_CONFIG_HOME=" + filepath.Join(dir, ".config"),
+		"TMPDIR=" + dir,
+		"GOROOT=" + goRoot,
+		"GOPATH=" + filepath.Join(dir, "gopath"),
+		"GOCACHE=" + cacheDir,
+		"GO111MODULE=off",
+		"GOPROXY=off",
+		"GOTOOLCHAIN=local",
+		"CGO_ENABLED=0",
+	}
+
+	buildLimits := executor.Limits
+	buildLimits.MemoryBytes = 0
+
+	buildJail := &jail{
+		Root:   root,
+		Binds:  append(deviceBinds(), bind{Path: goRoot}, bind{Path: dir, Writable: true}, bind{Path: cacheDir, Writable: true}),
+		Limits: buildLimits,
+	}
+
+	compiled, err := executor.exec(ctx, build, "", executor.Limits.CompileTimeout, buildJail)
+
+	if err != nil {
+		return nil, runErr(err)
+	}
+
+	report.CompileOutput = compiled.Stdout + compiled.Stderr
+
+	if compiled.TimedOut || compiled.ExitCode != 0 {
+		if compiled.TimedOut {
+			report.CompileOutput += "\ncompilation timed out"
+		}
+
+		return report, nil
+	}
+
+	report.Compiled = true
+
+	// Program sees only own read-only directory and writes only to temporary directory
+	runJail := &jail{
+		Root:   root,
+		Binds:  append(deviceBinds(), bind{Path: dir}),
+		Limits: executor.Limits,
+	}
+
+	for _, input := range inputs {
+		run := exec.Command(filepath.Join(dir, "program"))
+		run.Dir = dir
+		run.Env = []string{"HOME=/tmp", "TMPDIR=/tmp", "GOMAXPROCS=1"}
+
+		result, err := executor.exec(ctx, run, input, executor.Limits.Timeout, runJail)
+
+		if err != nil {
+			return nil, runErr(err)
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	return report, nil
+}
+
+// exec run command in jail with timeout and output limit
+func (executor *LocalExecutor) exec(ctx context.Context, cmd *exec.Cmd, input string,
+	timeout time.Duration, jail *jail) (*Result, error) {
+
+	started, err := isolate(cmd, jail)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := &limitedBuffer{Limit: executor.Limits.OutputBytes}
+	stderr := &limitedBuffer{Limit: executor.Limits.OutputBytes}
+
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Start()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait until jail is ready, so its setup isn't counted in run duration
+	err = started()
+
+	if err != nil {
+		kill(cmd)
+		_ = cmd.Wait()
+
+		return nil, err
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	result := &Result{}
+
+	select {
+	case err = <-done:
+	case <-timer.C:
+		result.TimedOut = true
+		kill(cmd)
+		err = <-done
+	case <-ctx.Done():
+		kill(cmd)
+		<-done
+		return nil, ctx.Err()
+	}
+
+	result.Duration = time.Since(start)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.Truncated = stdout.Truncated || stderr.Truncated
+	result.ExitCode = cmd.ProcessState.ExitCode()
+
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// bind host path, which is mounted to the same path in jail
+type bind struct {
+	Path     string // Absolute host path of directory or file
+	Writable bool   // Path is mounted for writing, otherwise read-only
+}
+
+// jail isolated file system and resource limits of command
+type jail struct {
+	Root   string // Empty host directory, which becomes root of jail
+	Binds  []bind // The only host paths, which are visible in jail
+	Limits Limits // Resource limits. Memory isn't limited if MemoryBytes is 0
+}
+
+// deviceBinds return devices, which are available in jail
+func deviceBinds() []bind {
+	return []bind{
+		{Path: "/dev/null", Writable: true},
+		{Path: "/dev/zero", Writable: true},
+		{Path: "/dev/random", Writable: true},
+		{Path: "/dev/urandom", Writable: true},
+	}
+}
+
+// findGoRoot return go root of go command in PATH
+func findGoRoot() (string, error) {
+	path, err := exec.LookPath("go")
+
+	if err != nil {
+		return "", err
+	}
+
+	path, err = filepath.EvalSymlinks(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	path, err = filepath.Abs(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Dir(filepath.Dir(path)), nil
+}
+
+// limitedBuffer buffer which drops data after limit. Limit 0 means no limit
+type limitedBuffer struct {
+	bytes.Buffer
+	Limit     int64 // Max buffer size
+	Truncated bool  // Some data was dropped
+}
+
+func (buffer *limitedBuffer) Write(data []byte) (int, error) {
+	if buffer.Limit > 0 {
+		free := buffer.Limit - int64(buffer.Len())
+
+		if int64(len(data)) > free {
+			buffer.Truncated = true
+
+			if free > 0 {
+				buffer.Buffer.Write(data[:free])
+			}
+
+			// Report full write, so program isn't stopped by broken pipe
+			return len(data), nil
+		}
+	}
+
+	return buffer.Buffer.Write(data)
+}
+
+// runErr wrap error of program execution
+func runErr(err error) error {
+	return openerrors.DefaultErr{
+		BaseErr: openerrors.BaseErr{
+			File:   "sandbox/local.go",
+			Method: "Run",
+		},
+		Msg: err.Error(),
+	}
+}
@@ -0,0 +1,179 @@
+//go:build linux && (amd64 || arm64)
+
+package sandbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// workDir work directory of executors. Build cache is shared by tests
+var workDir string
+
+func TestMain(m *testing.M) {
+	var err error
+
+	workDir, err = os.MkdirTemp("", "sandbox-test-")
+
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	_ = os.RemoveAll(workDir)
+	os.Exit(code)
+}
+
+// getExecutor return executor or skip test, if go or namespaces are not available
+func getExecutor(t *testing.T) *LocalExecutor {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go isn't installed")
+	}
+
+	limits := DefaultLimits
+	limits.Timeout = 5 * time.Second
+
+	executor := NewLocalExecutor(workDir, limits)
+
+	_, err := executor.Run(context.Background(), &Program{Language: LanguageGo, Source: "package main\n\nfunc main() {}\n"}, nil)
+
+	if err != nil && strings.Contains(err.Error(), "operation not permitted") {
+		t.Skipf("namespaces aren't available: %v", err)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return executor
+}
+
+func runProgram(t *testing.T, executor *LocalExecutor, source string, inputs ...string) *Report {
+	report, err := executor.Run(context.Background(), &Program{Language: LanguageGo, Source: source}, inputs)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Compiled {
+		t.Fatalf("program isn't compiled: %s", report.CompileOutput)
+	}
+
+	return report
+}
+
+// TestRun program reads input and its errors are returned
+func TestRun(t *testing.T) {
+	executor := getExecutor(t)
+
+	report := runProgram(t, executor, `package main
+
+import "fmt"
+
+func main() {
+	var a, b int
+	fmt.Scan(&a, &b)
+	fmt.Println(a + b)
+}
+`, "1 2", "40 2")
+
+	if len(report.Results) != 2 || report.Results[0].Stdout != "3\n" || report.Results[1].Stdout != "42\n" {
+		t.Errorf("unexpected results %+v", report.Results)
+	}
+
+	report, err := executor.Run(context.Background(), &Program{Language: LanguageGo, Source: "package main\n\nfunc main() {"}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Compiled || len(report.CompileOutput) == 0 {
+		t.Error("compile error must be returned in report")
+	}
+}
+
+// TestRunIsolation program runs as unprivileged user without host files, network and namespace syscalls
+func TestRunIsolation(t *testing.T) {
+	executor := getExecutor(t)
+
+	report := runProgram(t, executor, `package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+func main() {
+	fmt.Println("uid", os.Getuid())
+
+	if _, err := os.ReadFile("/etc/passwd"); err == nil {
+		fmt.Println("host file is readable")
+	}
+
+	if err := os.WriteFile("main.go", nil, 0600); err == nil {
+		fmt.Println("program directory is writable")
+	}
+
+	if err := os.WriteFile("/tmp/file", []byte("data"), 0600); err != nil {
+		fmt.Println("temporary directory isn't writable")
+	}
+
+	if _, err := net.Dial("tcp", "1.1.1.1:80"); err == nil {
+		fmt.Println("network is available")
+	}
+
+	if err := syscall.Mount("tmpfs", "/tmp", "tmpfs", 0, ""); err != syscall.EPERM {
+		fmt.Println("mount isn't denied:", err)
+	}
+
+	if err := syscall.Unshare(syscall.CLONE_NEWUSER); err != syscall.EPERM {
+		fmt.Println("unshare isn't denied:", err)
+	}
+
+	if err := syscall.Setuid(0); err == nil {
+		fmt.Println("root is available")
+	}
+}
+`, "")
+
+	if output := report.Results[0].Stdout; output != "uid 65534\n" {
+		t.Errorf("program isn't isolated: %s %s", output, report.Results[0].Stderr)
+	}
+}
+
+// TestRunLimits timeout and file size limit stop program
+func TestRunLimits(t *testing.T) {
+	executor := getExecutor(t)
+	executor.Limits.Timeout = time.Second
+	executor.Limits.FileBytes = 8 << 20
+
+	report := runProgram(t, executor, `package main
+
+import "os"
+
+func main() {
+	if err := os.WriteFile("/tmp/big", make([]byte, 16<<20), 0600); err == nil {
+		println("file size isn't limited")
+	}
+}
+`, "")
+
+	result := report.Results[0]
+
+	if strings.Contains(result.Stderr, "isn't limited") {
+		t.Errorf("file size isn't limited: %+v", result)
+	}
+
+	report = runProgram(t, executor, "package main\n\nfunc main() {\n\tfor {\n\t}\n}\n", "")
+
+	if result = report.Results[0]; !result.TimedOut || result.ExitCode == 0 {
+		t.Errorf("program must be killed by timeout: %+v", result)
+	}
+}
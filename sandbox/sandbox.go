@@ -0,0 +1,73 @@
+package sandbox
+
+import (
+	"context"
+	"time"
+)
+
+/*
+This file contains sandbox subsystem API. Executor compiles user program once and runs it
+for every input in a separate resource-limited process.
+*/
+
+// Supported program languages
+const (
+	LanguageGo = "go" // Go program with main package in one file
+)
+
+// Limits resource limits of program
+type Limits struct {
+	CompileTimeout time.Duration // Max duration of program compilation
+	Timeout        time.Duration // Max duration of one program run
+	MemoryBytes    int64         // Max data segment size (RLIMIT_DATA) of one program run
+	OutputBytes    int64         // Max size of stdout and stderr. Rest of output is dropped
+	FileBytes      int64         // Max size of file, which compiler or program writes (RLIMIT_FSIZE)
+	OpenFiles      uint64        // Max count of open files (RLIMIT_NOFILE)
+	Processes      uint64        // Max count of processes and threads (RLIMIT_NPROC)
+}
+
+// DefaultLimits limits for exercises of courses
+var DefaultLimits = Limits{
+	CompileTimeout: 60 * time.Second,
+	Timeout:        2 * time.Second,
+	MemoryBytes:    512 << 20,
+	OutputBytes:    64 << 10,
+	FileBytes:      64 << 20,
+	OpenFiles:      256,
+	Processes:      256,
+}
+
+// Program user program
+type Program struct {
+	Language string // Program language
+	Source   string // Program source code
+}
+
+// Result result of one program run
+type Result struct {
+	Stdout    string        // Program standard output
+	Stderr    string        // Program standard error output
+	ExitCode  int           // Program exit code. -1 if program was killed
+	Duration  time.Duration // Run duration
+	TimedOut  bool          // Program was killed by timeout
+	Truncated bool          // Output is bigger than limit and was truncated
+}
+
+// Report result of program execution
+type Report struct {
+	Compiled      bool      // Program is compiled. If false, Results are empty
+	CompileOutput string    // Compiler output
+	Results       []*Result // Result for every input in the same order
+}
+
+// Executor runs user programs
+type Executor interface {
+	/*
+		Run compile program and run it for every input. Compilation errors are returned in report,
+		error is returned only if program can't be executed. Parameters:
+		ctx - context, cancel it to stop execution;
+		program - user program;
+		inputs - standard input for every run;
+	*/
+	Run(ctx context.Context, program *Program, inputs []string) (*Report, error)
+}
@@ -0,0 +1,496 @@
+package scorm
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"opencourse/bundle"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"strings"
+)
+
+/*
+This file contains SCORM package exporter. Every stage is rendered as HTML SCO,
+stage tests are rendered as separate assessment SCO which reports score to host LMS
+through SCORM runtime API.
+*/
+
+// SCORM versions
+const (
+	Version12   = "1.2"  // SCORM 1.2
+	Version2004 = "2004" // SCORM 2004 4th edition
+)
+
+const (
+	ManifestFile = "imsmanifest.xml" // Manifest file name
+	ApiFile      = "scorm_api.js"    // SCORM runtime API wrapper file name
+	MediaFolder  = "media/"          // Folder for media files in package
+)
+
+// sco rendered shareable content object
+type sco struct {
+	Id    string   // Item and resource identifier
+	Title string   // Item title
+	Href  string   // Launch file
+	Data  []byte   // Launch file content
+	Media []string // Local media files used by launch file
+}
+
+/*
+Export write SCORM zip package for course bundle. Parameters:
+writer - output;
+courseBundle - course bundle;
+version - SCORM version: Version12 or Version2004;
+store - media store. Optional, may be nil;
+*/
+func Export(writer io.Writer, courseBundle *common.CourseBundle, version string, store bundle.MediaStore) error {
+	if courseBundle == nil || courseBundle.Course == nil {
+		return openerrors.ModelNilOrEmptyErr{
+			BaseErr: openerrors.BaseErr{
+				File:   "scorm/scorm.go",
+				Method: "Export",
+			},
+			Model: "courseBundle",
+		}
+	}
+
+	if version != Version12 && version != Version2004 {
+		return exportErr(fmt.Errorf("unsupported SCORM version %s", version))
+	}
+
+	var scos []*sco
+
+	for i, bundleStage := range courseBundle.Stages {
+		stageSco, err := renderStage(bundleStage.Stage, i+1, version)
+
+		if err != nil {
+			return exportErr(err)
+		}
+
+		scos = append(scos, stageSco)
+
+		if len(bundleStage.Tests) == 0 {
+			continue
+		}
+
+		assessmentSco, err := renderAssessment(bundleStage, i+1, version)
+
+		if err != nil {
+			return exportErr(err)
+		}
+
+		// Stage without supported tests hasn't assessment
+		if assessmentSco != nil {
+			scos = append(scos, assessmentSco)
+		}
+	}
+
+	archive := zip.NewWriter(writer)
+
+	files := map[string][]byte{ApiFile: []byte(apiScript)}
+
+	for _, item := range scos {
+		files[item.Href] = item.Data
+	}
+
+	for _, name := range append([]string{ApiFile}, hrefs(scos)...) {
+		err := writeFile(archive, name, files[name])
+
+		if err != nil {
+			return exportErr(err)
+		}
+	}
+
+	// Manifest lists only media files, which are found in store
+	copied := make(map[string]bool)
+
+	if store != nil {
+		for _, media := range stageMedia(courseBundle) {
+			found, err := copyMedia(archive, store, media)
+
+			if err != nil {
+				return exportErr(err)
+			}
+
+			copied[media] = found
+		}
+	}
+
+	manifest, err := buildManifest(courseBundle, scos, version, copied)
+
+	if err != nil {
+		return exportErr(err)
+	}
+
+	err = writeFile(archive, ManifestFile, manifest)
+
+	if err != nil {
+		return exportErr(err)
+	}
+
+	err = archive.Close()
+
+	if err != nil {
+		return exportErr(err)
+	}
+
+	return nil
+}
+
+// renderStage render stage content as HTML page
+func renderStage(stage *common.Stage, number int, version string) (*sco, error) {
+	var builder strings.Builder
+
+	data := struct {
+		Title      string
+		Version    string
+		Paragraphs []string
+		Media      []string
+	}{Title: stage.Name, Version: version}
+
+	var stageMedia []string
+
+	if stage.Content != nil {
+		data.Paragraphs = paragraphs(stage.Content.Body)
+
+		for _, media := range stage.Content.MediaItems {
+			if clean, ok := bundle.CleanMediaPath(media); ok {
+				data.Media = append(data.Media, "../"+MediaFolder+clean)
+				stageMedia = append(stageMedia, clean)
+			} else {
+				data.Media = append(data.Media, media)
+			}
+		}
+	}
+
+	err := stageTemplate.Execute(&builder, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &sco{
+		Id:    fmt.Sprintf("stage_%02d", number),
+		Title: stage.Name,
+		Href:  fmt.Sprintf("stages/stage_%02d.html", number),
+		Data:  []byte(builder.String()),
+		Media: stageMedia,
+	}, nil
+}
+
+// assessmentQuestion question model for assessment page script
+type assessmentQuestion struct {
+	Id         string   `json:"id"`          // Interaction id
+	Type       string   `json:"type"`        // Test type
+	Question   string   `json:"question"`    // Question text
+	Options    []string `json:"options"`     // Option answers for option and multi-select tests
+	Right      []int    `json:"right"`       // Right option indexes for option and multi-select tests
+	Answers    []string `json:"answers"`     // Right and accepted answers for rewrite test
+	IgnoreCase bool     `json:"ignore_case"` // Compare answers of rewrite test without case
+	Weight     int      `json:"weight"`      // Question weight, lemmings count
+}
+
+// renderAssessment render stage tests as HTML page which reports result to LMS. Returns nil, if stage hasn't
+// tests supported by SCORM package
+func renderAssessment(bundleStage *common.BundleStage, number int, version string) (*sco, error) {
+	var questions []*assessmentQuestion
+
+	for i, test := range bundleStage.Tests {
+		question := &assessmentQuestion{
+			Id:     fmt.Sprintf("q%02d", i+1),
+			Type:   test.TestType,
+			Weight: test.LemmingsCount,
+		}
+
+		switch {
+		case test.OptionTest != nil:
+			question.Question = test.OptionTest.Question
+
+			for j, option := range test.OptionTest.Options {
+				question.Options = append(question.Options, option.Answer)
+
+				if option.IsRight {
+					question.Right = append(question.Right, j)
+				}
+			}
+		case test.MultiSelectTest != nil:
+			question.Question = test.MultiSelectTest.Question
+
+			for j, option := range test.MultiSelectTest.Options {
+				question.Options = append(question.Options, option.Answer)
+
+				if option.IsRight {
+					question.Right = append(question.Right, j)
+				}
+			}
+		case test.RewriteTest != nil:
+			question.Question = test.RewriteTest.Question
+			question.Answers = []string{test.RewriteTest.RightAnswer}
+
+			// Normalization, patterns and fuzzy matching are not exported, only accepted answers
+			if matching := test.RewriteTest.Matching; matching != nil {
+				question.Answers = append(question.Answers, matching.AcceptedAnswers...)
+				question.IgnoreCase = matching.IgnoreCase
+			}
+		default:
+			// Test types without offline grading are not supported by SCORM package
+			continue
+		}
+
+		questions = append(questions, question)
+	}
+
+	if len(questions) == 0 {
+		return nil, nil
+	}
+
+	questionsJson, err := json.Marshal(questions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var builder strings.Builder
+
+	err = assessmentTemplate.Execute(&builder, struct {
+		Title        string
+		Version      string
+		Questions    template.JS
+		PassingScore float64
+	}{
+		Title:        bundleStage.Stage.Name,
+		Version:      version,
+		Questions:    template.JS(questionsJson),
+		PassingScore: PassingScore,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &sco{
+		Id:    fmt.Sprintf("assessment_%02d", number),
+		Title: fmt.Sprintf("%s: test", bundleStage.Stage.Name),
+		Href:  fmt.Sprintf("assessments/assessment_%02d.html", number),
+		Data:  []byte(builder.String()),
+	}, nil
+}
+
+// manifest xml models
+
+type manifestItem struct {
+	Identifier    string `xml:"identifier,attr"`
+	IdentifierRef string `xml:"identifierref,attr"`
+	Title         string `xml:"title"`
+}
+
+type manifestFile struct {
+	Href string `xml:"href,attr"`
+}
+
+type manifestResource struct {
+	Identifier string         `xml:"identifier,attr"`
+	Type       string         `xml:"type,attr"`
+	ScormType  string         `xml:"adlcp:scormtype,attr,omitempty"`
+	ScormType4 string         `xml:"adlcp:scormType,attr,omitempty"`
+	Href       string         `xml:"href,attr"`
+	Files      []manifestFile `xml:"file"`
+}
+
+type manifest struct {
+	XMLName     xml.Name `xml:"manifest"`
+	Identifier  string   `xml:"identifier,attr"`
+	Version     string   `xml:"version,attr"`
+	Xmlns       string   `xml:"xmlns,attr"`
+	XmlnsAdlcp  string   `xml:"xmlns:adlcp,attr"`
+	XmlnsAdlseq string   `xml:"xmlns:adlseq,attr,omitempty"`
+	XmlnsImsss  string   `xml:"xmlns:imsss,attr,omitempty"`
+	Metadata    struct {
+		Schema        string `xml:"schema"`
+		SchemaVersion string `xml:"schemaversion"`
+	} `xml:"metadata"`
+	Organizations struct {
+		Default      string `xml:"default,attr"`
+		Organization struct {
+			Identifier string          `xml:"identifier,attr"`
+			Title      string          `xml:"title"`
+			Items      []*manifestItem `xml:"item"`
+		} `xml:"organization"`
+	} `xml:"organizations"`
+	Resources struct {
+		Resources []*manifestResource `xml:"resource"`
+	} `xml:"resources"`
+}
+
+/*
+buildManifest create imsmanifest.xml for SCORM version. Parameters:
+courseBundle - course bundle;
+scos - rendered SCOs;
+version - SCORM version;
+copied - media files, which are found in store and copied to package;
+*/
+func buildManifest(courseBundle *common.CourseBundle, scos []*sco, version string, copied map[string]bool) ([]byte, error) {
+	var doc manifest
+
+	doc.Identifier = fmt.Sprintf("opencourse_%s", courseBundle.Course.Id)
+	doc.Version = "1"
+	doc.Metadata.Schema = "ADL SCORM"
+	doc.Organizations.Default = "opencourse_org"
+	doc.Organizations.Organization.Identifier = "opencourse_org"
+	doc.Organizations.Organization.Title = courseBundle.Course.Name
+
+	if version == Version12 {
+		doc.Xmlns = "http://www.imsproject.org/xsd/imscp_rootv1p1p2"
+		doc.XmlnsAdlcp = "http://www.adlnet.org/xsd/adlcp_rootv1p2"
+		doc.Metadata.SchemaVersion = "1.2"
+	} else {
+		doc.Xmlns = "http://www.imsglobal.org/xsd/imscp_v1p1"
+		doc.XmlnsAdlcp = "http://www.adlnet.org/xsd/adlcp_v1p3"
+		doc.XmlnsAdlseq = "http://www.adlnet.org/xsd/adlseq_v1p3"
+		doc.XmlnsImsss = "http://www.imsglobal.org/xsd/imsss"
+		doc.Metadata.SchemaVersion = "2004 4th Edition"
+	}
+
+	for _, item := range scos {
+		doc.Organizations.Organization.Items = append(doc.Organizations.Organization.Items, &manifestItem{
+			Identifier:    "item_" + item.Id,
+			IdentifierRef: "res_" + item.Id,
+			Title:         item.Title,
+		})
+
+		resource := &manifestResource{
+			Identifier: "res_" + item.Id,
+			Type:       "webcontent",
+			Href:       item.Href,
+			Files:      []manifestFile{{Href: item.Href}, {Href: ApiFile}},
+		}
+
+		for _, media := range item.Media {
+			if copied[media] {
+				resource.Files = append(resource.Files, manifestFile{Href: MediaFolder + media})
+			}
+		}
+
+		if version == Version12 {
+			resource.ScormType = "sco"
+		} else {
+			resource.ScormType4 = "sco"
+		}
+
+		doc.Resources.Resources = append(doc.Resources.Resources, resource)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+// paragraphs split stage body to paragraphs by empty lines
+func paragraphs(body string) []string {
+	var result []string
+
+	for _, paragraph := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+
+		if len(paragraph) > 0 {
+			result = append(result, paragraph)
+		}
+	}
+
+	return result
+}
+
+func hrefs(scos []*sco) []string {
+	var result []string
+
+	for _, item := range scos {
+		result = append(result, item.Href)
+	}
+
+	return result
+}
+
+// stageMedia return local media files of stages
+func stageMedia(courseBundle *common.CourseBundle) []string {
+	var result []string
+	unique := make(map[string]bool)
+
+	for _, bundleStage := range courseBundle.Stages {
+		if bundleStage.Stage.Content == nil {
+			continue
+		}
+
+		for _, media := range bundleStage.Stage.Content.MediaItems {
+			clean, ok := bundle.CleanMediaPath(media)
+
+			if ok && !unique[clean] {
+				unique[clean] = true
+				result = append(result, clean)
+			}
+		}
+	}
+
+	return result
+}
+
+// copyMedia copy media file from store to package. Missing files are skipped, then false is returned
+func copyMedia(archive *zip.Writer, store bundle.MediaStore, media string) (bool, error) {
+	reader, err := store.Open(media)
+
+	if errors.Is(err, bundle.ErrMediaNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	file, err := archive.Create(MediaFolder + media)
+
+	if err != nil {
+		return false, err
+	}
+
+	_, err = io.Copy(file, reader)
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func writeFile(archive *zip.Writer, name string, data []byte) error {
+	file, err := archive.Create(name)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+
+	return err
+}
+
+func exportErr(err error) error {
+	return openerrors.DefaultErr{
+		BaseErr: openerrors.BaseErr{
+			File:   "scorm/scorm.go",
+			Method: "Export",
+		},
+		Msg: err.Error(),
+	}
+}
@@ -0,0 +1,154 @@
+package scorm
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"opencourse/bundle"
+	"opencourse/common"
+	"strings"
+	"testing"
+)
+
+// memoryStore media store in memory
+type memoryStore map[string]string
+
+func (store memoryStore) Open(name string) (io.ReadCloser, error) {
+	content, ok := store[name]
+
+	if !ok {
+		return nil, bundle.ErrMediaNotFound
+	}
+
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+func (store memoryStore) Save(name string, reader io.Reader) error {
+	content, err := io.ReadAll(reader)
+	store[name] = string(content)
+
+	return err
+}
+
+// failStore media store which can't read files
+type failStore struct{}
+
+func (failStore) Open(string) (io.ReadCloser, error) {
+	return nil, errors.New("disk error")
+}
+
+func (failStore) Save(string, io.Reader) error {
+	return errors.New("disk error")
+}
+
+func getScormBundle() *common.CourseBundle {
+	return &common.CourseBundle{
+		Version: common.BundleVersion,
+		Id:      "golang",
+		Course:  &common.Course{Id: "golang", Name: "The greatest golang"},
+		Stages: []*common.BundleStage{
+			{
+				Stage: &common.Stage{Id: "intro", Name: "Intro", Content: &common.PostContent{
+					Body:       "Hello",
+					MediaItems: []string{"img/gopher.png", "img/missing.png", "https://example.com/remote.png"},
+				}},
+				Tests: []*common.Test{{
+					Id:       "option",
+					TestType: common.TestOption,
+					OptionTest: &common.OptionTest{
+						Question: "What is goroutine?",
+						Options:  []*common.Option{{Answer: "Lightweight thread", IsRight: true}, {Answer: "Package"}},
+					},
+				}},
+			},
+			{
+				// Ordering test hasn't offline grading
+				Stage: &common.Stage{Id: "order", Name: "Order", Content: &common.PostContent{Body: "Order"}},
+				Tests: []*common.Test{{
+					Id:           "ordering",
+					TestType:     common.TestOrdering,
+					OrderingTest: &common.OrderingTest{Question: "Order", Items: []string{"a", "b"}},
+				}},
+			},
+		},
+	}
+}
+
+func readPackage(t *testing.T, data []byte) map[string]string {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(reader)
+		_ = reader.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files[file.Name] = string(content)
+	}
+
+	return files
+}
+
+// TestExport
+func TestExport(t *testing.T) {
+	for _, version := range []string{Version12, Version2004} {
+		var buffer bytes.Buffer
+
+		err := Export(&buffer, getScormBundle(), version, memoryStore{"img/gopher.png": "png"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files := readPackage(t, buffer.Bytes())
+		manifest := files[ManifestFile]
+
+		for _, name := range []string{ApiFile, "stages/stage_01.html", "stages/stage_02.html",
+			"assessments/assessment_01.html", MediaFolder + "img/gopher.png"} {
+
+			if _, ok := files[name]; !ok {
+				t.Errorf("version %s: package hasn't file %s", version, name)
+			}
+
+			if !strings.Contains(manifest, `href="`+name+`"`) {
+				t.Errorf("version %s: manifest hasn't file %s", version, name)
+			}
+		}
+
+		// Stage without supported tests hasn't assessment
+		if _, ok := files["assessments/assessment_02.html"]; ok || strings.Contains(manifest, "assessment_02") {
+			t.Errorf("version %s: assessment without questions is exported", version)
+		}
+
+		if strings.Contains(manifest, "missing.png") || strings.Contains(manifest, "remote.png") {
+			t.Errorf("version %s: manifest lists media, which isn't in package", version)
+		}
+
+		if !strings.Contains(files["assessments/assessment_01.html"], "What is goroutine?") {
+			t.Errorf("version %s: assessment hasn't question", version)
+		}
+	}
+}
+
+// TestExportMediaErr store error fails export
+func TestExportMediaErr(t *testing.T) {
+	var buffer bytes.Buffer
+
+	err := Export(&buffer, getScormBundle(), Version2004, failStore{})
+
+	if err == nil {
+		t.Error("store error must fail export")
+	}
+}
@@ -0,0 +1,171 @@
+package scorm
+
+import "html/template"
+
+// PassingScore scaled score from which assessment is passed
+const PassingScore = 0.8
+
+// apiScript wrapper for SCORM 1.2 (API) and SCORM 2004 (API_1484_11) runtime API of host LMS
+const apiScript = `var OpenScorm = (function () {
+  var api = null;
+  var version = null;
+
+  function find(win) {
+    for (var i = 0; win && i < 10; i++) {
+      if (version === "2004" && win.API_1484_11) return win.API_1484_11;
+      if (version === "1.2" && win.API) return win.API;
+      if (win.parent === win) break;
+      win = win.parent;
+    }
+    return null;
+  }
+
+  function call(name12, name2004, args) {
+    if (!api) return "false";
+    var fn = version === "2004" ? api[name2004] : api[name12];
+    return fn ? fn.apply(api, args) : "false";
+  }
+
+  return {
+    init: function (v) {
+      version = v;
+      api = find(window) || (window.opener ? find(window.opener) : null);
+      return call("LMSInitialize", "Initialize", [""]);
+    },
+    set: function (key, value) {
+      return call("LMSSetValue", "SetValue", [key, String(value)]);
+    },
+    commit: function () {
+      return call("LMSCommit", "Commit", [""]);
+    },
+    finish: function () {
+      return call("LMSFinish", "Terminate", [""]);
+    },
+    complete: function () {
+      if (version === "2004") {
+        this.set("cmi.completion_status", "completed");
+      } else {
+        this.set("cmi.core.lesson_status", "completed");
+      }
+      this.commit();
+    }
+  };
+})();
+`
+
+var stageTemplate = template.Must(template.New("stage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <script src="../scorm_api.js"></script>
+</head>
+<body onload="OpenScorm.init('{{.Version}}'); OpenScorm.complete();" onunload="OpenScorm.finish();">
+  <h1>{{.Title}}</h1>
+  {{range .Paragraphs}}<p style="white-space: pre-wrap">{{.}}</p>
+  {{end}}
+  {{range .Media}}<p><a href="{{.}}">{{.}}</a></p>
+  {{end}}
+</body>
+</html>
+`))
+
+var assessmentTemplate = template.Must(template.New("assessment").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <script src="../scorm_api.js"></script>
+</head>
+<body onload="OpenScorm.init('{{.Version}}'); render();" onunload="OpenScorm.finish();">
+  <h1>{{.Title}}</h1>
+  <form id="quiz" onsubmit="submitQuiz(); return false;"></form>
+  <p id="result"></p>
+  <script>
+    var version = "{{.Version}}";
+    var questions = {{.Questions}};
+    var passingScore = {{.PassingScore}};
+
+    function render() {
+      var form = document.getElementById("quiz");
+      questions.forEach(function (q, i) {
+        var block = document.createElement("fieldset");
+        var legend = document.createElement("legend");
+        legend.textContent = q.question;
+        block.appendChild(legend);
+        if (q.type === "option" || q.type === "multi_select") {
+          var multiple = q.type === "multi_select" || q.right.length > 1;
+          q.options.forEach(function (option, j) {
+            var label = document.createElement("label");
+            var input = document.createElement("input");
+            input.type = multiple ? "checkbox" : "radio";
+            input.name = q.id;
+            input.value = j;
+            label.appendChild(input);
+            label.appendChild(document.createTextNode(" " + option));
+            block.appendChild(label);
+            block.appendChild(document.createElement("br"));
+          });
+        } else {
+          var text = document.createElement("input");
+          text.type = "text";
+          text.name = q.id;
+          block.appendChild(text);
+        }
+        form.appendChild(block);
+      });
+      var button = document.createElement("button");
+      button.type = "submit";
+      button.textContent = "Submit";
+      form.appendChild(button);
+    }
+
+    function submitQuiz() {
+      var form = document.getElementById("quiz");
+      var score = 0, max = 0;
+      questions.forEach(function (q, i) {
+        var response, correct;
+        if (q.type === "option" || q.type === "multi_select") {
+          var checked = [];
+          form.querySelectorAll("input[name='" + q.id + "']:checked").forEach(function (input) {
+            checked.push(parseInt(input.value, 10));
+          });
+          response = checked.join(",");
+          correct = checked.length === q.right.length && checked.every(function (c) { return q.right.indexOf(c) >= 0; });
+        } else {
+          response = form.querySelector("input[name='" + q.id + "']").value;
+          var given = q.ignore_case ? response.trim().toLowerCase() : response.trim();
+          correct = q.answers.some(function (answer) {
+            return given === (q.ignore_case ? answer.trim().toLowerCase() : answer.trim());
+          });
+        }
+        max += q.weight;
+        if (correct) score += q.weight;
+        var prefix = "cmi.interactions." + i + ".";
+        OpenScorm.set(prefix + "id", q.id);
+        OpenScorm.set(prefix + "type", q.type === "option" || q.type === "multi_select" ? "choice" : "fill-in");
+        OpenScorm.set(prefix + (version === "2004" ? "learner_response" : "student_response"), response);
+        OpenScorm.set(prefix + "result", correct ? "correct" : (version === "2004" ? "incorrect" : "wrong"));
+      });
+      var scaled = max > 0 ? score / max : 1;
+      var passed = scaled >= passingScore;
+      if (version === "2004") {
+        OpenScorm.set("cmi.score.raw", score);
+        OpenScorm.set("cmi.score.min", 0);
+        OpenScorm.set("cmi.score.max", max);
+        OpenScorm.set("cmi.score.scaled", scaled.toFixed(2));
+        OpenScorm.set("cmi.success_status", passed ? "passed" : "failed");
+        OpenScorm.set("cmi.completion_status", "completed");
+      } else {
+        OpenScorm.set("cmi.core.score.raw", Math.round(scaled * 100));
+        OpenScorm.set("cmi.core.score.min", 0);
+        OpenScorm.set("cmi.core.score.max", 100);
+        OpenScorm.set("cmi.core.lesson_status", passed ? "passed" : "failed");
+      }
+      OpenScorm.commit();
+      document.getElementById("result").textContent = (passed ? "Passed: " : "Failed: ") + score + " / " + max;
+    }
+  </script>
+</body>
+</html>
+`))
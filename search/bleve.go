@@ -0,0 +1,353 @@
+package search
+
+import (
+	"errors"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/de"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/en"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/fr"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/it"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/ru"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/highlight/highlighter/html"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"opencourse/common"
+	"opencourse/database"
+	"opencourse/events"
+	"strings"
+)
+
+/*
+This file contains embedded Bleve index of courses and stages. Every language has own fields with analyzer
+of language, so words are stemmed by language of course. Index is updated by content events of database
+and can be rebuilt by Reindex.
+*/
+
+// Kinds of indexed documents
+const (
+	kindCourse = "course"
+	kindStage  = "stage"
+)
+
+// stageWeight weight of stage score in course relevance
+const stageWeight = 0.5
+
+// languages languages, which have analyzers. Empty language is indexed by standard analyzer
+var languages = []string{common.LangEn, common.LangFr, common.LangDe, common.LangIt, common.LangRu, ""}
+
+// BleveIndex embedded index of course and stage content
+type BleveIndex struct {
+	db    *database.DbContext
+	index bleve.Index
+	onErr func(error)
+}
+
+/*
+OpenBleveIndex open index in directory or create it. Returns true, if index is created and has to be reindexed.
+Parameters:
+db - database context;
+path - index directory;
+onErr - handler of indexing errors, which happen in event handlers;
+*/
+func OpenBleveIndex(db *database.DbContext, path string, onErr func(error)) (*BleveIndex, bool, error) {
+	index, err := bleve.Open(path)
+	created := false
+
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = bleve.New(path, indexMapping())
+		created = true
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &BleveIndex{db: db, index: index, onErr: onErr}, created, nil
+}
+
+// Close close index
+func (index *BleveIndex) Close() error {
+	return index.index.Close()
+}
+
+/*
+Subscribe subscribe index to content events of bus. Parameters:
+bus - events bus;
+*/
+func (index *BleveIndex) Subscribe(bus *events.Bus) {
+	for _, eventType := range []string{events.CourseSaved, events.StageSaved, events.StageDeleted} {
+		bus.Subscribe(eventType, index.Handle)
+	}
+}
+
+/*
+Handle update documents of event. Errors are passed to error handler, because content is saved. Parameters:
+event - content event;
+*/
+func (index *BleveIndex) Handle(event events.Event) error {
+	var err error
+
+	switch event.Type {
+	case events.CourseSaved:
+		err = index.indexCourse(event.CourseId)
+	case events.StageSaved:
+		err = index.indexStage(event.CourseId, event.StageId)
+	case events.StageDeleted:
+		err = index.index.Delete(kindStage + "/" + event.StageId)
+	}
+
+	if err != nil && index.onErr != nil {
+		index.onErr(err)
+	}
+
+	return nil
+}
+
+// Reindex index every course with stages
+func (index *BleveIndex) Reindex() error {
+	courseIds, err := index.db.GetCourseIds()
+
+	if err != nil {
+		return err
+	}
+
+	for _, courseId := range courseIds {
+		err = index.indexCourse(courseId)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Search return page of found courses with highlighted fragments of stages. Parameters:
+searchQuery - search query;
+*/
+func (index *BleveIndex) Search(searchQuery *common.SearchQuery) (*common.SearchResult, error) {
+	if len(searchQuery.Text) == 0 {
+		return index.db.SearchCourses(searchQuery, nil)
+	}
+
+	langs := languages
+
+	if len(searchQuery.Lang) > 0 {
+		langs = []string{searchQuery.Lang}
+	}
+
+	var matches []query.Query
+
+	for _, lang := range langs {
+		name := bleve.NewMatchQuery(searchQuery.Text)
+		name.SetField(field("name", lang))
+		name.SetBoost(2)
+
+		body := bleve.NewMatchQuery(searchQuery.Text)
+		body.SetField(field("body", lang))
+
+		matches = append(matches, name, body)
+	}
+
+	request := bleve.NewSearchRequestOptions(bleve.NewDisjunctionQuery(matches...), maxMatches, 0, false)
+	request.Fields = []string{"kind", "course_id"}
+	request.Highlight = bleve.NewHighlightWithStyle(html.Name)
+
+	found, err := index.index.Search(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64, len(found.Hits))
+	stageScores := make(map[string]float64)
+	highlights := make(map[string][]*common.Highlight)
+
+	for _, hit := range found.Hits {
+		courseId, _ := hit.Fields["course_id"].(string)
+
+		if hit.Fields["kind"] == kindCourse {
+			scores[courseId] += hit.Score
+			continue
+		}
+
+		// Course relevance is increased by the best matched stage
+		if hit.Score > stageScores[courseId] {
+			stageScores[courseId] = hit.Score
+		}
+
+		highlight := &common.Highlight{CourseId: courseId, StageId: strings.TrimPrefix(hit.ID, kindStage+"/")}
+
+		for _, lang := range langs {
+			highlight.Fragments = append(highlight.Fragments, hit.Fragments[field("body", lang)]...)
+		}
+
+		if len(highlight.Fragments) > 0 {
+			highlights[courseId] = append(highlights[courseId], highlight)
+		}
+	}
+
+	for courseId, score := range stageScores {
+		scores[courseId] += score * stageWeight
+	}
+
+	result, err := index.db.SearchCourses(searchQuery, scores)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, course := range result.Courses {
+		result.Highlights = append(result.Highlights, highlights[course.Id]...)
+	}
+
+	return result, nil
+}
+
+/*
+indexCourse index course and its stages. Parameters:
+courseId - course id;
+*/
+func (index *BleveIndex) indexCourse(courseId string) error {
+	course, err := index.db.GetCourse(courseId)
+
+	if err != nil || course == nil {
+		return err
+	}
+
+	batch := index.index.NewBatch()
+
+	err = batch.Index(kindCourse+"/"+course.Id, document(kindCourse, course.Id, course.Lang, course.Name,
+		course.Description+"\n"+strings.Join(course.Tags, " ")))
+
+	if err != nil {
+		return err
+	}
+
+	stages, err := index.db.GetStages(courseId, 0, 0)
+
+	if err != nil {
+		return err
+	}
+
+	for _, preview := range stages {
+		stage, err := index.db.GetStage(preview.Id)
+
+		if err != nil {
+			return err
+		}
+
+		if stage == nil {
+			continue
+		}
+
+		err = batch.Index(kindStage+"/"+stage.Id, stageDocument(course, stage))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return index.index.Batch(batch)
+}
+
+/*
+indexStage index stage. Parameters:
+courseId - course id;
+stageId - stage id;
+*/
+func (index *BleveIndex) indexStage(courseId string, stageId string) error {
+	course, err := index.db.GetCourse(courseId)
+
+	if err != nil || course == nil {
+		return err
+	}
+
+	stage, err := index.db.GetStage(stageId)
+
+	if err != nil || stage == nil {
+		return err
+	}
+
+	return index.index.Index(kindStage+"/"+stage.Id, stageDocument(course, stage))
+}
+
+// stageDocument return document of stage, which is analyzed by language of course
+func stageDocument(course *common.Course, stage *common.Stage) map[string]interface{} {
+	body := ""
+
+	if stage.Content != nil {
+		body = stage.Content.Body
+	}
+
+	return document(kindStage, course.Id, course.Lang, stage.Name, body)
+}
+
+// document return indexed document. Name and body are put to fields of language
+func document(kind string, courseId string, lang string, name string, body string) map[string]interface{} {
+	if !supported(lang) {
+		lang = ""
+	}
+
+	return map[string]interface{}{
+		"kind":              kind,
+		"course_id":         courseId,
+		field("name", lang): name,
+		field("body", lang): body,
+	}
+}
+
+// field return field name of language
+func field(name string, lang string) string {
+	if len(lang) == 0 {
+		return name
+	}
+
+	return name + "_" + lang
+}
+
+// supported check that language has analyzer
+func supported(lang string) bool {
+	for _, language := range languages {
+		if language == lang {
+			return true
+		}
+	}
+
+	return false
+}
+
+// indexMapping return mapping with text fields for every language
+func indexMapping() mapping.IndexMapping {
+	documentMapping := bleve.NewDocumentStaticMapping()
+
+	for _, name := range []string{"kind", "course_id"} {
+		keyword := bleve.NewKeywordFieldMapping()
+		keyword.Store = true
+		documentMapping.AddFieldMappingsAt(name, keyword)
+	}
+
+	for _, lang := range languages {
+		analyzer := lang
+
+		if len(analyzer) == 0 {
+			analyzer = standard.Name
+		}
+
+		for _, name := range []string{"name", "body"} {
+			text := bleve.NewTextFieldMapping()
+			text.Analyzer = analyzer
+			text.Store = true
+			text.IncludeTermVectors = true
+			documentMapping.AddFieldMappingsAt(field(name, lang), text)
+		}
+	}
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = documentMapping
+	indexMapping.DefaultAnalyzer = standard.Name
+
+	return indexMapping
+}
@@ -0,0 +1,50 @@
+package search
+
+import (
+	"opencourse/common"
+	"opencourse/database"
+)
+
+/*
+This file contains course search. Index matches text and returns relevance of courses, filters and facets
+are applied by database for every index, so indexes differ only in text matching.
+*/
+
+// maxMatches max count of courses and stages matched by text
+const maxMatches = 1000
+
+// Index full-text index of courses
+type Index interface {
+	Search(query *common.SearchQuery) (*common.SearchResult, error) // Search return page of found courses
+}
+
+// MongoIndex index over Mongo text indexes of courses and stages
+type MongoIndex struct {
+	db *database.DbContext
+}
+
+/*
+NewMongoIndex return index over Mongo text indexes. Parameters:
+db - database context;
+*/
+func NewMongoIndex(db *database.DbContext) *MongoIndex {
+	return &MongoIndex{db: db}
+}
+
+/*
+Search return page of found courses. Parameters:
+query - search query;
+*/
+func (index *MongoIndex) Search(query *common.SearchQuery) (*common.SearchResult, error) {
+	if len(query.Text) == 0 {
+		return index.db.SearchCourses(query, nil)
+	}
+
+	scores, err := index.db.MatchCourseText(query.Text, maxMatches)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return index.db.SearchCourses(query, scores)
+}
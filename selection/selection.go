@@ -0,0 +1,295 @@
+package selection
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"hash/fnv"
+	mathrand "math/rand"
+	"opencourse/common"
+	"opencourse/database"
+	"strconv"
+)
+
+/*
+This file contains question pools. Learner gets tests without pool and DrawCount random tests of every stage pool.
+Drawn tests are stored as selection: one for stage without time limit and one for every quiz session.
+Tests without pool, which are added to stage later, are added to stored selection.
+Options are shuffled with attempt seed, which is derived from selection seed, test id and attempt number,
+so the order shown for the next attempt is known on answer. Question and options order are saved with attempt.
+*/
+
+/*
+Select return stored selection of user or draw tests and store new selection, if user hasn't one.
+Returns selection and its tests with right answers. Parameters:
+db - database context;
+userId - user id;
+stage - stage with question pools;
+sessionId - quiz session id. Empty for stage without time limit;
+*/
+func Select(db *database.DbContext, userId string, stage *common.Stage, sessionId string) (*common.TestSelection, []*common.Test, error) {
+	tests, err := db.GetStageTests(stage.Id)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selection, err := db.FindTestSelection(userId, stage.Id, sessionId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Selection is drawn once, the next requests only read it
+	if selection == nil {
+		seed := NewSeed()
+		drawn := &common.TestSelection{UserId: userId, StageId: stage.Id, SessionId: sessionId, Seed: seed}
+
+		for _, test := range Draw(tests, stage.Pools, seed) {
+			drawn.TestIds = append(drawn.TestIds, test.Id)
+		}
+
+		// Selection stored by concurrent request is returned
+		selection, err = db.SaveTestSelection(drawn)
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Tests without pool added to stage after selection are given to learner too
+	added := missingTests(tests, stage.Pools, selection)
+
+	if len(added) > 0 {
+		err = db.AddSelectionTests(selection.Id, added)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		selection.TestIds = append(selection.TestIds, added...)
+	}
+
+	return selection, Filter(tests, selection), nil
+}
+
+/*
+Previews return selected tests without right answers. Options are shuffled for the next attempt of user. Parameters:
+db - database context;
+selection - stored selection;
+*/
+func Previews(db *database.DbContext, selection *common.TestSelection) ([]*common.TestPreview, error) {
+	previews, err := db.GetTestPreviews(selection.TestIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := db.GetAttemptCounts(selection.UserId, selection.StageId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, preview := range previews {
+		preview.Options = Shuffle(preview.Options, AttemptSeed(selection.Seed, preview.Id, counts[preview.Id]+1))
+	}
+
+	return previews, nil
+}
+
+/*
+Draw return tests without pool and DrawCount random tests of every pool. Tests of pool, which isn't set
+for stage, are given to every learner. Tests keep their order. Parameters:
+tests - stage tests ordered by order number;
+pools - stage question pools;
+seed - random seed;
+*/
+func Draw(tests []*common.Test, pools []*common.QuestionPool, seed int64) []*common.Test {
+	drawCounts := make(map[string]int, len(pools))
+
+	for _, pool := range pools {
+		drawCounts[pool.Name] = pool.DrawCount
+	}
+
+	poolTests := make(map[string][]int)
+
+	for i, test := range tests {
+		if _, ok := drawCounts[test.Pool]; ok {
+			poolTests[test.Pool] = append(poolTests[test.Pool], i)
+		}
+	}
+
+	skipped := make(map[int]bool)
+
+	for name, indexes := range poolTests {
+		drawCount := drawCounts[name]
+
+		if drawCount == 0 || drawCount >= len(indexes) {
+			continue
+		}
+
+		random := mathrand.New(mathrand.NewSource(seed ^ hashString(name)))
+
+		for _, i := range random.Perm(len(indexes))[drawCount:] {
+			skipped[indexes[i]] = true
+		}
+	}
+
+	var drawn []*common.Test
+
+	for i, test := range tests {
+		if !skipped[i] {
+			drawn = append(drawn, test)
+		}
+	}
+
+	return drawn
+}
+
+// Filter return tests of selection in selection order
+func Filter(tests []*common.Test, selection *common.TestSelection) []*common.Test {
+	byId := make(map[string]*common.Test, len(tests))
+
+	for _, test := range tests {
+		byId[test.Id] = test
+	}
+
+	var selected []*common.Test
+
+	for _, testId := range selection.TestIds {
+		if test, ok := byId[testId]; ok {
+			selected = append(selected, test)
+		}
+	}
+
+	return selected
+}
+
+// Contains check that test is drawn in selection
+func Contains(selection *common.TestSelection, testId string) bool {
+	for _, id := range selection.TestIds {
+		if id == testId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// missingTests return ids of tests without pool, which are not in selection
+func missingTests(tests []*common.Test, pools []*common.QuestionPool, selection *common.TestSelection) []string {
+	pooled := make(map[string]bool, len(pools))
+
+	for _, pool := range pools {
+		pooled[pool.Name] = true
+	}
+
+	var missing []string
+
+	for _, test := range tests {
+		if !pooled[test.Pool] && !Contains(selection, test.Id) {
+			missing = append(missing, test.Id)
+		}
+	}
+
+	return missing
+}
+
+// AttemptSeed return seed of options order for test attempt
+func AttemptSeed(seed int64, testId string, attempt int) int64 {
+	return seed ^ hashString(testId+"/"+strconv.Itoa(attempt))
+}
+
+// Shuffle return shuffled copy of values
+func Shuffle(values []string, seed int64) []string {
+	if len(values) == 0 {
+		return values
+	}
+
+	shuffled := make([]string, len(values))
+	copy(shuffled, values)
+
+	random := mathrand.New(mathrand.NewSource(seed))
+	random.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	return shuffled
+}
+
+/*
+Snapshot set question and options order shown to learner for attempt in result, so they are saved with attempt
+and changes of test don't change review. Parameters:
+result - grade result of answer;
+test - answered test;
+selection - selection of test;
+attempt - attempt number;
+*/
+func Snapshot(result *common.GradeResult, test *common.Test, selection *common.TestSelection, attempt int) {
+	result.Question = Question(test)
+	result.Options = Shuffle(OptionAnswers(test), AttemptSeed(selection.Seed, test.Id, attempt))
+}
+
+// Question return question of test. For cloze test it's a text with blanks
+func Question(test *common.Test) string {
+	switch {
+	case test.OptionTest != nil:
+		return test.OptionTest.Question
+	case test.RewriteTest != nil:
+		return test.RewriteTest.Question
+	case test.MultiSelectTest != nil:
+		return test.MultiSelectTest.Question
+	case test.OrderingTest != nil:
+		return test.OrderingTest.Question
+	case test.MatchingTest != nil:
+		return test.MatchingTest.Question
+	case test.ClozeTest != nil:
+		return test.ClozeTest.Text
+	case test.NumericTest != nil:
+		return test.NumericTest.Question
+	case test.CodeTest != nil:
+		return test.CodeTest.Question
+	}
+
+	return ""
+}
+
+// OptionAnswers return answers of option and multi-select tests in stored order
+func OptionAnswers(test *common.Test) []string {
+	var options []*common.Option
+
+	switch {
+	case test.OptionTest != nil:
+		options = test.OptionTest.Options
+	case test.MultiSelectTest != nil:
+		options = test.MultiSelectTest.Options
+	}
+
+	answers := make([]string, 0, len(options))
+
+	for _, option := range options {
+		answers = append(answers, option.Answer)
+	}
+
+	return answers
+}
+
+// NewSeed return random seed
+func NewSeed() int64 {
+	var buffer [8]byte
+
+	_, err := rand.Read(buffer[:])
+
+	// Crypto source isn't available, seed still differs for learners
+	if err != nil {
+		return mathrand.Int63()
+	}
+
+	return int64(binary.LittleEndian.Uint64(buffer[:]))
+}
+
+// hashString return FNV-1a hash of text
+func hashString(text string) int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(text))
+
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,84 @@
+package selection
+
+import (
+	"opencourse/common"
+	"reflect"
+	"testing"
+)
+
+func poolTests() []*common.Test {
+	return []*common.Test{
+		{Id: "a"},
+		{Id: "b", Pool: "easy"},
+		{Id: "c", Pool: "easy"},
+		{Id: "d", Pool: "easy"},
+		{Id: "e", Pool: "other"},
+	}
+}
+
+// TestDraw tests without pool and tests of pool, which isn't set for stage, are given to every learner
+func TestDraw(t *testing.T) {
+	pools := []*common.QuestionPool{{Name: "easy", DrawCount: 2}}
+
+	for seed := int64(0); seed < 20; seed++ {
+		drawn := Draw(poolTests(), pools, seed)
+
+		if len(drawn) != 4 || drawn[0].Id != "a" || drawn[3].Id != "e" {
+			t.Fatalf("seed %d: unexpected tests %v", seed, ids(drawn))
+		}
+
+		if !reflect.DeepEqual(ids(drawn), ids(Draw(poolTests(), pools, seed))) {
+			t.Fatalf("seed %d: draw isn't repeated", seed)
+		}
+	}
+}
+
+// TestMissingTests only tests without pool are added to stored selection
+func TestMissingTests(t *testing.T) {
+	pools := []*common.QuestionPool{{Name: "easy", DrawCount: 1}}
+	stored := &common.TestSelection{TestIds: []string{"b"}}
+
+	missing := missingTests(poolTests(), pools, stored)
+
+	if !reflect.DeepEqual(missing, []string{"a", "e"}) {
+		t.Errorf("unexpected missing tests %v", missing)
+	}
+}
+
+// TestSnapshot options are saved in order shown by preview of the same attempt
+func TestSnapshot(t *testing.T) {
+	test := &common.Test{
+		Id: "a",
+		OptionTest: &common.OptionTest{
+			Question: "Capital of France?",
+			Options:  []*common.Option{{Answer: "Paris"}, {Answer: "Rome"}, {Answer: "Berlin"}, {Answer: "Madrid"}},
+		},
+	}
+
+	stored := &common.TestSelection{Seed: 42}
+	result := &common.GradeResult{}
+
+	Snapshot(result, test, stored, 3)
+
+	shown := Shuffle(OptionAnswers(test), AttemptSeed(stored.Seed, test.Id, 3))
+
+	if result.Question != "Capital of France?" || !reflect.DeepEqual(result.Options, shown) {
+		t.Errorf("unexpected snapshot %q %v, shown %v", result.Question, result.Options, shown)
+	}
+
+	test.OptionTest.Options[0].Answer = "Lyon"
+
+	if reflect.DeepEqual(result.Options, Shuffle(OptionAnswers(test), AttemptSeed(stored.Seed, test.Id, 3))) {
+		t.Error("snapshot is changed with test")
+	}
+}
+
+func ids(tests []*common.Test) []string {
+	var result []string
+
+	for _, test := range tests {
+		result = append(result, test.Id)
+	}
+
+	return result
+}
@@ -0,0 +1,196 @@
+package integration
+
+import (
+	"errors"
+	"opencourse/clock"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/database"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+getLimitedTest add course, stage and rewrite test with attempts limit and cooldown. Returns course id and test.
+Parameters:
+context - connected database context;
+maxAttempts - max count of attempts;
+cooldown - min seconds between attempts;
+*/
+func getLimitedTest(t *testing.T, context *database.DbContext, maxAttempts int, cooldown int) (string, *common.Test) {
+	addCourseQuery := getAddCourseQuery()
+	courseId, err := context.AddCourse(&addCourseQuery)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stageId, err := context.AddStage(&common.AddStageQuery{
+		CourseId: courseId,
+		Name:     "Limited",
+		Content:  &common.PostContent{Body: "package main"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testId, err := context.AddTest(&common.AddTestQuery{
+		StageId:     stageId,
+		TestType:    common.TestRewrite,
+		MaxAttempts: maxAttempts,
+		Cooldown:    cooldown,
+		RewriteTest: &common.RewriteTest{Question: "Keyword of function", RightAnswer: "func"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test, err := context.GetTest(testId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return courseId, test
+}
+
+// TestAttemptLimit the last allowed attempt is saved, the next one is denied
+func TestAttemptLimit(t *testing.T) {
+	context := getContext()
+	context.Clock = clock.NewManualClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	courseId, test := getLimitedTest(t, context, 2, 0)
+	userId := primitive.NewObjectID().Hex()
+	answer := &common.TestAnswer{Text: "fn"}
+
+	for number := 1; number <= 2; number++ {
+		err = context.CheckAttempt(userId, test)
+
+		if err != nil {
+			t.Fatalf("attempt %d must be allowed: %v", number, err)
+		}
+
+		attempt, _, err := context.SaveTestResult(userId, courseId, test, answer, &common.GradeResult{})
+
+		if err != nil {
+			t.Fatalf("attempt %d must be saved: %v", number, err)
+		}
+
+		if attempt.Number != number {
+			t.Errorf("expected attempt number %d, got %d", number, attempt.Number)
+		}
+	}
+
+	var denied openerrors.AttemptDeniedErr
+
+	err = context.CheckAttempt(userId, test)
+
+	if !errors.As(err, &denied) || denied.Attempts != 2 || denied.MaxAttempts != 2 {
+		t.Fatalf("attempt after limit must be denied, got %v", err)
+	}
+
+	_, _, err = context.SaveTestResult(userId, courseId, test, answer, &common.GradeResult{})
+
+	if !errors.As(err, &denied) {
+		t.Fatalf("attempt after limit mustn't be saved, got %v", err)
+	}
+
+	attempts, err := context.GetAttempts(userId, test.Id)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attempts) != 2 {
+		t.Errorf("expected 2 saved attempts, got %d", len(attempts))
+	}
+}
+
+// TestAttemptCooldown attempt is denied until cooldown from date of the last attempt is over
+func TestAttemptCooldown(t *testing.T) {
+	context := getContext()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	manual := clock.NewManualClock(start)
+	context.Clock = manual
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	courseId, test := getLimitedTest(t, context, 0, 60)
+	userId := primitive.NewObjectID().Hex()
+	answer := &common.TestAnswer{Text: "fn"}
+
+	_, _, err = context.SaveTestResult(userId, courseId, test, answer, &common.GradeResult{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manual.Advance(59 * time.Second)
+
+	var denied openerrors.AttemptDeniedErr
+
+	err = context.CheckAttempt(userId, test)
+
+	if !errors.As(err, &denied) {
+		t.Fatalf("attempt before cooldown is over must be denied, got %v", err)
+	}
+
+	if !denied.RetryAfter.Equal(start.Add(60 * time.Second)) {
+		t.Errorf("expected retry after %v, got %v", start.Add(60*time.Second), denied.RetryAfter)
+	}
+
+	_, _, err = context.SaveTestResult(userId, courseId, test, answer, &common.GradeResult{})
+
+	if !errors.As(err, &denied) {
+		t.Fatalf("attempt before cooldown is over mustn't be saved, got %v", err)
+	}
+
+	manual.Advance(time.Second)
+
+	err = context.CheckAttempt(userId, test)
+
+	if err != nil {
+		t.Fatalf("attempt after cooldown must be allowed: %v", err)
+	}
+
+	_, _, err = context.SaveTestResult(userId, courseId, test, answer, &common.GradeResult{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cooldown starts again from date of the new attempt
+	manual.Advance(30 * time.Second)
+
+	err = context.CheckAttempt(userId, test)
+
+	if !errors.As(err, &denied) || !denied.RetryAfter.Equal(start.Add(120*time.Second)) {
+		t.Fatalf("attempt in cooldown of the new attempt must be denied, got %v", err)
+	}
+}
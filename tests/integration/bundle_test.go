@@ -0,0 +1,196 @@
+package integration
+
+import (
+	"opencourse/common"
+	"opencourse/events"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getCourseBundle() *common.CourseBundle {
+	return &common.CourseBundle{
+		Version: common.BundleVersion,
+		Id:      primitive.NewObjectID().Hex(),
+		Course:  &common.Course{Id: "course", Name: "The greatest golang", Lang: common.LangEn},
+		Stages: []*common.BundleStage{
+			{
+				Stage: &common.Stage{Id: "stage", Name: "Hello world", Content: &common.PostContent{Body: "package main"}},
+				Tests: []*common.Test{
+					{
+						Id:            "test",
+						TestType:      common.TestRewrite,
+						LemmingsCount: 1,
+						RewriteTest:   &common.RewriteTest{Question: "Keyword of function", RightAnswer: "func"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// TestImportCourse
+func TestImportCourse(t *testing.T) {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	categoryId := primitive.NewObjectID().Hex()
+	authorId := primitive.NewObjectID().Hex()
+	courseBundle := getCourseBundle()
+
+	id, err := context.ImportCourse(courseBundle, categoryId, authorId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	course, err := context.GetCourse(id)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if course == nil || course.AuthorId != authorId {
+		t.Fatal("imported course must belong to author")
+	}
+
+	// Repeated import of author updates the same course
+	reimportId, err := context.ImportCourse(courseBundle, categoryId, authorId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reimportId != id {
+		t.Error("repeated import must update the same course")
+	}
+
+	// The same bundle of other author creates own copy and doesn't change course of the first author
+	courseBundle.Stages = nil
+
+	otherId, err := context.ImportCourse(courseBundle, categoryId, primitive.NewObjectID().Hex())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if otherId == id {
+		t.Fatal("import of other author must create new course")
+	}
+
+	stages, err := context.GetStages(id, 10, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stages) != 1 {
+		t.Error("import of other author mustn't delete stages")
+	}
+}
+
+// TestImportCourseInvalidTest
+func TestImportCourseInvalidTest(t *testing.T) {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	courseBundle := getCourseBundle()
+
+	// Option test without right option is rejected as in AddTest
+	courseBundle.Stages[0].Tests[0] = &common.Test{
+		Id:            "test",
+		TestType:      common.TestOption,
+		LemmingsCount: 1,
+		OptionTest: &common.OptionTest{
+			Question: "Keyword of function",
+			Options:  []*common.Option{{Answer: "func"}, {Answer: "fn"}},
+		},
+	}
+
+	_, err = context.ImportCourse(courseBundle, primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex())
+
+	if err == nil {
+		t.Error("test without right option must be rejected")
+	}
+}
+
+// TestImportCourseEvents saved course and removed stages are published after import
+func TestImportCourseEvents(t *testing.T) {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	var published []events.Event
+
+	context.Events = events.NewBus()
+	context.Events.Subscribe("", func(event events.Event) error {
+		published = append(published, event)
+		return nil
+	})
+
+	categoryId := primitive.NewObjectID().Hex()
+	authorId := primitive.NewObjectID().Hex()
+	courseBundle := getCourseBundle()
+
+	id, err := context.ImportCourse(courseBundle, categoryId, authorId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(published) != 1 || published[0].Type != events.CourseSaved || published[0].CourseId != id {
+		t.Fatalf("unexpected events of import %+v", published)
+	}
+
+	stages, err := context.GetStages(id, 10, 0)
+
+	if err != nil || len(stages) != 1 {
+		t.Fatalf("imported stage isn't found: %v", err)
+	}
+
+	published = nil
+	courseBundle.Stages = nil
+
+	_, err = context.ImportCourse(courseBundle, categoryId, authorId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(published) != 2 || published[0].Type != events.StageDeleted || published[0].StageId != stages[0].Id ||
+		published[1].Type != events.CourseSaved {
+		t.Errorf("unexpected events of repeated import %+v", published)
+	}
+}
@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"opencourse/common"
+	"opencourse/database"
+	"testing"
+)
+
+/*
+addCategory add category and return its id. Parameters:
+context - connected database context;
+parentId - parent category id. Empty for root;
+lang - category language;
+*/
+func addCategory(t *testing.T, context *database.DbContext, parentId string, lang string) string {
+	id, err := context.AddCategory(&common.AddCategoryQuery{ParentId: parentId, Lang: lang, Name: "Category " + lang})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+/*
+getCategories return categories of language by id. Parameters:
+context - connected database context;
+lang - categories language;
+*/
+func getCategories(t *testing.T, context *database.DbContext, lang string) map[string]*common.Category {
+	categories, err := context.GetCategories(lang)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byId := make(map[string]*common.Category, len(categories))
+
+	for _, category := range categories {
+		byId[category.Id] = category
+	}
+
+	return byId
+}
+
+// TestMoveAndDeleteCategory paths and course counts of subtree follow moved and deleted categories
+func TestMoveAndDeleteCategory(t *testing.T) {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// a -> b -> c and root d
+	a := addCategory(t, context, "", common.LangDe)
+	b := addCategory(t, context, a, common.LangDe)
+	c := addCategory(t, context, b, common.LangDe)
+	d := addCategory(t, context, "", common.LangDe)
+	foreign := addCategory(t, context, "", common.LangFr)
+
+	for _, categoryId := range []string{b, c} {
+		addCourseQuery := getAddCourseQuery()
+		addCourseQuery.CategoryId = categoryId
+
+		_, err = context.AddCourse(&addCourseQuery)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Category can't be moved to own subtree
+	for _, parentId := range []string{b, c} {
+		if context.MoveCategory(b, parentId) == nil {
+			t.Errorf("category mustn't be moved to own subtree %s", parentId)
+		}
+	}
+
+	err = context.MoveCategory(b, d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	categories := getCategories(t, context, common.LangDe)
+
+	for id, expected := range map[string]string{b: "/" + d + "/" + b + "/", c: "/" + d + "/" + b + "/" + c + "/"} {
+		if categories[id].Path != expected {
+			t.Errorf("category %s: expected path %s, got %s", id, expected, categories[id].Path)
+		}
+	}
+
+	for id, expected := range map[string]int{a: 0, b: 2, c: 1, d: 2} {
+		if categories[id].CourseCount != expected {
+			t.Errorf("category %s after move: expected %d courses, got %d", id, expected, categories[id].CourseCount)
+		}
+	}
+
+	// Courses can't be moved to subtree of deleted category or to category with other language
+	for _, targetId := range []string{c, foreign} {
+		if context.DeleteCategory(b, targetId) == nil {
+			t.Errorf("courses of deleted category mustn't be moved to %s", targetId)
+		}
+	}
+
+	err = context.DeleteCategory(b, a)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	categories = getCategories(t, context, common.LangDe)
+
+	if _, ok := categories[b]; ok {
+		t.Error("category must be deleted")
+	}
+
+	if categories[c].ParentId != d || categories[c].Path != "/"+d+"/"+c+"/" {
+		t.Errorf("child must be moved to parent of deleted category, got %s %s", categories[c].ParentId, categories[c].Path)
+	}
+
+	for id, expected := range map[string]int{a: 1, c: 1, d: 1} {
+		if categories[id].CourseCount != expected {
+			t.Errorf("category %s after delete: expected %d courses, got %d", id, expected, categories[id].CourseCount)
+		}
+	}
+}
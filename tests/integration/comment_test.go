@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"opencourse/common"
+	"opencourse/database"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+addComment add comment to stage and return it. Parameters:
+context - connected database context;
+stageId - stage id;
+parentId - replied comment id. Empty for thread;
+*/
+func addComment(t *testing.T, context *database.DbContext, stageId string, parentId string) *common.StageComment {
+	comment, err := context.AddStageComment(primitive.NewObjectID().Hex(), "Learner", stageId,
+		&common.AddStageCommentQuery{ParentId: parentId, Body: "Comment"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return comment
+}
+
+/*
+getComment return comment. Parameters:
+context - connected database context;
+commentId - comment id;
+*/
+func getComment(t *testing.T, context *database.DbContext, commentId string) *common.StageComment {
+	comment, err := context.GetStageComment(commentId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return comment
+}
+
+// TestUnmarkCommentAnswer removing mark of reply, which isn't answer, keeps answer of thread
+func TestUnmarkCommentAnswer(t *testing.T) {
+	stageId := getAuthorStage(t, primitive.NewObjectID().Hex())
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := addComment(t, context, stageId, "")
+	answer := addComment(t, context, stageId, root.Id)
+	other := addComment(t, context, stageId, root.Id)
+
+	_, err = context.MarkCommentAnswer(answer.Id, true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := context.MarkCommentAnswer(other.Id, false)
+
+	if err != nil || !found {
+		t.Fatalf("reply must be found, got %v, %v", found, err)
+	}
+
+	if getComment(t, context, root.Id).AnswerId != answer.Id || !getComment(t, context, answer.Id).IsAnswer {
+		t.Error("answer of thread mustn't be removed by other reply")
+	}
+
+	_, err = context.MarkCommentAnswer(answer.Id, false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if getComment(t, context, root.Id).AnswerId != "" || getComment(t, context, answer.Id).IsAnswer {
+		t.Error("answer of thread must be removed by answer reply")
+	}
+}
+
+// TestReplyClosedComment hidden or deleted comment isn't replied, reply of reply needs open root too
+func TestReplyClosedComment(t *testing.T) {
+	stageId := getAuthorStage(t, primitive.NewObjectID().Hex())
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, item := range []struct {
+		name  string
+		root  bool
+		close func(root *common.StageComment, reply *common.StageComment) (bool, error)
+	}{
+		{"hidden root", true, func(root *common.StageComment, reply *common.StageComment) (bool, error) {
+			return context.HideStageComment(root.Id, true)
+		}},
+		{"deleted root", true, func(root *common.StageComment, reply *common.StageComment) (bool, error) {
+			return context.DeleteStageComment(root.Id)
+		}},
+		{"hidden reply", false, func(root *common.StageComment, reply *common.StageComment) (bool, error) {
+			return context.HideStageComment(reply.Id, true)
+		}},
+		{"deleted reply", false, func(root *common.StageComment, reply *common.StageComment) (bool, error) {
+			return context.DeleteStageComment(reply.Id)
+		}},
+	} {
+		root := addComment(t, context, stageId, "")
+		reply := addComment(t, context, stageId, root.Id)
+
+		_, err = item.close(root, reply)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Reply of closed root is rejected for replies of root and of its replies
+		parents := []string{reply.Id}
+
+		if item.root {
+			parents = append(parents, root.Id)
+		}
+
+		for _, parentId := range parents {
+			_, err = context.AddStageComment(primitive.NewObjectID().Hex(), "Learner", stageId,
+				&common.AddStageCommentQuery{ParentId: parentId, Body: "Reply"})
+
+			if err == nil {
+				t.Errorf("%s: reply to %s must be rejected", item.name, parentId)
+			}
+		}
+
+		if getComment(t, context, root.Id).ReplyCount != 1 {
+			t.Errorf("%s: rejected reply mustn't be counted", item.name)
+		}
+	}
+}
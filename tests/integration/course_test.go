@@ -16,7 +16,8 @@ func getContext() *database.DbContext {
 	context := &database.DbContext{}
 
 	// Init default values
-	context.Defaults(os.Getenv("OPENCOURSE_CON_STR"))
+	context.Defaults(os.Getenv("OPENCOURSE_CON_STR"), os.Getenv("OPENCOURSE_SMTP_ACCOUNT"),
+		os.Getenv("OPENCOURSE_SMTP_ACCOUNT_PASS"), os.Getenv("OPENCOURSE_ENDPOINT"))
 
 	return context
 }
@@ -251,3 +252,121 @@ func TestRemoveCourseTags(t *testing.T) {
 		}
 	}
 }
+
+// TestCloneCourse
+func TestCloneCourse(t *testing.T) {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	addCourseQuery := getAddCourseQuery()
+	id, err := context.AddCourse(&addCourseQuery)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = context.AddStage(&common.AddStageQuery{
+		CourseId:    id,
+		Name:        "Hello world",
+		Content:     &common.PostContent{Body: "package main"},
+		HeaderImg:   "header.png",
+		OrderNumber: 1,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cloneId, err := context.CloneCourse(id, "", &common.CloneCourseQuery{Lang: common.LangRu})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cloneCourse, err := context.GetCourse(cloneId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cloneCourse.Lang != common.LangRu || cloneCourse.Name != addCourseQuery.Name {
+		t.Error("clone options are not applied")
+	}
+
+	stages, err := context.GetStages(cloneId, 10, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stages) != 1 || stages[0].CourseId != cloneId {
+		t.Error("clone must contains 1 stage with new course id")
+	}
+}
+
+// TestGetCoursesTranslated
+func TestGetCoursesTranslated(t *testing.T) {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	addCourseQuery := getAddCourseQuery()
+	addCourseQuery.Lang = common.LangEn
+	id, err := context.AddCourse(&addCourseQuery)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cloneId, err := context.CloneCourse(id, "", &common.CloneCourseQuery{Lang: common.LangRu})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = context.LinkTranslation(common.TranslationCourse, id, cloneId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		langs []string
+		id    string
+	}{
+		{[]string{common.LangRu, common.LangEn}, cloneId},
+		{[]string{common.LangEn}, id},
+		{[]string{common.LangDe}, id},
+	} {
+		courses, _, err := context.GetCoursesPage(addCourseQuery.CategoryId, test.langs, &common.PageQuery{Take: 10})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(courses) != 1 || courses[0].Id != test.id {
+			t.Errorf("translation group must be listed once as variant %s for %v", test.id, test.langs)
+		}
+	}
+}
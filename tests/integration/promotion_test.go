@@ -0,0 +1,184 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"opencourse/clock"
+	"opencourse/common"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// TestPromotionSchedule promotion is shown in feed and labels until expiration time, past expiration is rejected
+func TestPromotionSchedule(t *testing.T) {
+	context := getContext()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	manual := clock.NewManualClock(start)
+	context.Clock = manual
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	addCourseQuery := getAddCourseQuery()
+	courseId, err := context.AddCourse(&addCourseQuery)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired promotion isn't added
+	_, err = context.AddPromotion(&common.AddPromotionQuery{
+		CourseId:       courseId,
+		PromotionType:  common.PromotionNew,
+		Label:          "Expired",
+		ExpirationTime: start,
+	})
+
+	if err == nil {
+		t.Error("promotion with expiration time in past must be rejected")
+	}
+
+	for _, query := range []*common.AddPromotionQuery{
+		{CourseId: courseId, PromotionType: common.PromotionNew, Label: "New", ExpirationTime: start.Add(time.Hour)},
+		{CourseId: courseId, PromotionType: common.PromotionUpdate, Label: "Updated", ExpirationTime: start.Add(2 * time.Hour)},
+	} {
+		_, err = context.AddPromotion(query)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// labels return labels of course from feed and course labels
+	labels := func() ([]string, []string) {
+		promotions, err := context.GetPromotionFeed(common.MaxPageSize)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var feed []string
+
+		for _, promotion := range promotions {
+			if promotion.CourseId == courseId {
+				feed = append(feed, promotion.Label)
+			}
+		}
+
+		course := &common.Course{Id: courseId}
+
+		err = context.AttachPromotionLabels([]*common.Course{course})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return feed, course.Labels
+	}
+
+	for _, item := range []struct {
+		advance time.Duration
+		feed    []string
+		labels  []string
+	}{
+		{0, []string{"Updated", "New"}, []string{"New", "Updated"}},
+		{time.Hour, []string{"Updated"}, []string{"Updated"}},
+		{time.Hour, nil, nil},
+	} {
+		manual.Advance(item.advance)
+
+		feed, courseLabels := labels()
+
+		if !slices.Equal(feed, item.feed) || !slices.Equal(courseLabels, item.labels) {
+			t.Errorf("at %v: expected feed %v and labels %v, got %v and %v",
+				manual.Now(), item.feed, item.labels, feed, courseLabels)
+		}
+	}
+}
+
+// TestPromotionFeedRoute feed is public, promotions are created by admin only
+func TestPromotionFeedRoute(t *testing.T) {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	addCourseQuery := getAddCourseQuery()
+	courseId, err := context.AddCourse(&addCourseQuery)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := getRouter(context)
+
+	body := getPayload(t, common.AddPromotionQuery{
+		CourseId:       courseId,
+		PromotionType:  common.PromotionNew,
+		Label:          "Route",
+		ExpirationTime: time.Now().Add(time.Hour),
+	})
+
+	response := serve(t, router, http.MethodPost, "/promotions", body, "author", common.RoleAuthor)
+
+	if response.Code != http.StatusForbidden {
+		t.Errorf("author: expected 403, got %d %s", response.Code, response.Body)
+	}
+
+	response = serve(t, router, http.MethodPost, "/promotions", body, "admin", common.RoleAdmin)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("admin: expected 200, got %d %s", response.Code, response.Body)
+	}
+
+	// Feed is requested without token
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/promotions?take=100", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("feed: expected 200, got %d %s", recorder.Code, recorder.Body)
+	}
+
+	var feed struct {
+		Payload []*common.DbCoursePromotion `json:"payload"`
+	}
+
+	err = json.Unmarshal(recorder.Body.Bytes(), &feed)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+
+	for _, promotion := range feed.Payload {
+		if promotion.CourseId == courseId {
+			found = promotion.Label == "Route" && promotion.Course != nil && promotion.Course.Id == courseId
+		}
+	}
+
+	if !found {
+		t.Error("feed must contain promotion with course")
+	}
+}
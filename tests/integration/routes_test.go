@@ -0,0 +1,115 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opencourse/common"
+	"opencourse/database"
+	"opencourse/notifications"
+	v1 "opencourse/openrouters/v1"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tokenAuth = jwtauth.New("HS256", []byte("integration"), nil)
+
+// getRouter return api routes of context
+func getRouter(context *database.DbContext) http.Handler {
+	return v1.RouteTable(*context, tokenAuth, nil, nil, nil, notifications.NewHub(), nil)
+}
+
+/*
+serve send request of user to router and return response. Parameters:
+router - api routes;
+method - http method;
+url - request url;
+body - request body. Optional, may be set empty string;
+userId - user id of token;
+roles - roles of token;
+*/
+func serve(t *testing.T, router http.Handler, method string, url string, body string, userId string,
+	roles ...string) *httptest.ResponseRecorder {
+
+	_, token, err := tokenAuth.Encode(map[string]interface{}{
+		"id":    userId,
+		"roles": strings.Join(roles, ","),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(method, url, strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+// TestContentNotFound missing content is returned as nil and answered by 404
+func TestContentNotFound(t *testing.T) {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	missingId := primitive.NewObjectID().Hex()
+
+	course, err := context.GetCourse(missingId)
+
+	if err != nil || course != nil {
+		t.Errorf("missing course must be nil without error, got %v, %v", course, err)
+	}
+
+	stage, err := context.GetStage(missingId)
+
+	if err != nil || stage != nil {
+		t.Errorf("missing stage must be nil without error, got %v, %v", stage, err)
+	}
+
+	test, err := context.GetTest(missingId)
+
+	if err != nil || test != nil {
+		t.Errorf("missing test must be nil without error, got %v, %v", test, err)
+	}
+
+	router := getRouter(context)
+	authorId := primitive.NewObjectID().Hex()
+
+	for _, item := range []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{http.MethodGet, "/courses/" + missingId, ""},
+		{http.MethodPost, "/courses/" + missingId + "/clone", `{"payload": {}}`},
+		{http.MethodGet, "/stages/" + missingId, ""},
+		{http.MethodPost, "/translations",
+			`{"payload": {"kind": "` + common.TranslationTest + `", "source_id": "` + missingId + `", "translation_id": "` + missingId + `"}}`},
+		{http.MethodPost, "/translations",
+			`{"payload": {"kind": "` + common.TranslationStage + `", "source_id": "` + missingId + `", "translation_id": "` + missingId + `"}}`},
+	} {
+		response := serve(t, router, item.method, item.url, item.body, authorId, common.RoleAuthor)
+
+		if response.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected 404, got %d %s", item.method, item.url, response.Code, response.Body)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package integration
+
+import (
+	gocontext "context"
+	"errors"
+	"opencourse/clock"
+	"opencourse/common"
+	"opencourse/common/openerrors"
+	"opencourse/quizsession"
+	"opencourse/sandbox"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestSaveSessionAnswerDeadline answer after deadline is rejected and session is returned as expired
+func TestSaveSessionAnswerDeadline(t *testing.T) {
+	context := getContext()
+	manual := clock.NewManualClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	context.Clock = manual
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	addCourseQuery := getAddCourseQuery()
+	courseId, err := context.AddCourse(&addCourseQuery)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stageId, err := context.AddStage(&common.AddStageQuery{
+		CourseId:  courseId,
+		Name:      "Timed",
+		Content:   &common.PostContent{Body: "package main"},
+		TimeLimit: 60,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testId, err := context.AddTest(&common.AddTestQuery{
+		StageId:     stageId,
+		TestType:    common.TestRewrite,
+		RewriteTest: &common.RewriteTest{Question: "Keyword of function", RightAnswer: "func"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userId := primitive.NewObjectID().Hex()
+
+	session, err := context.StartQuizSession(userId, stageId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manual.Advance(59 * time.Second)
+
+	err = context.SaveSessionAnswer(session.Id, testId, &common.TestAnswer{Text: "func"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manual.Advance(time.Second)
+
+	err = context.SaveSessionAnswer(session.Id, testId, &common.TestAnswer{Text: "fn"})
+
+	var closedErr openerrors.SessionClosedErr
+
+	if !errors.As(err, &closedErr) {
+		t.Fatalf("answer after deadline must be rejected, got %v", err)
+	}
+
+	expired, err := context.GetExpiredQuizSessions(10)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(expired) != 1 || expired[0].Id != session.Id {
+		t.Error("session after deadline must be returned for auto-submit")
+	}
+}
+
+// failExecutor executor, which can't run programs
+type failExecutor struct{}
+
+func (executor failExecutor) Run(ctx gocontext.Context, program *sandbox.Program, inputs []string) (*sandbox.Report, error) {
+	return nil, errors.New("sandbox is unavailable")
+}
+
+// TestSubmitGradingError session with answer, which can't be graded, is closed with zero score for it
+func TestSubmitGradingError(t *testing.T) {
+	context := getContext()
+	context.Clock = clock.NewManualClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	addCourseQuery := getAddCourseQuery()
+	courseId, err := context.AddCourse(&addCourseQuery)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stageId, err := context.AddStage(&common.AddStageQuery{
+		CourseId:  courseId,
+		Name:      "Timed",
+		Content:   &common.PostContent{Body: "package main"},
+		TimeLimit: 60,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testId, err := context.AddTest(&common.AddTestQuery{
+		StageId:  stageId,
+		TestType: common.TestCode,
+		CodeTest: &common.CodeTest{
+			Question: "Sum of two numbers",
+			Language: sandbox.LanguageGo,
+			Cases:    []*common.CodeCase{{Input: "1 2", ExpectedOutput: "3"}},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	onErr := func(err error) { errs = append(errs, err) }
+	userId := primitive.NewObjectID().Hex()
+
+	session, err := quizsession.Start(gocontext.Background(), context, failExecutor{}, userId, stageId, onErr)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = context.SaveSessionAnswer(session.Id, testId, &common.TestAnswer{Code: "package main"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err = quizsession.Submit(gocontext.Background(), context, failExecutor{}, session.Id, onErr)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if session.Status != common.SessionSubmitted {
+		t.Errorf("session must be submitted, got %s", session.Status)
+	}
+
+	if len(session.Results) != 1 || !session.Results[0].Answered || session.Results[0].Score != 0 {
+		t.Errorf("answer, which isn't graded, must get zero score, got %+v", session.Results)
+	}
+
+	if len(errs) != 1 {
+		t.Errorf("grading error must be passed to handler, got %v", errs)
+	}
+
+	attempts, err := context.GetAttempts(userId, testId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attempts) != 0 {
+		t.Errorf("attempt mustn't be saved for answer, which isn't graded, got %d", len(attempts))
+	}
+}
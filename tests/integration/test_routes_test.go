@@ -0,0 +1,167 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"opencourse/common"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+getAuthorStage add course of author with one stage and return stage id. Parameters:
+authorId - author of course;
+*/
+func getAuthorStage(t *testing.T, authorId string) string {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	addCourseQuery := getAddCourseQuery()
+	addCourseQuery.AuthorId = authorId
+	courseId, err := context.AddCourse(&addCourseQuery)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stageId, err := context.AddStage(&common.AddStageQuery{
+		CourseId: courseId,
+		Name:     "Hello world",
+		Content:  &common.PostContent{Body: "package main"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stageId
+}
+
+// getPayload return request body with payload
+func getPayload(t *testing.T, payload interface{}) string {
+	data, err := json.Marshal(map[string]interface{}{"payload": payload})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+// TestImportTestsAccess author imports tests only to stages of own courses
+func TestImportTestsAccess(t *testing.T) {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	authorId := primitive.NewObjectID().Hex()
+	stageId := getAuthorStage(t, authorId)
+	router := getRouter(context)
+
+	body := getPayload(t, common.ImportTestsQuery{Format: common.QuizFormatGift, Content: "What is 2+2? {=4 ~3 ~5}"})
+	url := "/stages/" + stageId + "/tests/import"
+
+	response := serve(t, router, http.MethodPost, url, body, primitive.NewObjectID().Hex(), common.RoleAuthor)
+
+	if response.Code != http.StatusForbidden {
+		t.Errorf("other author: expected 403, got %d %s", response.Code, response.Body)
+	}
+
+	tests, err := context.GetStageTests(stageId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tests) != 0 {
+		t.Fatalf("tests of other author mustn't be imported, got %d", len(tests))
+	}
+
+	response = serve(t, router, http.MethodPost, url, body, authorId, common.RoleAuthor)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("author: expected 200, got %d %s", response.Code, response.Body)
+	}
+
+	tests, err = context.GetStageTests(stageId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tests) != 1 {
+		t.Errorf("test of author must be imported, got %d", len(tests))
+	}
+}
+
+// TestPostTestAccess author adds tests only to stages of own courses
+func TestPostTestAccess(t *testing.T) {
+	context := getContext()
+
+	err := context.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = context.Disconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	authorId := primitive.NewObjectID().Hex()
+	stageId := getAuthorStage(t, authorId)
+	router := getRouter(context)
+
+	body := getPayload(t, common.AddTestQuery{
+		StageId:       stageId,
+		TestType:      common.TestNumeric,
+		LemmingsCount: 1,
+		NumericTest:   &common.NumericTest{Question: "2 + 2", Answer: 4},
+	})
+
+	response := serve(t, router, http.MethodPost, "/tests", body, primitive.NewObjectID().Hex(), common.RoleAuthor)
+
+	if response.Code != http.StatusForbidden {
+		t.Errorf("other author: expected 403, got %d %s", response.Code, response.Body)
+	}
+
+	response = serve(t, router, http.MethodPost, "/tests", body, authorId, common.RoleAuthor)
+
+	if response.Code != http.StatusOK {
+		t.Errorf("author: expected 200, got %d %s", response.Code, response.Body)
+	}
+
+	tests, err := context.GetStageTests(stageId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tests) != 1 {
+		t.Errorf("only test of author must be added, got %d", len(tests))
+	}
+}